@@ -287,7 +287,7 @@ func importChain(ctx *cli.Context) error {
 	// Start metrics export if enabled
 	utils.SetupMetrics(&cfg.Metrics)
 
-	chain, db := utils.MakeChain(ctx, stack, false)
+	chain, db := utils.MakeChain(ctx, stack, false, cfg.Eth.Indexer)
 	defer db.Close()
 
 	// Start periodically gathering memory profiles
@@ -359,10 +359,10 @@ func exportChain(ctx *cli.Context) error {
 		utils.Fatalf("This command requires an argument.")
 	}
 
-	stack, _ := makeConfigNode(ctx)
+	stack, cfg := makeConfigNode(ctx)
 	defer stack.Close()
 
-	chain, db := utils.MakeChain(ctx, stack, true)
+	chain, db := utils.MakeChain(ctx, stack, true, cfg.Eth.Indexer)
 	defer db.Close()
 	start := time.Now()
 
@@ -397,10 +397,10 @@ func importHistory(ctx *cli.Context) error {
 		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
 	}
 
-	stack, _ := makeConfigNode(ctx)
+	stack, cfg := makeConfigNode(ctx)
 	defer stack.Close()
 
-	chain, db := utils.MakeChain(ctx, stack, false)
+	chain, db := utils.MakeChain(ctx, stack, false, cfg.Eth.Indexer)
 	defer db.Close()
 
 	var (
@@ -453,10 +453,10 @@ func exportHistory(ctx *cli.Context) error {
 		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
 	}
 
-	stack, _ := makeConfigNode(ctx)
+	stack, cfg := makeConfigNode(ctx)
 	defer stack.Close()
 
-	chain, _ := utils.MakeChain(ctx, stack, true)
+	chain, _ := utils.MakeChain(ctx, stack, true, cfg.Eth.Indexer)
 	start := time.Now()
 
 	var (
@@ -197,26 +197,16 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	utils.SetupMetrics(&cfg.Metrics)
 
 	// Create indexer plugin if enabled
-	var indexerPlugin *core.IndexerPlugin
-	if ctx.Bool(utils.IndexerEnabledFlag.Name) {
-		indexerConfig := core.IndexerConfig{
-			Host:     ctx.String(utils.IndexerHostFlag.Name),
-			Port:     ctx.Int(utils.IndexerPortFlag.Name),
-			User:     ctx.String(utils.IndexerUserFlag.Name),
-			Password: ctx.String(utils.IndexerPasswordFlag.Name),
-			DBName:   ctx.String(utils.IndexerDBNameFlag.Name),
-			SSLMode:  ctx.String(utils.IndexerSSLModeFlag.Name),
-		}
-
-		db, err := core.NewDB(indexerConfig)
+	if cfg.Eth.Indexer.Enabled {
+		db, err := core.NewDB(cfg.Eth.Indexer)
 		if err != nil {
 			utils.Fatalf("Failed to create indexer db: %v", err)
 		}
-		indexerPlugin = core.NewIndexerPlugin(db)
+		cfg.Eth.Plugins = append(cfg.Eth.Plugins, utils.MakeIndexerPlugin(cfg.Eth.Indexer, db))
 	}
 
-	// Register Ethereum service with optional indexer plugin
-	backend, eth := utils.RegisterEthService(stack, &cfg.Eth, indexerPlugin)
+	// Register Ethereum service with any configured chain plugins
+	backend, eth := utils.RegisterEthService(stack, &cfg.Eth)
 
 	// Create gauge with geth system and build information
 	if eth != nil { // The 'eth' backend may be nil in light mode
@@ -0,0 +1,276 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"runtime"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	backfillFromFlag = &cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block to index",
+	}
+	backfillToFlag = &cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block to index (default = current head)",
+	}
+	backfillWorkersFlag = &cli.IntFlag{
+		Name:  "workers",
+		Usage: "Number of batches to index concurrently",
+		Value: runtime.NumCPU(),
+	}
+	backfillBatchFlag = &cli.Uint64Flag{
+		Name:  "batch",
+		Usage: "Number of blocks to index in a single database transaction (default = --indexer.batch)",
+	}
+	verifyFromFlag = &cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block to verify",
+		Value: 1,
+	}
+	verifyToFlag = &cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block to verify (default = current head)",
+	}
+	verifyRepairFlag = &cli.BoolFlag{
+		Name:  "repair",
+		Usage: "Repair the block ranges with divergences",
+	}
+
+	indexerCommand = &cli.Command{
+		Name:      "indexer",
+		Usage:     "Indexer database operations",
+		ArgsUsage: "",
+		Subcommands: []*cli.Command{
+			indexerBackfillCmd,
+			indexerMigrateCmd,
+			indexerStatusCmd,
+			indexerVerifyCmd,
+		},
+	}
+	indexerBackfillCmd = &cli.Command{
+		Action: indexerBackfill,
+		Name:   "backfill",
+		Usage:  "Index historical blocks from the local chain database",
+		Flags: slices.Concat([]cli.Flag{
+			backfillFromFlag,
+			backfillToFlag,
+			backfillWorkersFlag,
+			backfillBatchFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags, utils.IndexerFlags),
+		Description: `
+The backfill command replays the stored canonical blocks and receipts in the
+given range through the indexer. Blocks which are already indexed are skipped,
+so it can also be used to fill gaps left by a database outage. Progress is
+checkpointed in the indexer database, an interrupted backfill of the same range
+resumes where it left off.`,
+	}
+	indexerMigrateCmd = &cli.Command{
+		Action: indexerMigrate,
+		Name:   "migrate",
+		Usage:  "Upgrade the indexer database schema to the latest version",
+		Flags:  slices.Concat(utils.NetworkFlags, utils.DatabaseFlags, utils.IndexerFlags),
+		Description: `
+The migrate command applies all pending schema migrations to the indexer
+database. Nodes do the same on startup, running it beforehand keeps the
+upgrade out of the node's startup path. Migrations already applied are
+verified against the ones known to this release, the command refuses to
+touch a database whose schema was modified or upgraded by a newer release.`,
+	}
+	indexerStatusCmd = &cli.Command{
+		Action: indexerStatus,
+		Name:   "status",
+		Usage:  "Show the schema version of the indexer database",
+		Flags:  slices.Concat(utils.NetworkFlags, utils.DatabaseFlags, utils.IndexerFlags),
+		Description: `
+The status command lists the applied and pending schema migrations of the
+indexer database, without modifying it.`,
+	}
+	indexerVerifyCmd = &cli.Command{
+		Action: indexerVerify,
+		Name:   "verify",
+		Usage:  "Check the indexer database against the local chain database",
+		Flags: slices.Concat([]cli.Flag{
+			verifyFromFlag,
+			verifyToFlag,
+			verifyRepairFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags, utils.IndexerFlags),
+		Description: `
+The verify command walks the given block range and compares the indexed rows
+with the canonical chain: block hashes, transactions, receipt statuses, log
+counts and finalized flags. Missing blocks, rows left behind by non-canonical
+blocks and stale finalized flags are reported, along with the total of the
+indexed state changes. Verifying up to the head also checks for rows indexed
+beyond it.
+
+With --repair, the ranges with divergences are repaired from the chain. Missing
+and non-canonical blocks are re-indexed: as with a backfill, their state changes
+and traces can't be recovered from the chain database and are dropped. Flags,
+transactions, receipts and logs of canonical blocks are fixed in place, keeping
+their state changes and traces.`,
+	}
+)
+
+func indexerBackfill(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	// Writable, as the indexer drains its pending queue alongside
+	chain, db := utils.MakeChain(ctx, stack, false, cfg.Eth.Indexer)
+	defer db.Close()
+	defer chain.Stop()
+
+	indexer := chainIndexer(chain)
+	to := chain.CurrentBlock().Number.Uint64()
+	if ctx.IsSet(backfillToFlag.Name) {
+		to = ctx.Uint64(backfillToFlag.Name)
+	}
+	config := core.BackfillConfig{
+		Workers:   ctx.Int(backfillWorkersFlag.Name),
+		BatchSize: ctx.Uint64(backfillBatchFlag.Name),
+	}
+	// Stop at the next batch if interrupted, keeping the checkpoint
+	sigctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	status, err := indexer.Backfill(sigctx, ctx.Uint64(backfillFromFlag.Name), to, config)
+	if err != nil {
+		utils.Fatalf("Backfill failed: %v", err)
+	}
+	fmt.Printf("Backfill done in %v: %d blocks indexed, %d already present\n",
+		common.PrettyDuration(time.Since(start)), status.Indexed, status.Skipped)
+	return nil
+}
+
+// chainIndexer returns the indexer plugin attached to the chain, exiting if
+// there's none.
+func chainIndexer(chain *core.BlockChain) *core.IndexerPlugin {
+	for _, plugin := range chain.Plugins() {
+		if p, ok := plugin.(*core.IndexerPlugin); ok {
+			return p
+		}
+	}
+	utils.Fatalf("Indexer not enabled, check the --%s and --%s flags", utils.IndexerEnabledFlag.Name, utils.IndexerDriverFlag.Name)
+	return nil
+}
+
+// openIndexerDB connects to the configured indexer database without touching
+// its schema.
+func openIndexerDB(ctx *cli.Context) core.IndexerDB {
+	db, err := core.OpenDB(utils.MakeIndexerConfig(ctx))
+	if err != nil {
+		utils.Fatalf("Failed to open indexer database: %v", err)
+	}
+	return db
+}
+
+func indexerMigrate(ctx *cli.Context) error {
+	db := openIndexerDB(ctx)
+	defer db.Close()
+
+	applied, err := db.Migrate()
+	if err != nil {
+		utils.Fatalf("Migration failed: %v", err)
+	}
+	status, err := db.MigrationStatus()
+	if err != nil {
+		utils.Fatalf("Failed to read schema version: %v", err)
+	}
+	fmt.Printf("Applied %d migrations, schema at version %d\n", applied, len(status))
+	return nil
+}
+
+func indexerStatus(ctx *cli.Context) error {
+	db := openIndexerDB(ctx)
+	defer db.Close()
+
+	status, err := db.MigrationStatus()
+	if err != nil {
+		utils.Fatalf("Failed to read schema version: %v", err)
+	}
+	fmt.Printf("Indexer database (%s)\n", db.Driver())
+	for _, s := range status {
+		state := "pending"
+		switch {
+		case s.Unknown:
+			state = "unknown, applied by a newer release"
+		case s.Modified:
+			state = "modified, checksum mismatch"
+		case s.Applied:
+			state = "applied " + s.AppliedAt.Format(time.DateTime)
+		}
+		fmt.Printf("  %04d %-24s %s\n", s.Version, s.Name, state)
+	}
+	return nil
+}
+
+func indexerVerify(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	// Writable, as the indexer drains its pending queue alongside
+	chain, db := utils.MakeChain(ctx, stack, false, cfg.Eth.Indexer)
+	defer db.Close()
+	defer chain.Stop()
+
+	indexer := chainIndexer(chain)
+	to := chain.CurrentBlock().Number.Uint64()
+	if ctx.IsSet(verifyToFlag.Name) {
+		to = ctx.Uint64(verifyToFlag.Name)
+	}
+	sigctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	report, err := indexer.Verify(sigctx, ctx.Uint64(verifyFromFlag.Name), to)
+	if err != nil {
+		utils.Fatalf("Verification failed: %v", err)
+	}
+	for _, d := range report.Divergences {
+		fmt.Println(d)
+	}
+	ranges := report.Ranges()
+	fmt.Printf("Verified blocks %d-%d in %v: %d blocks, %d state changes, %d divergences in %d ranges\n",
+		report.From, report.To, common.PrettyDuration(time.Since(start)), report.Blocks, report.StateChanges, len(report.Divergences), len(ranges))
+
+	if len(ranges) == 0 {
+		return nil
+	}
+	if !ctx.Bool(verifyRepairFlag.Name) {
+		utils.Fatalf("Indexer database diverges from the chain, rerun with --%s to repair the affected ranges", verifyRepairFlag.Name)
+	}
+	for _, r := range ranges {
+		if err := indexer.Repair(sigctx, r[0], r[1]); err != nil {
+			utils.Fatalf("Repair of blocks %d-%d failed: %v", r[0], r[1], err)
+		}
+		fmt.Printf("Repaired blocks %d-%d\n", r[0], r[1])
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestIndexerDisabled checks that --indexer=false keeps the indexer database
+// closed, even if the config file enables it and its location is given.
+func TestIndexerDisabled(t *testing.T) {
+	t.Parallel()
+	var (
+		dir    = t.TempDir()
+		path   = filepath.Join(dir, "indexer.sqlite")
+		config = filepath.Join(dir, "config.toml")
+	)
+	toml := fmt.Sprintf("[Eth.Indexer]\nEnabled = true\nDriver = \"sqlite\"\nPath = %q\n", path)
+	if err := os.WriteFile(config, []byte(toml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	datadir := initGeth(t)
+
+	geth := runGeth(t, "--datadir", datadir, "--config", config, "--indexer=false", "--indexer.driver", "sqlite", "--indexer.path", path, "export", filepath.Join(dir, "disabled.out"))
+	geth.WaitExit()
+	if have, want := geth.ExitStatus(), 0; have != want {
+		t.Fatalf("exit error, have %d want %d", have, want)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("indexer database created while disabled: %v", err)
+	}
+	// The config file alone enables the indexer
+	geth = runGeth(t, "--datadir", datadir, "--config", config, "export", filepath.Join(dir, "enabled.out"))
+	geth.WaitExit()
+	if have, want := geth.ExitStatus(), 0; have != want {
+		t.Fatalf("exit error, have %d want %d", have, want)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("indexer database not created while enabled: %v", err)
+	}
+}
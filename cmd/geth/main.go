@@ -65,13 +65,6 @@ var (
 		utils.OverrideCancun,
 		utils.OverrideVerkle,
 		utils.EnablePersonal, // deprecated
-		utils.IndexerEnabledFlag,
-		utils.IndexerHostFlag,
-		utils.IndexerPortFlag,
-		utils.IndexerUserFlag,
-		utils.IndexerPasswordFlag,
-		utils.IndexerDBNameFlag,
-		utils.IndexerSSLModeFlag,
 		utils.TxPoolLocalsFlag,
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
@@ -161,7 +154,7 @@ var (
 		utils.BeaconGenesisRootFlag,
 		utils.BeaconGenesisTimeFlag,
 		utils.BeaconCheckpointFlag,
-	}, utils.NetworkFlags, utils.DatabaseFlags)
+	}, utils.NetworkFlags, utils.DatabaseFlags, utils.IndexerFlags)
 
 	rpcFlags = []cli.Flag{
 		utils.HTTPEnabledFlag,
@@ -244,6 +237,8 @@ func init() {
 		dumpConfigCommand,
 		// see dbcmd.go
 		dbCommand,
+		// see indexercmd.go
+		indexerCommand,
 		// See cmd/utils/flags_legacy.go
 		utils.ShowDeprecated,
 		// See snapshot.go
@@ -31,6 +31,7 @@ import (
 	"os"
 	"path/filepath"
 	godebug "runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -42,6 +43,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
+	_ "github.com/ethereum/go-ethereum/core/indexer/sqlite" // SQLite indexer database
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
@@ -932,43 +934,114 @@ Please note that --` + MetricsHTTPFlag.Name + ` must be set to start the server.
 	// Indexer settings
 	IndexerEnabledFlag = &cli.BoolFlag{
 		Name:     "indexer",
-		Usage:    "Enable the PostgreSQL indexer",
+		Usage:    "Enable the SQL database indexer",
+		Category: flags.EthCategory,
+	}
+	IndexerDriverFlag = &cli.StringFlag{
+		Name:     "indexer.driver",
+		Usage:    "Indexer database driver (postgres, sqlite)",
+		Value:    core.DefaultIndexerConfig.Driver,
+		Category: flags.EthCategory,
+	}
+	IndexerPathFlag = &cli.StringFlag{
+		Name:     "indexer.path",
+		Usage:    "SQLite database file (default = inside the datadir)",
+		Category: flags.EthCategory,
+	}
+	IndexerDSNFlag = &cli.StringFlag{
+		Name:     "indexer.dsn",
+		Usage:    "PostgreSQL connection string or URL, overriding the other connection flags",
 		Category: flags.EthCategory,
 	}
 	IndexerHostFlag = &cli.StringFlag{
 		Name:     "indexer.host",
 		Usage:    "PostgreSQL host",
-		Value:    "localhost",
+		Value:    core.DefaultIndexerConfig.Host,
 		Category: flags.EthCategory,
 	}
 	IndexerPortFlag = &cli.IntFlag{
 		Name:     "indexer.port",
 		Usage:    "PostgreSQL port",
-		Value:    5432,
+		Value:    core.DefaultIndexerConfig.Port,
 		Category: flags.EthCategory,
 	}
 	IndexerUserFlag = &cli.StringFlag{
 		Name:     "indexer.user",
 		Usage:    "PostgreSQL user",
-		Value:    "postgres",
+		Value:    core.DefaultIndexerConfig.User,
 		Category: flags.EthCategory,
 	}
+	// IndexerPasswordFlag is rejected, as the password would be visible in the
+	// process list. It's kept to point users at the alternatives.
 	IndexerPasswordFlag = &cli.StringFlag{
 		Name:     "indexer.password",
-		Usage:    "PostgreSQL password",
-		Value:    "postgres",
+		Usage:    "Removed, use --indexer.password.file, the DSN or PGPASSWORD",
+		Category: flags.EthCategory,
+		Hidden:   true,
+	}
+	IndexerPasswordFileFlag = &cli.StringFlag{
+		Name:     "indexer.password.file",
+		Usage:    "File containing the PostgreSQL password",
 		Category: flags.EthCategory,
 	}
 	IndexerDBNameFlag = &cli.StringFlag{
 		Name:     "indexer.dbname",
 		Usage:    "PostgreSQL database name",
-		Value:    "geth_indexer",
+		Value:    core.DefaultIndexerConfig.DBName,
 		Category: flags.EthCategory,
 	}
 	IndexerSSLModeFlag = &cli.StringFlag{
 		Name:     "indexer.sslmode",
 		Usage:    "PostgreSQL SSL mode (disable, require, verify-ca, verify-full)",
-		Value:    "disable",
+		Value:    core.DefaultIndexerConfig.SSLMode,
+		Category: flags.EthCategory,
+	}
+	IndexerBatchFlag = &cli.Uint64Flag{
+		Name:     "indexer.batch",
+		Usage:    "Number of blocks indexed in a single database transaction by backfills",
+		Value:    core.DefaultIndexerConfig.BatchSize,
+		Category: flags.EthCategory,
+	}
+	IndexerStartBlockFlag = &cli.Uint64Flag{
+		Name:     "indexer.startblock",
+		Usage:    "First block to index, older blocks are ignored",
+		Category: flags.EthCategory,
+	}
+	IndexerTracesFlag = &cli.BoolFlag{
+		Name:     "indexer.traces",
+		Usage:    "Index the internal calls of all transactions",
+		Category: flags.EthCategory,
+	}
+	IndexerEventsFlag = &cli.BoolFlag{
+		Name:     "indexer.events",
+		Usage:    "Decode the indexed logs into events and ERC-20/721/1155 token transfers",
+		Category: flags.EthCategory,
+	}
+	IndexerABIDirFlag = &cli.StringFlag{
+		Name:     "indexer.abis",
+		Usage:    "Directory of contract ABIs to decode the indexed logs with",
+		Category: flags.EthCategory,
+	}
+	IndexerSinkFlag = &cli.StringFlag{
+		Name:     "indexer.sink",
+		Usage:    "Export the indexed records into files of the given format (ndjson, parquet)",
+		Category: flags.EthCategory,
+	}
+	IndexerSinkDirFlag = &cli.StringFlag{
+		Name:     "indexer.sink.dir",
+		Usage:    "Output directory of the indexer file export (default = inside the datadir)",
+		Category: flags.EthCategory,
+	}
+	IndexerSinkPartitionFlag = &cli.Uint64Flag{
+		Name:     "indexer.sink.partition",
+		Usage:    "Number of blocks exported into a single file",
+		Value:    core.DefaultIndexerConfig.SinkPartition,
+		Category: flags.EthCategory,
+	}
+	IndexerMaxLagFlag = &cli.Uint64Flag{
+		Name:     "indexer.maxlag",
+		Usage:    "Number of blocks the indexer may trail the chain head before it's reported unhealthy",
+		Value:    core.DefaultIndexerConfig.MaxLag,
 		Category: flags.EthCategory,
 	}
 )
@@ -991,6 +1064,30 @@ var (
 		StateSchemeFlag,
 		HttpHeaderFlag,
 	}
+
+	// IndexerFlags is the flag group of all indexer database flags.
+	IndexerFlags = []cli.Flag{
+		IndexerEnabledFlag,
+		IndexerDriverFlag,
+		IndexerPathFlag,
+		IndexerDSNFlag,
+		IndexerHostFlag,
+		IndexerPortFlag,
+		IndexerUserFlag,
+		IndexerPasswordFlag,
+		IndexerPasswordFileFlag,
+		IndexerDBNameFlag,
+		IndexerSSLModeFlag,
+		IndexerBatchFlag,
+		IndexerStartBlockFlag,
+		IndexerTracesFlag,
+		IndexerEventsFlag,
+		IndexerABIDirFlag,
+		IndexerSinkFlag,
+		IndexerSinkDirFlag,
+		IndexerSinkPartitionFlag,
+		IndexerMaxLagFlag,
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -1877,16 +1974,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 			cfg.VMTraceJsonConfig = ctx.String(VMTraceJsonConfigFlag.Name)
 		}
 	}
-	if ctx.IsSet("indexer.host") {
-		cfg.IndexerConfig = &core.IndexerConfig{
-			Host:     ctx.String("indexer.host"),
-			Port:     ctx.Int("indexer.port"),
-			User:     ctx.String("indexer.user"),
-			Password: ctx.String("indexer.password"),
-			DBName:   ctx.String("indexer.dbname"),
-			SSLMode:  ctx.String("indexer.sslmode"),
-		}
-	}
+	setIndexerConfig(ctx, &cfg.Indexer)
 }
 
 // MakeBeaconLightConfig constructs a beacon light client config based on the
@@ -1979,9 +2067,8 @@ func SetDNSDiscoveryDefaults(cfg *ethconfig.Config, genesis common.Hash) {
 
 // RegisterEthService adds an Ethereum client to the stack.
 // The second return value is the full node instance.
-func RegisterEthService(stack *node.Node, cfg *ethconfig.Config, indexerPlugin *core.IndexerPlugin) (*eth.EthAPIBackend, *eth.Ethereum) {
-	// Create Ethereum backend with the provided plugin
-	backend, err := eth.New(stack, cfg, indexerPlugin)
+func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (*eth.EthAPIBackend, *eth.Ethereum) {
+	backend, err := eth.New(stack, cfg)
 	if err != nil {
 		Fatalf("Failed to register the Ethereum service: %v", err)
 	}
@@ -2006,9 +2093,16 @@ func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, filterSyst
 
 // RegisterFilterAPI adds the eth log filtering RPC API to the node.
 func RegisterFilterAPI(stack *node.Node, backend ethapi.Backend, ethcfg *ethconfig.Config) *filters.FilterSystem {
-	filterSystem := filters.NewFilterSystem(backend, filters.Config{
+	config := filters.Config{
 		LogCacheSize: ethcfg.FilterLogCacheSize,
-	})
+	}
+	// Serve log queries from the indexer database where it covers them
+	for _, plugin := range ethcfg.Plugins {
+		if indexer, ok := plugin.(*core.IndexerPlugin); ok && indexer.DB() != nil {
+			config.LogIndex = indexer.DB()
+		}
+	}
+	filterSystem := filters.NewFilterSystem(backend, config)
 	stack.RegisterAPIs([]rpc.API{{
 		Namespace: "eth",
 		Service:   filters.NewFilterAPI(filterSystem),
@@ -2175,8 +2269,9 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	return genesis
 }
 
-// MakeChain creates a chain manager from set command line flags.
-func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockChain, ethdb.Database) {
+// MakeChain creates a chain manager from set command line flags, running the
+// indexer if enabled in the given configuration.
+func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool, indexer core.IndexerConfig) (*core.BlockChain, ethdb.Database) {
 	var (
 		gspec   = MakeGenesis(ctx)
 		chainDb = MakeChainDatabase(ctx, stack, readonly)
@@ -2239,9 +2334,11 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		}
 	}
 	// Disable transaction indexing/unindexing by default.
-	db := MakeIndexerDB(ctx)
-	indexer := core.NewIndexerPlugin(db)
-	chain, err := core.NewBlockChain(chainDb, cache, gspec, nil, engine, vmcfg, nil, indexer)
+	var plugins []core.Plugin
+	if db := MakeIndexerDB(indexer); db != nil {
+		plugins = append(plugins, MakeIndexerPlugin(indexer, db))
+	}
+	chain, err := core.NewBlockChain(chainDb, cache, gspec, nil, engine, vmcfg, nil, plugins...)
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
 	}
@@ -2290,22 +2387,105 @@ func MakeTrieDatabase(ctx *cli.Context, disk ethdb.Database, preimage bool, read
 	return triedb.NewDatabase(disk, config)
 }
 
-// MakeIndexerDB creates a database connection for the indexer plugin
-func MakeIndexerDB(ctx *cli.Context) *core.IndexerDB {
-	// Only create indexer DB if host is specified
-	if !ctx.IsSet(IndexerHostFlag.Name) {
-		return nil
+// setIndexerConfig applies the indexer related command line flags to the config.
+func setIndexerConfig(ctx *cli.Context, cfg *core.IndexerConfig) {
+	if ctx.IsSet(IndexerEnabledFlag.Name) {
+		cfg.Enabled = ctx.Bool(IndexerEnabledFlag.Name)
+	}
+	if ctx.IsSet(IndexerDriverFlag.Name) {
+		cfg.Driver = ctx.String(IndexerDriverFlag.Name)
+	}
+	if ctx.IsSet(IndexerPathFlag.Name) {
+		cfg.Path = ctx.String(IndexerPathFlag.Name)
+	}
+	if ctx.IsSet(IndexerDSNFlag.Name) {
+		cfg.DSN = ctx.String(IndexerDSNFlag.Name)
+	}
+	if ctx.IsSet(IndexerHostFlag.Name) {
+		cfg.Host = ctx.String(IndexerHostFlag.Name)
+	}
+	if ctx.IsSet(IndexerPortFlag.Name) {
+		cfg.Port = ctx.Int(IndexerPortFlag.Name)
+	}
+	if ctx.IsSet(IndexerUserFlag.Name) {
+		cfg.User = ctx.String(IndexerUserFlag.Name)
+	}
+	if ctx.IsSet(IndexerPasswordFlag.Name) {
+		Fatalf("Option %q is not supported, as the password is visible to other users. Supply it via %q, the DSN or the PGPASSWORD environment variable", IndexerPasswordFlag.Name, IndexerPasswordFileFlag.Name)
+	}
+	if ctx.IsSet(IndexerPasswordFileFlag.Name) {
+		cfg.PasswordFile = ctx.String(IndexerPasswordFileFlag.Name)
+	}
+	if ctx.IsSet(IndexerDBNameFlag.Name) {
+		cfg.DBName = ctx.String(IndexerDBNameFlag.Name)
+	}
+	if ctx.IsSet(IndexerSSLModeFlag.Name) {
+		cfg.SSLMode = ctx.String(IndexerSSLModeFlag.Name)
+	}
+	if ctx.IsSet(IndexerBatchFlag.Name) {
+		cfg.BatchSize = ctx.Uint64(IndexerBatchFlag.Name)
+	}
+	if ctx.IsSet(IndexerStartBlockFlag.Name) {
+		cfg.StartBlock = ctx.Uint64(IndexerStartBlockFlag.Name)
+	}
+	if ctx.Bool(IndexerTracesFlag.Name) && !slices.Contains(cfg.Tables, core.IndexerTableTraces) {
+		cfg.Tables = append(cfg.Tables, core.IndexerTableTraces)
+	}
+	if ctx.Bool(IndexerEventsFlag.Name) {
+		for _, table := range []string{core.IndexerTableDecodedEvents, core.IndexerTableTokenTransfers} {
+			if !slices.Contains(cfg.Tables, table) {
+				cfg.Tables = append(cfg.Tables, table)
+			}
+		}
+	}
+	if ctx.IsSet(IndexerABIDirFlag.Name) {
+		cfg.ABIDir = ctx.String(IndexerABIDirFlag.Name)
+	}
+	if ctx.IsSet(IndexerSinkFlag.Name) {
+		cfg.Sink = ctx.String(IndexerSinkFlag.Name)
+	}
+	if ctx.IsSet(IndexerSinkDirFlag.Name) {
+		cfg.SinkDir = ctx.String(IndexerSinkDirFlag.Name)
+	}
+	if ctx.IsSet(IndexerSinkPartitionFlag.Name) {
+		cfg.SinkPartition = ctx.Uint64(IndexerSinkPartitionFlag.Name)
+	}
+	if ctx.IsSet(IndexerMaxLagFlag.Name) {
+		cfg.MaxLag = ctx.Uint64(IndexerMaxLagFlag.Name)
+	}
+	if cfg.Driver == core.DriverSQLite && cfg.Path == "" {
+		cfg.Path = filepath.Join(MakeDataDir(ctx), "indexer.sqlite")
 	}
+	if cfg.Sink != "" && cfg.SinkDir == "" {
+		cfg.SinkDir = filepath.Join(MakeDataDir(ctx), "indexer-export")
+	}
+}
+
+// MakeIndexerConfig creates the indexer configuration from the defaults and the
+// command line flags.
+func MakeIndexerConfig(ctx *cli.Context) core.IndexerConfig {
+	config := core.DefaultIndexerConfig
+	setIndexerConfig(ctx, &config)
+	return config
+}
 
-	config := core.IndexerConfig{
-		Host:     ctx.String(IndexerHostFlag.Name),
-		Port:     ctx.Int(IndexerPortFlag.Name),
-		DBName:   ctx.String(IndexerDBNameFlag.Name),
-		User:     ctx.String(IndexerUserFlag.Name),
-		Password: ctx.String(IndexerPasswordFlag.Name),
-		SSLMode:  ctx.String(IndexerSSLModeFlag.Name),
+// MakeIndexerPlugin creates the indexer plugin writing into the given database,
+// with the plugin settings of the indexer configuration.
+func MakeIndexerPlugin(config core.IndexerConfig, db core.IndexerDB) *core.IndexerPlugin {
+	plugin := core.NewIndexerPlugin(db)
+	if err := plugin.Configure(config); err != nil {
+		Fatalf("Failed to configure indexer: %v", err)
 	}
+	return plugin
+}
 
+// MakeIndexerDB creates a database connection for the indexer plugin, or returns
+// nil if the indexer is disabled. The config is the resolved one, with the
+// command line flags applied over the [Eth.Indexer] section of the config file.
+func MakeIndexerDB(config core.IndexerConfig) core.IndexerDB {
+	if !config.Enabled {
+		return nil
+	}
 	db, err := core.NewDB(config)
 	if err != nil {
 		log.Error("Failed to connect to indexer database", "error", err)
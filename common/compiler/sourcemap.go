@@ -0,0 +1,456 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package compiler
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SourceMapConfig selects the contracts of a solc standard-JSON compilation
+// whose code is mapped back to its source. The layout matches the build info
+// files of Hardhat and Foundry, with the deployment of the contracts added.
+type SourceMapConfig struct {
+	// Input is the standard-JSON input of the compilation. The sources in it
+	// are used to resolve lines, which are omitted without it.
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// Output is the standard-JSON output of the compilation, which must contain
+	// the deployed bytecode and source map of the contracts, as well as the AST
+	// of the sources to resolve function names.
+	Output json.RawMessage `json:"output"`
+
+	// Contracts maps addresses or code hashes to the fully qualified name of
+	// the contract deployed, e.g. "contracts/Token.sol:Token", or its name if
+	// unique. Contracts without immutables or libraries are also found by the
+	// hash of their code.
+	Contracts map[string]string `json:"contracts,omitempty"`
+}
+
+// SourceLocation is the position in the source of an instruction.
+type SourceLocation struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`     // 1-based, omitted if the source is unknown
+	Column   int    `json:"column,omitempty"`   // 1-based byte offset in the line
+	Function string `json:"function,omitempty"` // Contract.function, omitted outside of functions
+}
+
+// String implements fmt.Stringer.
+func (l *SourceLocation) String() string {
+	s := l.File
+	if l.Line > 0 {
+		s += fmt.Sprintf(":%d:%d", l.Line, l.Column)
+	}
+	if l.Function != "" {
+		s += " (" + l.Function + ")"
+	}
+	return s
+}
+
+// SourceMapper maps the program counters of deployed contracts back to their
+// Solidity source. It can be unmarshalled from a SourceMapConfig or a list of
+// them.
+type SourceMapper struct {
+	configs   []SourceMapConfig
+	addresses map[common.Address]*SourceProgram
+	hashes    map[common.Hash]*SourceProgram
+}
+
+// NewSourceMapper creates a source mapper for the contracts of the given
+// compilations.
+func NewSourceMapper(configs ...SourceMapConfig) (*SourceMapper, error) {
+	m := &SourceMapper{
+		configs:   configs,
+		addresses: make(map[common.Address]*SourceProgram),
+		hashes:    make(map[common.Hash]*SourceProgram),
+	}
+	for i := range configs {
+		if err := m.add(&configs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *SourceMapper) UnmarshalJSON(input []byte) error {
+	var configs []SourceMapConfig
+	if trimmed := bytes.TrimSpace(input); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(input, &configs); err != nil {
+			return err
+		}
+	} else {
+		var config SourceMapConfig
+		if err := json.Unmarshal(input, &config); err != nil {
+			return err
+		}
+		configs = append(configs, config)
+	}
+	mapper, err := NewSourceMapper(configs...)
+	if err != nil {
+		return err
+	}
+	*m = *mapper
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *SourceMapper) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.configs)
+}
+
+// Program returns the program of the contract at the given address, or with the
+// given code. Nil is returned if the contract is unknown.
+func (m *SourceMapper) Program(addr common.Address, code []byte) *SourceProgram {
+	if p, ok := m.addresses[addr]; ok {
+		return p
+	}
+	if len(m.hashes) == 0 || len(code) == 0 {
+		return nil
+	}
+	return m.hashes[crypto.Keccak256Hash(code)]
+}
+
+// standardInput is the part of the solc standard-JSON input holding the sources.
+type standardInput struct {
+	Sources map[string]struct {
+		Content string `json:"content"`
+	} `json:"sources"`
+}
+
+// standardOutput is the part of the solc standard-JSON output holding the
+// deployed code of the contracts.
+type standardOutput struct {
+	Sources map[string]struct {
+		ID  int      `json:"id"`
+		AST *astNode `json:"ast"`
+	} `json:"sources"`
+	Contracts map[string]map[string]struct {
+		EVM struct {
+			DeployedBytecode struct {
+				Object              string                     `json:"object"`
+				SourceMap           string                     `json:"sourceMap"`
+				LinkReferences      map[string]json.RawMessage `json:"linkReferences"`
+				ImmutableReferences map[string]json.RawMessage `json:"immutableReferences"`
+			} `json:"deployedBytecode"`
+		} `json:"evm"`
+	} `json:"contracts"`
+}
+
+// astNode is a node of the solc AST, down to function definitions.
+type astNode struct {
+	NodeType string    `json:"nodeType"`
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind"`
+	Src      string    `json:"src"`
+	Nodes    []astNode `json:"nodes"`
+}
+
+// add adds the contracts of a compilation to the mapper.
+func (m *SourceMapper) add(config *SourceMapConfig) error {
+	if len(config.Output) == 0 {
+		return errors.New("missing compiler output")
+	}
+	var output standardOutput
+	if err := json.Unmarshal(config.Output, &output); err != nil {
+		return fmt.Errorf("invalid compiler output: %v", err)
+	}
+	var input standardInput
+	if len(config.Input) > 0 {
+		if err := json.Unmarshal(config.Input, &input); err != nil {
+			return fmt.Errorf("invalid compiler input: %v", err)
+		}
+	}
+	files := make(map[int]*sourceFile)
+	for path, source := range output.Sources {
+		file := &sourceFile{path: path}
+		if in, ok := input.Sources[path]; ok {
+			file.lines = lineStarts(in.Content)
+		}
+		if source.AST != nil {
+			file.functions = functions(source.AST)
+		}
+		files[source.ID] = file
+	}
+	programs := make(map[string]*SourceProgram)
+	for path, contracts := range output.Contracts {
+		for name, contract := range contracts {
+			code := contract.EVM.DeployedBytecode
+			if code.Object == "" || code.SourceMap == "" {
+				continue
+			}
+			p, err := newSourceProgram(path+":"+name, code.Object, code.SourceMap, files)
+			if err != nil {
+				return fmt.Errorf("contract %s:%s: %v", path, name, err)
+			}
+			programs[p.Name] = p
+
+			// Code with placeholders differs from the deployed one
+			if len(code.LinkReferences) == 0 && len(code.ImmutableReferences) == 0 {
+				m.hashes[crypto.Keccak256Hash(p.code)] = p
+			}
+		}
+	}
+	for key, name := range config.Contracts {
+		p, err := lookupProgram(programs, name)
+		if err != nil {
+			return err
+		}
+		switch raw := common.FromHex(key); len(raw) {
+		case common.AddressLength:
+			m.addresses[common.BytesToAddress(raw)] = p
+		case common.HashLength:
+			m.hashes[common.BytesToHash(raw)] = p
+		default:
+			return fmt.Errorf("invalid contract key %q, want address or code hash", key)
+		}
+	}
+	return nil
+}
+
+// lookupProgram finds a contract by its fully qualified name, or by its name if
+// unique.
+func lookupProgram(programs map[string]*SourceProgram, name string) (*SourceProgram, error) {
+	if p, ok := programs[name]; ok {
+		return p, nil
+	}
+	var found *SourceProgram
+	for qualified, p := range programs {
+		if qualified[strings.LastIndexByte(qualified, ':')+1:] != name {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("ambiguous contract name %q", name)
+		}
+		found = p
+	}
+	if found == nil {
+		return nil, fmt.Errorf("contract %q not found in compiler output", name)
+	}
+	return found, nil
+}
+
+// sourceFile is a source of a compilation.
+type sourceFile struct {
+	path      string
+	lines     []int // offsets of the line starts, nil if the content is unknown
+	functions []sourceFunction
+}
+
+// sourceFunction is the source range of a function or modifier.
+type sourceFunction struct {
+	start, end int
+	name       string
+}
+
+// lineStarts returns the offsets at which the lines of a source start.
+func lineStarts(content string) []int {
+	lines := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return lines
+}
+
+// functions collects the functions and modifiers of a source unit, ordered by
+// their position.
+func functions(unit *astNode) []sourceFunction {
+	var fns []sourceFunction
+	var walk func(node *astNode, contract string)
+	walk = func(node *astNode, contract string) {
+		switch node.NodeType {
+		case "FunctionDefinition", "ModifierDefinition":
+			start, length, _, ok := parseSrc(node.Src)
+			if !ok {
+				return
+			}
+			name := node.Name
+			if name == "" {
+				name = node.Kind // constructor, fallback and receive
+			}
+			if contract != "" {
+				name = contract + "." + name
+			}
+			fns = append(fns, sourceFunction{start, start + length, name})
+			return
+		case "ContractDefinition":
+			contract = node.Name
+		}
+		for i := range node.Nodes {
+			walk(&node.Nodes[i], contract)
+		}
+	}
+	walk(unit, "")
+	sort.Slice(fns, func(i, j int) bool { return fns[i].start < fns[j].start })
+	return fns
+}
+
+// parseSrc parses a "start:length:file" source range of the AST.
+func parseSrc(src string) (start, length, file int, ok bool) {
+	parts := strings.Split(src, ":")
+	if len(parts) != 3 {
+		return 0, 0, 0, false
+	}
+	var err [3]error
+	start, err[0] = strconv.Atoi(parts[0])
+	length, err[1] = strconv.Atoi(parts[1])
+	file, err[2] = strconv.Atoi(parts[2])
+	return start, length, file, err[0] == nil && err[1] == nil && err[2] == nil
+}
+
+// function returns the name of the function enclosing an offset of the source.
+func (f *sourceFile) function(offset int) string {
+	i := sort.Search(len(f.functions), func(i int) bool { return f.functions[i].start > offset })
+	if i == 0 || offset >= f.functions[i-1].end {
+		return ""
+	}
+	return f.functions[i-1].name
+}
+
+// sourceMapEntry is a decoded entry of a source map, covering an instruction.
+type sourceMapEntry struct {
+	start, length int
+	file          int // -1 for compiler generated code
+}
+
+// parseSourceMap decodes a compressed solc source map of "s:l:f:j:m" entries
+// separated by semicolons, where omitted fields repeat the previous entry.
+func parseSourceMap(srcmap string) ([]sourceMapEntry, error) {
+	var (
+		entries []sourceMapEntry
+		last    = sourceMapEntry{file: -1}
+	)
+	for i, item := range strings.Split(srcmap, ";") {
+		fields := strings.Split(item, ":")
+		for j, dst := range []*int{&last.start, &last.length, &last.file} {
+			if j >= len(fields) || fields[j] == "" {
+				continue
+			}
+			n, err := strconv.Atoi(fields[j])
+			if err != nil {
+				return nil, fmt.Errorf("invalid source map entry %d: %q", i, item)
+			}
+			*dst = n
+		}
+		entries = append(entries, last)
+	}
+	return entries, nil
+}
+
+// SourceProgram is the deployed code of a contract along with its source map.
+type SourceProgram struct {
+	Name string // Fully qualified name of the contract
+
+	code         []byte
+	instructions []int // instruction index by program counter, -1 within push data
+	entries      []sourceMapEntry
+	files        map[int]*sourceFile
+}
+
+// newSourceProgram decodes the deployed code and source map of a contract.
+// Placeholders of linked libraries in the code are zeroed.
+func newSourceProgram(name string, object string, srcmap string, files map[int]*sourceFile) (*SourceProgram, error) {
+	object = strings.Map(func(r rune) rune {
+		if strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return r
+		}
+		return '0'
+	}, strings.TrimPrefix(object, "0x"))
+
+	code, err := hex.DecodeString(object)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bytecode: %v", err)
+	}
+	entries, err := parseSourceMap(srcmap)
+	if err != nil {
+		return nil, err
+	}
+	instructions := make([]int, len(code))
+	for pc, index := 0, 0; pc < len(code); index++ {
+		instructions[pc] = index
+		next := pc + 1
+		// PUSH1 to PUSH32 are followed by their immediate data
+		if op := code[pc]; op >= 0x60 && op <= 0x7f {
+			next += int(op - 0x5f)
+		}
+		for pc++; pc < next && pc < len(code); pc++ {
+			instructions[pc] = -1
+		}
+	}
+	return &SourceProgram{
+		Name:         name,
+		code:         code,
+		instructions: instructions,
+		entries:      entries,
+		files:        files,
+	}, nil
+}
+
+// entry returns the source map entry of the instruction at the given program
+// counter, if it's mapped to a source of the compilation.
+func (p *SourceProgram) entry(pc uint64) (sourceMapEntry, *sourceFile, bool) {
+	if pc >= uint64(len(p.instructions)) {
+		return sourceMapEntry{}, nil, false
+	}
+	index := p.instructions[pc]
+	if index < 0 || index >= len(p.entries) {
+		return sourceMapEntry{}, nil, false
+	}
+	entry := p.entries[index]
+	file, ok := p.files[entry.file]
+	if !ok || entry.start < 0 {
+		return sourceMapEntry{}, nil, false
+	}
+	return entry, file, true
+}
+
+// Mapped reports whether the instruction at the given program counter is mapped
+// to a source of the compilation, as opposed to code generated by the compiler.
+func (p *SourceProgram) Mapped(pc uint64) bool {
+	_, _, ok := p.entry(pc)
+	return ok
+}
+
+// Locate returns the source location of the instruction at the given program
+// counter, or nil if it isn't mapped to a source of the compilation.
+func (p *SourceProgram) Locate(pc uint64) *SourceLocation {
+	entry, file, ok := p.entry(pc)
+	if !ok {
+		return nil
+	}
+	loc := &SourceLocation{
+		File:     file.path,
+		Function: file.function(entry.start),
+	}
+	if file.lines != nil {
+		line := sort.SearchInts(file.lines, entry.start+1)
+		loc.Line = line
+		loc.Column = entry.start - file.lines[line-1] + 1
+	}
+	return loc
+}
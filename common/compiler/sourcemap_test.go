@@ -0,0 +1,192 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package compiler
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParseSourceMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		srcmap  string
+		want    []sourceMapEntry
+		wantErr bool
+	}{
+		{
+			name:   "full entries",
+			srcmap: "0:10:0:-:0;12:3:1:i:1",
+			want:   []sourceMapEntry{{0, 10, 0}, {12, 3, 1}},
+		},
+		{
+			name:   "compressed",
+			srcmap: "0:10:0;;2:3;:4;5::1",
+			want:   []sourceMapEntry{{0, 10, 0}, {0, 10, 0}, {2, 3, 0}, {2, 4, 0}, {5, 4, 1}},
+		},
+		{
+			name:   "empty fields",
+			srcmap: "1:2:0;::;:::o;::::2",
+			want:   []sourceMapEntry{{1, 2, 0}, {1, 2, 0}, {1, 2, 0}, {1, 2, 0}},
+		},
+		{
+			name:   "empty map",
+			srcmap: "",
+			want:   []sourceMapEntry{{0, 0, -1}},
+		},
+		{
+			name:   "multiple files",
+			srcmap: "1:2:0;3:4:1;;5:6:2;7::0",
+			want:   []sourceMapEntry{{1, 2, 0}, {3, 4, 1}, {3, 4, 1}, {5, 6, 2}, {7, 6, 0}},
+		},
+		{
+			name:   "generated code",
+			srcmap: "1:2:0;0:0:-1;;-1:-1:-1;3:4:0",
+			want:   []sourceMapEntry{{1, 2, 0}, {0, 0, -1}, {0, 0, -1}, {-1, -1, -1}, {3, 4, 0}},
+		},
+		{
+			name:   "file before the first entry",
+			srcmap: "1:2;3:4:0",
+			want:   []sourceMapEntry{{1, 2, -1}, {3, 4, 0}},
+		},
+		{
+			name:    "invalid number",
+			srcmap:  "1:2:0;x:1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid file",
+			srcmap:  "1:2:a.sol",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			have, err := parseSourceMap(tt.srcmap)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("invalid source map accepted: %v", have)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse source map: %v", err)
+			}
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("entries mismatch:\nhave %v\nwant %v", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineStarts(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []int
+	}{
+		{"", []int{0}},
+		{"a", []int{0}},
+		{"a\nb\n", []int{0, 2, 4}},
+		{"a\r\nb\r\n", []int{0, 3, 6}},
+		{"é\nü", []int{0, 3}},
+		{"\n\n", []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		if have := lineStarts(tt.content); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("%q: line starts mismatch: have %v, want %v", tt.content, have, tt.want)
+		}
+	}
+}
+
+// sourceMapTestConfig is a compilation of three sources. a.sol uses CRLF line
+// endings and starts with a multi-byte character, b.sol has no AST and c.sol
+// no content. The code is PUSH1 1, PUSH1 2, ADD, POP, DUP1, STOP, STOP, INVALID.
+var sourceMapTestConfig = SourceMapConfig{
+	Input: json.RawMessage(`{"sources": {
+		"a.sol": {"content": "// é\r\ncontract A {\r\n  function f() {}\r\n}\r\n"},
+		"b.sol": {"content": "contract B {}\n"}
+	}}`),
+	Output: json.RawMessage(`{
+		"sources": {
+			"a.sol": {"id": 0, "ast": {"nodeType": "SourceUnit", "src": "0:41:0", "nodes": [
+				{"nodeType": "ContractDefinition", "name": "A", "src": "7:32:0", "nodes": [
+					{"nodeType": "FunctionDefinition", "name": "f", "kind": "function", "src": "23:15:0"}
+				]}
+			]}},
+			"b.sol": {"id": 1},
+			"c.sol": {"id": 2}
+		},
+		"contracts": {"a.sol": {"A": {"evm": {"deployedBytecode": {
+			"object": "600160020150800000fe",
+			"sourceMap": "23:15:0;7:1;0:13:1;0:0:-1;5:1:0;4:2:2;9:1:3"
+		}}}}}
+	}`),
+	Contracts: map[string]string{"0x0000000000000000000000000000000000000001": "A"},
+}
+
+func TestSourceProgramLocate(t *testing.T) {
+	mapper, err := NewSourceMapper(sourceMapTestConfig)
+	if err != nil {
+		t.Fatalf("failed to create source mapper: %v", err)
+	}
+	program := mapper.Program(common.HexToAddress("0x01"), nil)
+	if program == nil {
+		t.Fatal("program not found by address")
+	}
+	if program.Name != "a.sol:A" {
+		t.Errorf("program name mismatch: have %s, want a.sol:A", program.Name)
+	}
+	if byHash := mapper.Program(common.Address{}, common.FromHex("600160020150800000fe")); byHash != program {
+		t.Error("program not found by code hash")
+	}
+	tests := []struct {
+		pc   uint64
+		want *SourceLocation
+	}{
+		// Inside a function, after a CRLF and a multi-byte character
+		{0, &SourceLocation{File: "a.sol", Line: 3, Column: 3, Function: "A.f"}},
+		// Push data isn't an instruction
+		{1, nil},
+		// Compressed entry in the same file, outside of functions
+		{2, &SourceLocation{File: "a.sol", Line: 2, Column: 1}},
+		// Another file without AST
+		{4, &SourceLocation{File: "b.sol", Line: 1, Column: 1}},
+		// Compiler generated code
+		{5, nil},
+		// Byte columns after a multi-byte character
+		{6, &SourceLocation{File: "a.sol", Line: 1, Column: 6}},
+		// Source without content
+		{7, &SourceLocation{File: "c.sol"}},
+		// Unknown file index
+		{8, nil},
+		// Past the end of the code
+		{9, nil},
+		{100, nil},
+	}
+	for _, tt := range tests {
+		have := program.Locate(tt.pc)
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("pc %d: location mismatch: have %v, want %v", tt.pc, have, tt.want)
+		}
+		if mapped := program.Mapped(tt.pc); mapped != (tt.want != nil) {
+			t.Errorf("pc %d: mapped mismatch: have %t, want %t", tt.pc, mapped, tt.want != nil)
+		}
+	}
+}
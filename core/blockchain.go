@@ -259,13 +259,15 @@ type BlockChain struct {
 	processor  Processor // Block transaction processor interface
 	vmConfig   vm.Config
 	logger     *tracing.Hooks
-	plugin     *IndexerPlugin // Optional plugin for indexing (nil if not configured)
+	plugins    pluginRegistry // Plugins receiving chain events, in registration order
+
+	publicVMConfig atomic.Pointer[vm.Config] // Copy of vmConfig handed out by GetVMConfig
 }
 
 // NewBlockChain returns a fully initialised block chain using information
 // available in the database. It initialises the default Ethereum Validator
 // and Processor.
-func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis, overrides *ChainOverrides, engine consensus.Engine, vmConfig vm.Config, txLookupLimit *uint64, plugin *IndexerPlugin) (*BlockChain, error) {
+func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis, overrides *ChainOverrides, engine consensus.Engine, vmConfig vm.Config, txLookupLimit *uint64, plugins ...Plugin) (*BlockChain, error) {
 	if cacheConfig == nil {
 		cacheConfig = defaultCacheConfig
 	}
@@ -303,8 +305,8 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		engine:        engine,
 		vmConfig:      vmConfig,
 		logger:        vmConfig.Tracer,
-		plugin:        plugin, // Initialize plugin directly
 	}
+	bc.publicVMConfig.Store(&vmConfig)
 	var err error
 	bc.hc, err = NewHeaderChain(db, chainConfig, engine, bc.insertStopped)
 	if err != nil {
@@ -469,9 +471,12 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	if txLookupLimit != nil {
 		bc.txIndexer = newTxIndexer(*txLookupLimit, bc)
 	}
-	// Initialize plugin if provided
-	if plugin != nil {
-		plugin.OnInit(bc)
+	// Attach any plugins supplied by the caller. A plugin failing to
+	// initialize is reported but doesn't prevent the chain from starting.
+	for _, plugin := range plugins {
+		if err := bc.RegisterPlugin(plugin); err != nil {
+			log.Error("Failed to register chain plugin", "plugin", fmt.Sprintf("%T", plugin), "err", err)
+		}
 	}
 	return bc, nil
 }
@@ -584,9 +589,7 @@ func (bc *BlockChain) SetHead(head uint64) error {
 		log.Error("Current block not found in database", "block", header.Number, "hash", header.Hash())
 		return fmt.Errorf("current block missing: #%d [%x..]", header.Number, header.Hash().Bytes()[:4])
 	}
-	if bc.plugin != nil {
-		bc.plugin.OnHead(header)
-	}
+	bc.plugins.onHead(header)
 	bc.chainHeadFeed.Send(ChainHeadEvent{Header: header})
 	return nil
 }
@@ -608,9 +611,7 @@ func (bc *BlockChain) SetHeadWithTimestamp(timestamp uint64) error {
 		log.Error("Current block not found in database", "block", header.Number, "hash", header.Hash())
 		return fmt.Errorf("current block missing: #%d [%x..]", header.Number, header.Hash().Bytes()[:4])
 	}
-	if bc.plugin != nil {
-		bc.plugin.OnHead(header)
-	}
+	bc.plugins.onHead(header)
 	bc.chainHeadFeed.Send(ChainHeadEvent{Header: header})
 	return nil
 }
@@ -621,9 +622,7 @@ func (bc *BlockChain) SetFinalized(header *types.Header) {
 	if header != nil {
 		rawdb.WriteFinalizedBlockHash(bc.db, header.Hash())
 		headFinalizedBlockGauge.Update(int64(header.Number.Uint64()))
-		if bc.plugin != nil {
-			bc.plugin.OnFinal(header)
-		}
+		bc.plugins.onFinal(header)
 	} else {
 		rawdb.WriteFinalizedBlockHash(bc.db, common.Hash{})
 		headFinalizedBlockGauge.Update(0)
@@ -1007,6 +1006,7 @@ func (bc *BlockChain) ResetWithGenesisBlock(genesis *types.Block) error {
 		log.Crit("Failed to write genesis block", "err", err)
 	}
 	bc.writeHeadBlock(genesis)
+	bc.dispatchLocked(func(r *pluginRegistry) { r.onHead(genesis.Header()) })
 
 	// Last update all in-memory chain markers
 	bc.genesisBlock = genesis
@@ -1083,10 +1083,6 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 
 	bc.currentBlock.Store(block.Header())
 	headBlockGauge.Update(int64(block.NumberU64()))
-
-	if bc.plugin != nil {
-		bc.plugin.OnHead(block.Header())
-	}
 }
 
 // stopWithoutSaving stops the blockchain service. If any imports are currently in progress
@@ -1176,9 +1172,7 @@ func (bc *BlockChain) Stop() {
 	if bc.logger != nil && bc.logger.OnClose != nil {
 		bc.logger.OnClose()
 	}
-	if bc.plugin != nil {
-		bc.plugin.OnClose()
-	}
+	bc.plugins.close()
 	// Close the trie database, release all the held resources as the last step.
 	if err := bc.triedb.Close(); err != nil {
 		log.Error("Failed to close trie database", "err", err)
@@ -1463,6 +1457,7 @@ func (bc *BlockChain) writeKnownBlock(block *types.Block) error {
 		}
 	}
 	bc.writeHeadBlock(block)
+	bc.dispatchLocked(func(r *pluginRegistry) { r.onHead(block.Header()) })
 	return nil
 }
 
@@ -1572,21 +1567,11 @@ func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types
 	// Set new head.
 	bc.writeHeadBlock(block)
 
-	if bc.plugin != nil {
-		bc.plugin.OnHead(block.Header())
-	}
+	bc.dispatchLocked(func(r *pluginRegistry) { r.onHead(block.Header()) })
 	bc.chainFeed.Send(ChainEvent{Header: block.Header()})
 	if len(logs) > 0 {
 		bc.logsFeed.Send(logs)
 	}
-	// In theory, we should fire a ChainHeadEvent when we inject
-	// a canonical block, but sometimes we can insert a batch of
-	// canonical blocks. Avoid firing too many ChainHeadEvents,
-	// we will fire an accumulated ChainHeadEvent and disable fire
-	// event here.
-	if emitHeadEvent {
-		bc.plugin.OnHead(block.Header())
-	}
 	return CanonStatTy, nil
 }
 
@@ -1643,18 +1628,8 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool, makeWitness
 	// Start a parallel signature recovery (signer will fluke on fork transition, minimal perf loss)
 	SenderCacher.RecoverFromBlocks(types.MakeSigner(bc.chainConfig, chain[0].Number(), chain[0].Time()), chain)
 
-	var (
-		stats     = insertStats{startTime: mclock.Now()}
-		lastCanon *types.Block
-	)
-	// Fire a single chain head event if we've progressed the chain
-	defer func() {
-		if lastCanon != nil && bc.CurrentBlock().Hash() == lastCanon.Hash() {
-			if bc.plugin != nil {
-				bc.plugin.OnHead(lastCanon.Header())
-			}
-		}
-	}()
+	stats := insertStats{startTime: mclock.Now()}
+
 	// Start the parallel header verifier
 	headers := make([]*types.Header, len(chain))
 	for i, block := range chain {
@@ -1697,8 +1672,6 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool, makeWitness
 			if err := bc.writeKnownBlock(block); err != nil {
 				return nil, it.index, err
 			}
-			lastCanon = block
-
 			block, err = it.next()
 		}
 		// Falls through to the block import
@@ -1786,9 +1759,6 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool, makeWitness
 					Safe:      bc.CurrentSafeBlock(),
 				})
 			}
-			// We can assume that logs are empty here, since the only way for consecutive
-			// Clique blocks to have the same state is if there are no transactions.
-			lastCanon = block
 			continue
 		}
 		// Retrieve the parent block and it's state to execute on top
@@ -1870,8 +1840,6 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool, makeWitness
 				"elapsed", common.PrettyDuration(time.Since(start)),
 				"root", block.Root())
 
-			lastCanon = block
-
 			// Only count canonical blocks for GC processing time
 			bc.gcproc += res.procTime
 
@@ -2387,36 +2355,16 @@ func (bc *BlockChain) reorg(oldHead *types.Header, newHead *types.Header) error
 	// Release the tx-lookup lock after mutation.
 	bc.txLookupLock.Unlock()
 
-	// Trigger revertal reorgs
-	if len(oldChain) > 0 {
-		if bc.plugin != nil {
-			bc.plugin.OnReorg(oldChain, nil)
-		}
+	// Notify plugins once the new chain is written, so they see the reorg as a
+	// whole. Both sides are passed in ascending order, the new side including
+	// the new head. Extending the chain by several blocks at once is announced
+	// too, with nothing dropped, as only the new head reaches OnHead.
+	if len(oldChain) > 0 || len(newChain) > 1 {
+		oldHeaders, newHeaders := slices.Clone(oldChain), slices.Clone(newChain)
+		slices.Reverse(oldHeaders)
+		slices.Reverse(newHeaders)
+		bc.dispatchLocked(func(r *pluginRegistry) { r.onReorg(oldHeaders, newHeaders) })
 	}
-
-	// Trigger application reorgs
-	if len(newChain) > 1 {
-		slices.Reverse(newChain)
-		if bc.plugin != nil {
-			bc.plugin.OnReorg(newChain[:len(newChain)-1], nil)
-		}
-		slices.Reverse(newChain)
-	}
-
-	// Only trigger plugin hooks if plugin is configured
-	if bc.plugin != nil {
-		if len(oldChain) > 0 || len(newChain) > 1 {
-			var newHeaders []*types.Header
-			if len(newChain) > 1 {
-				// Skip the last header as it's handled separately
-				newHeaders = make([]*types.Header, len(newChain)-1)
-				copy(newHeaders, newChain)
-				slices.Reverse(newHeaders)
-			}
-			bc.plugin.OnReorg(oldChain, newHeaders)
-		}
-	}
-
 	return nil
 }
 
@@ -2466,9 +2414,7 @@ func (bc *BlockChain) SetCanonical(head *types.Block) (common.Hash, error) {
 	if len(logs) > 0 {
 		bc.logsFeed.Send(logs)
 	}
-	if bc.plugin != nil {
-		bc.plugin.OnHead(head.Header())
-	}
+	bc.dispatchLocked(func(r *pluginRegistry) { r.onHead(head.Header()) })
 	bc.chainHeadFeed.Send(ChainHeadEvent{Header: head.Header()})
 
 	context := []interface{}{
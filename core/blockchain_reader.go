@@ -396,9 +396,10 @@ func (bc *BlockChain) Genesis() *types.Block {
 	return bc.genesisBlock
 }
 
-// GetVMConfig returns the block chain VM config.
+// GetVMConfig returns the block chain VM config, including the tracing hooks of
+// the plugins registered so far.
 func (bc *BlockChain) GetVMConfig() *vm.Config {
-	return &bc.vmConfig
+	return bc.publicVMConfig.Load()
 }
 
 // TxIndexProgress returns the transaction indexing progress.
@@ -1,335 +1,569 @@
-// TODO add retries
 package core
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/lib/pq"
 )
 
 // Block represents the blocks table schema
 type Block struct {
 	Number                uint64         `db:"number"`
-	Hash                  string         `db:"hash"`
-	ParentHash            string         `db:"parent_hash"`
+	Hash                  common.Hash    `db:"hash"`
+	ParentHash            common.Hash    `db:"parent_hash"`
 	Timestamp             time.Time      `db:"timestamp"`
-	Nonce                 string         `db:"nonce"`
-	BaseFeePerGas         sql.NullString `db:"base_fee_per_gas"`
-	BlobGasUsed           sql.NullString `db:"blob_gas_used"`
-	Difficulty            string         `db:"difficulty"`
-	ExcessBlobGas         sql.NullString `db:"excess_blob_gas"`
-	ExtraData             string         `db:"extra_data"`
-	GasLimit              string         `db:"gas_limit"`
-	GasUsed               string         `db:"gas_used"`
-	LogsBloom             sql.NullString `db:"logs_bloom"`
-	Miner                 string         `db:"miner"`
-	MixHash               string         `db:"mix_hash"`
-	ParentBeaconBlockRoot sql.NullString `db:"parent_beacon_block_root"`
-	ReceiptsRoot          string         `db:"receipts_root"`
-	Sha3Uncles            string         `db:"sha3_uncles"`
-	Size                  string         `db:"size"`
-	StateRoot             string         `db:"state_root"`
-	TotalDifficulty       sql.NullString `db:"total_difficulty"`
-	TransactionsRoot      string         `db:"transactions_root"`
-	WithdrawalsRoot       sql.NullString `db:"withdrawals_root"`
-	SealFields            []string       `db:"seal_fields"`
-	Transactions          []string       `db:"transactions"`
-	Uncles                []string       `db:"uncles"`
-	BlockReward           string         `db:"block_reward"`
-	UncleReward           string         `db:"uncle_reward"`
+	Nonce                 []byte         `db:"nonce"`
+	BaseFeePerGas         *BigInt        `db:"base_fee_per_gas"`
+	BlobGasUsed           *uint64        `db:"blob_gas_used"`
+	Difficulty            *BigInt        `db:"difficulty"`
+	ExcessBlobGas         *uint64        `db:"excess_blob_gas"`
+	ExtraData             []byte         `db:"extra_data"`
+	GasLimit              uint64         `db:"gas_limit"`
+	GasUsed               uint64         `db:"gas_used"`
+	LogsBloom             []byte         `db:"logs_bloom"`
+	Miner                 common.Address `db:"miner"`
+	MixHash               common.Hash    `db:"mix_hash"`
+	ParentBeaconBlockRoot *common.Hash   `db:"parent_beacon_block_root"`
+	ReceiptsRoot          common.Hash    `db:"receipts_root"`
+	Sha3Uncles            common.Hash    `db:"sha3_uncles"`
+	Size                  *uint64        `db:"size"`
+	StateRoot             common.Hash    `db:"state_root"`
+	TotalDifficulty       *BigInt        `db:"total_difficulty"`
+	TransactionsRoot      common.Hash    `db:"transactions_root"`
+	WithdrawalsRoot       *common.Hash   `db:"withdrawals_root"`
+	SealFields            pq.StringArray `db:"seal_fields"`
+	Transactions          pq.ByteaArray  `db:"transactions"`
+	Uncles                pq.ByteaArray  `db:"uncles"`
+	BlockReward           *BigInt        `db:"block_reward"`
+	UncleReward           *BigInt        `db:"uncle_reward"`
+	Finalized             bool           `db:"finalized"`
 }
 
 // Transaction represents the transactions table schema
 type Transaction struct {
-	Hash           string         `db:"hash"`
-	BlockNumber    uint64         `db:"block_number"`
-	From           string         `db:"from"`
-	To             sql.NullString `db:"to"`
-	Value          string         `db:"value"`
-	Nonce          uint64         `db:"nonce"`
-	GasPrice       string         `db:"gas_price"`
-	GasLimit       uint64         `db:"gas_limit"`
-	GasUsed        uint64         `db:"gas_used"`
-	Input          string         `db:"input"`
-	Status         uint64         `db:"status"`
-	Type           uint64         `db:"type"`
-	MaxFeePerGas   sql.NullString `db:"max_fee_per_gas"`
-	MaxPriorityFee sql.NullString `db:"max_priority_fee"`
-	BlobGasUsed    sql.NullString `db:"blob_gas_used"`
-	BlobGasPrice   sql.NullString `db:"blob_gas_price"`
-	Error          sql.NullString `db:"error"`
+	Hash             common.Hash     `db:"hash"`
+	BlockNumber      uint64          `db:"block_number"`
+	TransactionIndex uint64          `db:"transaction_index"`
+	From             common.Address  `db:"from"`
+	To               *common.Address `db:"to"` // null for contract creations
+	Value            *BigInt         `db:"value"`
+	Nonce            uint64          `db:"nonce"`
+	GasPrice         *BigInt         `db:"gas_price"`
+	GasLimit         uint64          `db:"gas_limit"`
+	GasUsed          uint64          `db:"gas_used"`
+	Input            []byte          `db:"input"`
+	Status           uint64          `db:"status"`
+	Type             uint64          `db:"type"`
+	MaxFeePerGas     *BigInt         `db:"max_fee_per_gas"`
+	MaxPriorityFee   *BigInt         `db:"max_priority_fee"`
+	BlobGasUsed      *uint64         `db:"blob_gas_used"`
+	BlobGasPrice     *BigInt         `db:"blob_gas_price"`
+	Error            sql.NullString  `db:"error"`
 }
 
 // Log represents the logs table schema
 type Log struct {
-	ID              uint64   `db:"id"`
-	TransactionHash string   `db:"transaction_hash"`
-	BlockNumber     uint64   `db:"block_number"`
-	Address         string   `db:"address"`
-	Topics          []string `db:"topics"`
-	Data            string   `db:"data"`
-	LogIndex        uint64   `db:"log_index"`
-	Removed         bool     `db:"removed"`
+	ID              uint64         `db:"id"`
+	TransactionHash common.Hash    `db:"transaction_hash"`
+	BlockNumber     uint64         `db:"block_number"`
+	BlockHash       common.Hash    `db:"block_hash"`
+	Address         common.Address `db:"address"`
+	Topics          pq.ByteaArray  `db:"topics"`
+	Data            []byte         `db:"data"`
+	LogIndex        uint64         `db:"log_index"`
+	Removed         bool           `db:"removed"` // block was dropped by a reorg
+}
+
+// Reorg represents an audit record of a chain reorganization
+type Reorg struct {
+	ID             uint64        `db:"id"`
+	DetectedAt     time.Time     `db:"detected_at"`
+	AncestorNumber uint64        `db:"ancestor_number"` // common ancestor of both chains
+	AncestorHash   common.Hash   `db:"ancestor_hash"`
+	Depth          uint64        `db:"depth"`    // number of blocks dropped
+	OldHead        *common.Hash  `db:"old_head"` // null if no block was dropped
+	NewHead        *common.Hash  `db:"new_head"` // null if no block was added
+	OldHashes      pq.ByteaArray `db:"old_hashes"`
+	NewHashes      pq.ByteaArray `db:"new_hashes"`
 }
 
-// StateChange represents state changes within a transaction
+// StateChange represents the net change of a piece of state within a transaction,
+// system call or block-level operation
 type StateChange struct {
 	ID              uint64         `db:"id"`
 	BlockNumber     uint64         `db:"block_number"`
-	TransactionHash string         `db:"transaction_hash"`
-	Address         string         `db:"address"`
-	StorageKey      sql.NullString `db:"storage_key"`
+	TransactionHash *common.Hash   `db:"transaction_hash"` // null outside of transactions
+	Address         common.Address `db:"address"`
+	StorageKey      *common.Hash   `db:"storage_key"`
 	PrevValue       string         `db:"prev_value"`
 	NewValue        string         `db:"new_value"`
 	ChangeType      string         `db:"change_type"` // balance, nonce, code, storage
+	Source          string         `db:"source"`      // transaction, system_call, withdrawal, reward, block
+}
+
+// Trace represents a call frame executed by a transaction, the internal
+// transactions of Parity style traces. Frames are numbered in the order they
+// were entered, the top-level call being the first.
+type Trace struct {
+	ID              uint64          `db:"id"`
+	BlockNumber     uint64          `db:"block_number"`
+	TransactionHash common.Hash     `db:"transaction_hash"`
+	TraceIndex      uint64          `db:"trace_index"`   // position of the frame within the transaction
+	TraceAddress    pq.Int64Array   `db:"trace_address"` // child indices leading from the top-level call
+	Type            string          `db:"type"`          // CALL, STATICCALL, DELEGATECALL, CALLCODE, CREATE, CREATE2, SELFDESTRUCT
+	From            common.Address  `db:"from"`
+	To              *common.Address `db:"to"`
+	Value           *BigInt         `db:"value"`
+	Gas             uint64          `db:"gas"`
+	GasUsed         uint64          `db:"gas_used"`
+	Input           []byte          `db:"input"`
+	Output          []byte          `db:"output"`
+	Error           sql.NullString  `db:"error"`
+}
+
+// DecodedEvent represents a log decoded into an event of a known contract ABI
+type DecodedEvent struct {
+	ID              uint64         `db:"id"`
+	BlockNumber     uint64         `db:"block_number"`
+	TransactionHash common.Hash    `db:"transaction_hash"`
+	LogIndex        uint64         `db:"log_index"`
+	Address         common.Address `db:"address"`
+	Contract        string         `db:"contract"`  // name of the ABI, or the token standard
+	Event           string         `db:"event"`     // event name
+	Signature       string         `db:"signature"` // canonical event signature
+	Args            string         `db:"args"`      // JSON arguments by name, integers as decimal strings
+}
+
+// TokenTransfer represents a single token moved by an ERC-20, ERC-721 or
+// ERC-1155 transfer event
+type TokenTransfer struct {
+	ID              uint64          `db:"id"`
+	BlockNumber     uint64          `db:"block_number"`
+	TransactionHash common.Hash     `db:"transaction_hash"`
+	LogIndex        uint64          `db:"log_index"`
+	BatchIndex      uint64          `db:"batch_index"` // position within an ERC-1155 batch
+	Token           common.Address  `db:"token"`
+	Standard        string          `db:"standard"` // erc20, erc721, erc1155
+	Operator        *common.Address `db:"operator"` // null unless ERC-1155
+	From            common.Address  `db:"from"`
+	To              common.Address  `db:"to"`
+	TokenID         *BigInt         `db:"token_id"` // null for ERC-20
+	Value           *BigInt         `db:"value"`
+}
+
+// Withdrawal represents a validator withdrawal (EIP-4895) credited by a block
+type Withdrawal struct {
+	ID              uint64         `db:"id"`
+	BlockNumber     uint64         `db:"block_number"`
+	WithdrawalIndex uint64         `db:"withdrawal_index"` // monotonic index across all withdrawals
+	ValidatorIndex  uint64         `db:"validator_index"`
+	Address         common.Address `db:"address"`
+	Amount          uint64         `db:"amount"` // in Gwei
+}
+
+// BlobHash represents the versioned hash of a blob carried by a blob
+// transaction (EIP-4844)
+type BlobHash struct {
+	ID              uint64      `db:"id"`
+	BlockNumber     uint64      `db:"block_number"`
+	TransactionHash common.Hash `db:"transaction_hash"`
+	BlobIndex       uint64      `db:"blob_index"` // position within the transaction
+	VersionedHash   common.Hash `db:"versioned_hash"`
+	BlobGasPrice    *BigInt     `db:"blob_gas_price"`
 }
 
 // AccessList represents transaction access lists
 type AccessList struct {
-	ID              uint64 `db:"id"`
-	TransactionHash string `db:"transaction_hash"`
-	Address         string `db:"address"`
-	StorageKey      string `db:"storage_key"`
+	ID              uint64         `db:"id"`
+	TransactionHash common.Hash    `db:"transaction_hash"`
+	Address         common.Address `db:"address"`
+	StorageKey      common.Hash    `db:"storage_key"`
 }
 
 // Account represents both EOAs and Contracts
 type Account struct {
-	Address        string         `db:"address"`
-	Balance        string         `db:"balance"`
-	Nonce          uint64         `db:"nonce"`
-	Code           sql.NullString `db:"code"`            // null for EOA, populated for contracts
-	CreatorAddress sql.NullString `db:"creator_address"` // null for EOA
-	CreatorTxHash  sql.NullString `db:"creator_tx_hash"` // null for EOA
-	CreatedAt      sql.NullTime   `db:"created_at"`      // block timestamp when created
+	Address        common.Address  `db:"address"`
+	Balance        *BigInt         `db:"balance"`
+	Nonce          uint64          `db:"nonce"`
+	Code           []byte          `db:"code"`            // null for EOA, populated for contracts
+	CreatorAddress *common.Address `db:"creator_address"` // null for EOA
+	CreatorTxHash  *common.Hash    `db:"creator_tx_hash"` // null for EOA
+	CreatedAt      sql.NullTime    `db:"created_at"`      // block timestamp when created
+	SelfDestructed bool            `db:"self_destructed"` // contract was removed from the state
+	UpdatedBlock   uint64          `db:"updated_block"`   // block of the state snapshot
+}
+
+// Checkpoint records a contiguous range of blocks processed by a long running
+// indexer job, allowing it to resume after an interruption
+type Checkpoint struct {
+	Name       string    `db:"name"`
+	FirstBlock uint64    `db:"first_block"`
+	LastBlock  uint64    `db:"last_block"`
+	UpdatedAt  time.Time `db:"updated_at"`
 }
 
 // Receipt represents a transaction receipt in the database
 type Receipt struct {
-	ID               uint64 `db:"id"`
-	BlockNumber      uint64 `db:"block_number"`
-	BlockHash        string `db:"block_hash"`
-	TransactionHash  string `db:"transaction_hash"`
-	TransactionIndex uint   `db:"transaction_index"`
-	ContractAddress  string `db:"contract_address"`
-	GasUsed         uint64 `db:"gas_used"`
-	Status          uint64 `db:"status"`
-}
-
-// CreateTablesSQL contains the SQL statements to create the tables
-const CreateTablesSQL = `
-CREATE TABLE IF NOT EXISTS blocks (
-    number BIGINT PRIMARY KEY,
-    hash VARCHAR(66) NOT NULL UNIQUE,
-    parent_hash VARCHAR(66) NOT NULL,
-    timestamp TIMESTAMP NOT NULL,
-    nonce VARCHAR(255) NOT NULL,
-    base_fee_per_gas VARCHAR(255),
-    blob_gas_used VARCHAR(255),
-    difficulty VARCHAR(255) NOT NULL,
-    excess_blob_gas VARCHAR(255),
-    extra_data VARCHAR(255) NOT NULL,
-    gas_limit VARCHAR(255) NOT NULL,
-    gas_used VARCHAR(255) NOT NULL,
-    logs_bloom TEXT,
-    miner VARCHAR(42) NOT NULL,
-    mix_hash VARCHAR(66) NOT NULL,
-    parent_beacon_block_root VARCHAR(66),
-    receipts_root VARCHAR(66) NOT NULL,
-    sha3_uncles VARCHAR(66) NOT NULL,
-    size VARCHAR(255) NOT NULL,
-    state_root VARCHAR(66) NOT NULL,
-    total_difficulty VARCHAR(255),
-    transactions_root VARCHAR(66) NOT NULL,
-    withdrawals_root VARCHAR(66),
-    seal_fields TEXT[],
-    transactions TEXT[],
-    uncles TEXT[],
-    block_reward VARCHAR(255) NOT NULL,
-    uncle_reward VARCHAR(255) NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS transactions (
-    hash VARCHAR(66) PRIMARY KEY,
-    block_number BIGINT NOT NULL REFERENCES blocks(number),
-    "from" VARCHAR(42) NOT NULL,
-    "to" VARCHAR(42),
-    value VARCHAR(255) NOT NULL,
-    nonce BIGINT NOT NULL,
-    gas_price VARCHAR(255) NOT NULL,
-    gas_limit BIGINT NOT NULL,
-    gas_used BIGINT NOT NULL,
-    input TEXT NOT NULL,
-    status SMALLINT NOT NULL,
-    type SMALLINT NOT NULL,
-    max_fee_per_gas VARCHAR(255),
-    max_priority_fee VARCHAR(255),
-    blob_gas_used VARCHAR(255),
-    blob_gas_price VARCHAR(255),
-    error TEXT
-);
-
-CREATE TABLE IF NOT EXISTS logs (
-    id BIGSERIAL PRIMARY KEY,
-    transaction_hash VARCHAR(66) NOT NULL REFERENCES transactions(hash),
-    block_number BIGINT NOT NULL REFERENCES blocks(number),
-    address VARCHAR(42) NOT NULL,
-    topics TEXT[] NOT NULL,
-    data TEXT NOT NULL,
-    log_index BIGINT NOT NULL,
-    removed BOOLEAN NOT NULL DEFAULT FALSE
-);
-
-CREATE TABLE IF NOT EXISTS state_changes (
-    id BIGSERIAL PRIMARY KEY,
-    block_number BIGINT NOT NULL REFERENCES blocks(number),
-    transaction_hash VARCHAR(66) NOT NULL REFERENCES transactions(hash),
-    address VARCHAR(42) NOT NULL,
-    storage_key VARCHAR(66),
-    prev_value TEXT NOT NULL,
-    new_value TEXT NOT NULL,
-    change_type VARCHAR(20) NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS access_lists (
-    id BIGSERIAL PRIMARY KEY,
-    transaction_hash VARCHAR(66) NOT NULL REFERENCES transactions(hash),
-    address VARCHAR(42) NOT NULL,
-    storage_key VARCHAR(66) NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS accounts (
-    address VARCHAR(42) PRIMARY KEY,
-    balance VARCHAR(255) NOT NULL,
-    nonce BIGINT NOT NULL,
-    code TEXT,
-    creator_address VARCHAR(42),
-    creator_tx_hash VARCHAR(66) REFERENCES transactions(hash),
-    created_at TIMESTAMP
-);
-
-CREATE TABLE IF NOT EXISTS receipts (
-    id BIGSERIAL PRIMARY KEY,
-    block_number BIGINT NOT NULL REFERENCES blocks(number),
-    block_hash VARCHAR(66) NOT NULL,
-    transaction_hash VARCHAR(66) NOT NULL REFERENCES transactions(hash),
-    transaction_index BIGINT NOT NULL,
-    contract_address VARCHAR(42),
-    gas_used BIGINT NOT NULL,
-    status SMALLINT NOT NULL,
-    UNIQUE(transaction_hash)
-);
-
-CREATE INDEX IF NOT EXISTS idx_logs_address ON logs(address);
-CREATE INDEX IF NOT EXISTS idx_logs_topics ON logs USING gin(topics);
-CREATE INDEX IF NOT EXISTS idx_state_changes_address ON state_changes(address);
-CREATE INDEX IF NOT EXISTS idx_access_lists_address ON access_lists(address);
-CREATE INDEX IF NOT EXISTS idx_accounts_creator ON accounts(creator_address);
-CREATE INDEX IF NOT EXISTS idx_receipts_block ON receipts(block_number);
-CREATE INDEX IF NOT EXISTS idx_receipts_contract ON receipts(contract_address);
-CREATE OR REPLACE FUNCTION notify_new_block()
-RETURNS TRIGGER AS $$
-BEGIN
-    PERFORM pg_notify('new_block', row_to_json(NEW)::text);
-    RETURN NEW;
-END;
-$$ LANGUAGE plpgsql;
-
-CREATE TRIGGER blocks_notify_trigger
-    AFTER INSERT ON blocks
-    FOR EACH ROW
-    EXECUTE FUNCTION notify_new_block();
-`
-
-// IndexerDB handles the database connection and operations
-type IndexerDB struct {
-	db *sqlx.DB
-}
-
-// IndexerConfig holds database connection configuration
+	ID               uint64          `db:"id"`
+	BlockNumber      uint64          `db:"block_number"`
+	BlockHash        common.Hash     `db:"block_hash"`
+	TransactionHash  common.Hash     `db:"transaction_hash"`
+	TransactionIndex uint            `db:"transaction_index"`
+	ContractAddress  *common.Address `db:"contract_address"` // null unless a contract was created
+	GasUsed          uint64          `db:"gas_used"`
+	Status           uint64          `db:"status"`
+}
+
+// BigInt is an arbitrary precision integer column, stored as NUMERIC(78,0) on
+// PostgreSQL which fits any uint256 value. SQLite lacks an exact type of that
+// width and stores the decimal text instead.
+type BigInt struct {
+	big.Int
+}
+
+// NewBigInt converts a big integer into a column value, nil is kept as NULL.
+func NewBigInt(x *big.Int) *BigInt {
+	if x == nil {
+		return nil
+	}
+	b := new(BigInt)
+	b.Set(x)
+	return b
+}
+
+// ToBig returns the column value as a big integer, or nil if NULL.
+func (b *BigInt) ToBig() *big.Int {
+	if b == nil {
+		return nil
+	}
+	return new(big.Int).Set(&b.Int)
+}
+
+// Value implements driver.Valuer.
+func (b *BigInt) Value() (driver.Value, error) {
+	if b == nil {
+		return nil, nil
+	}
+	return b.String(), nil
+}
+
+// Scan implements sql.Scanner.
+func (b *BigInt) Scan(src any) error {
+	switch src := src.(type) {
+	case int64:
+		b.SetInt64(src)
+	case []byte:
+		return b.Scan(string(src))
+	case string:
+		if _, ok := b.SetString(src, 10); !ok {
+			return fmt.Errorf("invalid numeric value %q", src)
+		}
+	default:
+		return fmt.Errorf("can't scan %T into BigInt", src)
+	}
+	return nil
+}
+
+// hashesValue converts a list of hashes into a bytea array column value.
+func hashesValue(hashes []common.Hash) pq.ByteaArray {
+	array := make(pq.ByteaArray, len(hashes))
+	for i, hash := range hashes {
+		array[i] = hash.Bytes()
+	}
+	return array
+}
+
+// IndexerDB is the storage layer of the indexer. Writes belonging to a single
+// block are grouped into a database transaction obtained from Begin, so that
+// readers never observe partially indexed blocks.
+type IndexerDB interface {
+	// Driver returns the name of the database driver in use.
+	Driver() string
+
+	// Migrate brings the database schema up to date, returning the number of
+	// migrations applied.
+	Migrate() (int, error)
+
+	// MigrationStatus returns the state of all known and applied schema
+	// migrations, ordered by version.
+	MigrationStatus() ([]*MigrationStatus, error)
+
+	// Begin starts a new database transaction.
+	Begin() (*sqlx.Tx, error)
+
+	// Close closes the database connection.
+	Close() error
+
+	InsertBlock(block *Block) error
+	InsertBlockWithTx(tx *sqlx.Tx, block *Block) error
+	DeleteBlockAndDescendants(blockNumber uint64) error
+	DeleteBlockAndDescendantsWithTx(tx *sqlx.Tx, blockNumber uint64) error
+	DeleteBlockWithTx(tx *sqlx.Tx, blockNumber uint64) error
+	MarkBlockFinalized(blockNumber uint64) error
+	GetLatestFinalizedBlock() (uint64, error)
+	GetBlockByNumber(number uint64) (*Block, error)
+	GetBlockByHash(hash common.Hash) (*Block, error)
+	GetBlockHashes(from, to uint64) (map[uint64]common.Hash, error)
+	GetIndexedRange(from, to uint64) (uint64, uint64, common.Hash, bool, error)
+	GetLogs(ctx context.Context, from, to uint64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error)
+	GetLatestBlock() (uint64, error)
+	GetTransactionsByAddress(ctx context.Context, address common.Address, cursor string, limit int) ([]*Transaction, string, error)
+	GetContractsCreatedBy(ctx context.Context, creator common.Address, cursor string, limit int) ([]*Account, string, error)
+	GetStateChanges(ctx context.Context, address common.Address, slot *common.Hash, from, to uint64, cursor string, limit int) ([]*StateChange, string, error)
+	GetBlockSummaries(ctx context.Context, from, to uint64) (map[uint64]*BlockSummary, error)
+	GetCheckpoint(name string) (*Checkpoint, error)
+	SetCheckpoint(checkpoint *Checkpoint) error
+	InsertTransactionWithTx(tx *sqlx.Tx, transaction *Transaction) error
+	UpsertAccountWithTx(tx *sqlx.Tx, account *Account) error
+	InsertStateChangeWithTx(tx *sqlx.Tx, change *StateChange) error
+	InsertTraceWithTx(tx *sqlx.Tx, trace *Trace) error
+	InsertDecodedEventWithTx(tx *sqlx.Tx, event *DecodedEvent) error
+	InsertTokenTransferWithTx(tx *sqlx.Tx, transfer *TokenTransfer) error
+	InsertWithdrawalWithTx(tx *sqlx.Tx, withdrawal *Withdrawal) error
+	InsertBlobHashWithTx(tx *sqlx.Tx, blob *BlobHash) error
+	InsertReceiptWithTx(tx *sqlx.Tx, receipt *Receipt) error
+	InsertLogWithTx(tx *sqlx.Tx, log *Log) error
+	DeleteLogsWithTx(tx *sqlx.Tx, blockHash common.Hash) error
+	MarkLogsRemovedWithTx(tx *sqlx.Tx, blockNumber uint64, canonical common.Hash) error
+	DeleteReceiptsWithTx(tx *sqlx.Tx, blockNumber uint64) error
+	RepairTransactionsWithTx(tx *sqlx.Tx, blockNumber uint64, canonical []common.Hash) error
+	UnmarkBlockFinalizedWithTx(tx *sqlx.Tx, blockNumber uint64) error
+	InsertReorgWithTx(tx *sqlx.Tx, reorg *Reorg) error
+}
+
+// Supported indexer database drivers
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Optional tables of the indexer, only filled in if enabled in the config.
+const (
+	IndexerTableTraces         = "traces"
+	IndexerTableDecodedEvents  = "decoded_events"
+	IndexerTableTokenTransfers = "token_transfers"
+)
+
+// IndexerConfig holds the indexer database connection and plugin configuration
 type IndexerConfig struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
-}
-
-// NewDB creates a new database connection
-func NewDB(config IndexerConfig) (*IndexerDB, error) {
-	log.Info("Creating new database connection",
-		"host", config.Host,
-		"port", config.Port,
-		"database", config.DBName,
-		"user", config.User,
-		"sslMode", config.SSLMode)
-
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode)
-
-	// Connect to database
-	log.Info("Attempting to connect to database...")
-	db, err := sqlx.Connect("postgres", psqlInfo)
-	if err != nil {
-		log.Error("Database connection failed", "error", err)
-		return nil, fmt.Errorf("error connecting to the database: %v", err)
+	Enabled bool   // Whether the node runs the indexer
+	Driver  string // postgres (default) or sqlite
+
+	// Connection of the postgres driver, either as a libpq connection string
+	// or URL, or in parts. Settings left empty fall back to the libpq defaults
+	// and PG* environment variables. The password is never written to the
+	// config file, supply it via a file, the DSN or PGPASSWORD instead.
+	DSN          string `toml:",omitempty"` // overrides the settings below
+	Host         string `toml:",omitempty"`
+	Port         int    `toml:",omitempty"`
+	User         string `toml:",omitempty"`
+	PasswordFile string `toml:",omitempty"` // takes precedence over any other password
+	DBName       string `toml:",omitempty"`
+	SSLMode      string `toml:",omitempty"`
+
+	Path string `toml:",omitempty"` // database file, sqlite only
+
+	BatchSize  uint64   // Blocks per database transaction of backfills
+	StartBlock uint64   // First block indexed, older chain events are ignored
+	Tables     []string // Optional tables to fill: traces, decoded_events, token_transfers
+	ABIDir     string   `toml:",omitempty"` // Contract ABIs to decode events with, see NewEventDecoder
+
+	// Export of the indexed records into files, see FileSink
+	Sink          string `toml:",omitempty"` // file format (ndjson, parquet), empty to disable
+	SinkDir       string `toml:",omitempty"` // output directory
+	SinkPartition uint64 // blocks per file
+
+	MaxLag uint64 // Blocks the indexer may trail the chain head before it's reported unhealthy
+}
+
+// DefaultIndexerConfig contains the default indexer settings.
+var DefaultIndexerConfig = IndexerConfig{
+	Driver:        DriverPostgres,
+	Host:          "localhost",
+	Port:          5432,
+	User:          "postgres",
+	DBName:        "geth_indexer",
+	SSLMode:       "disable",
+	BatchSize:     defaultBackfillBatch,
+	SinkPartition: defaultSinkPartition,
+	MaxLag:        defaultIndexerMaxLag,
+}
+
+// UnmarshalTOML implements toml.Unmarshaler, starting from the defaults so that
+// a config file only needs to list the settings it changes.
+func (c *IndexerConfig) UnmarshalTOML(unmarshal func(any) error) error {
+	type config IndexerConfig
+	dec := config(DefaultIndexerConfig)
+	if err := unmarshal(&dec); err != nil {
+		return err
+	}
+	*c = IndexerConfig(dec)
+	return nil
+}
+
+// postgresDSN assembles the libpq connection string of the postgres driver.
+func (c *IndexerConfig) postgresDSN() (string, error) {
+	var password string
+	if c.PasswordFile != "" {
+		blob, err := os.ReadFile(c.PasswordFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %v", err)
+		}
+		password = strings.TrimRight(string(blob), "\r\n")
+	}
+	if c.DSN != "" {
+		dsn := c.DSN
+		if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+			var err error
+			if dsn, err = pq.ParseURL(dsn); err != nil {
+				return "", fmt.Errorf("invalid DSN: %v", err)
+			}
+		}
+		if password != "" {
+			dsn += " password=" + quoteConnValue(password)
+		}
+		return dsn, nil
+	}
+	var params []string
+	for _, param := range []struct{ key, value string }{
+		{"host", c.Host},
+		{"port", strconv.Itoa(c.Port)},
+		{"user", c.User},
+		{"password", password},
+		{"dbname", c.DBName},
+		{"sslmode", c.SSLMode},
+	} {
+		if param.value != "" && param.value != "0" {
+			params = append(params, param.key+"="+quoteConnValue(param.value))
+		}
 	}
+	return strings.Join(params, " "), nil
+}
+
+// quoteConnValue quotes a value of a libpq connection string.
+func quoteConnValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
 
-	// Test the connection
-	log.Info("Testing database connection...")
-	err = db.Ping()
+// NewDB creates a new database connection using the configured driver and
+// upgrades the database schema to the latest version
+func NewDB(config IndexerConfig) (IndexerDB, error) {
+	db, err := OpenDB(config)
 	if err != nil {
-		log.Error("Database ping failed", "error", err)
-		return nil, fmt.Errorf("error pinging the database: %v", err)
+		return nil, err
 	}
-	log.Info("Database ping successful")
+	if _, err := db.Migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error migrating database schema: %v", err)
+	}
+	return db, nil
+}
+
+// IndexerDriver opens a connection to an indexer database.
+type IndexerDriver func(config IndexerConfig) (IndexerDB, error)
+
+var (
+	// indexerDrivers are the available database drivers by name. Drivers which
+	// need cgo live in their own packages and register when imported, so they
+	// are only linked into the binaries using them, see core/indexer/sqlite.
+	indexerDrivers = map[string]IndexerDriver{
+		DriverPostgres: func(config IndexerConfig) (IndexerDB, error) {
+			db, err := newPostgresDB(config)
+			if err != nil {
+				return nil, err
+			}
+			return db, nil
+		},
+	}
+	indexerDriversLock sync.RWMutex
+)
+
+// RegisterIndexerDriver makes an indexer database driver available under the
+// given name, replacing any registered before.
+func RegisterIndexerDriver(name string, open IndexerDriver) {
+	indexerDriversLock.Lock()
+	defer indexerDriversLock.Unlock()
 
-	// Set connection pool settings
-	log.Info("Configuring connection pool",
-		"maxOpenConns", 25,
-		"maxIdleConns", 25,
-		"connMaxLifetime", "5m")
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(25)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	indexerDrivers[name] = open
+}
+
+// NewSQLIndexerDB creates an IndexerDB running the shared queries on an open
+// database connection. The driver name selects the SQL dialect and migrations,
+// it must be one of the supported drivers.
+func NewSQLIndexerDB(db *sqlx.DB, driver string) IndexerDB {
+	return &sqlDB{db: db, driver: driver}
+}
 
-	// Create new IndexerDB instance
-	indexerDB := &IndexerDB{
-		db: db,
+// OpenDB creates a new database connection using the configured driver, without
+// touching the database schema
+func OpenDB(config IndexerConfig) (IndexerDB, error) {
+	driver := config.Driver
+	if driver == "" {
+		driver = DriverPostgres
 	}
+	indexerDriversLock.RLock()
+	open, ok := indexerDrivers[driver]
+	indexerDriversLock.RUnlock()
 
-	// Initialize database schema
-	log.Info("Initializing database schema...")
-	if err := indexerDB.initSchema(); err != nil {
-		log.Error("Schema initialization failed", "error", err)
-		return nil, fmt.Errorf("error initializing database schema: %v", err)
+	if !ok {
+		return nil, fmt.Errorf("unsupported indexer database driver %q", config.Driver)
 	}
-	log.Info("Database schema initialized successfully")
+	return open(config)
+}
+
+// sqlDB implements the queries of IndexerDB shared by all SQL databases. The
+// statements are written with '?' placeholders and rebound to the bindvar
+// style of the driver.
+type sqlDB struct {
+	db     *sqlx.DB
+	driver string
+}
+
+// Driver implements IndexerDB
+func (idb *sqlDB) Driver() string {
+	return idb.driver
+}
 
-	log.Info("Database connection and setup completed successfully")
-	return indexerDB, nil
+// Begin starts a new database transaction
+func (idb *sqlDB) Begin() (*sqlx.Tx, error) {
+	return idb.db.Beginx()
 }
 
-// initSchema creates the database tables if they don't exist
-func (idb *IndexerDB) initSchema() error {
-	_, err := idb.db.Exec(CreateTablesSQL)
+// execRowWithTx writes a single row into a table within a transaction, tracking
+// the write latency, failures and the rows written per table.
+func (idb *sqlDB) execRowWithTx(tx *sqlx.Tx, table string, query string, row any) error {
+	start := time.Now()
+	_, err := tx.NamedExec(query, row)
+	indexerWriteTimer.UpdateSince(start)
 	if err != nil {
-		return fmt.Errorf("error creating tables: %v", err)
+		indexerWriteErrorMeter.Mark(1)
+		return err
 	}
+	indexerRowsMeter(table).Mark(1)
 	return nil
 }
 
 // Close closes the database connection
-func (idb *IndexerDB) Close() error {
+func (idb *sqlDB) Close() error {
 	return idb.db.Close()
 }
 
 // InsertBlock inserts a block into the database
-func (idb *IndexerDB) InsertBlock(block *Block) error {
+func (idb *sqlDB) InsertBlock(block *Block) error {
 	query := `
 		INSERT INTO blocks (
 			number, hash, parent_hash, timestamp, nonce, base_fee_per_gas,
@@ -354,8 +588,9 @@ func (idb *IndexerDB) InsertBlock(block *Block) error {
 	return nil
 }
 
-// DeleteBlockAndDescendants deletes a block and all its associated data
-func (idb *IndexerDB) DeleteBlockAndDescendants(blockNumber uint64) error {
+// DeleteBlockAndDescendants deletes a block and all its associated data. Logs
+// are kept, but marked as removed.
+func (idb *sqlDB) DeleteBlockAndDescendants(blockNumber uint64) error {
 	tx, err := idb.db.Beginx()
 	if err != nil {
 		return fmt.Errorf("error starting transaction: %v", err)
@@ -364,16 +599,25 @@ func (idb *IndexerDB) DeleteBlockAndDescendants(blockNumber uint64) error {
 	// Delete in reverse order of dependencies to respect foreign key constraints
 	deleteQueries := []string{
 		`DELETE FROM access_lists WHERE transaction_hash IN (
-			SELECT hash FROM transactions WHERE block_number >= $1
+			SELECT hash FROM transactions WHERE block_number >= ?
+		)`,
+		`DELETE FROM accounts WHERE creator_tx_hash IN (
+			SELECT hash FROM transactions WHERE block_number >= ?
 		)`,
-		`DELETE FROM state_changes WHERE block_number >= $1`,
-		`DELETE FROM logs WHERE block_number >= $1`,
-		`DELETE FROM transactions WHERE block_number >= $1`,
-		`DELETE FROM blocks WHERE number >= $1`,
+		`DELETE FROM traces WHERE block_number >= ?`,
+		`DELETE FROM decoded_events WHERE block_number >= ?`,
+		`DELETE FROM token_transfers WHERE block_number >= ?`,
+		`DELETE FROM blob_hashes WHERE block_number >= ?`,
+		`DELETE FROM withdrawals WHERE block_number >= ?`,
+		`DELETE FROM state_changes WHERE block_number >= ?`,
+		`UPDATE logs SET removed = TRUE WHERE block_number >= ?`,
+		`DELETE FROM receipts WHERE block_number >= ?`,
+		`DELETE FROM transactions WHERE block_number >= ?`,
+		`DELETE FROM blocks WHERE number >= ?`,
 	}
 
 	for _, query := range deleteQueries {
-		_, err := tx.Exec(query, blockNumber)
+		_, err := tx.Exec(tx.Rebind(query), blockNumber)
 		if err != nil {
 			tx.Rollback()
 			return fmt.Errorf("error executing delete query: %v", err)
@@ -383,8 +627,10 @@ func (idb *IndexerDB) DeleteBlockAndDescendants(blockNumber uint64) error {
 	return tx.Commit()
 }
 
-// InsertBlockWithTx inserts a block using an existing database transaction
-func (idb *IndexerDB) InsertBlockWithTx(tx *sqlx.Tx, block *Block) error {
+// InsertBlockWithTx inserts a block using an existing database transaction. A
+// block already indexed at the same height is overwritten, so replaying a chain
+// event is idempotent.
+func (idb *sqlDB) InsertBlockWithTx(tx *sqlx.Tx, block *Block) error {
 	query := `
 		INSERT INTO blocks (
 			number, hash, parent_hash, timestamp, nonce, base_fee_per_gas,
@@ -400,30 +646,68 @@ func (idb *IndexerDB) InsertBlockWithTx(tx *sqlx.Tx, block *Block) error {
 			:receipts_root, :sha3_uncles, :size, :state_root, :total_difficulty,
 			:transactions_root, :withdrawals_root, :seal_fields, :transactions,
 			:uncles, :block_reward, :uncle_reward
-		)`
+		)
+		ON CONFLICT (number) DO UPDATE SET
+			hash = EXCLUDED.hash,
+			parent_hash = EXCLUDED.parent_hash,
+			timestamp = EXCLUDED.timestamp,
+			nonce = EXCLUDED.nonce,
+			base_fee_per_gas = EXCLUDED.base_fee_per_gas,
+			blob_gas_used = EXCLUDED.blob_gas_used,
+			difficulty = EXCLUDED.difficulty,
+			excess_blob_gas = EXCLUDED.excess_blob_gas,
+			extra_data = EXCLUDED.extra_data,
+			gas_limit = EXCLUDED.gas_limit,
+			gas_used = EXCLUDED.gas_used,
+			logs_bloom = EXCLUDED.logs_bloom,
+			miner = EXCLUDED.miner,
+			mix_hash = EXCLUDED.mix_hash,
+			parent_beacon_block_root = EXCLUDED.parent_beacon_block_root,
+			receipts_root = EXCLUDED.receipts_root,
+			sha3_uncles = EXCLUDED.sha3_uncles,
+			size = EXCLUDED.size,
+			state_root = EXCLUDED.state_root,
+			total_difficulty = EXCLUDED.total_difficulty,
+			transactions_root = EXCLUDED.transactions_root,
+			withdrawals_root = EXCLUDED.withdrawals_root,
+			seal_fields = EXCLUDED.seal_fields,
+			transactions = EXCLUDED.transactions,
+			uncles = EXCLUDED.uncles,
+			block_reward = EXCLUDED.block_reward,
+			uncle_reward = EXCLUDED.uncle_reward`
 
-	_, err := tx.NamedExec(query, block)
+	err := idb.execRowWithTx(tx, "blocks", query, block)
 	if err != nil {
 		return fmt.Errorf("error inserting block: %v", err)
 	}
 	return nil
 }
 
-// DeleteBlockAndDescendantsWithTx deletes a block and all its associated data using an existing transaction
-func (idb *IndexerDB) DeleteBlockAndDescendantsWithTx(tx *sqlx.Tx, blockNumber uint64) error {
+// DeleteBlockAndDescendantsWithTx deletes a block and all its associated data using an existing transaction.
+// Logs are kept, but marked as removed.
+func (idb *sqlDB) DeleteBlockAndDescendantsWithTx(tx *sqlx.Tx, blockNumber uint64) error {
 	// Delete in reverse order of dependencies to respect foreign key constraints
 	deleteQueries := []string{
 		`DELETE FROM access_lists WHERE transaction_hash IN (
-			SELECT hash FROM transactions WHERE block_number >= $1
+			SELECT hash FROM transactions WHERE block_number >= ?
+		)`,
+		`DELETE FROM accounts WHERE creator_tx_hash IN (
+			SELECT hash FROM transactions WHERE block_number >= ?
 		)`,
-		`DELETE FROM state_changes WHERE block_number >= $1`,
-		`DELETE FROM logs WHERE block_number >= $1`,
-		`DELETE FROM transactions WHERE block_number >= $1`,
-		`DELETE FROM blocks WHERE number >= $1`,
+		`DELETE FROM traces WHERE block_number >= ?`,
+		`DELETE FROM decoded_events WHERE block_number >= ?`,
+		`DELETE FROM token_transfers WHERE block_number >= ?`,
+		`DELETE FROM blob_hashes WHERE block_number >= ?`,
+		`DELETE FROM withdrawals WHERE block_number >= ?`,
+		`DELETE FROM state_changes WHERE block_number >= ?`,
+		`UPDATE logs SET removed = TRUE WHERE block_number >= ?`,
+		`DELETE FROM receipts WHERE block_number >= ?`,
+		`DELETE FROM transactions WHERE block_number >= ?`,
+		`DELETE FROM blocks WHERE number >= ?`,
 	}
 
 	for _, query := range deleteQueries {
-		_, err := tx.Exec(query, blockNumber)
+		_, err := tx.Exec(tx.Rebind(query), blockNumber)
 		if err != nil {
 			return fmt.Errorf("error executing delete query: %v", err)
 		}
@@ -432,23 +716,47 @@ func (idb *IndexerDB) DeleteBlockAndDescendantsWithTx(tx *sqlx.Tx, blockNumber u
 	return nil
 }
 
-// MarkBlockFinalized marks a block as finalized in the database
-func (idb *IndexerDB) MarkBlockFinalized(blockNumber uint64) error {
-	// Add finalized column if it doesn't exist
-	_, err := idb.db.Exec(`
-		ALTER TABLE blocks
-		ADD COLUMN IF NOT EXISTS finalized boolean DEFAULT false
-	`)
-	if err != nil {
-		return fmt.Errorf("error adding finalized column: %v", err)
+// DeleteBlockWithTx deletes a single block and all its associated data using an
+// existing transaction, leaving its descendants in place. Logs are kept, but
+// marked as removed.
+func (idb *sqlDB) DeleteBlockWithTx(tx *sqlx.Tx, blockNumber uint64) error {
+	// Delete in reverse order of dependencies to respect foreign key constraints
+	deleteQueries := []string{
+		`DELETE FROM access_lists WHERE transaction_hash IN (
+			SELECT hash FROM transactions WHERE block_number = ?
+		)`,
+		`DELETE FROM accounts WHERE creator_tx_hash IN (
+			SELECT hash FROM transactions WHERE block_number = ?
+		)`,
+		`DELETE FROM traces WHERE block_number = ?`,
+		`DELETE FROM decoded_events WHERE block_number = ?`,
+		`DELETE FROM token_transfers WHERE block_number = ?`,
+		`DELETE FROM blob_hashes WHERE block_number = ?`,
+		`DELETE FROM withdrawals WHERE block_number = ?`,
+		`DELETE FROM state_changes WHERE block_number = ?`,
+		`UPDATE logs SET removed = TRUE WHERE block_number = ?`,
+		`DELETE FROM receipts WHERE block_number = ?`,
+		`DELETE FROM transactions WHERE block_number = ?`,
+		`DELETE FROM blocks WHERE number = ?`,
+	}
+
+	for _, query := range deleteQueries {
+		_, err := tx.Exec(tx.Rebind(query), blockNumber)
+		if err != nil {
+			return fmt.Errorf("error executing delete query: %v", err)
+		}
 	}
 
-	// Mark block as finalized
-	_, err = idb.db.Exec(`
+	return nil
+}
+
+// MarkBlockFinalized marks a block as finalized in the database
+func (idb *sqlDB) MarkBlockFinalized(blockNumber uint64) error {
+	_, err := idb.db.Exec(idb.db.Rebind(`
 		UPDATE blocks
 		SET finalized = true
-		WHERE number = $1
-	`, blockNumber)
+		WHERE number = ?
+	`), blockNumber)
 	if err != nil {
 		return fmt.Errorf("error marking block as finalized: %v", err)
 	}
@@ -457,7 +765,7 @@ func (idb *IndexerDB) MarkBlockFinalized(blockNumber uint64) error {
 }
 
 // GetLatestFinalizedBlock returns the number of the latest finalized block
-func (idb *IndexerDB) GetLatestFinalizedBlock() (uint64, error) {
+func (idb *sqlDB) GetLatestFinalizedBlock() (uint64, error) {
 	var blockNumber uint64
 	err := idb.db.Get(&blockNumber, `
 		SELECT COALESCE(MAX(number), 0)
@@ -470,12 +778,25 @@ func (idb *IndexerDB) GetLatestFinalizedBlock() (uint64, error) {
 	return blockNumber, nil
 }
 
+// GetLatestBlock retrieves the highest indexed block number
+func (idb *sqlDB) GetLatestBlock() (uint64, error) {
+	var blockNumber uint64
+	err := idb.db.Get(&blockNumber, `
+		SELECT COALESCE(MAX(number), 0)
+		FROM blocks
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("error getting latest block: %v", err)
+	}
+	return blockNumber, nil
+}
+
 // GetBlockByNumber retrieves a block by its number
-func (idb *IndexerDB) GetBlockByNumber(number uint64) (*Block, error) {
+func (idb *sqlDB) GetBlockByNumber(number uint64) (*Block, error) {
 	var block Block
-	err := idb.db.Get(&block, `
-		SELECT * FROM blocks WHERE number = $1
-	`, number)
+	err := idb.db.Get(&block, idb.db.Rebind(`
+		SELECT * FROM blocks WHERE number = ?
+	`), number)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -486,11 +807,11 @@ func (idb *IndexerDB) GetBlockByNumber(number uint64) (*Block, error) {
 }
 
 // GetBlockByHash retrieves a block by its hash
-func (idb *IndexerDB) GetBlockByHash(hash string) (*Block, error) {
+func (idb *sqlDB) GetBlockByHash(hash common.Hash) (*Block, error) {
 	var block Block
-	err := idb.db.Get(&block, `
-		SELECT * FROM blocks WHERE hash = $1
-	`, hash)
+	err := idb.db.Get(&block, idb.db.Rebind(`
+		SELECT * FROM blocks WHERE hash = ?
+	`), hash)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -500,8 +821,244 @@ func (idb *IndexerDB) GetBlockByHash(hash string) (*Block, error) {
 	return &block, nil
 }
 
-// InsertReceiptWithTx inserts a receipt using an existing database transaction
-func (idb *IndexerDB) InsertReceiptWithTx(tx *sqlx.Tx, receipt *Receipt) error {
+// GetBlockHashes returns the hashes of the indexed blocks in the given inclusive
+// range, keyed by block number
+func (idb *sqlDB) GetBlockHashes(from, to uint64) (map[uint64]common.Hash, error) {
+	var rows []struct {
+		Number uint64      `db:"number"`
+		Hash   common.Hash `db:"hash"`
+	}
+	err := idb.db.Select(&rows, idb.db.Rebind(`
+		SELECT number, hash FROM blocks WHERE number BETWEEN ? AND ?
+	`), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error getting block hashes: %v", err)
+	}
+	hashes := make(map[uint64]common.Hash, len(rows))
+	for _, row := range rows {
+		hashes[row.Number] = row.Hash
+	}
+	return hashes, nil
+}
+
+// GetCheckpoint retrieves the named checkpoint, or nil if there is none
+func (idb *sqlDB) GetCheckpoint(name string) (*Checkpoint, error) {
+	var checkpoint Checkpoint
+	err := idb.db.Get(&checkpoint, idb.db.Rebind(`
+		SELECT * FROM checkpoints WHERE name = ?
+	`), name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting checkpoint: %v", err)
+	}
+	return &checkpoint, nil
+}
+
+// SetCheckpoint creates or replaces the named checkpoint
+func (idb *sqlDB) SetCheckpoint(checkpoint *Checkpoint) error {
+	query := `
+		INSERT INTO checkpoints (
+			name, first_block, last_block, updated_at
+		) VALUES (
+			:name, :first_block, :last_block, :updated_at
+		)
+		ON CONFLICT (name) DO UPDATE SET
+			first_block = EXCLUDED.first_block,
+			last_block = EXCLUDED.last_block,
+			updated_at = EXCLUDED.updated_at`
+
+	_, err := idb.db.NamedExec(query, checkpoint)
+	if err != nil {
+		return fmt.Errorf("error setting checkpoint: %v", err)
+	}
+	return nil
+}
+
+// InsertTransactionWithTx inserts a transaction using an existing database
+// transaction, overwriting an already indexed one with the same hash.
+func (idb *sqlDB) InsertTransactionWithTx(tx *sqlx.Tx, transaction *Transaction) error {
+	query := `
+		INSERT INTO transactions (
+			hash, block_number, transaction_index, "from", "to", value, nonce, gas_price,
+			gas_limit, gas_used, input, status, type, max_fee_per_gas,
+			max_priority_fee, blob_gas_used, blob_gas_price, error
+		) VALUES (
+			:hash, :block_number, :transaction_index, :from, :to, :value, :nonce, :gas_price,
+			:gas_limit, :gas_used, :input, :status, :type, :max_fee_per_gas,
+			:max_priority_fee, :blob_gas_used, :blob_gas_price, :error
+		)
+		ON CONFLICT (hash) DO UPDATE SET
+			block_number = EXCLUDED.block_number,
+			transaction_index = EXCLUDED.transaction_index,
+			"from" = EXCLUDED."from",
+			"to" = EXCLUDED."to",
+			value = EXCLUDED.value,
+			nonce = EXCLUDED.nonce,
+			gas_price = EXCLUDED.gas_price,
+			gas_limit = EXCLUDED.gas_limit,
+			gas_used = EXCLUDED.gas_used,
+			input = EXCLUDED.input,
+			status = EXCLUDED.status,
+			type = EXCLUDED.type,
+			max_fee_per_gas = EXCLUDED.max_fee_per_gas,
+			max_priority_fee = EXCLUDED.max_priority_fee,
+			blob_gas_used = EXCLUDED.blob_gas_used,
+			blob_gas_price = EXCLUDED.blob_gas_price,
+			error = EXCLUDED.error`
+
+	err := idb.execRowWithTx(tx, "transactions", query, transaction)
+	if err != nil {
+		return fmt.Errorf("error inserting transaction: %v", err)
+	}
+	return nil
+}
+
+// UpsertAccountWithTx inserts or updates an account using an existing database
+// transaction. Creation details are only overwritten if the new row has them,
+// as most updates are just balance and nonce changes. The state fields are
+// never replaced by the ones of an older block, which a backfill may produce.
+func (idb *sqlDB) UpsertAccountWithTx(tx *sqlx.Tx, account *Account) error {
+	query := `
+		INSERT INTO accounts (
+			address, balance, nonce, code, creator_address,
+			creator_tx_hash, created_at, self_destructed, updated_block
+		) VALUES (
+			:address, :balance, :nonce, :code, :creator_address,
+			:creator_tx_hash, :created_at, :self_destructed, :updated_block
+		)
+		ON CONFLICT (address) DO UPDATE SET
+			balance = CASE WHEN EXCLUDED.updated_block >= accounts.updated_block
+				THEN EXCLUDED.balance ELSE accounts.balance END,
+			nonce = CASE WHEN EXCLUDED.updated_block >= accounts.updated_block
+				THEN EXCLUDED.nonce ELSE accounts.nonce END,
+			code = CASE WHEN EXCLUDED.updated_block >= accounts.updated_block
+				THEN EXCLUDED.code ELSE accounts.code END,
+			self_destructed = CASE WHEN EXCLUDED.updated_block >= accounts.updated_block
+				THEN EXCLUDED.self_destructed ELSE accounts.self_destructed END,
+			updated_block = CASE WHEN EXCLUDED.updated_block >= accounts.updated_block
+				THEN EXCLUDED.updated_block ELSE accounts.updated_block END,
+			creator_address = COALESCE(EXCLUDED.creator_address, accounts.creator_address),
+			creator_tx_hash = COALESCE(EXCLUDED.creator_tx_hash, accounts.creator_tx_hash),
+			created_at = COALESCE(EXCLUDED.created_at, accounts.created_at)`
+
+	err := idb.execRowWithTx(tx, "accounts", query, account)
+	if err != nil {
+		return fmt.Errorf("error upserting account: %v", err)
+	}
+	return nil
+}
+
+// InsertStateChangeWithTx inserts a state change using an existing database transaction
+func (idb *sqlDB) InsertStateChangeWithTx(tx *sqlx.Tx, change *StateChange) error {
+	query := `
+		INSERT INTO state_changes (
+			block_number, transaction_hash, address, storage_key,
+			prev_value, new_value, change_type, source
+		) VALUES (
+			:block_number, :transaction_hash, :address, :storage_key,
+			:prev_value, :new_value, :change_type, :source
+		)`
+
+	err := idb.execRowWithTx(tx, "state_changes", query, change)
+	if err != nil {
+		return fmt.Errorf("error inserting state change: %v", err)
+	}
+	return nil
+}
+
+// InsertTraceWithTx inserts a call frame using an existing database transaction
+func (idb *sqlDB) InsertTraceWithTx(tx *sqlx.Tx, trace *Trace) error {
+	query := `
+		INSERT INTO traces (
+			block_number, transaction_hash, trace_index, trace_address, type,
+			"from", "to", value, gas, gas_used, input, output, error
+		) VALUES (
+			:block_number, :transaction_hash, :trace_index, :trace_address, :type,
+			:from, :to, :value, :gas, :gas_used, :input, :output, :error
+		)`
+
+	err := idb.execRowWithTx(tx, "traces", query, trace)
+	if err != nil {
+		return fmt.Errorf("error inserting trace: %v", err)
+	}
+	return nil
+}
+
+// InsertDecodedEventWithTx inserts a decoded event using an existing database transaction
+func (idb *sqlDB) InsertDecodedEventWithTx(tx *sqlx.Tx, event *DecodedEvent) error {
+	query := `
+		INSERT INTO decoded_events (
+			block_number, transaction_hash, log_index, address, contract,
+			event, signature, args
+		) VALUES (
+			:block_number, :transaction_hash, :log_index, :address, :contract,
+			:event, :signature, :args
+		)`
+
+	err := idb.execRowWithTx(tx, "decoded_events", query, event)
+	if err != nil {
+		return fmt.Errorf("error inserting decoded event: %v", err)
+	}
+	return nil
+}
+
+// InsertTokenTransferWithTx inserts a token transfer using an existing database transaction
+func (idb *sqlDB) InsertTokenTransferWithTx(tx *sqlx.Tx, transfer *TokenTransfer) error {
+	query := `
+		INSERT INTO token_transfers (
+			block_number, transaction_hash, log_index, batch_index, token,
+			standard, operator, "from", "to", token_id, value
+		) VALUES (
+			:block_number, :transaction_hash, :log_index, :batch_index, :token,
+			:standard, :operator, :from, :to, :token_id, :value
+		)`
+
+	err := idb.execRowWithTx(tx, "token_transfers", query, transfer)
+	if err != nil {
+		return fmt.Errorf("error inserting token transfer: %v", err)
+	}
+	return nil
+}
+
+// InsertWithdrawalWithTx inserts a withdrawal using an existing database transaction
+func (idb *sqlDB) InsertWithdrawalWithTx(tx *sqlx.Tx, withdrawal *Withdrawal) error {
+	query := `
+		INSERT INTO withdrawals (
+			block_number, withdrawal_index, validator_index, address, amount
+		) VALUES (
+			:block_number, :withdrawal_index, :validator_index, :address, :amount
+		)
+		ON CONFLICT (block_number, withdrawal_index) DO NOTHING`
+
+	err := idb.execRowWithTx(tx, "withdrawals", query, withdrawal)
+	if err != nil {
+		return fmt.Errorf("error inserting withdrawal: %v", err)
+	}
+	return nil
+}
+
+// InsertBlobHashWithTx inserts a blob versioned hash using an existing database transaction
+func (idb *sqlDB) InsertBlobHashWithTx(tx *sqlx.Tx, blob *BlobHash) error {
+	query := `
+		INSERT INTO blob_hashes (
+			block_number, transaction_hash, blob_index, versioned_hash, blob_gas_price
+		) VALUES (
+			:block_number, :transaction_hash, :blob_index, :versioned_hash, :blob_gas_price
+		)
+		ON CONFLICT (transaction_hash, blob_index) DO NOTHING`
+
+	err := idb.execRowWithTx(tx, "blob_hashes", query, blob)
+	if err != nil {
+		return fmt.Errorf("error inserting blob hash: %v", err)
+	}
+	return nil
+}
+
+// InsertReceiptWithTx inserts a receipt using an existing database transaction,
+// overwriting an already indexed one of the same transaction.
+func (idb *sqlDB) InsertReceiptWithTx(tx *sqlx.Tx, receipt *Receipt) error {
 	query := `
 		INSERT INTO receipts (
 			block_number, block_hash, transaction_hash, transaction_index,
@@ -509,29 +1066,63 @@ func (idb *IndexerDB) InsertReceiptWithTx(tx *sqlx.Tx, receipt *Receipt) error {
 		) VALUES (
 			:block_number, :block_hash, :transaction_hash, :transaction_index,
 			:contract_address, :gas_used, :status
-		)`
+		)
+		ON CONFLICT (transaction_hash) DO UPDATE SET
+			block_number = EXCLUDED.block_number,
+			block_hash = EXCLUDED.block_hash,
+			transaction_index = EXCLUDED.transaction_index,
+			contract_address = EXCLUDED.contract_address,
+			gas_used = EXCLUDED.gas_used,
+			status = EXCLUDED.status`
 
-	_, err := tx.NamedExec(query, receipt)
+	err := idb.execRowWithTx(tx, "receipts", query, receipt)
 	if err != nil {
 		return fmt.Errorf("error inserting receipt: %v", err)
 	}
 	return nil
 }
 
-// InsertLogWithTx inserts a log entry using an existing database transaction
-func (idb *IndexerDB) InsertLogWithTx(tx *sqlx.Tx, log *Log) error {
+// InsertLogWithTx inserts a log entry using an existing database transaction.
+// A log already indexed at the same position of the block is overwritten, which
+// also revives the logs of a block returning to the canonical chain.
+func (idb *sqlDB) InsertLogWithTx(tx *sqlx.Tx, log *Log) error {
 	query := `
 		INSERT INTO logs (
-			transaction_hash, block_number, address, topics,
+			transaction_hash, block_number, block_hash, address, topics,
 			data, log_index, removed
 		) VALUES (
-			:transaction_hash, :block_number, :address, :topics,
+			:transaction_hash, :block_number, :block_hash, :address, :topics,
 			:data, :log_index, :removed
-		)`
+		)
+		ON CONFLICT (block_hash, log_index) DO UPDATE SET
+			transaction_hash = EXCLUDED.transaction_hash,
+			block_number = EXCLUDED.block_number,
+			address = EXCLUDED.address,
+			topics = EXCLUDED.topics,
+			data = EXCLUDED.data,
+			removed = EXCLUDED.removed`
 
-	_, err := tx.NamedExec(query, log)
+	err := idb.execRowWithTx(tx, "logs", query, log)
 	if err != nil {
 		return fmt.Errorf("error inserting log: %v", err)
 	}
 	return nil
 }
+
+// InsertReorgWithTx records a chain reorganization using an existing database transaction
+func (idb *sqlDB) InsertReorgWithTx(tx *sqlx.Tx, reorg *Reorg) error {
+	query := `
+		INSERT INTO reorgs (
+			detected_at, ancestor_number, ancestor_hash, depth,
+			old_head, new_head, old_hashes, new_hashes
+		) VALUES (
+			:detected_at, :ancestor_number, :ancestor_hash, :depth,
+			:old_head, :new_head, :old_hashes, :new_hashes
+		)`
+
+	err := idb.execRowWithTx(tx, "reorgs", query, reorg)
+	if err != nil {
+		return fmt.Errorf("error inserting reorg: %v", err)
+	}
+	return nil
+}
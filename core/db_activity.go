@@ -0,0 +1,183 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the sort key of the last row of a result page, from which the next
+// page resumes. It is handed out to clients in an opaque encoding.
+type pageCursor struct {
+	block   uint64
+	index   uint64
+	address common.Address // Tie breaker of contracts created by the same transaction
+}
+
+// encode converts the cursor into its opaque textual form.
+func (c *pageCursor) encode() string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d.%d.%x", c.block, c.index, c.address))
+}
+
+// decodeCursor parses an opaque cursor, where empty means the first page.
+func decodeCursor(text string) (*pageCursor, error) {
+	if text == "" {
+		return nil, nil
+	}
+	blob, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	parts := strings.Split(string(blob), ".")
+	if len(parts) != 3 {
+		return nil, errInvalidCursor
+	}
+	c := new(pageCursor)
+	if c.block, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return nil, errInvalidCursor
+	}
+	if c.index, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+		return nil, errInvalidCursor
+	}
+	address, err := hex.DecodeString(parts[2])
+	if err != nil || len(address) != common.AddressLength {
+		return nil, errInvalidCursor
+	}
+	c.address = common.BytesToAddress(address)
+	return c, nil
+}
+
+// GetTransactionsByAddress returns a page of the transactions sent from or to an
+// address in chain order, starting after the cursor, along with the cursor of
+// the next page. The next cursor is empty on the last page.
+func (idb *sqlDB) GetTransactionsByAddress(ctx context.Context, address common.Address, cursor string, limit int) ([]*Transaction, string, error) {
+	var (
+		query = `SELECT * FROM transactions WHERE ("from" = ? OR "to" = ?)`
+		args  = []any{address, address}
+	)
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if after != nil {
+		query += ` AND (block_number > ? OR (block_number = ? AND transaction_index > ?))`
+		args = append(args, after.block, after.block, after.index)
+	}
+	query += ` ORDER BY block_number, transaction_index LIMIT ?`
+	args = append(args, limit+1)
+
+	var txs []*Transaction
+	if err := idb.db.SelectContext(ctx, &txs, idb.db.Rebind(query), args...); err != nil {
+		return nil, "", fmt.Errorf("error getting transactions: %v", err)
+	}
+	if len(txs) <= limit {
+		return txs, "", nil
+	}
+	last := txs[limit-1]
+	next := &pageCursor{block: last.BlockNumber, index: last.TransactionIndex}
+	return txs[:limit], next.encode(), nil
+}
+
+// GetContractsCreatedBy returns a page of the contracts created by an address,
+// ordered by their creation, starting after the cursor, along with the cursor
+// of the next page. The next cursor is empty on the last page.
+func (idb *sqlDB) GetContractsCreatedBy(ctx context.Context, creator common.Address, cursor string, limit int) ([]*Account, string, error) {
+	var (
+		query = `
+			SELECT a.*, t.block_number, t.transaction_index FROM accounts a
+			JOIN transactions t ON t.hash = a.creator_tx_hash
+			WHERE a.creator_address = ?`
+		args = []any{creator}
+	)
+	// A transaction may create many contracts, which are ordered by address
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if after != nil {
+		query += ` AND (t.block_number > ? OR (t.block_number = ? AND (t.transaction_index > ? OR
+			(t.transaction_index = ? AND a.address > ?))))`
+		args = append(args, after.block, after.block, after.index, after.index, after.address)
+	}
+	query += ` ORDER BY t.block_number, t.transaction_index, a.address LIMIT ?`
+	args = append(args, limit+1)
+
+	var rows []struct {
+		Account
+		BlockNumber      uint64 `db:"block_number"`
+		TransactionIndex uint64 `db:"transaction_index"`
+	}
+	if err := idb.db.SelectContext(ctx, &rows, idb.db.Rebind(query), args...); err != nil {
+		return nil, "", fmt.Errorf("error getting created contracts: %v", err)
+	}
+	accounts := make([]*Account, 0, min(len(rows), limit))
+	for i := 0; i < len(rows) && i < limit; i++ {
+		accounts = append(accounts, &rows[i].Account)
+	}
+	if len(rows) <= limit {
+		return accounts, "", nil
+	}
+	last := rows[limit-1]
+	next := &pageCursor{block: last.BlockNumber, index: last.TransactionIndex, address: last.Address}
+	return accounts, next.encode(), nil
+}
+
+// GetStateChanges returns a page of the state changes of an address within the
+// inclusive block range in chain order, starting after the cursor, along with
+// the cursor of the next page. If a slot is given, only the changes of that
+// storage slot are returned. The next cursor is empty on the last page.
+func (idb *sqlDB) GetStateChanges(ctx context.Context, address common.Address, slot *common.Hash, from, to uint64, cursor string, limit int) ([]*StateChange, string, error) {
+	var (
+		query = `SELECT * FROM state_changes WHERE address = ? AND block_number BETWEEN ? AND ?`
+		args  = []any{address, from, to}
+	)
+	if slot != nil {
+		query += ` AND storage_key = ?`
+		args = append(args, *slot)
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if after != nil {
+		query += ` AND (block_number > ? OR (block_number = ? AND id > ?))`
+		args = append(args, after.block, after.block, after.index)
+	}
+	query += ` ORDER BY block_number, id LIMIT ?`
+	args = append(args, limit+1)
+
+	var changes []*StateChange
+	if err := idb.db.SelectContext(ctx, &changes, idb.db.Rebind(query), args...); err != nil {
+		return nil, "", fmt.Errorf("error getting state changes: %v", err)
+	}
+	if len(changes) <= limit {
+		return changes, "", nil
+	}
+	last := changes[limit-1]
+	next := &pageCursor{block: last.BlockNumber, index: last.ID}
+	return changes[:limit], next.encode(), nil
+}
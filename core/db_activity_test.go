@@ -0,0 +1,158 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the activity of an address is paginated in chain order without
+// skipping or repeating items across pages.
+func TestIndexerAddressActivity(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		receiver = common.HexToAddress("0xbeef")
+		factory  = common.HexToAddress("0xfac7")
+		initcode = program.New().Return(0, 0).Bytes()
+
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				factory: {Code: program.New().Mstore(initcode, 0).
+					Push(len(initcode)).Push(0).Push(0).Op(vm.CREATE, vm.POP).
+					Push(len(initcode)).Push(0).Push(0).Op(vm.CREATE, vm.POP).Bytes()},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	// Every block transfers to the receiver, deploys a contract and calls the
+	// factory, which creates two more
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 3, func(i int, gen *BlockGen) {
+		for _, tx := range []types.TxData{
+			&types.LegacyTx{To: &receiver, Value: big.NewInt(int64(i + 1)), Gas: params.TxGas},
+			&types.LegacyTx{Data: initcode, Gas: 100000},
+			&types.LegacyTx{To: &factory, Gas: 200000},
+		} {
+			tx := tx.(*types.LegacyTx)
+			tx.Nonce, tx.GasPrice = gen.TxNonce(sender), gen.BaseFee()
+			gen.AddTx(types.MustSignNewTx(key, signer, tx))
+		}
+	})
+	db := newTestIndexerDB(t)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, NewIndexerPlugin(db))
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed(t, db, blocks[2])
+
+	// The sender's transactions must be paged through in chain order
+	var (
+		ctx    = context.Background()
+		txs    []*Transaction
+		cursor string
+	)
+	for pages := 0; ; pages++ {
+		page, next, err := db.GetTransactionsByAddress(ctx, sender, cursor, 2)
+		if err != nil {
+			t.Fatalf("failed to get transactions: %v", err)
+		}
+		txs = append(txs, page...)
+		if cursor = next; cursor == "" {
+			if pages != 4 {
+				t.Errorf("page count mismatch: have %d, want 5", pages+1)
+			}
+			break
+		}
+	}
+	var want []common.Hash
+	for _, block := range blocks {
+		for _, tx := range block.Transactions() {
+			want = append(want, tx.Hash())
+		}
+	}
+	if len(txs) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(txs), len(want))
+	}
+	for i, tx := range txs {
+		if tx.Hash != want[i] || tx.TransactionIndex != uint64(i%3) {
+			t.Errorf("transaction %d mismatch: have %x at %d, want %x", i, tx.Hash, tx.TransactionIndex, want[i])
+		}
+	}
+	// The receiver only appears in the transfers
+	if txs, _, err := db.GetTransactionsByAddress(ctx, receiver, "", 10); err != nil || len(txs) != 3 || txs[2].Value.Int64() != 3 {
+		t.Errorf("wrong receiver transactions: %v (err %v)", txs, err)
+	}
+	// The contracts created by the factory, two per call, must be paged
+	// through in creation order
+	var contracts []*Account
+	for cursor = ""; ; {
+		page, next, err := db.GetContractsCreatedBy(ctx, factory, cursor, 4)
+		if err != nil {
+			t.Fatalf("failed to get contracts: %v", err)
+		}
+		contracts = append(contracts, page...)
+		if cursor = next; cursor == "" {
+			break
+		}
+	}
+	if len(contracts) != 6 {
+		t.Fatalf("contract count mismatch: have %d, want 6", len(contracts))
+	}
+	for i, contract := range contracts {
+		if *contract.CreatorTxHash != blocks[i/2].Transactions()[2].Hash() {
+			t.Errorf("contract %d created by wrong transaction: %x", i, *contract.CreatorTxHash)
+		}
+		if i%2 == 1 && *contract.CreatorTxHash == *contracts[i-1].CreatorTxHash && contract.Address.Cmp(contracts[i-1].Address) <= 0 {
+			t.Errorf("contract %d out of order", i)
+		}
+	}
+	if contracts, _, err := db.GetContractsCreatedBy(ctx, sender, "", 10); err != nil || len(contracts) != 3 {
+		t.Errorf("wrong contracts deployed by sender: have %d (err %v), want 3", len(contracts), err)
+	}
+	// Balance changes of the receiver are restricted to the range
+	changes, next, err := db.GetStateChanges(ctx, receiver, nil, 2, 3, "", 1)
+	if err != nil {
+		t.Fatalf("failed to get state changes: %v", err)
+	}
+	if len(changes) != 1 || changes[0].BlockNumber != 2 || next == "" {
+		t.Fatalf("wrong first state change page: %+v, next %q", changes, next)
+	}
+	if changes, next, err = db.GetStateChanges(ctx, receiver, nil, 2, 3, next, 1); err != nil || len(changes) != 1 || changes[0].BlockNumber != 3 || next != "" {
+		t.Fatalf("wrong last state change page: %+v, next %q (err %v)", changes, next, err)
+	}
+	if _, _, err := db.GetTransactionsByAddress(ctx, sender, "invalid", 1); err == nil {
+		t.Error("invalid cursor accepted")
+	}
+}
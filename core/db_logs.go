@@ -0,0 +1,154 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// GetIndexedRange returns the first gapless run of indexed blocks within the
+// inclusive range [from, to]: the numbers of its first and last blocks, and the
+// hash of the last one. It reports false if no block of the range is indexed.
+func (idb *sqlDB) GetIndexedRange(from, to uint64) (uint64, uint64, common.Hash, bool, error) {
+	var first sql.NullInt64
+	err := idb.db.Get(&first, idb.db.Rebind(`SELECT MIN(number) FROM blocks WHERE number BETWEEN ? AND ?`), from, to)
+	if err != nil {
+		return 0, 0, common.Hash{}, false, fmt.Errorf("error getting indexed range: %v", err)
+	}
+	if !first.Valid {
+		return 0, 0, common.Hash{}, false, nil
+	}
+	// The run ends at the first block lacking its successor, if there's none in
+	// the range it extends beyond it
+	var last sql.NullInt64
+	err = idb.db.Get(&last, idb.db.Rebind(`
+		SELECT MIN(number) FROM blocks b
+		WHERE number BETWEEN ? AND ? AND NOT EXISTS (
+			SELECT 1 FROM blocks n WHERE n.number = b.number + 1
+		)
+	`), first.Int64, to)
+	if err != nil {
+		return 0, 0, common.Hash{}, false, fmt.Errorf("error getting indexed range: %v", err)
+	}
+	head := to
+	if last.Valid {
+		head = uint64(last.Int64)
+	}
+	hashes, err := idb.GetBlockHashes(head, head)
+	if err != nil {
+		return 0, 0, common.Hash{}, false, err
+	}
+	hash, ok := hashes[head]
+	return uint64(first.Int64), head, hash, ok, nil
+}
+
+// GetLogs returns the logs of the indexed blocks in the given inclusive range,
+// emitted by any of the addresses and matching the topics by position, as in
+// an eth_getLogs query. Logs removed by reorgs are skipped.
+func (idb *sqlDB) GetLogs(ctx context.Context, from, to uint64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error) {
+	query, args := idb.logsQuery(from, to, addresses, topics)
+
+	var rows []struct {
+		Log
+		TransactionIndex uint `db:"transaction_index"`
+	}
+	if err := idb.db.SelectContext(ctx, &rows, idb.db.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("error getting logs: %v", err)
+	}
+	var logs []*types.Log
+	for _, row := range rows {
+		log := &types.Log{
+			Address:     row.Address,
+			Topics:      make([]common.Hash, len(row.Topics)),
+			Data:        row.Data,
+			BlockNumber: row.BlockNumber,
+			TxHash:      row.TransactionHash,
+			TxIndex:     row.TransactionIndex,
+			BlockHash:   row.BlockHash,
+			Index:       uint(row.LogIndex),
+		}
+		for i, topic := range row.Topics {
+			log.Topics[i] = common.BytesToHash(topic)
+		}
+		if matchTopics(log, topics) {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// logsQuery builds the query of GetLogs. Topics are matched natively on
+// PostgreSQL. SQLite only matches the first topic, on its expression index,
+// leaving the other positions to matchTopics.
+func (idb *sqlDB) logsQuery(from, to uint64, addresses []common.Address, topics [][]common.Hash) (string, []any) {
+	var (
+		query = `
+			SELECT l.*, r.transaction_index FROM logs l
+			JOIN receipts r ON r.transaction_hash = l.transaction_hash
+			WHERE l.block_number BETWEEN ? AND ? AND l.removed = FALSE`
+		args = []any{from, to}
+	)
+	if len(addresses) > 0 {
+		query += ` AND l.address IN (?` + strings.Repeat(`, ?`, len(addresses)-1) + `)`
+		for _, address := range addresses {
+			args = append(args, address)
+		}
+	}
+	switch idb.driver {
+	case DriverPostgres:
+		// Arrays are matched natively, using the GIN index for the overlap check
+		for i, sub := range topics {
+			if len(sub) == 0 {
+				continue
+			}
+			query += fmt.Sprintf(` AND l.topics && ? AND l.topics[%d] = ANY(?)`, i+1)
+			args = append(args, hashesValue(sub), hashesValue(sub))
+		}
+	case DriverSQLite:
+		// The hex digits of the first topic are indexed by migration 0008
+		if len(topics) > 0 && len(topics[0]) > 0 {
+			query += ` AND substr(l.topics, 6, 64) IN (?` + strings.Repeat(`, ?`, len(topics[0])-1) + `)`
+			for _, topic := range topics[0] {
+				args = append(args, hex.EncodeToString(topic[:]))
+			}
+		}
+	}
+	query += ` ORDER BY l.block_number, l.log_index`
+	return query, args
+}
+
+// matchTopics reports whether a log matches the positional topic filter, where
+// an empty position matches any topic.
+func matchTopics(log *types.Log, topics [][]common.Hash) bool {
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+	for i, sub := range topics {
+		if len(sub) > 0 && !slices.Contains(sub, log.Topics[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,268 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// migrationFiles contains the schema migrations of every supported driver, in
+// migrations/<driver>/<version>_<name>.sql files. Versions are numbered from 1
+// without gaps. Released migrations must never be edited, as their checksums
+// are verified against the ones recorded in the database.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// schemaVersionSQL creates the table tracking the applied migrations.
+const schemaVersionSQL = `
+CREATE TABLE IF NOT EXISTS schema_version (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`
+
+// Migration is a numbered upgrade step of the indexer database schema.
+type Migration struct {
+	Version  uint64
+	Name     string
+	Checksum string // Hex encoded SHA256 of the statements
+	SQL      string
+}
+
+// MigrationStatus reports the state of a schema migration in the database.
+type MigrationStatus struct {
+	Version   uint64    `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"` // Checksum of the applied migration, or the known one if pending
+	AppliedAt time.Time `db:"applied_at"`
+	Applied   bool      `db:"-"`
+	Modified  bool      `db:"-"` // Applied migration differs from the known one
+	Unknown   bool      `db:"-"` // Applied migration not known to this release
+}
+
+// loadMigrations returns the schema migrations of a database driver, ordered
+// by version.
+func loadMigrations(driver string) ([]*Migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver %q", driver)
+	}
+	var migrations []*Migration
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".sql")
+		if !ok {
+			continue
+		}
+		number, name, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		version, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %v", entry.Name(), err)
+		}
+		data, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		checksum := sha256.Sum256(data)
+		migrations = append(migrations, &Migration{
+			Version:  version,
+			Name:     name,
+			Checksum: hex.EncodeToString(checksum[:]),
+			SQL:      string(data),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, migration := range migrations {
+		if migration.Version != uint64(i+1) {
+			return nil, fmt.Errorf("migration %d (%s) out of sequence, want version %d", migration.Version, migration.Name, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// appliedMigrations returns the migrations recorded in the database, keyed by
+// version.
+func (idb *sqlDB) appliedMigrations() (map[uint64]*MigrationStatus, error) {
+	if _, err := idb.db.Exec(schemaVersionSQL); err != nil {
+		return nil, fmt.Errorf("error creating schema version table: %v", err)
+	}
+	var rows []*MigrationStatus
+	if err := idb.db.Select(&rows, `SELECT version, name, checksum, applied_at FROM schema_version`); err != nil {
+		return nil, fmt.Errorf("error reading schema version: %v", err)
+	}
+	applied := make(map[uint64]*MigrationStatus, len(rows))
+	for _, row := range rows {
+		row.Applied = true
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// MigrationStatus returns the state of all known and applied schema migrations,
+// ordered by version.
+func (idb *sqlDB) MigrationStatus() ([]*MigrationStatus, error) {
+	migrations, err := loadMigrations(idb.driver)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := idb.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var status []*MigrationStatus
+	for _, migration := range migrations {
+		if row, ok := applied[migration.Version]; ok {
+			row.Modified = row.Checksum != migration.Checksum
+			status = append(status, row)
+			delete(applied, migration.Version)
+			continue
+		}
+		status = append(status, &MigrationStatus{
+			Version:  migration.Version,
+			Name:     migration.Name,
+			Checksum: migration.Checksum,
+		})
+	}
+	// Anything left was applied by a newer release
+	for _, row := range applied {
+		row.Unknown = true
+		status = append(status, row)
+	}
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Version < status[j].Version
+	})
+	return status, nil
+}
+
+// Migrate brings the database schema up to date, returning the number of
+// migrations applied. Migrations recorded in the database must match the known
+// ones, a modified or unknown migration aborts without touching the schema.
+func (idb *sqlDB) Migrate() (int, error) {
+	status, err := idb.MigrationStatus()
+	if err != nil {
+		return 0, err
+	}
+	migrations, err := loadMigrations(idb.driver)
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range status {
+		if s.Unknown {
+			return 0, fmt.Errorf("database schema version %d (%s) is newer than supported version %d", s.Version, s.Name, len(migrations))
+		}
+		if s.Modified {
+			return 0, fmt.Errorf("checksum mismatch of applied migration %d (%s): have %s, want %s", s.Version, s.Name, s.Checksum, migrations[s.Version-1].Checksum)
+		}
+	}
+	var count int
+	for _, s := range status {
+		if s.Applied {
+			continue
+		}
+		applied, err := idb.applyMigration(migrations[s.Version-1])
+		if err != nil {
+			return count, err
+		}
+		if applied {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// applyMigration runs a single migration and records it in the same database
+// transaction. It reports false if the migration was applied concurrently by
+// another process in the meantime.
+func (idb *sqlDB) applyMigration(migration *Migration) (bool, error) {
+	ctx := context.Background()
+	conn, err := idb.db.Connx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("error acquiring connection: %v", err)
+	}
+	defer conn.Close()
+
+	// SQLite can only change column types by rebuilding tables, which requires
+	// foreign keys to be disabled outside of the transaction and verified before
+	// committing it.
+	if idb.driver == DriverSQLite {
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return false, fmt.Errorf("error disabling foreign keys: %v", err)
+		}
+		defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+	}
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// SQLite write transactions are exclusive already, PostgreSQL needs the
+	// version table locked to serialize nodes upgrading the same database.
+	if idb.driver == DriverPostgres {
+		if _, err := tx.Exec(`LOCK TABLE schema_version IN EXCLUSIVE MODE`); err != nil {
+			return false, fmt.Errorf("error locking schema version: %v", err)
+		}
+	}
+	var exists int
+	if err := tx.Get(&exists, tx.Rebind(`SELECT COUNT(*) FROM schema_version WHERE version = ?`), migration.Version); err != nil {
+		return false, fmt.Errorf("error reading schema version: %v", err)
+	}
+	if exists > 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(migration.SQL); err != nil {
+		return false, fmt.Errorf("error applying migration %d (%s): %v", migration.Version, migration.Name, err)
+	}
+	if idb.driver == DriverSQLite {
+		var violations int
+		if err := tx.Get(&violations, `SELECT COUNT(*) FROM pragma_foreign_key_check`); err != nil {
+			return false, fmt.Errorf("error checking foreign keys: %v", err)
+		}
+		if violations > 0 {
+			return false, fmt.Errorf("migration %d (%s) violates %d foreign keys", migration.Version, migration.Name, violations)
+		}
+	}
+	_, err = tx.Exec(tx.Rebind(`
+		INSERT INTO schema_version (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)
+	`), migration.Version, migration.Name, migration.Checksum, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("error recording migration %d: %v", migration.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing migration %d: %v", migration.Version, err)
+	}
+	log.Info("Applied indexer schema migration", "version", migration.Version, "name", migration.Name)
+	return true, nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that the embedded migrations of all drivers are well formed.
+func TestLoadMigrations(t *testing.T) {
+	for _, driver := range []string{DriverPostgres, DriverSQLite} {
+		migrations, err := loadMigrations(driver)
+		if err != nil {
+			t.Fatalf("%s: failed to load migrations: %v", driver, err)
+		}
+		if len(migrations) == 0 {
+			t.Fatalf("%s: no migrations", driver)
+		}
+		for i, migration := range migrations {
+			if migration.Version != uint64(i+1) || migration.Name == "" || len(migration.Checksum) != 64 {
+				t.Errorf("%s: malformed migration %d: %+v", driver, i, migration)
+			}
+		}
+	}
+	if _, err := loadMigrations("oracle"); err == nil {
+		t.Error("migrations of unknown driver loaded")
+	}
+}
+
+// Tests that migrations are applied once, and that modified or unknown applied
+// migrations are refused.
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "indexer.sqlite")
+	db, err := newTestSQLiteDB(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	migrations, _ := loadMigrations(DriverSQLite)
+	status, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatalf("failed to read migration status: %v", err)
+	}
+	for _, s := range status {
+		if s.Applied {
+			t.Errorf("migration %d applied on fresh database", s.Version)
+		}
+	}
+	if n, err := db.Migrate(); err != nil || n != len(migrations) {
+		t.Fatalf("initial migration: have %d/%v, want %d/nil", n, err, len(migrations))
+	}
+	if n, err := db.Migrate(); err != nil || n != 0 {
+		t.Fatalf("repeated migration: have %d/%v, want 0/nil", n, err)
+	}
+	if status, err = db.MigrationStatus(); err != nil {
+		t.Fatalf("failed to read migration status: %v", err)
+	}
+	if len(status) != len(migrations) {
+		t.Fatalf("status count mismatch: have %d, want %d", len(status), len(migrations))
+	}
+	for _, s := range status {
+		if !s.Applied || s.Modified || s.Unknown || s.AppliedAt.IsZero() {
+			t.Errorf("wrong status of migration %d: %+v", s.Version, s)
+		}
+	}
+	sqldb := db.db
+
+	// A migration edited after being applied must be detected
+	if _, err := sqldb.Exec(`UPDATE schema_version SET checksum = 'bad' WHERE version = 1`); err != nil {
+		t.Fatalf("failed to modify checksum: %v", err)
+	}
+	if _, err := db.Migrate(); err == nil {
+		t.Error("modified migration accepted")
+	}
+	if _, err := sqldb.Exec(`UPDATE schema_version SET checksum = ? WHERE version = 1`, migrations[0].Checksum); err != nil {
+		t.Fatalf("failed to restore checksum: %v", err)
+	}
+	// A database upgraded by a newer release must be refused
+	if _, err := sqldb.Exec(`INSERT INTO schema_version VALUES (?, 'future', '', CURRENT_TIMESTAMP)`, len(migrations)+1); err != nil {
+		t.Fatalf("failed to add migration: %v", err)
+	}
+	if _, err := db.Migrate(); err == nil {
+		t.Error("unknown migration accepted")
+	}
+	if status, _ = db.MigrationStatus(); !status[len(status)-1].Unknown {
+		t.Errorf("unknown migration not reported: %+v", status[len(status)-1])
+	}
+}
+
+// Tests that rows stored in the text encodings of the initial schema are
+// converted to native column types.
+func TestMigrateTypedColumns(t *testing.T) {
+	db, err := newTestSQLiteDB(filepath.Join(t.TempDir(), "indexer.sqlite"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	migrations, _ := loadMigrations(DriverSQLite)
+	if _, err := db.appliedMigrations(); err != nil {
+		t.Fatalf("failed to create version table: %v", err)
+	}
+	if _, err := db.applyMigration(migrations[0]); err != nil {
+		t.Fatalf("failed to apply initial migration: %v", err)
+	}
+	var (
+		hash   = common.HexToHash("0x01")
+		parent = common.HexToHash("0x02")
+		txHash = common.HexToHash("0x03")
+		sender = common.HexToAddress("0x04")
+		topic  = common.HexToHash("0x05")
+		value  = "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+	)
+	stmts := []string{
+		`INSERT INTO blocks (number, hash, parent_hash, timestamp, nonce, difficulty, extra_data, gas_limit, gas_used,
+			logs_bloom, miner, mix_hash, receipts_root, sha3_uncles, size, state_root, transactions_root, block_reward, uncle_reward)
+		VALUES (1, '` + hash.Hex() + `', '` + parent.Hex() + `', CURRENT_TIMESTAMP, '[0 0 0 0 0 0 0 0]', '131072', '0x', '30000000', '21000',
+			'0x00', '` + sender.Hex() + `', '` + parent.Hex() + `', '` + parent.Hex() + `', '` + parent.Hex() + `', '', '` + parent.Hex() + `',
+			'` + parent.Hex() + `', '', '')`,
+		`INSERT INTO transactions (hash, block_number, "from", value, nonce, gas_price, gas_limit, gas_used, input, status, type)
+		VALUES ('` + txHash.Hex() + `', 1, '` + sender.Hex() + `', '` + value + `', 0, '7', 21000, 21000, '0xc0de', 1, 0)`,
+		`INSERT INTO receipts (block_number, block_hash, transaction_hash, transaction_index, contract_address, gas_used, status)
+		VALUES (1, '` + hash.Hex() + `', '` + txHash.Hex() + `', 0, '0x0000000000000000000000000000000000000000', 21000, 1)`,
+		`INSERT INTO logs (transaction_hash, block_number, block_hash, address, topics, data, log_index)
+		VALUES ('` + txHash.Hex() + `', 1, '` + hash.Hex() + `', '` + sender.Hex() + `', '{"` + topic.Hex() + `"}', '0xff', 0)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.db.Exec(stmt); err != nil {
+			t.Fatalf("failed to insert legacy row: %v", err)
+		}
+	}
+	if n, err := db.Migrate(); err != nil || n != len(migrations)-1 {
+		t.Fatalf("migration failed: have %d/%v, want %d/nil", n, err, len(migrations)-1)
+	}
+	block, err := db.GetBlockByHash(hash)
+	if err != nil || block == nil {
+		t.Fatalf("failed to read migrated block: %v", err)
+	}
+	if block.ParentHash != parent || block.Miner != sender || block.GasLimit != 30000000 || block.Difficulty.Int64() != 131072 ||
+		block.Nonce != nil || block.Size != nil || block.BlockReward != nil || len(block.ExtraData) != 0 {
+		t.Errorf("wrong migrated block: %+v", block)
+	}
+	var tx Transaction
+	if err := db.db.Get(&tx, `SELECT * FROM transactions`); err != nil {
+		t.Fatalf("failed to read migrated transaction: %v", err)
+	}
+	if tx.Hash != txHash || tx.From != sender || tx.To != nil || tx.Value.String() != value || !bytes.Equal(tx.Input, []byte{0xc0, 0xde}) {
+		t.Errorf("wrong migrated transaction: %+v", tx)
+	}
+	var receipt Receipt
+	if err := db.db.Get(&receipt, `SELECT * FROM receipts`); err != nil {
+		t.Fatalf("failed to read migrated receipt: %v", err)
+	}
+	if receipt.TransactionHash != txHash || receipt.ContractAddress != nil {
+		t.Errorf("wrong migrated receipt: %+v", receipt)
+	}
+	var log Log
+	if err := db.db.Get(&log, `SELECT * FROM logs`); err != nil {
+		t.Fatalf("failed to read migrated log: %v", err)
+	}
+	if log.BlockHash != hash || len(log.Topics) != 1 || common.BytesToHash(log.Topics[0]) != topic || !bytes.Equal(log.Data, []byte{0xff}) {
+		t.Errorf("wrong migrated log: %+v", log)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq" // PostgreSQL driver
+)
+
+// postgresDB is the IndexerDB implementation backed by a PostgreSQL server
+type postgresDB struct {
+	*sqlDB
+}
+
+// newPostgresDB connects to a PostgreSQL server
+func newPostgresDB(config IndexerConfig) (*postgresDB, error) {
+	if config.DSN != "" {
+		log.Info("Creating new database connection", "dsn", "configured")
+	} else {
+		log.Info("Creating new database connection",
+			"host", config.Host,
+			"port", config.Port,
+			"database", config.DBName,
+			"user", config.User,
+			"sslMode", config.SSLMode)
+	}
+	psqlInfo, err := config.postgresDSN()
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to database
+	log.Info("Attempting to connect to database...")
+	db, err := sqlx.Connect("postgres", psqlInfo)
+	if err != nil {
+		log.Error("Database connection failed", "error", err)
+		return nil, fmt.Errorf("error connecting to the database: %v", err)
+	}
+
+	// Test the connection
+	log.Info("Testing database connection...")
+	err = db.Ping()
+	if err != nil {
+		log.Error("Database ping failed", "error", err)
+		return nil, fmt.Errorf("error pinging the database: %v", err)
+	}
+	log.Info("Database ping successful")
+
+	// Set connection pool settings
+	log.Info("Configuring connection pool",
+		"maxOpenConns", 25,
+		"maxIdleConns", 25,
+		"connMaxLifetime", "5m")
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	// Create new IndexerDB instance
+	indexerDB := &postgresDB{
+		sqlDB: &sqlDB{db: db, driver: DriverPostgres},
+	}
+
+	log.Info("Database connection and setup completed successfully")
+	return indexerDB, nil
+}
@@ -0,0 +1,566 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+)
+
+// newTestSQLiteDB opens an SQLite database like core/indexer/sqlite does, which
+// can't be imported from the tests of this package.
+func newTestSQLiteDB(path string) (*sqlDB, error) {
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=1&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate", path)
+	db, err := sqlx.Connect("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return &sqlDB{db: db, driver: DriverSQLite}, nil
+}
+
+// newTestIndexerDB opens a fresh SQLite indexer database in a temporary folder.
+func newTestIndexerDB(t *testing.T) *sqlDB {
+	t.Helper()
+
+	db, err := newTestSQLiteDB(filepath.Join(t.TempDir(), "indexer.sqlite"))
+	if err != nil {
+		t.Fatalf("failed to open indexer database: %v", err)
+	}
+	if _, err := db.Migrate(); err != nil {
+		t.Fatalf("failed to migrate indexer database: %v", err)
+	}
+	return db
+}
+
+// waitIndexed blocks until the given block is indexed by the plugin's
+// background processor.
+func waitIndexed(t *testing.T, db IndexerDB, block *types.Block) {
+	t.Helper()
+
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+		hashes, err := db.GetBlockHashes(block.NumberU64(), block.NumberU64())
+		if err != nil {
+			t.Fatalf("failed to read indexed blocks: %v", err)
+		}
+		if hashes[block.NumberU64()] == block.Hash() {
+			return
+		}
+	}
+	t.Fatalf("block #%d [%x] not indexed", block.NumberU64(), block.Hash())
+}
+
+// Tests the whole indexer plugin against an SQLite database: blocks imported
+// into the chain are indexed in the background, and a reorg replaces the
+// dropped blocks while keeping their logs flagged as removed.
+func TestIndexerSQLite(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		emitter = common.HexToAddress("0xe1e1")
+
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender:  {Balance: big.NewInt(params.Ether)},
+				emitter: {Code: program.New().Push(0).Push(0).Op(vm.LOG0).Bytes()},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		engine = ethash.NewFaker()
+	)
+	emit := func(coinbase common.Address) func(int, *BlockGen) {
+		return func(i int, gen *BlockGen) {
+			gen.SetCoinbase(coinbase)
+			gen.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{
+				Nonce:    gen.TxNonce(sender),
+				To:       &emitter,
+				Gas:      100000,
+				GasPrice: gen.header.BaseFee,
+			}))
+		}
+	}
+	gendb, blocks, _ := GenerateChainWithGenesis(gspec, engine, 3, emit(common.Address{}))
+	forks, _ := GenerateChain(gspec.Config, blocks[0], engine, gendb, 3, emit(common.Address{0x1}))
+
+	db := newTestIndexerDB(t)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, NewIndexerPlugin(db))
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed(t, db, blocks[2])
+
+	if _, err := chain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	waitIndexed(t, db, forks[2])
+
+	// The indexed chain must match the canonical one
+	hashes, err := db.GetBlockHashes(0, 10)
+	if err != nil {
+		t.Fatalf("failed to read indexed blocks: %v", err)
+	}
+	canonical := append([]*types.Block{blocks[0]}, forks...)
+	if len(hashes) != len(canonical) {
+		t.Fatalf("indexed block count mismatch: have %d, want %d", len(hashes), len(canonical))
+	}
+	for _, block := range canonical {
+		if hashes[block.NumberU64()] != block.Hash() {
+			t.Errorf("block #%d mismatch: have %x, want %x", block.NumberU64(), hashes[block.NumberU64()], block.Hash())
+		}
+	}
+	stored, err := db.GetBlockByHash(forks[2].Hash())
+	if err != nil || stored == nil {
+		t.Fatalf("failed to read block by hash: %v", err)
+	}
+	if stored.Number != forks[2].NumberU64() || stored.Miner != (common.Address{0x1}) {
+		t.Errorf("stored block mismatch: have #%d by %x", stored.Number, stored.Miner)
+	}
+	// Logs of the dropped blocks are kept, but flagged as removed
+	var logs []Log
+	if err := db.db.Select(&logs, `SELECT * FROM logs ORDER BY id`); err != nil {
+		t.Fatalf("failed to read logs: %v", err)
+	}
+	removed := make(map[common.Hash]bool)
+	for _, log := range logs {
+		removed[log.BlockHash] = log.Removed
+		if log.Address != emitter || len(log.Topics) != 0 {
+			t.Errorf("log %d mismatch: %+v", log.ID, log)
+		}
+	}
+	for _, block := range blocks[1:] {
+		if r, ok := removed[block.Hash()]; !ok || !r {
+			t.Errorf("log of dropped block #%d: have removed %v (found %v), want true", block.NumberU64(), r, ok)
+		}
+	}
+	for _, block := range canonical {
+		if r, ok := removed[block.Hash()]; !ok || r {
+			t.Errorf("log of canonical block #%d: have removed %v (found %v), want false", block.NumberU64(), r, ok)
+		}
+	}
+	// The reorg must have been recorded
+	var reorgs []Reorg
+	if err := db.db.Select(&reorgs, `SELECT * FROM reorgs`); err != nil {
+		t.Fatalf("failed to read reorgs: %v", err)
+	}
+	if len(reorgs) != 1 {
+		t.Fatalf("reorg count mismatch: have %d, want 1", len(reorgs))
+	}
+	if r := reorgs[0]; r.Depth != 2 || r.AncestorHash != blocks[0].Hash() || len(r.OldHashes) != 2 || common.BytesToHash(r.OldHashes[1]) != blocks[2].Hash() {
+		t.Errorf("reorg record mismatch: %+v", r)
+	}
+}
+
+// Tests that indexed blocks and their finalization are only announced once they
+// are committed to the database.
+func TestIndexerSubscription(t *testing.T) {
+	var (
+		gspec  = &Genesis{Config: params.TestChainConfig}
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 3, nil)
+
+	db := newTestIndexerDB(t)
+	plugin := NewIndexerPlugin(db)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, plugin)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	events := make(chan IndexedBlockEvent)
+	sub := plugin.SubscribeIndexedBlocks(events)
+	defer sub.Unsubscribe()
+
+	// Every event must find its block in the database already
+	check := func(want *types.Block, finalized bool) {
+		t.Helper()
+
+		select {
+		case ev := <-events:
+			if ev.Header.Hash() != want.Hash() || ev.Finalized != finalized {
+				t.Fatalf("event mismatch: have #%d [%x] finalized %v, want #%d [%x] finalized %v",
+					ev.Header.Number, ev.Header.Hash(), ev.Finalized, want.NumberU64(), want.Hash(), finalized)
+			}
+			stored, err := db.GetBlockByHash(want.Hash())
+			if err != nil || stored == nil {
+				t.Fatalf("announced block #%d not stored: %v", want.NumberU64(), err)
+			}
+			if stored.Finalized != finalized {
+				t.Errorf("announced block #%d finalization mismatch: have %v, want %v", want.NumberU64(), stored.Finalized, finalized)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("block #%d not announced", want.NumberU64())
+		}
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for _, block := range blocks {
+		check(block, false)
+	}
+	chain.SetFinalized(blocks[1].Header())
+	check(blocks[1], true)
+}
+
+// Tests that blocks before the configured start block are not indexed, and that
+// unknown optional tables are refused.
+func TestIndexerStartBlock(t *testing.T) {
+	var (
+		gspec  = &Genesis{Config: params.TestChainConfig}
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 3, nil)
+
+	db := newTestIndexerDB(t)
+	plugin := NewIndexerPlugin(db)
+	if err := plugin.Configure(IndexerConfig{Tables: []string{"receipts"}}); err == nil {
+		t.Error("unknown table accepted")
+	}
+	if err := plugin.Configure(IndexerConfig{StartBlock: 2}); err != nil {
+		t.Fatalf("failed to configure plugin: %v", err)
+	}
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, plugin)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed(t, db, blocks[2])
+
+	hashes, err := db.GetBlockHashes(0, 3)
+	if err != nil {
+		t.Fatalf("failed to read indexed blocks: %v", err)
+	}
+	if len(hashes) != 2 || hashes[2] != blocks[1].Hash() {
+		t.Errorf("wrong indexed blocks: %v", hashes)
+	}
+}
+
+// Tests that the withdrawals of blocks and the versioned hashes of blob
+// transactions are indexed.
+func TestIndexerWithdrawalsAndBlobs(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		config = *params.MergedTestChainConfig
+		gspec  = &Genesis{
+			Config: &config,
+			Alloc:  types.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		engine = beacon.NewFaker()
+		blobs  = []common.Hash{{0x01, 0x01}, {0x01, 0x02}}
+	)
+	config.PragueTime = nil // avoid the request system contracts
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 2, func(i int, gen *BlockGen) {
+		gen.AddWithdrawal(&types.Withdrawal{Validator: uint64(10 + i), Address: common.Address{byte(i + 1)}, Amount: 32})
+		if i == 1 {
+			gen.AddTx(types.MustSignNewTx(key, signer, &types.BlobTx{
+				Nonce:      gen.TxNonce(sender),
+				To:         common.Address{0xbb},
+				Gas:        21000,
+				GasFeeCap:  uint256.MustFromBig(gen.header.BaseFee),
+				GasTipCap:  new(uint256.Int),
+				BlobFeeCap: uint256.NewInt(params.GWei),
+				BlobHashes: blobs,
+				Value:      new(uint256.Int),
+			}))
+		}
+	})
+	db := newTestIndexerDB(t)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, NewIndexerPlugin(db))
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed(t, db, blocks[1])
+
+	var withdrawals []*Withdrawal
+	if err := db.db.Select(&withdrawals, `SELECT * FROM withdrawals ORDER BY withdrawal_index`); err != nil {
+		t.Fatalf("failed to read withdrawals: %v", err)
+	}
+	if len(withdrawals) != 2 {
+		t.Fatalf("withdrawal count mismatch: have %d, want 2", len(withdrawals))
+	}
+	for i, w := range withdrawals {
+		want := blocks[i].Withdrawals()[0]
+		if w.BlockNumber != uint64(i+1) || w.WithdrawalIndex != want.Index || w.ValidatorIndex != want.Validator || w.Address != want.Address || w.Amount != want.Amount {
+			t.Errorf("withdrawal %d mismatch: have %+v, want %+v", i, w, want)
+		}
+	}
+	var hashes []*BlobHash
+	if err := db.db.Select(&hashes, `SELECT * FROM blob_hashes ORDER BY blob_index`); err != nil {
+		t.Fatalf("failed to read blob hashes: %v", err)
+	}
+	if len(hashes) != len(blobs) {
+		t.Fatalf("blob hash count mismatch: have %d, want %d", len(hashes), len(blobs))
+	}
+	tx := blocks[1].Transactions()[0]
+	for i, h := range hashes {
+		if h.BlockNumber != 2 || h.TransactionHash != tx.Hash() || h.BlobIndex != uint64(i) || h.VersionedHash != blobs[i] || h.BlobGasPrice == nil {
+			t.Errorf("blob hash %d mismatch: %+v", i, h)
+		}
+	}
+}
+
+// Tests that indexing a block again without deleting it first, as a replayed
+// chain event may do, leaves a single copy of its rows and revives its logs.
+func TestIndexerReplayIdempotent(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		emitter = common.HexToAddress("0xe1e1")
+
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender:  {Balance: big.NewInt(params.Ether)},
+				emitter: {Code: program.New().Push(0).Push(0).Op(vm.LOG0).Push(0).Push(0).Op(vm.LOG0).Bytes()},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 1, func(i int, gen *BlockGen) {
+		gen.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    gen.TxNonce(sender),
+			To:       &emitter,
+			Gas:      100000,
+			GasPrice: gen.header.BaseFee,
+		}))
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	db := newTestIndexerDB(t)
+	plugin := NewIndexerPlugin(db)
+	plugin.chain = chain
+
+	index := func() {
+		t.Helper()
+
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("failed to begin transaction: %v", err)
+		}
+		if _, err := plugin.indexBlock(tx, blocks[0].Header()); err != nil {
+			tx.Rollback()
+			t.Fatalf("failed to index block: %v", err)
+		}
+		if err := commitIndexerTx(tx); err != nil {
+			t.Fatalf("failed to commit block: %v", err)
+		}
+	}
+	index()
+	if _, err := db.db.Exec(`UPDATE logs SET removed = TRUE`); err != nil {
+		t.Fatalf("failed to flag logs: %v", err)
+	}
+	index()
+
+	for table, want := range map[string]int{"blocks": 1, "transactions": 1, "receipts": 1, "logs": 2} {
+		var count int
+		if err := db.db.Get(&count, `SELECT COUNT(*) FROM `+table); err != nil {
+			t.Fatalf("failed to count %s: %v", table, err)
+		}
+		if count != want {
+			t.Errorf("%s count mismatch: have %d, want %d", table, count, want)
+		}
+	}
+	var removed int
+	if err := db.db.Get(&removed, `SELECT COUNT(*) FROM logs WHERE removed`); err != nil {
+		t.Fatalf("failed to count removed logs: %v", err)
+	}
+	if removed != 0 {
+		t.Errorf("replayed block left %d logs flagged removed", removed)
+	}
+}
+
+// Tests that rewinding the chain with SetHead drops the blocks indexed past the
+// new head, recording them as a reorg.
+func TestIndexerSetHead(t *testing.T) {
+	var (
+		gspec  = &Genesis{Config: params.TestChainConfig}
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 5, nil)
+
+	db := newTestIndexerDB(t)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, NewIndexerPlugin(db))
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed(t, db, blocks[4])
+
+	if err := chain.SetHead(2); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		latest, err := db.GetLatestBlock()
+		if err != nil {
+			t.Fatalf("failed to read latest block: %v", err)
+		}
+		if latest == 2 {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatalf("blocks past the new head still indexed, latest %d", latest)
+		}
+	}
+	waitIndexed(t, db, blocks[1])
+
+	var reorgs []Reorg
+	if err := db.db.Select(&reorgs, `SELECT * FROM reorgs`); err != nil {
+		t.Fatalf("failed to read reorgs: %v", err)
+	}
+	if len(reorgs) != 1 {
+		t.Fatalf("reorg count mismatch: have %d, want 1", len(reorgs))
+	}
+	if r := reorgs[0]; r.AncestorNumber != 2 || r.AncestorHash != blocks[1].Hash() || r.Depth != 3 || r.OldHead == nil || *r.OldHead != blocks[4].Hash() {
+		t.Errorf("reorg record mismatch: %+v", r)
+	}
+}
+
+// Tests that log queries match the topics by position on SQLite, looking up
+// the first topic in its index.
+func TestGetLogsSQLiteTopics(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		emitter = common.HexToAddress("0xe1e1")
+		topicA  = common.Hash{0xa}
+		topicB  = common.Hash{0xb}
+
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				emitter: {Code: program.New().
+					Push(topicB).Push(topicA).Push(0).Push(0).Op(vm.LOG2).
+					Push(topicB).Push(0).Push(0).Op(vm.LOG1).
+					Push(0).Push(0).Op(vm.LOG0).Bytes()},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 3, func(i int, gen *BlockGen) {
+		gen.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    gen.TxNonce(sender),
+			To:       &emitter,
+			Gas:      100000,
+			GasPrice: gen.header.BaseFee,
+		}))
+	})
+	db := newTestIndexerDB(t)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, NewIndexerPlugin(db))
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed(t, db, blocks[2])
+
+	tests := []struct {
+		topics [][]common.Hash
+		want   int // logs per block
+	}{
+		{nil, 3},
+		{[][]common.Hash{{topicA}}, 1},
+		{[][]common.Hash{{topicB}}, 1},
+		{[][]common.Hash{{topicA, topicB}}, 2},
+		{[][]common.Hash{{topicA}, {topicB}}, 1},
+		{[][]common.Hash{{topicB}, {topicB}}, 0},
+		{[][]common.Hash{{}, {topicB}}, 1},
+		{[][]common.Hash{{{0xc}}}, 0},
+	}
+	for _, tt := range tests {
+		logs, err := db.GetLogs(context.Background(), 1, 3, nil, tt.topics)
+		if err != nil {
+			t.Fatalf("%v: failed to get logs: %v", tt.topics, err)
+		}
+		if len(logs) != 3*tt.want {
+			t.Errorf("%v: log count mismatch: have %d, want %d", tt.topics, len(logs), 3*tt.want)
+		}
+		for _, log := range logs {
+			if !matchTopics(log, tt.topics) {
+				t.Errorf("%v: log %d of block %d doesn't match", tt.topics, log.Index, log.BlockNumber)
+			}
+		}
+	}
+	// The first topic must be looked up in its index
+	query, args := db.logsQuery(1, 3, nil, [][]common.Hash{{topicA}})
+	var plan []struct {
+		ID      int    `db:"id"`
+		Parent  int    `db:"parent"`
+		NotUsed int    `db:"notused"`
+		Detail  string `db:"detail"`
+	}
+	if err := db.db.Select(&plan, `EXPLAIN QUERY PLAN `+query, args...); err != nil {
+		t.Fatalf("failed to explain query: %v", err)
+	}
+	var indexed bool
+	for _, step := range plan {
+		indexed = indexed || strings.Contains(step.Detail, "idx_logs_topic0")
+	}
+	if !indexed {
+		t.Errorf("topic index not used: %+v", plan)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests that the postgres connection string is assembled from the configured
+// DSN or settings, with the password read from a file.
+func TestIndexerConfigDSN(t *testing.T) {
+	passfile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passfile, []byte("it's s3cret\n"), 0600); err != nil {
+		t.Fatalf("failed to write password file: %v", err)
+	}
+	tests := []struct {
+		config IndexerConfig
+		want   string
+	}{
+		{
+			config: DefaultIndexerConfig,
+			want:   "host='localhost' port='5432' user='postgres' dbname='geth_indexer' sslmode='disable'",
+		},
+		{
+			config: IndexerConfig{Host: "db", User: "geth", PasswordFile: passfile},
+			want:   `host='db' user='geth' password='it\'s s3cret'`,
+		},
+		{
+			config: IndexerConfig{DSN: "host=db dbname=indexer", Host: "ignored"},
+			want:   "host=db dbname=indexer",
+		},
+		{
+			config: IndexerConfig{DSN: "postgres://geth@db:5433/indexer?sslmode=require", PasswordFile: passfile},
+			want:   `dbname='indexer' host='db' port='5433' sslmode='require' user='geth' password='it\'s s3cret'`,
+		},
+	}
+	for i, tt := range tests {
+		have, err := tt.config.postgresDSN()
+		if err != nil {
+			t.Errorf("test %d: failed to assemble DSN: %v", i, err)
+			continue
+		}
+		if have != tt.want {
+			t.Errorf("test %d: DSN mismatch:\nhave %s\nwant %s", i, have, tt.want)
+		}
+	}
+	if _, err := (&IndexerConfig{PasswordFile: filepath.Join(t.TempDir(), "missing")}).postgresDSN(); err == nil {
+		t.Error("missing password file accepted")
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jmoiron/sqlx"
+)
+
+// BlockSummary aggregates the rows indexed at a block height, the input of the
+// consistency checks of the indexer. Rows of other tables may exist without the
+// block itself being indexed.
+type BlockSummary struct {
+	Hash         *common.Hash                // indexed block, nil if there's none
+	Finalized    bool                        // finalized flag of the indexed block
+	Transactions []common.Hash               // transactions in index order
+	Receipts     map[common.Hash]uint64      // receipt status by transaction, of any block hash
+	ReceiptHash  map[common.Hash]common.Hash // block hash referenced by each receipt
+	Logs         map[common.Hash]uint64      // logs not flagged removed, by block hash
+	StateChanges uint64
+}
+
+// GetBlockSummaries returns the summaries of all block heights with indexed
+// rows in the given inclusive range, keyed by block number.
+func (idb *sqlDB) GetBlockSummaries(ctx context.Context, from, to uint64) (map[uint64]*BlockSummary, error) {
+	summaries := make(map[uint64]*BlockSummary)
+	summary := func(number uint64) *BlockSummary {
+		if s, ok := summaries[number]; ok {
+			return s
+		}
+		s := &BlockSummary{
+			Receipts:    make(map[common.Hash]uint64),
+			ReceiptHash: make(map[common.Hash]common.Hash),
+			Logs:        make(map[common.Hash]uint64),
+		}
+		summaries[number] = s
+		return s
+	}
+	var blocks []struct {
+		Number    uint64      `db:"number"`
+		Hash      common.Hash `db:"hash"`
+		Finalized bool        `db:"finalized"`
+	}
+	if err := idb.db.SelectContext(ctx, &blocks, idb.db.Rebind(`
+		SELECT number, hash, finalized FROM blocks WHERE number BETWEEN ? AND ?
+	`), from, to); err != nil {
+		return nil, fmt.Errorf("error getting blocks: %v", err)
+	}
+	for _, block := range blocks {
+		s := summary(block.Number)
+		s.Hash = &block.Hash
+		s.Finalized = block.Finalized
+	}
+	var txs []struct {
+		BlockNumber uint64      `db:"block_number"`
+		Hash        common.Hash `db:"hash"`
+	}
+	if err := idb.db.SelectContext(ctx, &txs, idb.db.Rebind(`
+		SELECT block_number, hash FROM transactions WHERE block_number BETWEEN ? AND ?
+		ORDER BY block_number, transaction_index
+	`), from, to); err != nil {
+		return nil, fmt.Errorf("error getting transactions: %v", err)
+	}
+	for _, tx := range txs {
+		s := summary(tx.BlockNumber)
+		s.Transactions = append(s.Transactions, tx.Hash)
+	}
+	var receipts []struct {
+		BlockNumber     uint64      `db:"block_number"`
+		BlockHash       common.Hash `db:"block_hash"`
+		TransactionHash common.Hash `db:"transaction_hash"`
+		Status          uint64      `db:"status"`
+	}
+	if err := idb.db.SelectContext(ctx, &receipts, idb.db.Rebind(`
+		SELECT block_number, block_hash, transaction_hash, status FROM receipts WHERE block_number BETWEEN ? AND ?
+	`), from, to); err != nil {
+		return nil, fmt.Errorf("error getting receipts: %v", err)
+	}
+	for _, receipt := range receipts {
+		s := summary(receipt.BlockNumber)
+		s.Receipts[receipt.TransactionHash] = receipt.Status
+		s.ReceiptHash[receipt.TransactionHash] = receipt.BlockHash
+	}
+	var logs []struct {
+		BlockNumber uint64      `db:"block_number"`
+		BlockHash   common.Hash `db:"block_hash"`
+		Count       uint64      `db:"count"`
+	}
+	if err := idb.db.SelectContext(ctx, &logs, idb.db.Rebind(`
+		SELECT block_number, block_hash, COUNT(*) AS count FROM logs
+		WHERE block_number BETWEEN ? AND ? AND removed = FALSE
+		GROUP BY block_number, block_hash
+	`), from, to); err != nil {
+		return nil, fmt.Errorf("error getting log counts: %v", err)
+	}
+	for _, l := range logs {
+		summary(l.BlockNumber).Logs[l.BlockHash] = l.Count
+	}
+	var changes []struct {
+		BlockNumber uint64 `db:"block_number"`
+		Count       uint64 `db:"count"`
+	}
+	if err := idb.db.SelectContext(ctx, &changes, idb.db.Rebind(`
+		SELECT block_number, COUNT(*) AS count FROM state_changes
+		WHERE block_number BETWEEN ? AND ?
+		GROUP BY block_number
+	`), from, to); err != nil {
+		return nil, fmt.Errorf("error getting state change counts: %v", err)
+	}
+	for _, c := range changes {
+		summary(c.BlockNumber).StateChanges = c.Count
+	}
+	return summaries, nil
+}
+
+// DeleteLogsWithTx deletes all logs of a block, including the ones flagged as
+// removed, using an existing transaction.
+func (idb *sqlDB) DeleteLogsWithTx(tx *sqlx.Tx, blockHash common.Hash) error {
+	if _, err := tx.Exec(tx.Rebind(`DELETE FROM logs WHERE block_hash = ?`), blockHash); err != nil {
+		return fmt.Errorf("error deleting logs: %v", err)
+	}
+	return nil
+}
+
+// MarkLogsRemovedWithTx flags the live logs at a height which don't belong to
+// the given canonical block as removed, using an existing transaction.
+func (idb *sqlDB) MarkLogsRemovedWithTx(tx *sqlx.Tx, blockNumber uint64, canonical common.Hash) error {
+	if _, err := tx.Exec(tx.Rebind(`
+		UPDATE logs SET removed = TRUE
+		WHERE block_number = ? AND block_hash <> ? AND removed = FALSE
+	`), blockNumber, canonical); err != nil {
+		return fmt.Errorf("error flagging logs as removed: %v", err)
+	}
+	return nil
+}
+
+// DeleteReceiptsWithTx deletes all receipts indexed at a height, using an
+// existing transaction.
+func (idb *sqlDB) DeleteReceiptsWithTx(tx *sqlx.Tx, blockNumber uint64) error {
+	if _, err := tx.Exec(tx.Rebind(`DELETE FROM receipts WHERE block_number = ?`), blockNumber); err != nil {
+		return fmt.Errorf("error deleting receipts: %v", err)
+	}
+	return nil
+}
+
+// RepairTransactionsWithTx deletes the transactions indexed at a height which
+// aren't in the given canonical list, along with the rows referencing them, and
+// moves the remaining ones to their canonical position. Canonical transactions
+// missing from the database are left to the caller.
+func (idb *sqlDB) RepairTransactionsWithTx(tx *sqlx.Tx, blockNumber uint64, canonical []common.Hash) error {
+	var have []common.Hash
+	if err := tx.Select(&have, tx.Rebind(`SELECT hash FROM transactions WHERE block_number = ?`), blockNumber); err != nil {
+		return fmt.Errorf("error getting transactions: %v", err)
+	}
+	index := make(map[common.Hash]int)
+	for i, hash := range canonical {
+		index[hash] = i
+	}
+	for _, hash := range have {
+		if i, ok := index[hash]; ok {
+			if _, err := tx.Exec(tx.Rebind(`UPDATE transactions SET transaction_index = ? WHERE hash = ?`), i, hash); err != nil {
+				return fmt.Errorf("error moving transaction: %v", err)
+			}
+			continue
+		}
+		// Delete in reverse order of dependencies to respect foreign key constraints
+		deleteQueries := []string{
+			`DELETE FROM access_lists WHERE transaction_hash = ?`,
+			`DELETE FROM accounts WHERE creator_tx_hash = ?`,
+			`DELETE FROM traces WHERE transaction_hash = ?`,
+			`DELETE FROM decoded_events WHERE transaction_hash = ?`,
+			`DELETE FROM token_transfers WHERE transaction_hash = ?`,
+			`DELETE FROM blob_hashes WHERE transaction_hash = ?`,
+			`DELETE FROM state_changes WHERE transaction_hash = ?`,
+			`DELETE FROM logs WHERE transaction_hash = ?`,
+			`DELETE FROM receipts WHERE transaction_hash = ?`,
+			`DELETE FROM transactions WHERE hash = ?`,
+		}
+		for _, query := range deleteQueries {
+			if _, err := tx.Exec(tx.Rebind(query), hash); err != nil {
+				return fmt.Errorf("error deleting transaction: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// UnmarkBlockFinalizedWithTx clears the finalized flag of a block, using an
+// existing transaction.
+func (idb *sqlDB) UnmarkBlockFinalizedWithTx(tx *sqlx.Tx, blockNumber uint64) error {
+	if _, err := tx.Exec(tx.Rebind(`UPDATE blocks SET finalized = FALSE WHERE number = ?`), blockNumber); err != nil {
+		return fmt.Errorf("error clearing finalized flag: %v", err)
+	}
+	return nil
+}
@@ -33,3 +33,10 @@ type ChainEvent struct {
 type ChainHeadEvent struct {
 	Header *types.Header
 }
+
+// IndexedBlockEvent is posted when a canonical block, or its finalization, has
+// been committed to the indexer database.
+type IndexedBlockEvent struct {
+	Header    *types.Header
+	Finalized bool
+}
@@ -0,0 +1,276 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// errTruncated is returned when a file ends in the middle of a structure.
+var errTruncated = errors.New("truncated parquet data")
+
+// Read decodes a file of the subset written by Writer into rows keyed by
+// column name. Byte arrays are returned as strings, integers as int64 and
+// booleans as bool, nulls as nil.
+func Read(data []byte) ([]map[string]any, error) {
+	if len(data) < 12 || string(data[:4]) != magic || string(data[len(data)-4:]) != magic {
+		return nil, errors.New("invalid parquet framing")
+	}
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if size > len(data)-12 {
+		return nil, errTruncated
+	}
+	meta, err := readStruct(data[len(data)-8-size : len(data)-8])
+	if err != nil {
+		return nil, fmt.Errorf("invalid file metadata: %v", err)
+	}
+	schema, _ := meta[2].([]any)
+	if len(schema) == 0 {
+		return nil, errors.New("missing schema")
+	}
+	var (
+		names = make([]string, len(schema)-1)
+		types = make([]int64, len(schema)-1)
+		rows  []map[string]any
+	)
+	for i, element := range schema[1:] {
+		fields, _ := element.(map[int64]any)
+		names[i], _ = fields[4].(string)
+		types[i], _ = fields[1].(int64)
+	}
+	groups, _ := meta[4].([]any)
+	for _, group := range groups {
+		fields, _ := group.(map[int64]any)
+		chunks, _ := fields[1].([]any)
+		count, _ := fields[3].(int64)
+		if len(chunks) != len(names) {
+			return nil, fmt.Errorf("row group has %d columns, schema has %d", len(chunks), len(names))
+		}
+		first := len(rows)
+		for i := int64(0); i < count; i++ {
+			rows = append(rows, make(map[string]any))
+		}
+		for i, chunk := range chunks {
+			fields, _ := chunk.(map[int64]any)
+			column, _ := fields[3].(map[int64]any)
+			offset, _ := column[9].(int64)
+			if offset < 0 || offset >= int64(len(data)) {
+				return nil, fmt.Errorf("column %s: invalid page offset %d", names[i], offset)
+			}
+			values, err := readPage(data[offset:], types[i], int(count))
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %v", names[i], err)
+			}
+			for j, value := range values {
+				rows[first+j][names[i]] = value
+			}
+		}
+	}
+	if n, _ := meta[3].(int64); int(n) != len(rows) {
+		return nil, fmt.Errorf("row count mismatch: have %d, want %d", len(rows), n)
+	}
+	return rows, nil
+}
+
+// readPage decodes the values of a PLAIN encoded data page of optional values.
+func readPage(data []byte, typ int64, count int) ([]any, error) {
+	r := &thriftReader{buf: data}
+	header, err := r.readStruct()
+	if err != nil {
+		return nil, err
+	}
+	size, _ := header[3].(int64)
+	if size < 4 || size > int64(len(r.buf)) {
+		return nil, errTruncated
+	}
+	page := r.buf[:size]
+	if fields, _ := header[5].(map[int64]any); fields[1] != int64(count) {
+		return nil, fmt.Errorf("value count mismatch: have %v, want %d", fields[1], count)
+	}
+	// Expand the runs of definition levels, bit packed runs aren't written
+	length := binary.LittleEndian.Uint32(page)
+	if uint64(length) > uint64(len(page)-4) {
+		return nil, errTruncated
+	}
+	var (
+		levels  = page[4 : 4+length]
+		defined []bool
+	)
+	for len(levels) > 0 {
+		run, n := binary.Uvarint(levels)
+		if n <= 0 || n >= len(levels) {
+			return nil, errTruncated
+		}
+		if run&1 != 0 {
+			return nil, errors.New("unsupported bit packed run")
+		}
+		if uint64(len(defined))+run>>1 > uint64(count) {
+			return nil, errors.New("too many definition levels")
+		}
+		for j := uint64(0); j < run>>1; j++ {
+			defined = append(defined, levels[n] == 1)
+		}
+		levels = levels[n+1:]
+	}
+	if len(defined) != count {
+		return nil, fmt.Errorf("definition level count mismatch: have %d, want %d", len(defined), count)
+	}
+	var (
+		values = page[4+length:]
+		result = make([]any, count)
+		bit    int // index of the next boolean
+	)
+	for j, ok := range defined {
+		if !ok {
+			continue
+		}
+		switch typ {
+		case typeBoolean:
+			if bit/8 >= len(values) {
+				return nil, errTruncated
+			}
+			result[j] = values[bit/8]>>(bit%8)&1 == 1
+			bit++
+		case typeInt64:
+			if len(values) < 8 {
+				return nil, errTruncated
+			}
+			result[j] = int64(binary.LittleEndian.Uint64(values))
+			values = values[8:]
+		case typeByteArray:
+			if len(values) < 4 || uint64(binary.LittleEndian.Uint32(values)) > uint64(len(values)-4) {
+				return nil, errTruncated
+			}
+			n := binary.LittleEndian.Uint32(values)
+			result[j] = string(values[4 : 4+n])
+			values = values[4+n:]
+		default:
+			return nil, fmt.Errorf("unsupported physical type %d", typ)
+		}
+	}
+	return result, nil
+}
+
+// readStruct decodes a Thrift compact protocol struct.
+func readStruct(data []byte) (map[int64]any, error) {
+	return (&thriftReader{buf: data}).readStruct()
+}
+
+// thriftReader decodes the Thrift compact protocol into generic values: structs
+// into maps by field ID, lists into slices, integers into int64 and binaries
+// into strings.
+type thriftReader struct {
+	buf []byte
+}
+
+func (r *thriftReader) readStruct() (map[int64]any, error) {
+	v, err := r.value(thriftStruct)
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[int64]any), nil
+}
+
+func (r *thriftReader) byte() (byte, error) {
+	if len(r.buf) == 0 {
+		return 0, errTruncated
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b, nil
+}
+
+func (r *thriftReader) varint() (int64, error) {
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+func (r *thriftReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+func (r *thriftReader) value(typ byte) (any, error) {
+	switch typ {
+	case 1, 2:
+		return typ == 1, nil
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		size, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if size > uint64(len(r.buf)) {
+			return nil, errTruncated
+		}
+		v := string(r.buf[:size])
+		r.buf = r.buf[size:]
+		return v, nil
+	case thriftList:
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = r.uvarint(); err != nil {
+				return nil, err
+			}
+		}
+		if size > uint64(len(r.buf)) {
+			return nil, errTruncated // every element takes at least a byte
+		}
+		list := make([]any, size)
+		for i := range list {
+			if list[i], err = r.value(header & 0x0f); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case thriftStruct:
+		fields := make(map[int64]any)
+		for id := int64(0); ; {
+			header, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+			if header == 0 {
+				return fields, nil
+			}
+			if delta := int64(header >> 4); delta != 0 {
+				id += delta
+			} else if id, err = r.varint(); err != nil {
+				return nil, err
+			}
+			if fields[id], err = r.value(header & 0x0f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, fmt.Errorf("unsupported thrift type %d", typ)
+}
@@ -0,0 +1,421 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package parquet implements the subset of the Parquet format needed by the
+// file sink of the indexer: a flat schema of optional columns, stored
+// uncompressed in PLAIN encoded data pages, one page per column and row group.
+// The metadata is serialized in the Thrift compact protocol, see
+// https://github.com/apache/parquet-format/blob/master/src/main/thrift/parquet.thrift
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Magic bytes framing a Parquet file.
+const magic = "PAR1"
+
+// rowGroupSize is the number of rows buffered before they're written out
+// as a row group, bounding the memory used to convert a file.
+const rowGroupSize = 65536
+
+// Physical types, converted types and encodings of the Parquet format.
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+	convertedUint64          = 14
+	convertedJSON            = 19
+
+	encodingPlain = 0
+	encodingRLE   = 3
+)
+
+// Kind is the type of the values of a column.
+type Kind int
+
+const (
+	String    Kind = iota // UTF-8 string
+	JSON                  // JSON document
+	Uint                  // unsigned 64 bit integer
+	Timestamp             // time.Time, stored in milliseconds
+	Bool                  // boolean
+)
+
+// types returns the physical and the converted type of a column kind, the
+// latter is negative if the physical type needs no annotation.
+func (k Kind) types() (int32, int32) {
+	switch k {
+	case JSON:
+		return typeByteArray, convertedJSON
+	case Uint:
+		return typeInt64, convertedUint64
+	case Timestamp:
+		return typeInt64, convertedTimestampMillis
+	case Bool:
+		return typeBoolean, -1
+	default:
+		return typeByteArray, convertedUTF8
+	}
+}
+
+// Column is an optional column of a flat schema.
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+// columnChunk holds the values of a column buffered for the current row group.
+type columnChunk struct {
+	defined []bool // definition level of every row, false for nulls
+	values  []byte // PLAIN encoded values of the non-null rows
+	bools   []bool // non-null values of boolean columns, bit packed on flush
+}
+
+// chunkMeta locates a written column chunk.
+type chunkMeta struct {
+	offset int64 // position of the page header
+	size   int64 // size of the page header and data
+}
+
+// rowGroup describes a written row group.
+type rowGroup struct {
+	rows   int64
+	chunks []chunkMeta
+}
+
+// Writer encodes rows into a Parquet file.
+type Writer struct {
+	out     io.Writer
+	offset  int64
+	columns []Column
+	chunks  []columnChunk
+	rows    int // rows buffered in the chunks
+	groups  []rowGroup
+}
+
+// NewWriter starts a Parquet file of the given columns.
+func NewWriter(out io.Writer, columns []Column) (*Writer, error) {
+	w := &Writer{
+		out:     out,
+		columns: columns,
+		chunks:  make([]columnChunk, len(columns)),
+	}
+	if err := w.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Append adds a row holding a value per column: nil, a string for string and
+// JSON columns, an uint64, a time.Time or a bool. A mistyped row is rejected as
+// a whole.
+func (w *Writer) Append(row []any) error {
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("row has %d values, schema has %d columns", len(row), len(w.columns))
+	}
+	for i, value := range row {
+		var ok bool
+		switch w.columns[i].Kind {
+		case String, JSON:
+			_, ok = value.(string)
+		case Uint:
+			_, ok = value.(uint64)
+		case Timestamp:
+			_, ok = value.(time.Time)
+		case Bool:
+			_, ok = value.(bool)
+		}
+		if value != nil && !ok {
+			return fmt.Errorf("invalid value %v of column %s", value, w.columns[i].Name)
+		}
+	}
+	for i, value := range row {
+		chunk := &w.chunks[i]
+		chunk.defined = append(chunk.defined, value != nil)
+
+		switch v := value.(type) {
+		case string:
+			chunk.values = binary.LittleEndian.AppendUint32(chunk.values, uint32(len(v)))
+			chunk.values = append(chunk.values, v...)
+		case uint64:
+			chunk.values = binary.LittleEndian.AppendUint64(chunk.values, v)
+		case time.Time:
+			chunk.values = binary.LittleEndian.AppendUint64(chunk.values, uint64(v.UnixMilli()))
+		case bool:
+			chunk.bools = append(chunk.bools, v)
+		}
+	}
+	w.rows++
+	if w.rows >= rowGroupSize {
+		return w.flush()
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the file metadata, returning the size of
+// the file.
+func (w *Writer) Close() (int64, error) {
+	if err := w.flush(); err != nil {
+		return 0, err
+	}
+	meta := w.metadata()
+	if err := w.write(meta); err != nil {
+		return 0, err
+	}
+	if err := w.write(binary.LittleEndian.AppendUint32(nil, uint32(len(meta)))); err != nil {
+		return 0, err
+	}
+	if err := w.write([]byte(magic)); err != nil {
+		return 0, err
+	}
+	return w.offset, nil
+}
+
+// flush writes the buffered rows as a row group, with a single data page per
+// column.
+func (w *Writer) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	group := rowGroup{rows: int64(w.rows)}
+	for i := range w.chunks {
+		chunk := &w.chunks[i]
+
+		page := levels(chunk.defined)
+		if w.columns[i].Kind == Bool {
+			page = append(page, packBools(chunk.bools)...)
+		} else {
+			page = append(page, chunk.values...)
+		}
+		header := pageHeader(len(page), w.rows)
+
+		meta := chunkMeta{offset: w.offset, size: int64(len(header) + len(page))}
+		if err := w.write(header); err != nil {
+			return err
+		}
+		if err := w.write(page); err != nil {
+			return err
+		}
+		group.chunks = append(group.chunks, meta)
+		*chunk = columnChunk{}
+	}
+	w.groups = append(w.groups, group)
+	w.rows = 0
+	return nil
+}
+
+// write appends data to the file, tracking the offset.
+func (w *Writer) write(data []byte) error {
+	n, err := w.out.Write(data)
+	w.offset += int64(n)
+	return err
+}
+
+// metadata encodes the FileMetaData footer.
+func (w *Writer) metadata() []byte {
+	var rows int64
+	for _, group := range w.groups {
+		rows += group.rows
+	}
+	t := new(thriftWriter)
+	t.begin()
+	t.i32(1, 1) // version
+
+	t.list(2, thriftStruct, len(w.columns)+1) // schema
+	t.begin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(w.columns)))
+	t.end()
+	for _, column := range w.columns {
+		typ, converted := column.Kind.types()
+		t.begin()
+		t.i32(1, typ)
+		t.i32(3, 1) // OPTIONAL
+		t.binary(4, column.Name)
+		if converted >= 0 {
+			t.i32(6, converted)
+		}
+		t.end()
+	}
+	t.i64(3, rows)
+
+	t.list(4, thriftStruct, len(w.groups)) // row groups
+	for _, group := range w.groups {
+		var size int64
+		t.begin()
+		t.list(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			typ, _ := w.columns[i].Kind.types()
+			size += chunk.size
+
+			t.begin()
+			t.i64(2, chunk.offset) // file offset
+			t.field(3, thriftStruct)
+			t.begin()
+			t.i32(1, typ)
+			t.list(2, thriftI32, 2)
+			t.appendI32(encodingPlain)
+			t.appendI32(encodingRLE)
+			t.list(3, thriftBinary, 1)
+			t.appendBinary(w.columns[i].Name)
+			t.i32(4, 0) // UNCOMPRESSED
+			t.i64(5, group.rows)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset) // data page offset
+			t.end()
+			t.end()
+		}
+		t.i64(2, size)
+		t.i64(3, group.rows)
+		t.end()
+	}
+	t.binary(6, "go-ethereum") // created by
+	t.end()
+	return t.buf
+}
+
+// pageHeader encodes the header of a PLAIN encoded data page.
+func pageHeader(size int, rows int) []byte {
+	t := new(thriftWriter)
+	t.begin()
+	t.i32(1, 0) // DATA_PAGE
+	t.i32(2, int32(size))
+	t.i32(3, int32(size))
+	t.field(5, thriftStruct)
+	t.begin()
+	t.i32(1, int32(rows))
+	t.i32(2, encodingPlain)
+	t.i32(3, encodingRLE) // definition levels
+	t.i32(4, encodingRLE) // repetition levels
+	t.end()
+	t.end()
+	return t.buf
+}
+
+// levels encodes the definition levels of a page as runs of the RLE
+// hybrid encoding with a bit width of one, prefixed by their length.
+func levels(defined []bool) []byte {
+	var runs []byte
+	for i := 0; i < len(defined); {
+		j := i
+		for j < len(defined) && defined[j] == defined[i] {
+			j++
+		}
+		runs = binary.AppendUvarint(runs, uint64(j-i)<<1)
+		if defined[i] {
+			runs = append(runs, 1)
+		} else {
+			runs = append(runs, 0)
+		}
+		i = j
+	}
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(len(runs))), runs...)
+}
+
+// packBools encodes booleans PLAIN, one bit per value.
+func packBools(values []bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+// Field types of the Thrift compact protocol.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter serializes structs in the Thrift compact protocol.
+type thriftWriter struct {
+	buf    []byte
+	fields []int16 // last field ID of every open struct
+}
+
+// begin opens a struct, either at the top level, as a list element or after a
+// struct field header.
+func (t *thriftWriter) begin() {
+	t.fields = append(t.fields, 0)
+}
+
+// end closes the innermost struct.
+func (t *thriftWriter) end() {
+	t.buf = append(t.buf, 0)
+	t.fields = t.fields[:len(t.fields)-1]
+}
+
+// field writes a field header, delta encoding the ID if possible.
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.fields[len(t.fields)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.appendI32(v)
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thriftWriter) binary(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.appendBinary(v)
+}
+
+// list writes the header of a list field, followed by its elements.
+func (t *thriftWriter) list(id int16, typ byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|typ)
+	} else {
+		t.buf = append(t.buf, 0xf0|typ)
+		t.buf = binary.AppendUvarint(t.buf, uint64(size))
+	}
+}
+
+// appendI32 writes a zigzag encoded integer without field header.
+func (t *thriftWriter) appendI32(v int32) {
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+// appendBinary writes a length prefixed string without field header.
+func (t *thriftWriter) appendBinary(v string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
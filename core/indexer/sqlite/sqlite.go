@@ -0,0 +1,60 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package sqlite implements the indexer database on an embedded SQLite file, for
+// running the indexer without a database server. The driver needs cgo, so it is
+// kept out of package core and registers itself as core.DriverSQLite when
+// imported.
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+)
+
+func init() {
+	core.RegisterIndexerDriver(core.DriverSQLite, Open)
+}
+
+// Open opens (or creates) the SQLite database file
+func Open(config core.IndexerConfig) (core.IndexerDB, error) {
+	if config.Path == "" {
+		return nil, errors.New("no sqlite database file configured")
+	}
+	log.Info("Opening SQLite indexer database", "path", config.Path)
+
+	// Foreign keys are enforced like on PostgreSQL, and write transactions take
+	// the database lock upfront instead of failing on upgrade.
+	db, err := sqlx.Connect("sqlite3", DSN(config.Path))
+	if err != nil {
+		return nil, fmt.Errorf("error opening the database: %v", err)
+	}
+	// SQLite allows a single writer only, serialize all access through one
+	// connection instead of failing with busy errors
+	db.SetMaxOpenConns(1)
+
+	return core.NewSQLIndexerDB(db, core.DriverSQLite), nil
+}
+
+// DSN returns the connection string of the database file at path.
+func DSN(path string) string {
+	return fmt.Sprintf("file:%s?_foreign_keys=1&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate", path)
+}
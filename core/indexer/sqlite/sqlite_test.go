@@ -0,0 +1,46 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core"
+)
+
+// Tests that the registered driver opens and migrates a database file, which
+// can be opened again afterwards.
+func TestReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "indexer.sqlite")
+	for i := 0; i < 2; i++ {
+		db, err := core.NewDB(core.IndexerConfig{Driver: core.DriverSQLite, Path: path})
+		if err != nil {
+			t.Fatalf("open %d: failed to open database: %v", i, err)
+		}
+		if driver := db.Driver(); driver != core.DriverSQLite {
+			t.Errorf("open %d: driver mismatch: have %s, want %s", i, driver, core.DriverSQLite)
+		}
+		db.Close()
+	}
+	if _, err := core.NewDB(core.IndexerConfig{Driver: core.DriverSQLite}); err == nil {
+		t.Error("database without a file opened")
+	}
+	if _, err := core.NewDB(core.IndexerConfig{Driver: "oracle"}); err == nil {
+		t.Error("unknown driver accepted")
+	}
+}
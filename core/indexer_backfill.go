@@ -0,0 +1,397 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// backfillCheckpoint is the name of the checkpoint tracking backfill progress.
+	backfillCheckpoint = "backfill"
+
+	// defaultBackfillBatch is the number of blocks indexed in a single database
+	// transaction if not configured otherwise.
+	defaultBackfillBatch = 128
+)
+
+var (
+	errIndexerDisabled = errors.New("indexer disabled")
+	errBackfillRunning = errors.New("backfill already running")
+)
+
+// BackfillConfig contains the tunables of a historical backfill.
+type BackfillConfig struct {
+	Workers   int    // Number of batches indexed concurrently (default = number of CPUs)
+	BatchSize uint64 // Number of blocks per batch and database transaction (default = configured)
+}
+
+// BackfillStatus reports the progress of a historical backfill.
+type BackfillStatus struct {
+	Running bool   `json:"running"`
+	From    uint64 `json:"from"`
+	To      uint64 `json:"to"`
+	Next    uint64 `json:"next"`    // First block not yet covered by the checkpoint
+	Indexed uint64 `json:"indexed"` // Number of blocks (re)indexed
+	Skipped uint64 `json:"skipped"` // Number of blocks found already indexed
+	Error   string `json:"error,omitempty"`
+}
+
+// backfillTask is a single backfill run replaying a range of stored blocks
+// through the indexer.
+type backfillTask struct {
+	plugin *IndexerPlugin
+	config BackfillConfig
+	first  uint64 // First block of the checkpointed range, may precede status.From
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	lock     sync.Mutex
+	status   BackfillStatus
+	finished map[uint64]uint64 // Finished batches past the checkpoint, first -> last
+	logged   time.Time
+}
+
+// Backfill indexes the canonical blocks in the inclusive range [from, to] from
+// the local chain database, blocking until done. Blocks which are already
+// indexed are skipped, stale ones from side chains are replaced. Progress is
+// checkpointed in the indexer database, so an interrupted backfill of the same
+// range resumes where it left off.
+func (p *IndexerPlugin) Backfill(ctx context.Context, from, to uint64, config BackfillConfig) (BackfillStatus, error) {
+	task, err := p.newBackfill(from, to, config)
+	if err != nil {
+		return BackfillStatus{}, err
+	}
+	ctx, task.cancel = context.WithCancel(ctx)
+	err = task.run(ctx)
+	return task.progress(), err
+}
+
+// StartBackfill is the asynchronous version of Backfill, running it in the
+// background until done or the plugin is closed.
+func (p *IndexerPlugin) StartBackfill(from, to uint64, config BackfillConfig) error {
+	task, err := p.newBackfill(from, to, config)
+	if err != nil {
+		return err
+	}
+	var ctx context.Context
+	ctx, task.cancel = context.WithCancel(context.Background())
+	go func() {
+		if err := task.run(ctx); err != nil {
+			log.Error("Indexer backfill failed", "from", from, "to", to, "err", err)
+		}
+	}()
+	return nil
+}
+
+// BackfillStatus returns the progress of the running or last backfill.
+func (p *IndexerPlugin) BackfillStatus() BackfillStatus {
+	p.backfillLock.Lock()
+	defer p.backfillLock.Unlock()
+
+	if p.backfill == nil {
+		return BackfillStatus{}
+	}
+	return p.backfill.progress()
+}
+
+// stopBackfill aborts the running backfill, if any, and waits for it to exit.
+func (p *IndexerPlugin) stopBackfill() {
+	p.backfillLock.Lock()
+	task := p.backfill
+	p.backfillLock.Unlock()
+
+	if task != nil {
+		task.cancel()
+		<-task.done
+	}
+}
+
+// newBackfill validates the requested range and reserves the backfill slot.
+func (p *IndexerPlugin) newBackfill(from, to uint64, config BackfillConfig) (*backfillTask, error) {
+	if p.db == nil || p.chain == nil {
+		return nil, errIndexerDisabled
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid range: from %d > to %d", from, to)
+	}
+	if head := p.chain.CurrentBlock().Number.Uint64(); to > head {
+		return nil, fmt.Errorf("range end %d beyond head block %d", to, head)
+	}
+	if config.Workers <= 0 {
+		config.Workers = runtime.NumCPU()
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = p.batchSize
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = defaultBackfillBatch
+	}
+	p.backfillLock.Lock()
+	defer p.backfillLock.Unlock()
+
+	if p.backfill != nil && p.backfill.progress().Running {
+		return nil, errBackfillRunning
+	}
+	task := &backfillTask{
+		plugin:   p,
+		config:   config,
+		first:    from,
+		done:     make(chan struct{}),
+		finished: make(map[uint64]uint64),
+		logged:   time.Now(),
+		status: BackfillStatus{
+			Running: true,
+			From:    from,
+			To:      to,
+			Next:    from,
+		},
+	}
+	p.backfill = task
+	return task, nil
+}
+
+// progress returns a copy of the current backfill status.
+func (t *backfillTask) progress() BackfillStatus {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.status
+}
+
+// run indexes the requested range in parallel batches.
+func (t *backfillTask) run(ctx context.Context) (err error) {
+	defer close(t.done)
+	defer func() {
+		t.lock.Lock()
+		t.status.Running = false
+		if err != nil {
+			t.status.Error = err.Error()
+			if !errors.Is(err, context.Canceled) {
+				indexerFailureMeter.Mark(1)
+			}
+		}
+		t.lock.Unlock()
+	}()
+	start := time.Now()
+
+	// Resume from the checkpoint if it covers the start of the range
+	next, err := t.resume()
+	if err != nil {
+		return err
+	}
+	if next > t.status.To {
+		log.Info("Indexer backfill range already complete", "from", t.status.From, "to", t.status.To)
+		return nil
+	}
+	// Feed the batches to the workers, stopping at the first failure
+	var (
+		batches = make(chan [2]uint64)
+		errc    = make(chan error, t.config.Workers)
+		wg      sync.WaitGroup
+	)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for i := 0; i < t.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				if err := t.indexBatch(ctx, batch[0], batch[1]); err != nil {
+					errc <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+feed:
+	for first := next; first <= t.status.To; first += t.config.BatchSize {
+		last := min(first+t.config.BatchSize-1, t.status.To)
+		select {
+		case batches <- [2]uint64{first, last}:
+		case <-ctx.Done():
+			break feed
+		}
+		if last == t.status.To {
+			break // avoid overflowing at the top of the number space
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	select {
+	case err := <-errc:
+		return err
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	status := t.progress()
+	log.Info("Indexer backfill completed", "from", status.From, "to", status.To,
+		"indexed", status.Indexed, "skipped", status.Skipped, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// resume loads the backfill checkpoint and returns the first block which still
+// needs to be processed.
+func (t *backfillTask) resume() (uint64, error) {
+	checkpoint, err := t.plugin.db.GetCheckpoint(backfillCheckpoint)
+	if err != nil {
+		return 0, err
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	from := t.status.From
+	if checkpoint == nil || checkpoint.FirstBlock > from || checkpoint.LastBlock < from {
+		return from, nil
+	}
+	log.Info("Resuming indexer backfill", "from", from, "to", t.status.To, "checkpoint", checkpoint.LastBlock)
+	t.first = checkpoint.FirstBlock
+	t.status.Next = checkpoint.LastBlock + 1
+	return t.status.Next, nil
+}
+
+// indexBatch indexes the blocks [first, last] in a single database transaction,
+// skipping the ones already present with the canonical hash. A batch aborted by
+// the database to break a deadlock with a concurrent writer is retried.
+func (t *backfillTask) indexBatch(ctx context.Context, first, last uint64) error {
+	for {
+		err := t.tryIndexBatch(ctx, first, last)
+		if !isDeadlock(err) {
+			return err
+		}
+		log.Debug("Indexer backfill batch deadlocked, retrying", "from", first, "to", last)
+	}
+}
+
+// tryIndexBatch makes a single attempt at indexing the blocks [first, last].
+// The indexed blocks are only looked up once the range is locked against the
+// live indexer, so both never insert the same block.
+func (t *backfillTask) tryIndexBatch(ctx context.Context, first, last uint64) error {
+	var (
+		p       = t.plugin
+		indexed uint64
+		skipped uint64
+		writes  []sinkWrite
+	)
+	release := p.writing.acquire(first, last)
+	defer release()
+
+	hashes, err := p.db.GetBlockHashes(first, last)
+	if err != nil {
+		return err
+	}
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for blocks %d-%d: %v", first, last, err)
+	}
+	defer tx.Rollback()
+
+	for number := first; number <= last; number++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hash := p.chain.GetCanonicalHash(number)
+		if hash == (common.Hash{}) {
+			return fmt.Errorf("canonical block %d not found", number)
+		}
+		have, ok := hashes[number]
+		if ok && have == hash {
+			skipped++
+			continue
+		}
+		header := p.chain.GetHeader(hash, number)
+		if header == nil {
+			return fmt.Errorf("header %d [%x] not found", number, hash)
+		}
+		if ok {
+			// A block of a side chain was left behind, replace it
+			log.Debug("Replacing stale indexed block", "number", number, "have", have, "want", hash)
+			reorg, err := p.deleteStaleBlock(tx, header, have)
+			if err != nil {
+				return err
+			}
+			writes = append(writes, sinkWrite{reorg: reorg})
+		}
+		records, err := p.indexBlock(tx, header)
+		if err != nil {
+			return err
+		}
+		writes = append(writes, sinkWrite{block: records})
+		indexed++
+	}
+	if err := commitIndexerTx(tx); err != nil {
+		return fmt.Errorf("failed to commit blocks %d-%d: %v", first, last, err)
+	}
+	if err := p.export(writes...); err != nil {
+		return err
+	}
+	return t.finish(first, last, indexed, skipped)
+}
+
+// finish marks a batch as done, advancing the checkpoint past all batches
+// which are complete without gaps.
+func (t *backfillTask) finish(first, last uint64, indexed, skipped uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.status.Indexed += indexed
+	t.status.Skipped += skipped
+	t.finished[first] = last
+	t.plugin.health.unpark(first, last)
+
+	advanced := false
+	for {
+		last, ok := t.finished[t.status.Next]
+		if !ok {
+			break
+		}
+		delete(t.finished, t.status.Next)
+		t.status.Next = last + 1
+		advanced = true
+	}
+	if advanced {
+		err := t.plugin.db.SetCheckpoint(&Checkpoint{
+			Name:       backfillCheckpoint,
+			FirstBlock: t.first,
+			LastBlock:  t.status.Next - 1,
+			UpdatedAt:  time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if time.Since(t.logged) > 8*time.Second {
+		log.Info("Backfilling indexer", "next", t.status.Next, "to", t.status.To,
+			"indexed", t.status.Indexed, "skipped", t.status.Skipped)
+		t.logged = time.Now()
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that backfill requests are validated against the local chain and that
+// only one backfill may run at a time.
+func TestIndexerBackfillRange(t *testing.T) {
+	gspec := &Genesis{Config: params.TestChainConfig}
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 4, nil)
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, err := new(IndexerPlugin).newBackfill(0, 1, BackfillConfig{}); !errors.Is(err, errIndexerDisabled) {
+		t.Fatalf("backfill without database: have %v, want %v", err, errIndexerDisabled)
+	}
+	plugin := &IndexerPlugin{db: newTestIndexerDB(t), chain: chain}
+	if _, err := plugin.newBackfill(3, 2, BackfillConfig{}); err == nil {
+		t.Fatal("inverted range accepted")
+	}
+	if _, err := plugin.newBackfill(0, 5, BackfillConfig{}); err == nil {
+		t.Fatal("range beyond head accepted")
+	}
+	task, err := plugin.newBackfill(1, 4, BackfillConfig{})
+	if err != nil {
+		t.Fatalf("failed to create backfill: %v", err)
+	}
+	if task.config.Workers <= 0 || task.config.BatchSize != defaultBackfillBatch {
+		t.Errorf("defaults not applied: %+v", task.config)
+	}
+	if status := plugin.BackfillStatus(); !status.Running || status.From != 1 || status.To != 4 || status.Next != 1 {
+		t.Errorf("wrong status: %+v", status)
+	}
+	if _, err := plugin.newBackfill(1, 4, BackfillConfig{}); !errors.Is(err, errBackfillRunning) {
+		t.Fatalf("concurrent backfill: have %v, want %v", err, errBackfillRunning)
+	}
+}
+
+// Tests that a backfill batch waits for the live indexer writing blocks of its
+// range and skips the blocks committed meanwhile instead of inserting them again.
+func TestIndexerBackfillLiveOverlap(t *testing.T) {
+	gspec := &Genesis{Config: params.TestChainConfig}
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 4, nil)
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	db := newTestIndexerDB(t)
+	plugin := NewIndexerPlugin(db)
+	plugin.chain = chain
+
+	// Start the backfill while the live indexer writes a block of its range
+	release := plugin.writing.acquire(2, 2)
+
+	type result struct {
+		status BackfillStatus
+		err    error
+	}
+	done := make(chan result)
+	go func() {
+		status, err := plugin.Backfill(context.Background(), 1, 4, BackfillConfig{Workers: 2, BatchSize: 4})
+		done <- result{status, err}
+	}()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	if _, err := plugin.indexBlock(tx, blocks[1].Header()); err != nil {
+		t.Fatalf("failed to index block: %v", err)
+	}
+	if err := commitIndexerTx(tx); err != nil {
+		t.Fatalf("failed to commit block: %v", err)
+	}
+	release()
+
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("backfill failed: %v", res.err)
+	}
+	if res.status.Indexed != 3 || res.status.Skipped != 1 {
+		t.Errorf("wrong status: have indexed %d skipped %d, want 3 and 1", res.status.Indexed, res.status.Skipped)
+	}
+	for _, block := range blocks {
+		waitIndexed(t, db, block)
+	}
+}
+
+// Tests that overlapping block ranges are written one at a time.
+func TestIndexerRangesOverlap(t *testing.T) {
+	var ranges indexerRanges
+
+	release := ranges.acquire(10, 20)
+	ranges.acquire(0, 9)()
+	ranges.acquire(21, 30)()
+
+	acquired := make(chan struct{})
+	go func() {
+		ranges.acquire(20, 21)()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("overlapping range acquired while held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	<-acquired
+}
@@ -0,0 +1,354 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Token standards recognized by the event decoder.
+const (
+	TokenERC20   = "erc20"
+	TokenERC721  = "erc721"
+	TokenERC1155 = "erc1155"
+)
+
+// standardABIs are the events of the token standards, decoded for any contract.
+// ERC-20 and ERC-721 transfers and approvals share their signatures and only
+// differ in the number of indexed arguments. ApprovalForAll is identical in
+// ERC-721 and ERC-1155 and always reported as the former.
+var standardABIs = []struct {
+	name string
+	abi  string
+}{
+	{TokenERC20, `[
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]},
+		{"type":"event","name":"Approval","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"spender","type":"address","indexed":true},{"name":"value","type":"uint256"}]}
+	]`},
+	{TokenERC721, `[
+		{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]},
+		{"type":"event","name":"Approval","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"approved","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]},
+		{"type":"event","name":"ApprovalForAll","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"operator","type":"address","indexed":true},{"name":"approved","type":"bool"}]}
+	]`},
+	{TokenERC1155, `[
+		{"type":"event","name":"TransferSingle","inputs":[{"name":"operator","type":"address","indexed":true},{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"id","type":"uint256"},{"name":"value","type":"uint256"}]},
+		{"type":"event","name":"TransferBatch","inputs":[{"name":"operator","type":"address","indexed":true},{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"ids","type":"uint256[]"},{"name":"values","type":"uint256[]"}]},
+		{"type":"event","name":"URI","inputs":[{"name":"value","type":"string"},{"name":"id","type":"uint256","indexed":true}]}
+	]`},
+}
+
+// EventDecoder decodes logs into events of known contract ABIs. The events of
+// the ERC-20, ERC-721 and ERC-1155 token standards are always known, further
+// ABIs are loaded from a directory.
+type EventDecoder struct {
+	events map[common.Hash][]*decoderEvent // Candidate events by signature hash
+}
+
+// decoderEvent is a non-anonymous event of a contract ABI.
+type decoderEvent struct {
+	contract  string
+	standard  string                      // Token standard of the event, empty for custom ABIs
+	addresses map[common.Address]struct{} // Emitters to decode, nil for any
+	event     abi.Event
+	indexed   abi.Arguments // Indexed inputs, all named
+	data      abi.Arguments // Non-indexed inputs, all named
+}
+
+// abiFile is the format of an ABI file limiting the contracts it applies to.
+type abiFile struct {
+	Addresses []common.Address `json:"addresses"`
+	ABI       json.RawMessage  `json:"abi"`
+}
+
+// NewEventDecoder creates a decoder of the token standard events and of the
+// ABIs in the given directory, if any. Every .json file in the directory holds
+// either a plain contract ABI, which is applied to logs of any address, or an
+// object with the "abi" and the "addresses" it is limited to. The file name is
+// used as the contract name. Events of the loaded ABIs take precedence over the
+// standard ones.
+func NewEventDecoder(dir string) (*EventDecoder, error) {
+	d := &EventDecoder{events: make(map[common.Hash][]*decoderEvent)}
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			if err := d.loadFile(file); err != nil {
+				return nil, fmt.Errorf("failed to load ABI %s: %v", file, err)
+			}
+		}
+	}
+	for _, standard := range standardABIs {
+		parsed, err := abi.JSON(strings.NewReader(standard.abi))
+		if err != nil {
+			panic(fmt.Sprintf("invalid %s ABI: %v", standard.name, err))
+		}
+		d.add(strings.ToUpper(standard.name), standard.name, parsed, nil)
+	}
+	return d, nil
+}
+
+// loadFile adds the events of a single ABI file to the decoder.
+func (d *EventDecoder) loadFile(path string) error {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var (
+		name      = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		addresses map[common.Address]struct{}
+	)
+	if trimmed := bytes.TrimSpace(blob); len(trimmed) > 0 && trimmed[0] == '{' {
+		var file abiFile
+		if err := json.Unmarshal(blob, &file); err != nil {
+			return err
+		}
+		if len(file.Addresses) > 0 {
+			addresses = make(map[common.Address]struct{})
+			for _, addr := range file.Addresses {
+				addresses[addr] = struct{}{}
+			}
+		}
+		blob = file.ABI
+	}
+	parsed, err := abi.JSON(bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	d.add(name, "", parsed, addresses)
+	return nil
+}
+
+// add registers the events of a contract ABI. Anonymous events are skipped as
+// they can't be recognized by their topics.
+func (d *EventDecoder) add(contract string, standard string, parsed abi.ABI, addresses map[common.Address]struct{}) {
+	names := make([]string, 0, len(parsed.Events))
+	for name := range parsed.Events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		event := parsed.Events[name]
+		if event.Anonymous {
+			continue
+		}
+		ev := &decoderEvent{
+			contract:  contract,
+			standard:  standard,
+			addresses: addresses,
+			event:     event,
+		}
+		// Unnamed arguments are named after their position
+		for i, input := range event.Inputs {
+			if input.Name == "" {
+				input.Name = fmt.Sprintf("arg%d", i)
+			}
+			if input.Indexed {
+				ev.indexed = append(ev.indexed, input)
+			} else {
+				ev.data = append(ev.data, input)
+			}
+		}
+		d.events[event.ID] = append(d.events[event.ID], ev)
+	}
+}
+
+// decodedLog is a log decoded into the event of a known ABI.
+type decodedLog struct {
+	event *decoderEvent
+	args  map[string]any
+}
+
+// decode decodes a log into the first known event matching its signature,
+// emitter and layout. Nil is returned if the log matches none.
+func (d *EventDecoder) decode(log *types.Log) *decodedLog {
+	if len(log.Topics) == 0 {
+		return nil
+	}
+	for _, ev := range d.events[log.Topics[0]] {
+		if ev.addresses != nil {
+			if _, ok := ev.addresses[log.Address]; !ok {
+				continue
+			}
+		}
+		if len(ev.indexed) != len(log.Topics)-1 {
+			continue
+		}
+		args := make(map[string]any)
+		if err := ev.data.UnpackIntoMap(args, log.Data); err != nil {
+			continue
+		}
+		if err := parseTopics(args, ev.indexed, log.Topics[1:]); err != nil {
+			continue
+		}
+		return &decodedLog{event: ev, args: args}
+	}
+	return nil
+}
+
+// parseTopics decodes the indexed arguments of an event. Only the hash of the
+// dynamic and composite types is known, which is reported instead.
+func parseTopics(out map[string]any, fields abi.Arguments, topics []common.Hash) error {
+	for i, field := range fields {
+		switch field.Type.T {
+		case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+			out[field.Name] = topics[i]
+		default:
+			if err := abi.ParseTopicsIntoMap(out, abi.Arguments{field}, topics[i:i+1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newDecodedEvent converts a decoded log into a decoded_events table row.
+func newDecodedEvent(log *types.Log, decoded *decodedLog) (*DecodedEvent, error) {
+	args := make(map[string]any, len(decoded.args))
+	for name, value := range decoded.args {
+		args[name] = jsonArg(reflect.ValueOf(value))
+	}
+	blob, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments of %s: %v", decoded.event.event.Sig, err)
+	}
+	return &DecodedEvent{
+		BlockNumber:     log.BlockNumber,
+		TransactionHash: log.TxHash,
+		LogIndex:        uint64(log.Index),
+		Address:         log.Address,
+		Contract:        decoded.event.contract,
+		Event:           decoded.event.event.Name,
+		Signature:       decoded.event.event.Sig,
+		Args:            string(blob),
+	}, nil
+}
+
+// jsonArg converts a decoded ABI value into its JSON representation. Integers
+// are formatted as decimal strings, which keeps the full precision of 256 bit
+// values, and binary data as hex.
+func jsonArg(v reflect.Value) any {
+	switch value := v.Interface().(type) {
+	case *big.Int:
+		return value.String()
+	case common.Address:
+		return value
+	case common.Hash:
+		return value
+	case []byte:
+		return hexutil.Bytes(value)
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprint(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprint(v.Uint())
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			blob := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(blob), v)
+			return hexutil.Bytes(blob)
+		}
+		fallthrough
+	case reflect.Slice:
+		list := make([]any, v.Len())
+		for i := range list {
+			list[i] = jsonArg(v.Index(i))
+		}
+		return list
+	case reflect.Struct:
+		fields := make(map[string]any, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			name := v.Type().Field(i).Tag.Get("json")
+			if name == "" {
+				name = v.Type().Field(i).Name
+			}
+			fields[name] = jsonArg(v.Field(i))
+		}
+		return fields
+	}
+	return v.Interface()
+}
+
+// newTokenTransfers converts a decoded token standard transfer into token
+// transfer rows, one for each token moved in a batch. Nil is returned for any
+// other event.
+func newTokenTransfers(log *types.Log, decoded *decodedLog) []*TokenTransfer {
+	var (
+		ev   = decoded.event
+		args = decoded.args
+	)
+	if ev.standard == "" {
+		return nil
+	}
+	transfer := func(batchIndex uint64, tokenID, value *big.Int) *TokenTransfer {
+		t := &TokenTransfer{
+			BlockNumber:     log.BlockNumber,
+			TransactionHash: log.TxHash,
+			LogIndex:        uint64(log.Index),
+			BatchIndex:      batchIndex,
+			Token:           log.Address,
+			Standard:        ev.standard,
+			From:            args["from"].(common.Address),
+			To:              args["to"].(common.Address),
+			TokenID:         NewBigInt(tokenID),
+			Value:           NewBigInt(value),
+		}
+		if operator, ok := args["operator"].(common.Address); ok {
+			t.Operator = &operator
+		}
+		return t
+	}
+	switch {
+	case ev.standard == TokenERC20 && ev.event.Name == "Transfer":
+		return []*TokenTransfer{transfer(0, nil, args["value"].(*big.Int))}
+
+	case ev.standard == TokenERC721 && ev.event.Name == "Transfer":
+		return []*TokenTransfer{transfer(0, args["tokenId"].(*big.Int), big.NewInt(1))}
+
+	case ev.standard == TokenERC1155 && ev.event.Name == "TransferSingle":
+		return []*TokenTransfer{transfer(0, args["id"].(*big.Int), args["value"].(*big.Int))}
+
+	case ev.standard == TokenERC1155 && ev.event.Name == "TransferBatch":
+		ids, values := args["ids"].([]*big.Int), args["values"].([]*big.Int)
+		if len(ids) != len(values) {
+			return nil
+		}
+		transfers := make([]*TokenTransfer, len(ids))
+		for i := range ids {
+			transfers[i] = transfer(uint64(i), ids[i], values[i])
+		}
+		return transfers
+	}
+	return nil
+}
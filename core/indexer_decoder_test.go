@@ -0,0 +1,243 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that logs are decoded into the events of the loaded ABIs, restricted
+// to their addresses, and into the token standard events.
+func TestEventDecoder(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		vault = common.HexToAddress("0xa0a0")
+		other = common.HexToAddress("0xb0b0")
+		from  = common.HexToAddress("0x01")
+		to    = common.HexToAddress("0x02")
+	)
+	files := map[string]string{
+		"Vault.json":    `{"addresses": ["` + vault.Hex() + `"], "abi": [{"type":"event","name":"Deposit","inputs":[{"name":"owner","type":"address","indexed":true},{"name":"","type":"uint256"},{"name":"tag","type":"bytes4"}]}]}`,
+		"Pausable.json": `[{"type":"event","name":"Paused","inputs":[{"name":"account","type":"address"}]}]`,
+		"README.md":     `not an ABI`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	decoder, err := NewEventDecoder(dir)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	word := func(x int64) []byte { return common.LeftPadBytes(big.NewInt(x).Bytes(), 32) }
+
+	erc1155, _ := abi.JSON(strings.NewReader(standardABIs[2].abi))
+	batch, err := erc1155.Events["TransferBatch"].Inputs.NonIndexed().Pack([]*big.Int{big.NewInt(1), big.NewInt(2)}, []*big.Int{big.NewInt(10), big.NewInt(20)})
+	if err != nil {
+		t.Fatalf("failed to pack batch: %v", err)
+	}
+	var (
+		deposit  = crypto.Keccak256Hash([]byte("Deposit(address,uint256,bytes4)"))
+		paused   = crypto.Keccak256Hash([]byte("Paused(address)"))
+		transfer = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+		tbatch   = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+	)
+	tests := []struct {
+		log       *types.Log
+		contract  string
+		event     string
+		args      string
+		transfers []TokenTransfer
+	}{
+		// Custom ABI limited to an address, with an unnamed argument
+		{
+			log:      &types.Log{Address: vault, Topics: []common.Hash{deposit, common.BytesToHash(from.Bytes())}, Data: append(word(5), common.RightPadBytes([]byte{0xca, 0xfe}, 32)...)},
+			contract: "Vault",
+			event:    "Deposit",
+			args:     `{"arg1":"5","owner":"` + strings.ToLower(from.Hex()) + `","tag":"0xcafe0000"}`,
+		},
+		{
+			log: &types.Log{Address: other, Topics: []common.Hash{deposit, common.BytesToHash(from.Bytes())}, Data: append(word(5), word(0)...)},
+		},
+		// Custom ABI of any address
+		{
+			log:      &types.Log{Address: other, Topics: []common.Hash{paused}, Data: common.LeftPadBytes(from.Bytes(), 32)},
+			contract: "Pausable",
+			event:    "Paused",
+			args:     `{"account":"` + strings.ToLower(from.Hex()) + `"}`,
+		},
+		// ERC-20 and ERC-721 transfers told apart by their indexed arguments
+		{
+			log:       &types.Log{Address: other, Topics: []common.Hash{transfer, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())}, Data: word(1000)},
+			contract:  "ERC20",
+			event:     "Transfer",
+			args:      `{"from":"` + strings.ToLower(from.Hex()) + `","to":"` + strings.ToLower(to.Hex()) + `","value":"1000"}`,
+			transfers: []TokenTransfer{{Standard: TokenERC20, Value: NewBigInt(big.NewInt(1000))}},
+		},
+		{
+			log:       &types.Log{Address: other, Topics: []common.Hash{transfer, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes()), common.BigToHash(big.NewInt(7))}},
+			contract:  "ERC721",
+			event:     "Transfer",
+			args:      `{"from":"` + strings.ToLower(from.Hex()) + `","to":"` + strings.ToLower(to.Hex()) + `","tokenId":"7"}`,
+			transfers: []TokenTransfer{{Standard: TokenERC721, TokenID: NewBigInt(big.NewInt(7)), Value: NewBigInt(big.NewInt(1))}},
+		},
+		{
+			log: &types.Log{Address: other, Topics: []common.Hash{transfer, common.BytesToHash(from.Bytes())}, Data: word(1000)},
+		},
+		// ERC-1155 batches are split into a transfer for every token
+		{
+			log:      &types.Log{Address: other, Topics: []common.Hash{tbatch, common.BytesToHash(to.Bytes()), common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())}, Data: batch},
+			contract: "ERC1155",
+			event:    "TransferBatch",
+			args:     `{"from":"` + strings.ToLower(from.Hex()) + `","ids":["1","2"],"operator":"` + strings.ToLower(to.Hex()) + `","to":"` + strings.ToLower(to.Hex()) + `","values":["10","20"]}`,
+			transfers: []TokenTransfer{
+				{Standard: TokenERC1155, Operator: &to, TokenID: NewBigInt(big.NewInt(1)), Value: NewBigInt(big.NewInt(10))},
+				{Standard: TokenERC1155, Operator: &to, BatchIndex: 1, TokenID: NewBigInt(big.NewInt(2)), Value: NewBigInt(big.NewInt(20))},
+			},
+		},
+	}
+	for i, tt := range tests {
+		decoded := decoder.decode(tt.log)
+		if tt.contract == "" {
+			if decoded != nil {
+				t.Errorf("test %d: unexpectedly decoded as %s.%s", i, decoded.event.contract, decoded.event.event.Name)
+			}
+			continue
+		}
+		if decoded == nil {
+			t.Errorf("test %d: not decoded", i)
+			continue
+		}
+		event, err := newDecodedEvent(tt.log, decoded)
+		if err != nil {
+			t.Errorf("test %d: failed to convert event: %v", i, err)
+			continue
+		}
+		if event.Contract != tt.contract || event.Event != tt.event || event.Args != tt.args {
+			t.Errorf("test %d: event mismatch: have %s.%s %s, want %s.%s %s", i, event.Contract, event.Event, event.Args, tt.contract, tt.event, tt.args)
+		}
+		transfers := newTokenTransfers(tt.log, decoded)
+		if len(transfers) != len(tt.transfers) {
+			t.Errorf("test %d: transfer count mismatch: have %d, want %d", i, len(transfers), len(tt.transfers))
+			continue
+		}
+		for j, want := range tt.transfers {
+			want.Token, want.From, want.To = other, from, to
+			if !reflect.DeepEqual(*transfers[j], want) {
+				t.Errorf("test %d: transfer %d mismatch: have %+v, want %+v", i, j, *transfers[j], want)
+			}
+		}
+	}
+	// Malformed ABIs must be reported
+	if err := os.WriteFile(filepath.Join(dir, "Broken.json"), []byte(`{"abi": 1}`), 0644); err != nil {
+		t.Fatalf("failed to write broken ABI: %v", err)
+	}
+	if _, err := NewEventDecoder(dir); err == nil {
+		t.Error("malformed ABI accepted")
+	}
+}
+
+// Tests that the decoded events and token transfers of indexed blocks end up in
+// the database.
+func TestIndexerDecodedEvents(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		token  = common.HexToAddress("0xc0c0")
+		to     = common.HexToAddress("0x02")
+
+		transfer = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+		gspec    = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				token:  {Code: program.New().Mstore(common.LeftPadBytes([]byte{0x03, 0xe8}, 32), 0).Push(to).Op(vm.CALLER).Push(transfer).Push(32).Push(0).Op(vm.LOG3).Bytes()},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 1, func(i int, gen *BlockGen) {
+		gen.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    0,
+			To:       &token,
+			Gas:      100000,
+			GasPrice: gen.header.BaseFee,
+		}))
+	})
+	decoder, err := NewEventDecoder("")
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	db := newTestIndexerDB(t)
+	plugin := NewIndexerPlugin(db)
+	plugin.SetEventDecoder(decoder)
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, plugin)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed(t, db, blocks[0])
+
+	var events []DecodedEvent
+	if err := db.db.Select(&events, `SELECT * FROM decoded_events`); err != nil {
+		t.Fatalf("failed to read decoded events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("decoded event count mismatch: have %d, want 1", len(events))
+	}
+	var args map[string]string
+	if err := json.Unmarshal([]byte(events[0].Args), &args); err != nil {
+		t.Fatalf("failed to parse arguments: %v", err)
+	}
+	if e := events[0]; e.Address != token || e.Contract != "ERC20" || e.Signature != "Transfer(address,address,uint256)" || args["value"] != "1000" {
+		t.Errorf("decoded event mismatch: %+v", e)
+	}
+	var transfers []TokenTransfer
+	if err := db.db.Select(&transfers, `SELECT * FROM token_transfers`); err != nil {
+		t.Fatalf("failed to read token transfers: %v", err)
+	}
+	if len(transfers) != 1 {
+		t.Fatalf("token transfer count mismatch: have %d, want 1", len(transfers))
+	}
+	if tr := transfers[0]; tr.Token != token || tr.From != sender || tr.To != to || tr.TokenID != nil || tr.Value.Int64() != 1000 ||
+		tr.TransactionHash != blocks[0].Transactions()[0].Hash() {
+		t.Errorf("token transfer mismatch: %+v", tr)
+	}
+}
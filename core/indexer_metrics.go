@@ -0,0 +1,166 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// indexerHealthcheck is the name of the indexer's healthcheck in the
+	// metrics registry.
+	indexerHealthcheck = "indexer/health"
+
+	// defaultIndexerMaxLag is the number of blocks the indexer may trail the
+	// chain head before it's reported unhealthy.
+	defaultIndexerMaxLag = 64
+
+	// indexerFailureGrace is how long the indexer may keep failing before it's
+	// reported unhealthy, riding out short database hiccups.
+	indexerFailureGrace = time.Minute
+)
+
+var (
+	indexerHeadGauge      = metrics.NewRegisteredGauge("indexer/head", nil)
+	indexerFinalizedGauge = metrics.NewRegisteredGauge("indexer/finalized", nil)
+	indexerFailingGauge   = metrics.NewRegisteredGauge("indexer/failing", nil) // consecutive failed attempts
+	indexerFailureMeter   = metrics.NewRegisteredMeter("indexer/failures", nil)
+	indexerParkedGauge    = metrics.NewRegisteredGauge("indexer/parked", nil) // blocks skipped after repeated failures
+	indexerBlockTimer     = metrics.NewRegisteredTimer("indexer/block", nil)
+
+	indexerWriteTimer      = metrics.NewRegisteredTimer("indexer/db/write", nil)
+	indexerCommitTimer     = metrics.NewRegisteredTimer("indexer/db/commit", nil)
+	indexerWriteErrorMeter = metrics.NewRegisteredMeter("indexer/db/errors", nil)
+)
+
+// indexerRowsMeter returns the meter of the rows written into a table.
+func indexerRowsMeter(table string) *metrics.Meter {
+	return metrics.GetOrRegisterMeter("indexer/rows/"+table, nil)
+}
+
+// commitIndexerTx commits a transaction of the indexer, tracking its latency.
+func commitIndexerTx(tx *sqlx.Tx) error {
+	defer indexerCommitTimer.UpdateSince(time.Now())
+	return tx.Commit()
+}
+
+// indexerHealth tracks the state the health of the indexer is derived from.
+type indexerHealth struct {
+	head    uint64           // Last block committed by the live indexer
+	failing time.Time        // Start of the current run of failures, zero if none
+	err     error            // Last failure
+	parked  map[uint64]error // Blocks skipped after repeated failures
+	lock    sync.Mutex
+}
+
+// indexed records a block committed by the live indexer.
+func (h *indexerHealth) indexed(number uint64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.head = number
+	indexerHeadGauge.Update(int64(number))
+}
+
+// attempt records the outcome of an attempt to apply a chain event.
+func (h *indexerHealth) attempt(err error, attempts int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if err == nil {
+		h.failing, h.err = time.Time{}, nil
+		indexerFailingGauge.Update(0)
+		return
+	}
+	if h.failing.IsZero() {
+		h.failing = time.Now()
+	}
+	h.err = err
+	indexerFailureMeter.Mark(1)
+	indexerFailingGauge.Update(int64(attempts))
+}
+
+// park records a block skipped by the live indexer after repeated failures.
+func (h *indexerHealth) park(number uint64, err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.parked == nil {
+		h.parked = make(map[uint64]error)
+	}
+	h.parked[number] = err
+	indexerParkedGauge.Update(int64(len(h.parked)))
+}
+
+// unpark clears the skipped blocks in the range [first, last], once indexed.
+func (h *indexerHealth) unpark(first, last uint64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for number := range h.parked {
+		if number >= first && number <= last {
+			delete(h.parked, number)
+		}
+	}
+	indexerParkedGauge.Update(int64(len(h.parked)))
+}
+
+// Health reports whether the indexer keeps up with the chain: it's unhealthy
+// if it has been failing to write to the database for a while, skipped blocks
+// which weren't backfilled yet, or trails the chain head by more than the
+// configured number of blocks. A disabled indexer is always healthy.
+func (p *IndexerPlugin) Health() error {
+	if p.db == nil || p.chain == nil {
+		return nil
+	}
+	p.health.lock.Lock()
+	defer p.health.lock.Unlock()
+
+	if !p.health.failing.IsZero() && time.Since(p.health.failing) > indexerFailureGrace {
+		return fmt.Errorf("indexer failing for %v: %v", common.PrettyDuration(time.Since(p.health.failing)), p.health.err)
+	}
+	if len(p.health.parked) > 0 {
+		first := uint64(math.MaxUint64)
+		for number := range p.health.parked {
+			first = min(first, number)
+		}
+		return fmt.Errorf("indexer skipped %d blocks after repeated failures, first #%d: %v", len(p.health.parked), first, p.health.parked[first])
+	}
+	head := p.chain.CurrentBlock().Number.Uint64()
+	if indexed := max(p.health.head, p.startBlock); head > indexed && head-indexed > p.maxLag {
+		return fmt.Errorf("indexer %d blocks behind the chain head", head-indexed)
+	}
+	return nil
+}
+
+// healthcheck returns the metrics healthcheck reporting the indexer health.
+func (p *IndexerPlugin) healthcheck() *metrics.Healthcheck {
+	return metrics.NewHealthcheck(func(h *metrics.Healthcheck) {
+		if err := p.Health(); err != nil {
+			h.Unhealthy(err)
+		} else {
+			h.Healthy()
+		}
+	})
+}
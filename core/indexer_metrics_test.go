@@ -0,0 +1,95 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the indexer health reflects its lag behind the chain head and
+// persistent failures, and is exposed as a registered healthcheck.
+func TestIndexerHealth(t *testing.T) {
+	var (
+		gspec  = &Genesis{Config: params.TestChainConfig}
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 5, nil)
+
+	db := newTestIndexerDB(t)
+	plugin := NewIndexerPlugin(db)
+	if err := plugin.Configure(IndexerConfig{MaxLag: 2}); err != nil {
+		t.Fatalf("failed to configure plugin: %v", err)
+	}
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, plugin)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed(t, db, blocks[4])
+
+	if err := plugin.Health(); err != nil {
+		t.Fatalf("up to date indexer unhealthy: %v", err)
+	}
+	if head := indexerHeadGauge.Snapshot().Value(); head != 5 {
+		t.Errorf("indexed head gauge mismatch: have %d, want 5", head)
+	}
+	check, ok := metrics.DefaultRegistry.Get(indexerHealthcheck).(*metrics.Healthcheck)
+	if !ok {
+		t.Fatal("indexer healthcheck not registered")
+	}
+	// Trailing the chain head beyond the limit is unhealthy
+	plugin.health.indexed(2)
+	if err := plugin.Health(); err == nil {
+		t.Error("lagging indexer reported healthy")
+	}
+	check.Check()
+	if check.Error() == nil {
+		t.Error("lagging indexer passed the healthcheck")
+	}
+	plugin.health.indexed(3)
+	if err := plugin.Health(); err != nil {
+		t.Errorf("indexer within the lag limit unhealthy: %v", err)
+	}
+	// Failures are tolerated for a grace period only
+	plugin.health.attempt(errors.New("connection refused"), 1)
+	if err := plugin.Health(); err != nil {
+		t.Errorf("briefly failing indexer unhealthy: %v", err)
+	}
+	plugin.health.lock.Lock()
+	plugin.health.failing = time.Now().Add(-2 * indexerFailureGrace)
+	plugin.health.lock.Unlock()
+
+	if err := plugin.Health(); err == nil {
+		t.Error("persistently failing indexer reported healthy")
+	}
+	plugin.health.attempt(nil, 2)
+	if err := plugin.Health(); err != nil {
+		t.Errorf("recovered indexer unhealthy: %v", err)
+	}
+}
@@ -0,0 +1,487 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/lib/pq"
+)
+
+const (
+	// indexerQueueLimit is the maximum number of chain events buffered for the
+	// indexer. Beyond it, only the range of affected blocks is tracked and they
+	// are re-indexed from the chain once the indexer catches up.
+	indexerQueueLimit = 8192
+
+	indexerRetryMin = 100 * time.Millisecond // Delay before the first retry of a failed job
+	indexerRetryMax = 30 * time.Second       // Upper bound of the exponential retry backoff
+
+	// indexerRetryLimit is the number of attempts at a chain event before the
+	// indexer gives up on it, so a permanent failure doesn't hold back the
+	// events behind it.
+	indexerRetryLimit = 8
+)
+
+// errIndexerClosed is returned when retrying is interrupted by the plugin closing.
+var errIndexerClosed = errors.New("indexer closed")
+
+var (
+	indexerQueueDepthGauge    = metrics.NewRegisteredGauge("indexer/queue/depth", nil)
+	indexerQueueOverflowGauge = metrics.NewRegisteredGauge("indexer/queue/overflow", nil)
+	indexerLagGauge           = metrics.NewRegisteredGauge("indexer/lag", nil)
+	indexerRetryMeter         = metrics.NewRegisteredMeter("indexer/retries", nil)
+)
+
+// Kinds of chain events delivered to the indexer.
+const (
+	indexerJobHead uint8 = iota
+	indexerJobFinal
+	indexerJobReorg
+)
+
+// indexerJobBlock identifies a block referenced by an indexer job.
+type indexerJobBlock struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// indexerJob is a chain event waiting to be applied to the indexer database.
+type indexerJob struct {
+	Kind uint8
+	Old  []indexerJobBlock // Blocks removed from the canonical chain (reorg only)
+	New  []indexerJobBlock // New head, finalized block or blocks added by a reorg
+}
+
+// newIndexerJob creates a job referencing the given headers.
+func newIndexerJob(kind uint8, oldHeaders, newHeaders []*types.Header) *indexerJob {
+	refs := func(headers []*types.Header) []indexerJobBlock {
+		blocks := make([]indexerJobBlock, 0, len(headers))
+		for _, header := range headers {
+			blocks = append(blocks, indexerJobBlock{Number: header.Number.Uint64(), Hash: header.Hash()})
+		}
+		return blocks
+	}
+	return &indexerJob{Kind: kind, Old: refs(oldHeaders), New: refs(newHeaders)}
+}
+
+// indexerOverflow tracks the chain events the queue had no room for. Instead of
+// the events themselves, only the lowest affected block is recorded, from which
+// on the canonical chain is re-indexed once the queue drained.
+type indexerOverflow struct {
+	From  uint64 // First block to re-index, math.MaxUint64 if none
+	Final uint64 // Latest finalized block, zero if none
+}
+
+// indexerQueue is a bounded queue of chain events persisted in the chain
+// database, decoupling block import from the indexer database.
+type indexerQueue struct {
+	db    ethdb.Database
+	limit uint64
+
+	head     uint64           // Sequence number of the oldest queued job
+	tail     uint64           // Sequence number of the next job to be queued
+	overflow *indexerOverflow // Events deferred since the queue was full
+	version  uint64           // Counter of overflow updates, to detect races on clear
+	lock     sync.Mutex
+
+	wake chan struct{} // Notification channel for new events
+}
+
+// newIndexerQueue loads the queue persisted in the given database.
+func newIndexerQueue(db ethdb.Database, limit uint64) *indexerQueue {
+	q := &indexerQueue{
+		db:    db,
+		limit: limit,
+		wake:  make(chan struct{}, 1),
+	}
+	q.head, q.tail = rawdb.ReadIndexerQueueRange(db)
+	if blob := rawdb.ReadIndexerQueueOverflow(db); len(blob) > 0 {
+		q.overflow = new(indexerOverflow)
+		if err := rlp.DecodeBytes(blob, q.overflow); err != nil {
+			log.Error("Invalid indexer queue overflow, re-indexing from genesis", "err", err)
+			q.overflow = &indexerOverflow{}
+		}
+	}
+	if q.head != q.tail || q.overflow != nil {
+		log.Info("Loaded pending indexer jobs", "queued", q.tail-q.head, "overflow", q.overflow != nil)
+	}
+	q.updateMetrics()
+	return q
+}
+
+// push appends a job to the queue, or folds it into the overflow marker if
+// the queue is full. It never blocks on the indexer.
+func (q *indexerQueue) push(job *indexerJob) {
+	q.lock.Lock()
+	if q.overflow != nil || q.tail-q.head >= q.limit {
+		q.spill(job)
+	} else {
+		blob, err := rlp.EncodeToBytes(job)
+		if err != nil {
+			log.Crit("Failed to encode indexer job", "err", err)
+		}
+		rawdb.WriteIndexerJob(q.db, q.tail, blob)
+		q.tail++
+	}
+	q.updateMetrics()
+	q.lock.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// spill records a job which doesn't fit into the queue in the overflow marker.
+//
+// Note, this function assumes that the lock is held!
+func (q *indexerQueue) spill(job *indexerJob) {
+	if q.overflow == nil {
+		log.Warn("Indexer queue full, deferring blocks", "limit", q.limit)
+		q.overflow = &indexerOverflow{From: math.MaxUint64}
+	}
+	switch job.Kind {
+	case indexerJobFinal:
+		for _, block := range job.New {
+			q.overflow.Final = max(q.overflow.Final, block.Number)
+		}
+	default:
+		for _, block := range job.Old {
+			q.overflow.From = min(q.overflow.From, block.Number)
+		}
+		for _, block := range job.New {
+			q.overflow.From = min(q.overflow.From, block.Number)
+		}
+	}
+	q.version++
+	q.storeOverflow()
+}
+
+// storeOverflow persists the overflow marker.
+//
+// Note, this function assumes that the lock is held!
+func (q *indexerQueue) storeOverflow() {
+	if q.overflow == nil {
+		rawdb.DeleteIndexerQueueOverflow(q.db)
+		return
+	}
+	blob, err := rlp.EncodeToBytes(q.overflow)
+	if err != nil {
+		log.Crit("Failed to encode indexer queue overflow", "err", err)
+	}
+	rawdb.WriteIndexerQueueOverflow(q.db, blob)
+}
+
+// peek returns the oldest queued job without removing it.
+func (q *indexerQueue) peek() (uint64, *indexerJob, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for q.head < q.tail {
+		job := new(indexerJob)
+		blob := rawdb.ReadIndexerJob(q.db, q.head)
+		if err := rlp.DecodeBytes(blob, job); err != nil {
+			// Retrying won't fix a corrupted entry, leave it to a backfill
+			log.Error("Invalid indexer job, run a backfill to fill the gap", "seq", q.head, "err", err)
+			rawdb.DeleteIndexerJob(q.db, q.head)
+			q.head++
+			continue
+		}
+		return q.head, job, true
+	}
+	return 0, nil, false
+}
+
+// pop removes a processed job from the queue.
+func (q *indexerQueue) pop(seq uint64) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if seq != q.head {
+		return
+	}
+	rawdb.DeleteIndexerJob(q.db, seq)
+	q.head++
+	q.updateMetrics()
+}
+
+// postpone removes a job which keeps failing from the queue and folds it into
+// the overflow marker, so its blocks are re-indexed from the canonical chain
+// once the jobs behind it are processed.
+func (q *indexerQueue) postpone(seq uint64, job *indexerJob) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if seq != q.head {
+		return
+	}
+	rawdb.DeleteIndexerJob(q.db, seq)
+	q.head++
+	q.spill(job)
+	q.updateMetrics()
+}
+
+// deferred returns a copy of the overflow marker along with its version.
+func (q *indexerQueue) deferred() (indexerOverflow, uint64, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.overflow == nil {
+		return indexerOverflow{}, 0, false
+	}
+	return *q.overflow, q.version, true
+}
+
+// advance moves the overflow marker past a re-indexed block, unless an event
+// lowered it meanwhile.
+func (q *indexerQueue) advance(number uint64) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.overflow != nil && q.overflow.From == number {
+		q.overflow.From = number + 1
+		q.storeOverflow()
+	}
+}
+
+// clear removes the overflow marker, resuming regular queueing. It fails if
+// the marker was updated since the given version was retrieved.
+func (q *indexerQueue) clear(version uint64) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.overflow == nil || q.version != version {
+		return false
+	}
+	q.overflow = nil
+	q.storeOverflow()
+	q.updateMetrics()
+	return true
+}
+
+// updateMetrics reports the queue state.
+//
+// Note, this function assumes that the lock is held!
+func (q *indexerQueue) updateMetrics() {
+	indexerQueueDepthGauge.Update(int64(q.tail - q.head))
+	if q.overflow != nil {
+		indexerQueueOverflowGauge.Update(1)
+	} else {
+		indexerQueueOverflowGauge.Update(0)
+	}
+}
+
+// loop applies the queued chain events to the indexer database, retrying
+// failures a limited number of times. Events which keep failing are deferred
+// to the overflow marker, deferred blocks which keep failing are skipped and
+// reported by the healthcheck until a backfill indexes them.
+func (p *IndexerPlugin) loop() {
+	defer p.wg.Done()
+
+	for {
+		if seq, job, ok := p.queue.peek(); ok {
+			err := p.retry(func() error { return p.process(job) })
+			switch {
+			case errors.Is(err, errIndexerClosed):
+				return
+			case err != nil:
+				log.Error("Indexer giving up on chain event, deferring its blocks", "kind", job.Kind, "err", err)
+				p.queue.postpone(seq, job)
+			default:
+				p.queue.pop(seq)
+			}
+			continue
+		}
+		if overflow, version, ok := p.queue.deferred(); ok {
+			err := p.retry(func() error { return p.catchUp(overflow, version) })
+			if errors.Is(err, errIndexerClosed) {
+				return
+			}
+			// Skip a deferred block which can't be indexed, unless the
+			// failure happened after all blocks were re-indexed
+			if err != nil && overflow.From <= p.chain.CurrentBlock().Number.Uint64() {
+				log.Error("Indexer giving up on block, backfill it once fixed", "number", overflow.From, "err", err)
+				p.health.park(overflow.From, err)
+				p.queue.advance(overflow.From)
+			}
+			continue
+		}
+		indexerLagGauge.Update(0)
+
+		select {
+		case <-p.queue.wake:
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// retry runs fn until it succeeds or the retry limit is reached, backing off
+// exponentially between attempts. It returns the last failure, or
+// errIndexerClosed if the plugin was closed in the meantime.
+func (p *IndexerPlugin) retry(fn func() error) error {
+	delay := indexerRetryMin
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		p.health.attempt(err, attempt)
+		if err == nil || attempt >= p.retries {
+			return err
+		}
+		indexerRetryMeter.Mark(1)
+		log.Warn("Indexer update failed, retrying", "attempt", attempt, "delay", delay, "err", err)
+
+		select {
+		case <-time.After(delay):
+		case <-p.quit:
+			return errIndexerClosed
+		}
+		delay = min(2*delay, indexerRetryMax)
+	}
+}
+
+// process applies a single queued chain event, once the exports left over by
+// earlier events succeeded.
+func (p *IndexerPlugin) process(job *indexerJob) error {
+	if err := p.export(); err != nil {
+		return err
+	}
+	headers := func(blocks []indexerJobBlock) ([]*types.Header, error) {
+		headers := make([]*types.Header, 0, len(blocks))
+		for _, block := range blocks {
+			header := p.chain.GetHeader(block.Hash, block.Number)
+			if header == nil {
+				return nil, fmt.Errorf("header %d [%x] not found", block.Number, block.Hash)
+			}
+			headers = append(headers, header)
+		}
+		return headers, nil
+	}
+	newHeaders, err := headers(job.New)
+	if err != nil {
+		return err
+	}
+	switch job.Kind {
+	case indexerJobHead:
+		return p.processHead(newHeaders[0])
+	case indexerJobFinal:
+		return p.processFinal(newHeaders[0])
+	case indexerJobReorg:
+		oldHeaders, err := headers(job.Old)
+		if err != nil {
+			return err
+		}
+		return p.processReorg(oldHeaders, newHeaders)
+	default:
+		log.Error("Unknown indexer job, skipping", "kind", job.Kind)
+		return nil
+	}
+}
+
+// catchUp re-indexes the next block deferred by a queue overflow. Once the
+// current head is reached, blocks indexed beyond it are dropped and the marker
+// is cleared.
+func (p *IndexerPlugin) catchUp(overflow indexerOverflow, version uint64) error {
+	if err := p.export(); err != nil {
+		return err
+	}
+	head := p.chain.CurrentBlock().Number.Uint64()
+	if overflow.From <= head {
+		header := p.chain.GetHeaderByNumber(overflow.From)
+		if header == nil {
+			return fmt.Errorf("canonical header %d not found", overflow.From)
+		}
+		if err := p.processHead(header); err != nil {
+			return err
+		}
+		p.queue.advance(overflow.From)
+		return nil
+	}
+	// Caught up, drop the leftovers of a reorg to a shorter chain
+	release := p.writing.acquire(head+1, math.MaxUint64)
+	defer release()
+
+	dropped, err := p.droppedBlocks(head)
+	if err != nil {
+		return err
+	}
+	if err := p.db.DeleteBlockAndDescendants(head + 1); err != nil {
+		return err
+	}
+	if dropped != nil {
+		if err := p.export(sinkWrite{reorg: dropped}); err != nil {
+			return err
+		}
+	}
+	p.health.indexed(head)
+	if overflow.Final != 0 {
+		if err := p.db.MarkBlockFinalized(overflow.Final); err != nil {
+			return err
+		}
+		indexerFinalizedGauge.Update(int64(overflow.Final))
+		if p.sink != nil {
+			if err := p.sink.Finalize(overflow.Final, p.chain.GetCanonicalHash(overflow.Final)); err != nil {
+				return err
+			}
+		}
+	}
+	if p.queue.clear(version) {
+		log.Info("Indexer caught up with deferred blocks", "head", head)
+	}
+	return nil
+}
+
+// droppedBlocks returns the reorg recording the indexed blocks past the given
+// head as dropped, nil if there are none.
+func (p *IndexerPlugin) droppedBlocks(head uint64) (*Reorg, error) {
+	latest, err := p.db.GetLatestBlock()
+	if err != nil || latest <= head {
+		return nil, err
+	}
+	hashes, err := p.db.GetBlockHashes(head+1, latest)
+	if err != nil {
+		return nil, err
+	}
+	reorg := &Reorg{
+		DetectedAt:     time.Now(),
+		AncestorNumber: head,
+		AncestorHash:   p.chain.GetCanonicalHash(head),
+		Depth:          uint64(len(hashes)),
+		NewHashes:      pq.ByteaArray{},
+	}
+	for number := head + 1; number <= latest; number++ {
+		if hash, ok := hashes[number]; ok {
+			reorg.OldHashes = append(reorg.OldHashes, hash.Bytes())
+		}
+	}
+	if len(reorg.OldHashes) > 0 {
+		oldHead := common.BytesToHash(reorg.OldHashes[len(reorg.OldHashes)-1])
+		reorg.OldHead = &oldHead
+	}
+	return reorg, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/jmoiron/sqlx"
+)
+
+// Tests that the indexer queue persists its jobs across restarts and that
+// events beyond its limit are folded into the overflow marker.
+func TestIndexerQueue(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		headers = make([]*types.Header, 6)
+	)
+	for i := range headers {
+		headers[i] = &types.Header{Number: big.NewInt(int64(i + 1)), Extra: []byte{byte(i)}}
+	}
+	q := newIndexerQueue(db, 2)
+	q.push(newIndexerJob(indexerJobHead, nil, headers[:1]))
+	q.push(newIndexerJob(indexerJobReorg, headers[1:2], headers[2:3]))
+	if _, _, ok := q.deferred(); ok {
+		t.Fatal("overflow before reaching the limit")
+	}
+	q.push(newIndexerJob(indexerJobHead, nil, headers[4:5]))
+	q.push(newIndexerJob(indexerJobFinal, nil, headers[5:6]))
+	q.push(newIndexerJob(indexerJobReorg, headers[3:4], nil))
+
+	// Reopen the queue and check that nothing was lost
+	q = newIndexerQueue(db, 2)
+	seq, job, ok := q.peek()
+	if !ok || !reflect.DeepEqual(job, newIndexerJob(indexerJobHead, nil, headers[:1])) {
+		t.Fatalf("wrong first job: %+v", job)
+	}
+	q.pop(seq)
+	seq, job, ok = q.peek()
+	if !ok || !reflect.DeepEqual(job, newIndexerJob(indexerJobReorg, headers[1:2], headers[2:3])) {
+		t.Fatalf("wrong second job: %+v", job)
+	}
+	q.pop(seq)
+	if _, job, ok := q.peek(); ok {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+	overflow, version, ok := q.deferred()
+	if !ok || overflow.From != 4 || overflow.Final != 6 {
+		t.Fatalf("wrong overflow: %+v", overflow)
+	}
+	// Events arriving while catching up must keep the marker alive
+	q.advance(overflow.From)
+	q.push(newIndexerJob(indexerJobHead, nil, headers[2:3]))
+	if q.clear(version) {
+		t.Fatal("cleared overflow despite concurrent update")
+	}
+	if overflow, version, _ = q.deferred(); overflow.From != 3 {
+		t.Fatalf("wrong overflow start: have %d, want 3", overflow.From)
+	}
+	if !q.clear(version) {
+		t.Fatal("failed to clear overflow")
+	}
+	// Once cleared, regular queueing resumes
+	q.push(newIndexerJob(indexerJobHead, nil, headers[5:6]))
+	if _, _, ok := q.deferred(); ok {
+		t.Fatal("overflow after clearing")
+	}
+	if _, job, ok := q.peek(); !ok || job.New[0].Hash != headers[5].Hash() {
+		t.Fatalf("wrong job after overflow: %+v", job)
+	}
+	if q = newIndexerQueue(db, 2); q.tail-q.head != 1 || q.overflow != nil {
+		t.Fatalf("wrong persisted state: %d jobs, overflow %v", q.tail-q.head, q.overflow)
+	}
+}
+
+// failingIndexerDB is an indexer database failing to insert a given block.
+type failingIndexerDB struct {
+	IndexerDB
+	number  uint64
+	failing atomic.Bool
+}
+
+func (db *failingIndexerDB) InsertBlockWithTx(tx *sqlx.Tx, block *Block) error {
+	if db.failing.Load() && block.Number == db.number {
+		return errors.New("constraint violation")
+	}
+	return db.IndexerDB.InsertBlockWithTx(tx, block)
+}
+
+// Tests that a block failing permanently doesn't hold back the blocks behind
+// it: it's skipped after a few attempts and reported by the healthcheck until
+// backfilled.
+func TestIndexerRetryLimit(t *testing.T) {
+	var (
+		gspec  = &Genesis{Config: params.TestChainConfig}
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 5, nil)
+
+	db := &failingIndexerDB{IndexerDB: newTestIndexerDB(t), number: 3}
+	db.failing.Store(true)
+	plugin := NewIndexerPlugin(db)
+	plugin.retries = 2
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, plugin)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed(t, db, blocks[4])
+
+	// Wait for the failing block to be given up on
+	for start := time.Now(); plugin.Health() == nil; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("skipped block not reported by the healthcheck")
+		}
+	}
+	if hashes, _ := db.GetBlockHashes(3, 3); len(hashes) != 0 {
+		t.Fatalf("failing block indexed: %v", hashes)
+	}
+	// Backfilling the block once fixed restores the health
+	db.failing.Store(false)
+	if _, err := plugin.Backfill(context.Background(), 3, 3, BackfillConfig{}); err != nil {
+		t.Fatalf("failed to backfill: %v", err)
+	}
+	waitIndexed(t, db, blocks[2])
+	if err := plugin.Health(); err != nil {
+		t.Fatalf("backfilled indexer unhealthy: %v", err)
+	}
+}
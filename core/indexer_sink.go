@@ -0,0 +1,660 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/indexer/parquet"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/lib/pq"
+)
+
+// Supported export formats of the indexer file sink.
+const (
+	SinkFormatNDJSON  = "ndjson"
+	SinkFormatParquet = "parquet"
+)
+
+const (
+	// defaultSinkPartition is the number of blocks exported into a single file.
+	defaultSinkPartition = 10000
+
+	// sinkManifestName is the file describing the contents of a sink directory.
+	sinkManifestName = "manifest.json"
+
+	// maxSinkOpenFiles is the number of files kept open for appending, reached
+	// when blocks of several partitions are exported alternately, e.g. while a
+	// backfill runs behind the chain head.
+	maxSinkOpenFiles = 64
+)
+
+// IndexerSink receives the records of the blocks indexed by the plugin, as an
+// export of the indexed data outside of the database. Blocks and reorgs are
+// handed to the sink once their database transaction committed, so the sink
+// never sees records which were rolled back, nor the same block twice because
+// of a retried transaction. Failed exports are retried ahead of the next chain
+// event, exports still failing when the plugin is closed are lost.
+type IndexerSink interface {
+	// WriteBlock exports all records of a new canonical block.
+	WriteBlock(records *BlockRecords) error
+
+	// WriteReorg records the blocks dropped from the canonical chain, whose
+	// exported records are void from now on.
+	WriteReorg(reorg *Reorg) error
+
+	// Finalize records the latest finalized block.
+	Finalize(number uint64, hash common.Hash) error
+
+	// Close flushes and releases the sink.
+	Close() error
+}
+
+// BlockRecords holds the rows of all tables created by indexing a block. The
+// optional tables are only filled in if enabled, accounts are left out since
+// they're a snapshot of the state rather than part of the block.
+type BlockRecords struct {
+	Block          *Block
+	Transactions   []*Transaction
+	Receipts       []*Receipt
+	Logs           []*Log
+	StateChanges   []*StateChange
+	Traces         []*Trace
+	DecodedEvents  []*DecodedEvent
+	TokenTransfers []*TokenTransfer
+	Withdrawals    []*Withdrawal
+	BlobHashes     []*BlobHash
+}
+
+// tables returns the records grouped by table name, in export order.
+func (r *BlockRecords) tables() []sinkTable {
+	return []sinkTable{
+		newSinkTable("blocks", []*Block{r.Block}),
+		newSinkTable("transactions", r.Transactions),
+		newSinkTable("blob_hashes", r.BlobHashes),
+		newSinkTable("withdrawals", r.Withdrawals),
+		newSinkTable("receipts", r.Receipts),
+		newSinkTable("logs", r.Logs),
+		newSinkTable("state_changes", r.StateChanges),
+		newSinkTable(IndexerTableTraces, r.Traces),
+		newSinkTable(IndexerTableDecodedEvents, r.DecodedEvents),
+		newSinkTable(IndexerTableTokenTransfers, r.TokenTransfers),
+	}
+}
+
+type sinkTable struct {
+	name    string
+	typ     reflect.Type // record struct type
+	records []any
+}
+
+func newSinkTable[T any](name string, records []*T) sinkTable {
+	list := make([]any, len(records))
+	for i, record := range records {
+		list[i] = record
+	}
+	return sinkTable{name: name, typ: reflect.TypeOf((*T)(nil)).Elem(), records: list}
+}
+
+// SinkManifest describes the contents of a file sink directory. It is replaced
+// atomically after every change, files may hold trailing data of an interrupted
+// write past the size recorded here, which readers must ignore.
+type SinkManifest struct {
+	Format     string           `json:"format"`
+	Partition  uint64           `json:"partition"` // blocks per file
+	Files      []*SinkFile      `json:"files"`
+	Finalized  *SinkBlock       `json:"finalized,omitempty"`
+	Tombstones []*SinkTombstone `json:"tombstones"`
+}
+
+// SinkFile is a file of exported rows of a single table and block range.
+type SinkFile struct {
+	Table     string `json:"table"`
+	Path      string `json:"path"` // relative to the sink directory
+	FromBlock uint64 `json:"from_block"`
+	ToBlock   uint64 `json:"to_block"`
+	Rows      uint64 `json:"rows"`
+	Size      int64  `json:"size"`             // bytes of complete rows
+	Final     bool   `json:"final"`            // all blocks of the range are finalized
+	Staged    bool   `json:"staged,omitempty"` // NDJSON rows awaiting conversion to parquet
+}
+
+// SinkBlock identifies an exported block.
+type SinkBlock struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+}
+
+// SinkTombstone lists blocks dropped from the canonical chain. Every exported
+// row carries the hash of its block, rows of the listed blocks are to be
+// discarded.
+type SinkTombstone struct {
+	DetectedAt     time.Time     `json:"detected_at"`
+	AncestorNumber uint64        `json:"ancestor_number"`
+	AncestorHash   common.Hash   `json:"ancestor_hash"`
+	Blocks         []common.Hash `json:"blocks"`
+}
+
+// FileSink is an IndexerSink writing rolling newline-delimited JSON or Parquet
+// files, one directory per table and one file per block range:
+//
+//	<dir>/<table>/<first block>-<last block>.ndjson
+//	<dir>/<table>/<first block>-<last block>.<part>.parquet
+//	<dir>/manifest.json
+//
+// Rows are keyed by the database column names. Hashes, addresses and byte
+// strings are hex encoded, big integers are decimal strings so no precision is
+// lost in JSON. Reorged blocks are not removed from the files, they're listed
+// as tombstones in the manifest instead.
+//
+// Parquet files can't be appended to, so the rows of a range are staged as
+// newline-delimited JSON and converted once all blocks of the range are
+// finalized. Blocks exported into a converted range later on, e.g. by a
+// backfill, end up in an additional part. Parquet columns hold the same values
+// as the JSON rows, except for integers, timestamps and booleans stored in
+// their native types and lists stored as JSON documents.
+type FileSink struct {
+	dir       string
+	format    string
+	partition uint64
+
+	manifest *SinkManifest
+	files    map[string]*SinkFile // manifest entries by path
+	open     map[string]*os.File  // files open for appending by path
+	lock     sync.Mutex
+}
+
+// NewFileSink opens or creates a file sink in the given directory, exporting
+// into files spanning the given number of blocks. An existing sink must have
+// been created with the same format and partition size. Trailing data of an
+// interrupted write is truncated.
+func NewFileSink(dir string, format string, partition uint64) (*FileSink, error) {
+	switch format {
+	case SinkFormatNDJSON, SinkFormatParquet:
+	default:
+		return nil, fmt.Errorf("unsupported indexer sink format %q", format)
+	}
+	if dir == "" {
+		return nil, errors.New("no indexer sink directory configured")
+	}
+	if partition == 0 {
+		partition = defaultSinkPartition
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sink directory: %v", err)
+	}
+	sink := &FileSink{
+		dir:       dir,
+		format:    format,
+		partition: partition,
+		manifest:  &SinkManifest{Format: format, Partition: partition, Files: []*SinkFile{}, Tombstones: []*SinkTombstone{}},
+		files:     make(map[string]*SinkFile),
+		open:      make(map[string]*os.File),
+	}
+	blob, err := os.ReadFile(filepath.Join(dir, sinkManifestName))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return sink, sink.writeManifest()
+	case err != nil:
+		return nil, fmt.Errorf("failed to read sink manifest: %v", err)
+	}
+	if err := json.Unmarshal(blob, sink.manifest); err != nil {
+		return nil, fmt.Errorf("invalid sink manifest: %v", err)
+	}
+	if sink.manifest.Format != format || sink.manifest.Partition != partition {
+		return nil, fmt.Errorf("sink directory holds %s files of %d blocks, have %s files of %d blocks",
+			sink.manifest.Format, sink.manifest.Partition, format, partition)
+	}
+	for _, file := range sink.manifest.Files {
+		sink.files[file.Path] = file
+
+		info, err := os.Stat(filepath.Join(dir, file.Path))
+		if err != nil {
+			return nil, fmt.Errorf("missing sink file: %v", err)
+		}
+		if info.Size() < file.Size {
+			return nil, fmt.Errorf("sink file %s truncated: have %d bytes, want %d", file.Path, info.Size(), file.Size)
+		}
+		if info.Size() > file.Size {
+			if err := os.Truncate(filepath.Join(dir, file.Path), file.Size); err != nil {
+				return nil, fmt.Errorf("failed to drop partial write of %s: %v", file.Path, err)
+			}
+		}
+	}
+	return sink, nil
+}
+
+// WriteBlock implements IndexerSink, appending the rows of the block to the
+// files of its range. If any of them fails, the rows written so far are
+// truncated again.
+func (s *FileSink) WriteBlock(records *BlockRecords) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Encode all rows upfront, failing without touching any file
+	var (
+		number = records.Block.Number
+		tables = records.tables()
+		data   = make([][]byte, len(tables))
+	)
+	for i, table := range tables {
+		var buf bytes.Buffer
+		for _, record := range table.records {
+			blob, err := json.Marshal(sinkRow(record, records.Block.Hash))
+			if err != nil {
+				return fmt.Errorf("failed to encode %s row of block %d: %v", table.name, number, err)
+			}
+			buf.Write(blob)
+			buf.WriteByte('\n')
+		}
+		data[i] = buf.Bytes()
+	}
+	var (
+		written = make(map[*SinkFile]SinkFile) // entries before the write
+		err     error
+	)
+	for i, table := range tables {
+		if len(table.records) == 0 {
+			continue
+		}
+		file := s.file(table.name, number)
+		written[file] = *file
+		if err = s.append(file, data[i]); err != nil {
+			break
+		}
+		file.Rows += uint64(len(table.records))
+		file.Size += int64(len(data[i]))
+	}
+	if err == nil {
+		err = s.writeManifest()
+	}
+	if err != nil {
+		for file, prev := range written {
+			if terr := os.Truncate(filepath.Join(s.dir, file.Path), prev.Size); terr != nil {
+				return fmt.Errorf("failed to revert sink file %s: %v (export failed: %v)", file.Path, terr, err)
+			}
+			*file = prev
+		}
+		return fmt.Errorf("failed to export block %d: %v", number, err)
+	}
+	return nil
+}
+
+// WriteReorg implements IndexerSink, adding a tombstone of the dropped blocks
+// to the manifest.
+func (s *FileSink) WriteReorg(reorg *Reorg) error {
+	if len(reorg.OldHashes) == 0 {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tombstone := &SinkTombstone{
+		DetectedAt:     reorg.DetectedAt.UTC(),
+		AncestorNumber: reorg.AncestorNumber,
+		AncestorHash:   reorg.AncestorHash,
+		Blocks:         make([]common.Hash, len(reorg.OldHashes)),
+	}
+	for i, hash := range reorg.OldHashes {
+		tombstone.Blocks[i] = common.BytesToHash(hash)
+	}
+	s.manifest.Tombstones = append(s.manifest.Tombstones, tombstone)
+	if err := s.writeManifest(); err != nil {
+		s.manifest.Tombstones = s.manifest.Tombstones[:len(s.manifest.Tombstones)-1]
+		return err
+	}
+	return nil
+}
+
+// Finalize implements IndexerSink, marking the files of ranges up to the given
+// block as final and converting the staged ones into parquet.
+func (s *FileSink) Finalize(number uint64, hash common.Hash) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.manifest.Finalized = &SinkBlock{Number: number, Hash: hash}
+	for _, file := range s.manifest.Files {
+		if file.ToBlock <= number {
+			file.Final = true
+		}
+	}
+	if err := s.writeManifest(); err != nil {
+		return err
+	}
+	for _, file := range s.manifest.Files {
+		if file.Staged && file.Final {
+			if err := s.seal(file); err != nil {
+				return fmt.Errorf("failed to convert %s to parquet: %v", file.Path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Close implements IndexerSink.
+func (s *FileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.closeFiles()
+}
+
+// file returns the manifest entry of the file to append the rows of the given
+// table and block to, creating it if needed.
+func (s *FileSink) file(table string, number uint64) *SinkFile {
+	var (
+		from = number / s.partition * s.partition
+		to   = from + s.partition - 1
+		path = filepath.ToSlash(filepath.Join(table, fmt.Sprintf("%012d-%012d.%s", from, to, SinkFormatNDJSON)))
+	)
+	if file, ok := s.files[path]; ok {
+		return file
+	}
+	file := &SinkFile{Table: table, Path: path, FromBlock: from, ToBlock: to, Staged: s.format == SinkFormatParquet}
+	if fin := s.manifest.Finalized; fin != nil && to <= fin.Number {
+		file.Final = true
+	}
+	s.files[path] = file
+	s.manifest.Files = append(s.manifest.Files, file)
+	return file
+}
+
+// append writes data to the end of a file. A new file replaces any left over by
+// an interrupted write or conversion.
+func (s *FileSink) append(file *SinkFile, data []byte) error {
+	f, ok := s.open[file.Path]
+	if !ok {
+		if len(s.open) >= maxSinkOpenFiles {
+			if err := s.closeFiles(); err != nil {
+				return err
+			}
+		}
+		path := filepath.Join(s.dir, file.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		if file.Size == 0 {
+			flags |= os.O_TRUNC
+		}
+		var err error
+		if f, err = os.OpenFile(path, flags, 0644); err != nil {
+			return err
+		}
+		s.open[file.Path] = f
+	}
+	_, err := f.Write(data)
+	return err
+}
+
+// seal converts the staged rows of a finalized range into a parquet file, which
+// replaces the staged file in the manifest.
+func (s *FileSink) seal(staged *SinkFile) error {
+	if f, ok := s.open[staged.Path]; ok {
+		delete(s.open, staged.Path)
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, staged.Path))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) < staged.Size {
+		return fmt.Errorf("staged file truncated: have %d bytes, want %d", len(data), staged.Size)
+	}
+	var part int
+	for _, file := range s.manifest.Files {
+		if file.Table == staged.Table && file.FromBlock == staged.FromBlock && !file.Staged {
+			part++
+		}
+	}
+	sealed := &SinkFile{
+		Table:     staged.Table,
+		Path:      filepath.ToSlash(filepath.Join(staged.Table, fmt.Sprintf("%012d-%012d.%d.%s", staged.FromBlock, staged.ToBlock, part, SinkFormatParquet))),
+		FromBlock: staged.FromBlock,
+		ToBlock:   staged.ToBlock,
+		Rows:      staged.Rows,
+		Final:     true,
+	}
+	if sealed.Size, err = writeParquet(filepath.Join(s.dir, sealed.Path), staged.Table, data[:staged.Size]); err != nil {
+		return err
+	}
+	// Swap the manifest entries, the staged rows are only dropped afterwards
+	i := slices.Index(s.manifest.Files, staged)
+	s.manifest.Files[i] = sealed
+	if err := s.writeManifest(); err != nil {
+		s.manifest.Files[i] = staged
+		return err
+	}
+	delete(s.files, staged.Path)
+	s.files[sealed.Path] = sealed
+
+	if err := os.Remove(filepath.Join(s.dir, staged.Path)); err != nil {
+		log.Warn("Failed to delete staged sink file", "path", staged.Path, "err", err)
+	}
+	return nil
+}
+
+// closeFiles closes all files open for appending.
+func (s *FileSink) closeFiles() error {
+	var errs []error
+	for path, f := range s.open {
+		errs = append(errs, f.Close())
+		delete(s.open, path)
+	}
+	return errors.Join(errs...)
+}
+
+// writeManifest atomically replaces the manifest with the current one.
+func (s *FileSink) writeManifest() error {
+	blob, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.dir, sinkManifestName+".tmp")
+	if err := os.WriteFile(tmp, blob, 0644); err != nil {
+		return fmt.Errorf("failed to write sink manifest: %v", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, sinkManifestName)); err != nil {
+		return fmt.Errorf("failed to replace sink manifest: %v", err)
+	}
+	return nil
+}
+
+// writeParquet converts the staged rows of a table into a parquet file at the
+// given path, returning its size.
+func writeParquet(path string, table string, data []byte) (int64, error) {
+	var typ reflect.Type
+	for _, t := range new(BlockRecords).tables() {
+		if t.name == table {
+			typ = t.typ
+		}
+	}
+	if typ == nil {
+		return 0, fmt.Errorf("unknown table %s", table)
+	}
+	columns := sinkColumns(typ)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	out := bufio.NewWriter(f)
+	w, err := parquet.NewWriter(out, columns)
+	if err != nil {
+		return 0, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	for {
+		var row map[string]any
+		if err := dec.Decode(&row); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, fmt.Errorf("invalid staged row: %v", err)
+		}
+		values := make([]any, len(columns))
+		for i, column := range columns {
+			if values[i], err = parquetValue(column.Kind, row[column.Name]); err != nil {
+				return 0, fmt.Errorf("invalid staged %s value: %v", column.Name, err)
+			}
+		}
+		if err := w.Append(values); err != nil {
+			return 0, err
+		}
+	}
+	size, err := w.Close()
+	if err != nil {
+		return 0, err
+	}
+	if err := out.Flush(); err != nil {
+		return 0, err
+	}
+	return size, f.Close()
+}
+
+// sinkColumns returns the parquet schema of the rows of a record type, matching
+// the keys of sinkRow.
+func sinkColumns(typ reflect.Type) []parquet.Column {
+	columns := []parquet.Column{{Name: "block_hash", Kind: parquet.String}}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		name := field.Tag.Get("db")
+		if name == "" || name == "id" || name == "block_hash" {
+			continue
+		}
+		kind := parquet.String
+		switch field.Type {
+		case reflect.TypeOf(uint64(0)), reflect.TypeOf(uint(0)), reflect.TypeOf((*uint64)(nil)):
+			kind = parquet.Uint
+		case reflect.TypeOf(false):
+			kind = parquet.Bool
+		case reflect.TypeOf(time.Time{}), reflect.TypeOf(sql.NullTime{}):
+			kind = parquet.Timestamp
+		case reflect.TypeOf(pq.ByteaArray{}), reflect.TypeOf(pq.Int64Array{}), reflect.TypeOf(pq.StringArray{}):
+			kind = parquet.JSON
+		}
+		columns = append(columns, parquet.Column{Name: name, Kind: kind})
+	}
+	return columns
+}
+
+// parquetValue converts a staged JSON value, decoded with numbers kept as
+// json.Number, into the value of a parquet column.
+func parquetValue(kind parquet.Kind, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch kind {
+	case parquet.Uint:
+		if n, ok := value.(json.Number); ok {
+			return strconv.ParseUint(string(n), 10, 64)
+		}
+	case parquet.Timestamp:
+		if s, ok := value.(string); ok {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+	case parquet.Bool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case parquet.JSON:
+		blob, err := json.Marshal(value)
+		return string(blob), err
+	default:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected value %v", value)
+}
+
+// sinkRow converts a record into a row keyed by its database column names. The
+// hash of the containing block is added to the rows of all tables, allowing to
+// drop the rows of tombstoned blocks. Database generated IDs are left out.
+func sinkRow(record any, blockHash common.Hash) map[string]any {
+	var (
+		v   = reflect.ValueOf(record).Elem()
+		row = map[string]any{"block_hash": blockHash}
+	)
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Tag.Get("db")
+		if name == "" || name == "id" {
+			continue
+		}
+		row[name] = sinkValue(v.Field(i).Interface())
+	}
+	return row
+}
+
+// sinkValue converts a column value into its JSON representation.
+func sinkValue(value any) any {
+	switch value := value.(type) {
+	case []byte:
+		if value == nil {
+			return nil
+		}
+		return hexutil.Bytes(value)
+	case *BigInt:
+		if value == nil {
+			return nil
+		}
+		return value.String()
+	case time.Time:
+		return value.UTC()
+	case sql.NullString:
+		if !value.Valid {
+			return nil
+		}
+		return value.String
+	case sql.NullTime:
+		if !value.Valid {
+			return nil
+		}
+		return value.Time.UTC()
+	case pq.ByteaArray:
+		list := make([]hexutil.Bytes, len(value))
+		for i, item := range value {
+			list[i] = item
+		}
+		return list
+	case pq.Int64Array:
+		return []int64(value)
+	case pq.StringArray:
+		return []string(value)
+	}
+	return value
+}
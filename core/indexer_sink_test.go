@@ -0,0 +1,437 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/indexer/parquet"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// readSinkManifest loads the manifest of a sink directory.
+func readSinkManifest(t *testing.T, dir string) *SinkManifest {
+	t.Helper()
+
+	blob, err := os.ReadFile(filepath.Join(dir, sinkManifestName))
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	var manifest SinkManifest
+	if err := json.Unmarshal(blob, &manifest); err != nil {
+		t.Fatalf("failed to parse manifest: %v", err)
+	}
+	return &manifest
+}
+
+// readSinkRows loads the rows of an exported file.
+func readSinkRows(t *testing.T, dir string, path string) []map[string]any {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join(dir, path))
+	if err != nil {
+		t.Fatalf("failed to open sink file: %v", err)
+	}
+	defer f.Close()
+
+	var rows []map[string]any
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var row map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatalf("failed to parse row of %s: %v", path, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// readSinkParquet loads the rows of a parquet file.
+func readSinkParquet(t *testing.T, path string) []map[string]any {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read parquet file: %v", err)
+	}
+	rows, err := parquet.Read(data)
+	if err != nil {
+		t.Fatalf("failed to decode parquet file %s: %v", path, err)
+	}
+	return rows
+}
+
+// waitExported waits until the sink in the given directory exported a block.
+func waitExported(t *testing.T, dir string, block *types.Block) {
+	t.Helper()
+
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+		for _, file := range readSinkManifest(t, dir).Files {
+			if file.Table != "blocks" {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, file.Path))
+			if err != nil {
+				t.Fatalf("failed to read sink file: %v", err)
+			}
+			// Only the rows up to the size in the manifest are complete
+			for _, line := range bytes.Split(data[:file.Size], []byte("\n")) {
+				var row map[string]any
+				if json.Unmarshal(line, &row) == nil && row["block_hash"] == block.Hash().Hex() {
+					return
+				}
+			}
+		}
+	}
+	t.Fatalf("block #%d [%x] not exported", block.NumberU64(), block.Hash())
+}
+
+// testSinkRecords creates the records of a block with a transaction and a log.
+func testSinkRecords(number uint64) *BlockRecords {
+	hash := common.Hash{byte(number)}
+	return &BlockRecords{
+		Block: &Block{Number: number, Hash: hash, Timestamp: time.Unix(int64(number), 0), ExtraData: []byte{0xca, 0xfe}},
+		Transactions: []*Transaction{
+			{Hash: common.Hash{0xff, byte(number)}, BlockNumber: number, Value: NewBigInt(new(big.Int).Lsh(big.NewInt(1), 100))},
+		},
+		Logs: []*Log{
+			{BlockNumber: number, BlockHash: hash, Topics: pq.ByteaArray{hash.Bytes()}},
+		},
+	}
+}
+
+// Tests that the file sink partitions the rows by block range, records reorgs
+// and finality in the manifest and drops partial writes on reopen.
+func TestFileSink(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFileSink(dir, SinkFormatNDJSON, 2)
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	for number := uint64(1); number <= 3; number++ {
+		if err := sink.WriteBlock(testSinkRecords(number)); err != nil {
+			t.Fatalf("failed to export block %d: %v", number, err)
+		}
+	}
+	reorg := &Reorg{DetectedAt: time.Now(), AncestorNumber: 1, AncestorHash: common.Hash{1}, OldHashes: pq.ByteaArray{common.Hash{2}.Bytes(), common.Hash{3}.Bytes()}}
+	if err := sink.WriteReorg(reorg); err != nil {
+		t.Fatalf("failed to export reorg: %v", err)
+	}
+	if err := sink.Finalize(1, common.Hash{1}); err != nil {
+		t.Fatalf("failed to export finalization: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("failed to close sink: %v", err)
+	}
+	manifest := readSinkManifest(t, dir)
+	if manifest.Finalized == nil || manifest.Finalized.Number != 1 {
+		t.Errorf("finalized block mismatch: have %v, want 1", manifest.Finalized)
+	}
+	if len(manifest.Tombstones) != 1 || len(manifest.Tombstones[0].Blocks) != 2 || manifest.Tombstones[0].Blocks[1] != (common.Hash{3}) {
+		t.Errorf("tombstones mismatch: %+v", manifest.Tombstones)
+	}
+	want := map[string]SinkFile{
+		"blocks/000000000000-000000000001.ndjson":       {Table: "blocks", FromBlock: 0, ToBlock: 1, Rows: 1, Final: true},
+		"blocks/000000000002-000000000003.ndjson":       {Table: "blocks", FromBlock: 2, ToBlock: 3, Rows: 2},
+		"transactions/000000000000-000000000001.ndjson": {Table: "transactions", FromBlock: 0, ToBlock: 1, Rows: 1, Final: true},
+		"transactions/000000000002-000000000003.ndjson": {Table: "transactions", FromBlock: 2, ToBlock: 3, Rows: 2},
+		"logs/000000000000-000000000001.ndjson":         {Table: "logs", FromBlock: 0, ToBlock: 1, Rows: 1, Final: true},
+		"logs/000000000002-000000000003.ndjson":         {Table: "logs", FromBlock: 2, ToBlock: 3, Rows: 2},
+	}
+	if len(manifest.Files) != len(want) {
+		t.Fatalf("file count mismatch: have %d, want %d", len(manifest.Files), len(want))
+	}
+	for _, file := range manifest.Files {
+		w, ok := want[file.Path]
+		if !ok {
+			t.Errorf("unexpected file %s", file.Path)
+			continue
+		}
+		w.Path, w.Size = file.Path, file.Size
+		if *file != w {
+			t.Errorf("file %s mismatch: have %+v, want %+v", file.Path, *file, w)
+		}
+		if rows := readSinkRows(t, dir, file.Path); uint64(len(rows)) != file.Rows {
+			t.Errorf("file %s row count mismatch: have %d, want %d", file.Path, len(rows), file.Rows)
+		}
+	}
+	// Rows are keyed by column, with hex bytes and decimal big integers
+	block := readSinkRows(t, dir, "blocks/000000000002-000000000003.ndjson")[1]
+	if block["number"] != 3.0 || block["extra_data"] != "0xcafe" || block["timestamp"] != "1970-01-01T00:00:03Z" || block["base_fee_per_gas"] != nil {
+		t.Errorf("block row mismatch: %v", block)
+	}
+	if _, ok := block["id"]; ok {
+		t.Error("database ID exported")
+	}
+	tx := readSinkRows(t, dir, "transactions/000000000002-000000000003.ndjson")[0]
+	if tx["block_hash"] != (common.Hash{2}).Hex() || tx["value"] != "1267650600228229401496703205376" {
+		t.Errorf("transaction row mismatch: %v", tx)
+	}
+	// Partial writes past the manifest are dropped on reopen, settings must match
+	path := filepath.Join(dir, "blocks/000000000002-000000000003.ndjson")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("failed to open sink file: %v", err)
+	}
+	f.WriteString(`{"number":`)
+	f.Close()
+
+	if _, err := NewFileSink(dir, SinkFormatNDJSON, 4); err == nil {
+		t.Error("partition size change accepted")
+	}
+	if _, err := NewFileSink(dir, SinkFormatParquet, 2); err == nil {
+		t.Error("format change accepted")
+	}
+	if _, err := NewFileSink(dir, "csv", 2); err == nil {
+		t.Error("unsupported format accepted")
+	}
+	sink, err = NewFileSink(dir, SinkFormatNDJSON, 2)
+	if err != nil {
+		t.Fatalf("failed to reopen sink: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.WriteBlock(testSinkRecords(4)); err != nil {
+		t.Fatalf("failed to export block 4: %v", err)
+	}
+	if rows := readSinkRows(t, dir, "blocks/000000000002-000000000003.ndjson"); len(rows) != 2 {
+		t.Errorf("partial write kept: have %d rows, want 2", len(rows))
+	}
+	if rows := readSinkRows(t, dir, "blocks/000000000004-000000000005.ndjson"); len(rows) != 1 || rows[0]["number"] != 4.0 {
+		t.Errorf("new partition mismatch: %v", rows)
+	}
+}
+
+// Tests that a parquet sink stages the rows of a range until it's finalized,
+// converts them into parquet and adds parts for the blocks exported afterwards.
+func TestFileSinkParquet(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFileSink(dir, SinkFormatParquet, 2)
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	for number := uint64(1); number <= 3; number++ {
+		if err := sink.WriteBlock(testSinkRecords(number)); err != nil {
+			t.Fatalf("failed to export block %d: %v", number, err)
+		}
+	}
+	for _, file := range readSinkManifest(t, dir).Files {
+		if !file.Staged || filepath.Ext(file.Path) != ".ndjson" {
+			t.Errorf("file %s not staged before finality", file.Path)
+		}
+	}
+	if err := sink.Finalize(1, common.Hash{1}); err != nil {
+		t.Fatalf("failed to export finalization: %v", err)
+	}
+	// The finalized range is converted, the one after it is left staged
+	files := make(map[string]*SinkFile)
+	for _, file := range readSinkManifest(t, dir).Files {
+		files[file.Path] = file
+	}
+	for _, table := range []string{"blocks", "transactions", "logs"} {
+		sealed := files[table+"/000000000000-000000000001.0.parquet"]
+		if sealed == nil || sealed.Staged || !sealed.Final || sealed.Rows != 1 {
+			t.Errorf("%s: converted file mismatch: %+v", table, sealed)
+		}
+		if staged := files[table+"/000000000002-000000000003.ndjson"]; staged == nil || !staged.Staged || staged.Rows != 2 {
+			t.Errorf("%s: staged file mismatch: %+v", table, staged)
+		}
+		if _, err := os.Stat(filepath.Join(dir, table, "000000000000-000000000001.ndjson")); !os.IsNotExist(err) {
+			t.Errorf("%s: staged rows of the converted range kept: %v", table, err)
+		}
+	}
+	if len(files) != 6 {
+		t.Errorf("file count mismatch: have %d, want 6", len(files))
+	}
+	// Parquet rows carry the same values as JSON, with native numbers and times
+	rows := readSinkParquet(t, filepath.Join(dir, "blocks/000000000000-000000000001.0.parquet"))
+	if len(rows) != 1 {
+		t.Fatalf("block row count mismatch: have %d, want 1", len(rows))
+	}
+	block := rows[0]
+	if block["block_hash"] != (common.Hash{1}).Hex() || block["number"] != int64(1) || block["extra_data"] != "0xcafe" || block["timestamp"] != int64(1000) || block["base_fee_per_gas"] != nil {
+		t.Errorf("block row mismatch: %v", block)
+	}
+	if _, ok := block["id"]; ok {
+		t.Error("database ID exported")
+	}
+	if rows := readSinkParquet(t, filepath.Join(dir, "transactions/000000000000-000000000001.0.parquet")); rows[0]["value"] != "1267650600228229401496703205376" {
+		t.Errorf("transaction row mismatch: %v", rows[0])
+	}
+	if rows := readSinkParquet(t, filepath.Join(dir, "logs/000000000000-000000000001.0.parquet")); rows[0]["topics"] != `["`+(common.Hash{1}).Hex()+`"]` {
+		t.Errorf("log row mismatch: %v", rows[0])
+	}
+	// A block exported into a converted range ends up in another part
+	if err := sink.WriteBlock(testSinkRecords(0)); err != nil {
+		t.Fatalf("failed to export block 0: %v", err)
+	}
+	if err := sink.Finalize(1, common.Hash{1}); err != nil {
+		t.Fatalf("failed to export finalization: %v", err)
+	}
+	if rows := readSinkParquet(t, filepath.Join(dir, "blocks/000000000000-000000000001.1.parquet")); len(rows) != 1 || rows[0]["number"] != int64(0) {
+		t.Errorf("second part mismatch: %v", rows)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("failed to close sink: %v", err)
+	}
+	reopened, err := NewFileSink(dir, SinkFormatParquet, 2)
+	if err != nil {
+		t.Fatalf("failed to reopen sink: %v", err)
+	}
+	reopened.Close()
+}
+
+// flakySink is an indexer sink failing the first export of every block.
+type flakySink struct {
+	IndexerSink
+	failed map[common.Hash]bool
+}
+
+func (s *flakySink) WriteBlock(records *BlockRecords) error {
+	if !s.failed[records.Block.Hash] {
+		s.failed[records.Block.Hash] = true
+		return errors.New("disk full")
+	}
+	return s.IndexerSink.WriteBlock(records)
+}
+
+// flakyReorgDB is an indexer database failing to record the first reorg, after
+// the blocks of the new chain were written in the same transaction.
+type flakyReorgDB struct {
+	IndexerDB
+	failed atomic.Bool
+}
+
+func (db *flakyReorgDB) InsertReorgWithTx(tx *sqlx.Tx, reorg *Reorg) error {
+	if db.failed.CompareAndSwap(false, true) {
+		return errors.New("serialization failure")
+	}
+	return db.IndexerDB.InsertReorgWithTx(tx, reorg)
+}
+
+// Tests that the indexer exports the blocks of both sides of a reorg into the
+// sink, tombstoning the dropped ones.
+func TestIndexerSink(t *testing.T) { testIndexerSink(t, false) }
+
+// Tests that failed transactions and exports are retried without exporting
+// any block twice.
+func TestIndexerSinkRetry(t *testing.T) { testIndexerSink(t, true) }
+
+func testIndexerSink(t *testing.T, failing bool) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		emitter = common.HexToAddress("0xe1e1")
+
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender:  {Balance: big.NewInt(params.Ether)},
+				emitter: {Code: program.New().Push(0).Push(0).Op(vm.LOG0).Bytes()},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		engine = ethash.NewFaker()
+	)
+	emit := func(coinbase common.Address) func(int, *BlockGen) {
+		return func(i int, gen *BlockGen) {
+			gen.SetCoinbase(coinbase)
+			gen.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{
+				Nonce:    gen.TxNonce(sender),
+				To:       &emitter,
+				Gas:      100000,
+				GasPrice: gen.header.BaseFee,
+			}))
+		}
+	}
+	gendb, blocks, _ := GenerateChainWithGenesis(gspec, engine, 3, emit(common.Address{}))
+	forks, _ := GenerateChain(gspec.Config, blocks[0], engine, gendb, 3, emit(common.Address{0x1}))
+
+	var (
+		db  IndexerDB = newTestIndexerDB(t)
+		dir           = t.TempDir()
+	)
+	if failing {
+		db = &flakyReorgDB{IndexerDB: db}
+	}
+	plugin := NewIndexerPlugin(db)
+	if err := plugin.Configure(IndexerConfig{Sink: SinkFormatNDJSON, SinkDir: dir}); err != nil {
+		t.Fatalf("failed to configure plugin: %v", err)
+	}
+	if failing {
+		plugin.SetSink(&flakySink{IndexerSink: plugin.sink, failed: make(map[common.Hash]bool)})
+	}
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, plugin)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitExported(t, dir, blocks[2])
+
+	if _, err := chain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	waitExported(t, dir, forks[2])
+
+	manifest := readSinkManifest(t, dir)
+	if len(manifest.Tombstones) != 1 {
+		t.Fatalf("tombstone count mismatch: have %d, want 1", len(manifest.Tombstones))
+	}
+	if ts := manifest.Tombstones[0]; ts.AncestorHash != blocks[0].Hash() || len(ts.Blocks) != 2 || ts.Blocks[0] != blocks[1].Hash() || ts.Blocks[1] != blocks[2].Hash() {
+		t.Errorf("tombstone mismatch: %+v", ts)
+	}
+	// All exported blocks and logs must be present, the dropped ones included
+	exported := make(map[string]map[common.Hash]int)
+	for _, file := range manifest.Files {
+		if exported[file.Table] == nil {
+			exported[file.Table] = make(map[common.Hash]int)
+		}
+		for _, row := range readSinkRows(t, dir, file.Path) {
+			exported[file.Table][common.HexToHash(row["block_hash"].(string))]++
+		}
+	}
+	for _, block := range append(blocks, forks...) {
+		for _, table := range []string{"blocks", "transactions", "receipts", "logs"} {
+			if n := exported[table][block.Hash()]; n != 1 {
+				t.Errorf("block #%d [%x]: have %d %s rows, want 1", block.NumberU64(), block.Hash().Bytes()[:4], n, table)
+			}
+		}
+	}
+}
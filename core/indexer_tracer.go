@@ -0,0 +1,457 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/lib/pq"
+)
+
+// indexerTraceCacheLimit is the number of processed blocks whose execution
+// traces are retained until the indexer writes them.
+const indexerTraceCacheLimit = 256
+
+// indexerTracer collects the execution details of processed blocks which can't
+// be recovered from the stored receipts, buffering them until the block becomes
+// head and is written by the indexer.
+//
+// The hooks are only ever invoked from the block processing goroutine, so the
+// in-flight block and transaction need no locking.
+type indexerTracer struct {
+	traces *lru.Cache[common.Hash, *blockTrace] // Traces of recently processed blocks
+
+	block *blockTrace // Trace of the block currently being processed
+	tx    *txTrace    // Trace of the transaction currently being executed
+	scope *stateScope // State changes of the running transaction or system call
+
+	frames []*callFrame // Call stack of the running transaction
+
+	blockScopes []*stateScope // State changes made outside of any transaction
+
+	calls       bool                // Whether the call frames of transactions are recorded
+	config      *params.ChainConfig // Chain rules to look up the active precompiles with
+	precompiles []common.Address    // Precompiles active in the running transaction
+}
+
+// blockTrace holds the execution details of a single block.
+type blockTrace struct {
+	hash   common.Hash
+	number uint64
+	txs    map[common.Hash]*txTrace
+
+	stateChanges []*StateChange     // Net state changes in execution order
+	creations    []contractCreation // Contracts created, in execution order
+	destructs    []common.Address   // Contracts self-destructed, in execution order
+}
+
+// txTrace holds the execution details of a single transaction.
+type txTrace struct {
+	hash common.Hash
+	err  string // Error of the top-level call frame, including any revert reason
+
+	gas   uint64   // Gas limit of the transaction, the gas of the top-level call frame
+	calls []*Trace // Call frames in the order they were entered, if recorded
+}
+
+// contractCreation is a contract deployed by a transaction.
+type contractCreation struct {
+	address common.Address
+	creator common.Address // Account executing the CREATE/CREATE2, the sender for deployments
+	txHash  common.Hash
+}
+
+// callFrame tracks the contracts created and self-destructed within a call,
+// which only take effect if neither the call nor any of its parents revert.
+type callFrame struct {
+	creations []contractCreation
+	destructs []common.Address
+
+	trace    *Trace // Recorded row of the frame, nil if calls aren't recorded or skipped
+	children int64  // Number of recorded child frames
+}
+
+func newIndexerTracer() *indexerTracer {
+	return &indexerTracer{
+		traces: lru.NewCache[common.Hash, *blockTrace](indexerTraceCacheLimit),
+	}
+}
+
+// hooks returns the live tracing hooks feeding the tracer.
+func (t *indexerTracer) hooks() *tracing.Hooks {
+	return &tracing.Hooks{
+		OnBlockStart:        t.onBlockStart,
+		OnBlockEnd:          t.onBlockEnd,
+		OnTxStart:           t.onTxStart,
+		OnTxEnd:             t.onTxEnd,
+		OnEnter:             t.onEnter,
+		OnExit:              t.onExit,
+		OnSystemCallStartV2: t.onSystemCallStart,
+		OnSystemCallEnd:     t.onSystemCallEnd,
+		OnBalanceChange:     t.onBalanceChange,
+		OnNonceChange:       t.onNonceChange,
+		OnCodeChange:        t.onCodeChange,
+		OnStorageChange:     t.onStorageChange,
+	}
+}
+
+// trace returns the buffered execution trace of the given block, or nil if the
+// block was not processed by this node since startup.
+func (t *indexerTracer) trace(hash common.Hash) *blockTrace {
+	trace, _ := t.traces.Get(hash)
+	return trace
+}
+
+func (t *indexerTracer) onBlockStart(event tracing.BlockEvent) {
+	t.block = &blockTrace{
+		hash:   event.Block.Hash(),
+		number: event.Block.NumberU64(),
+		txs:    make(map[common.Hash]*txTrace),
+	}
+	t.tx, t.scope, t.blockScopes = nil, nil, nil
+}
+
+func (t *indexerTracer) onBlockEnd(err error) {
+	if err == nil && t.block != nil {
+		for _, scope := range t.blockScopes {
+			t.block.stateChanges = append(t.block.stateChanges, scope.changes(t.block.number)...)
+		}
+		t.traces.Add(t.block.hash, t.block)
+	}
+	t.block, t.tx, t.scope, t.blockScopes = nil, nil, nil, nil
+}
+
+func (t *indexerTracer) onTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	if t.block == nil {
+		return
+	}
+	t.tx = &txTrace{hash: tx.Hash(), gas: tx.Gas()}
+	t.block.txs[tx.Hash()] = t.tx
+	t.frames = t.frames[:0]
+	t.scope = newStateScope(&t.tx.hash, stateSourceTransaction, env.StateDB)
+
+	if t.calls && t.config != nil {
+		t.precompiles = vm.ActivePrecompiles(t.config.Rules(env.BlockNumber, env.Random != nil, env.Time))
+	}
+}
+
+func (t *indexerTracer) onTxEnd(receipt *types.Receipt, err error) {
+	// The top-level call frame is charged the whole gas of the transaction
+	if t.tx != nil && len(t.tx.calls) > 0 && receipt != nil {
+		t.tx.calls[0].GasUsed = receipt.GasUsed
+	}
+	t.closeScope()
+	t.tx = nil
+}
+
+func (t *indexerTracer) onSystemCallStart(vm *tracing.VMContext) {
+	if t.block == nil {
+		return
+	}
+	t.scope = newStateScope(nil, stateSourceSystemCall, vm.StateDB)
+}
+
+func (t *indexerTracer) onSystemCallEnd() {
+	t.closeScope()
+}
+
+// closeScope flushes the state changes of the running transaction or system
+// call into the block trace.
+func (t *indexerTracer) closeScope() {
+	if t.block != nil && t.scope != nil {
+		t.block.stateChanges = append(t.block.stateChanges, t.scope.changes(t.block.number)...)
+	}
+	t.scope = nil
+}
+
+// stateScopeFor returns the scope a state change should be attributed to. Any
+// change made outside of a transaction or system call is a block-level one,
+// e.g. a withdrawal or block reward, grouped by its source.
+func (t *indexerTracer) stateScopeFor(source string) *stateScope {
+	if t.scope != nil {
+		return t.scope
+	}
+	for _, scope := range t.blockScopes {
+		if scope.source == source {
+			return scope
+		}
+	}
+	scope := newStateScope(nil, source, nil)
+	t.blockScopes = append(t.blockScopes, scope)
+	return scope
+}
+
+func (t *indexerTracer) onBalanceChange(addr common.Address, prev, new *big.Int, reason tracing.BalanceChangeReason) {
+	if t.block == nil {
+		return
+	}
+	source := stateSourceBlock
+	switch reason {
+	case tracing.BalanceIncreaseWithdrawal:
+		source = stateSourceWithdrawal
+	case tracing.BalanceIncreaseRewardMineBlock, tracing.BalanceIncreaseRewardMineUncle:
+		source = stateSourceReward
+	}
+	t.stateScopeFor(source).record(stateKey{addr: addr, kind: stateChangeBalance}, prev.String(), new.String())
+}
+
+func (t *indexerTracer) onNonceChange(addr common.Address, prev, new uint64) {
+	if t.block == nil {
+		return
+	}
+	t.stateScopeFor(stateSourceBlock).record(stateKey{addr: addr, kind: stateChangeNonce}, strconv.FormatUint(prev, 10), strconv.FormatUint(new, 10))
+}
+
+func (t *indexerTracer) onCodeChange(addr common.Address, prevCodeHash common.Hash, prevCode []byte, codeHash common.Hash, code []byte) {
+	if t.block == nil {
+		return
+	}
+	t.stateScopeFor(stateSourceBlock).record(stateKey{addr: addr, kind: stateChangeCode}, codeHashString(prevCodeHash), codeHashString(codeHash))
+}
+
+func (t *indexerTracer) onStorageChange(addr common.Address, slot common.Hash, prev, new common.Hash) {
+	if t.block == nil {
+		return
+	}
+	t.stateScopeFor(stateSourceBlock).record(stateKey{addr: addr, kind: stateChangeStorage, slot: slot}, prev.Hex(), new.Hex())
+}
+
+func (t *indexerTracer) onEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.tx == nil {
+		return
+	}
+	frame := new(callFrame)
+	switch vm.OpCode(typ) {
+	case vm.CREATE, vm.CREATE2:
+		frame.creations = append(frame.creations, contractCreation{address: to, creator: from})
+	case vm.SELFDESTRUCT:
+		frame.destructs = append(frame.destructs, from)
+	}
+	if t.calls {
+		t.recordCall(frame, depth, typ, from, to, input, gas, value)
+	}
+	t.frames = append(t.frames, frame)
+}
+
+// recordCall adds the row of an entered call frame to the running transaction.
+// Like the flatCallTracer, calls into precompiles are left out.
+func (t *indexerTracer) recordCall(frame *callFrame, depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	op := vm.OpCode(typ)
+	address := pq.Int64Array{}
+	if depth == 0 {
+		gas = t.tx.gas
+	} else {
+		if op != vm.CREATE && op != vm.CREATE2 && slices.Contains(t.precompiles, to) {
+			return
+		}
+		parent := t.frames[len(t.frames)-1]
+		address = append(slices.Clone(parent.trace.TraceAddress), parent.children)
+		parent.children++
+	}
+	frame.trace = &Trace{
+		BlockNumber:     t.block.number,
+		TransactionHash: t.tx.hash,
+		TraceIndex:      uint64(len(t.tx.calls)),
+		TraceAddress:    address,
+		Type:            op.String(),
+		From:            from,
+		To:              &to,
+		Value:           NewBigInt(value),
+		Gas:             gas,
+		Input:           common.CopyBytes(input),
+	}
+	if frame.trace.Input == nil {
+		frame.trace.Input = []byte{}
+	}
+	t.tx.calls = append(t.tx.calls, frame.trace)
+}
+
+func (t *indexerTracer) onExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if t.tx == nil || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+
+	if frame.trace != nil {
+		frame.trace.GasUsed = gasUsed
+		if err == nil || errors.Is(err, vm.ErrExecutionReverted) {
+			frame.trace.Output = common.CopyBytes(output)
+		}
+		if err != nil {
+			frame.trace.Error = sql.NullString{String: executionError(err, output), Valid: true}
+		}
+	}
+
+	if depth != 0 {
+		if !reverted {
+			parent := t.frames[len(t.frames)-1]
+			parent.creations = append(parent.creations, frame.creations...)
+			parent.destructs = append(parent.destructs, frame.destructs...)
+		}
+		return
+	}
+	if err != nil {
+		t.tx.err = executionError(err, output)
+	}
+	if !reverted {
+		for _, creation := range frame.creations {
+			creation.txHash = t.tx.hash
+			t.block.creations = append(t.block.creations, creation)
+		}
+		t.block.destructs = append(t.block.destructs, frame.destructs...)
+	}
+}
+
+// Kinds of state changes tracked by the indexer.
+const (
+	stateChangeBalance = "balance"
+	stateChangeNonce   = "nonce"
+	stateChangeCode    = "code"
+	stateChangeStorage = "storage"
+)
+
+// Sources of state changes tracked by the indexer.
+const (
+	stateSourceTransaction = "transaction"
+	stateSourceSystemCall  = "system_call"
+	stateSourceWithdrawal  = "withdrawal"
+	stateSourceReward      = "reward"
+	stateSourceBlock       = "block"
+)
+
+// stateKey identifies a single piece of account state.
+type stateKey struct {
+	addr common.Address
+	kind string
+	slot common.Hash // Only set for storage changes
+}
+
+// stateDiff is the value of a piece of state before and after a scope.
+type stateDiff struct {
+	prev string
+	last string
+}
+
+// stateScope accumulates the net state changes made within a single transaction,
+// system call or block-level operation.
+type stateScope struct {
+	txHash  *common.Hash    // Transaction making the changes, nil outside of transactions
+	source  string          // Origin of the changes
+	statedb tracing.StateDB // State to resolve final values from, nil to trust the hooks
+
+	keys  []stateKey // Touched state in order of first modification
+	diffs map[stateKey]*stateDiff
+}
+
+func newStateScope(txHash *common.Hash, source string, statedb tracing.StateDB) *stateScope {
+	return &stateScope{
+		txHash:  txHash,
+		source:  source,
+		statedb: statedb,
+		diffs:   make(map[stateKey]*stateDiff),
+	}
+}
+
+// record tracks a modification of the given state. Only the value before the
+// first modification is retained, the final one is resolved when the scope is
+// closed.
+func (s *stateScope) record(key stateKey, prev, new string) {
+	if diff, ok := s.diffs[key]; ok {
+		diff.last = new
+		return
+	}
+	s.keys = append(s.keys, key)
+	s.diffs[key] = &stateDiff{prev: prev, last: new}
+}
+
+// changes returns the state change rows of the scope. Whenever a state is
+// available, the final values are read from it rather than taken from the last
+// hook invocation, since modifications made by reverted calls are not undone
+// through the hooks.
+func (s *stateScope) changes(number uint64) []*StateChange {
+	var changes []*StateChange
+	for _, key := range s.keys {
+		diff := s.diffs[key]
+
+		final := diff.last
+		if s.statedb != nil {
+			switch key.kind {
+			case stateChangeBalance:
+				final = s.statedb.GetBalance(key.addr).ToBig().String()
+			case stateChangeNonce:
+				final = strconv.FormatUint(s.statedb.GetNonce(key.addr), 10)
+			case stateChangeCode:
+				final = codeHashString(s.statedb.GetCodeHash(key.addr))
+			case stateChangeStorage:
+				final = s.statedb.GetState(key.addr, key.slot).Hex()
+			}
+		}
+		if final == diff.prev {
+			continue
+		}
+		change := &StateChange{
+			BlockNumber: number,
+			Address:     key.addr,
+			PrevValue:   diff.prev,
+			NewValue:    final,
+			ChangeType:  key.kind,
+			Source:      s.source,
+		}
+		if s.txHash != nil {
+			change.TransactionHash = s.txHash
+		}
+		if key.kind == stateChangeStorage {
+			slot := key.slot
+			change.StorageKey = &slot
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// codeHashString formats a code hash, treating the hash of a missing account
+// and that of an account without code alike.
+func codeHashString(hash common.Hash) string {
+	if hash == (common.Hash{}) {
+		hash = types.EmptyCodeHash
+	}
+	return hash.Hex()
+}
+
+// executionError formats the error of a failed call frame, decoding the revert
+// reason from the returned data if there is one.
+func executionError(err error, output []byte) string {
+	if errors.Is(err, vm.ErrExecutionReverted) && len(output) > 0 {
+		if reason, unpackErr := abi.UnpackRevert(output); unpackErr == nil {
+			return fmt.Sprintf("%v: %s", err, reason)
+		}
+	}
+	return err.Error()
+}
@@ -0,0 +1,423 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the indexer tracer captures the revert reasons of failed
+// transactions and that they end up in the generated transaction rows.
+func TestIndexerTransactions(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		reverter = common.HexToAddress("0xdeadbeef")
+		receiver = common.HexToAddress("0xcafebabe")
+
+		// Error("nope") encoded as revert data
+		reason = append(common.FromHex("08c379a0"), append(append(
+			common.LeftPadBytes([]byte{0x20}, 32),
+			common.LeftPadBytes([]byte{0x04}, 32)...),
+			common.RightPadBytes([]byte("nope"), 32)...)...)
+
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender:   {Balance: big.NewInt(params.Ether)},
+				reverter: {Code: program.New().Mstore(reason, 0).Push(len(reason)).Push(0).Op(vm.REVERT).Bytes()},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, func(i int, gen *BlockGen) {
+		gen.AddTx(types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   gspec.Config.ChainID,
+			Nonce:     0,
+			To:        &reverter,
+			Gas:       100000,
+			GasFeeCap: new(big.Int).Mul(gen.header.BaseFee, big.NewInt(2)),
+			GasTipCap: big.NewInt(1),
+		}))
+		gen.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    1,
+			To:       &receiver,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: gen.header.BaseFee,
+		}))
+	})
+	tracer := newIndexerTracer()
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{Tracer: tracer.hooks()}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	var (
+		block    = blocks[0]
+		trace    = tracer.trace(block.Hash())
+		receipts = chain.GetReceiptsByHash(block.Hash())
+	)
+	if trace == nil {
+		t.Fatal("missing block trace")
+	}
+	failed, err := newTransaction(signer, block.Header(), block.Transactions()[0], receipts[0], trace)
+	if err != nil {
+		t.Fatalf("failed to convert transaction: %v", err)
+	}
+	if failed.From != sender || failed.To == nil || *failed.To != reverter {
+		t.Errorf("wrong participants: from %x, to %x", failed.From, failed.To)
+	}
+	if failed.Status != types.ReceiptStatusFailed {
+		t.Errorf("wrong status: have %d, want %d", failed.Status, types.ReceiptStatusFailed)
+	}
+	if want := "execution reverted: nope"; !failed.Error.Valid || failed.Error.String != want {
+		t.Errorf("wrong error: have %q, want %q", failed.Error.String, want)
+	}
+	if failed.MaxFeePerGas == nil || failed.MaxPriorityFee == nil {
+		t.Error("missing dynamic fee fields")
+	}
+	if want := new(big.Int).Add(block.BaseFee(), big.NewInt(1)); failed.GasPrice.ToBig().Cmp(want) != 0 {
+		t.Errorf("wrong effective gas price: have %s, want %s", failed.GasPrice, want)
+	}
+
+	transfer, err := newTransaction(signer, block.Header(), block.Transactions()[1], receipts[1], trace)
+	if err != nil {
+		t.Fatalf("failed to convert transaction: %v", err)
+	}
+	if transfer.Status != types.ReceiptStatusSuccessful || transfer.Error.Valid {
+		t.Errorf("unexpected failure: status %d, error %q", transfer.Status, transfer.Error.String)
+	}
+	if transfer.Value.ToBig().Cmp(big.NewInt(1000)) != 0 || transfer.GasUsed != params.TxGas || transfer.Nonce != 1 {
+		t.Errorf("wrong transfer fields: value %s, gas used %d, nonce %d", transfer.Value, transfer.GasUsed, transfer.Nonce)
+	}
+	if transfer.MaxFeePerGas != nil || transfer.BlobGasUsed != nil {
+		t.Error("unexpected fee fields on legacy transaction")
+	}
+}
+
+// Tests that the indexer tracer records the net state changes of transactions,
+// system calls and withdrawals, ignoring the ones undone by a revert.
+func TestIndexerStateChanges(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		writer   = common.Address{0xaa}
+		reverter = common.Address{0xbb}
+		receiver = common.Address{0xee}
+		config   = *params.AllEthashProtocolChanges
+
+		gspec = &Genesis{
+			Config: &config,
+			Alloc: types.GenesisAlloc{
+				sender:                    {Balance: big.NewInt(params.Ether)},
+				writer:                    {Code: program.New().Sstore(1, 0x42).Bytes()},
+				reverter:                  {Code: program.New().Sstore(1, 0x42).Push(0).Push(0).Op(vm.REVERT).Bytes()},
+				params.BeaconRootsAddress: {Code: params.BeaconRootsCode},
+			},
+			BaseFee:    big.NewInt(params.InitialBaseFee),
+			Difficulty: common.Big1,
+		}
+	)
+	config.TerminalTotalDifficulty = common.Big0
+	config.ShanghaiTime = u64(0)
+	config.CancunTime = u64(0)
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, beacon.NewFaker(), 1, func(i int, gen *BlockGen) {
+		gen.SetParentBeaconRoot(common.Hash{0x01})
+		for _, to := range []common.Address{writer, reverter} {
+			gen.AddTx(types.MustSignNewTx(key, gen.Signer(), &types.LegacyTx{
+				Nonce:    gen.TxNonce(sender),
+				To:       &to,
+				Gas:      100000,
+				GasPrice: gen.BaseFee(),
+			}))
+		}
+		gen.AddWithdrawal(&types.Withdrawal{Validator: 42, Address: receiver, Amount: 1337})
+	})
+	tracer := newIndexerTracer()
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, beacon.NewFaker(), vm.Config{Tracer: tracer.hooks()}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	trace := tracer.trace(blocks[0].Hash())
+	if trace == nil {
+		t.Fatal("missing block trace")
+	}
+	find := func(addr common.Address, kind string) []*StateChange {
+		var found []*StateChange
+		for _, change := range trace.stateChanges {
+			if change.Address == addr && change.ChangeType == kind {
+				found = append(found, change)
+			}
+		}
+		return found
+	}
+	// The storage write of the first transaction must be recorded
+	txs := blocks[0].Transactions()
+	if changes := find(writer, stateChangeStorage); len(changes) != 1 {
+		t.Fatalf("wrong number of storage changes: have %d, want 1", len(changes))
+	} else if c := changes[0]; c.TransactionHash == nil || *c.TransactionHash != txs[0].Hash() || c.Source != stateSourceTransaction ||
+		c.StorageKey == nil || *c.StorageKey != common.BigToHash(common.Big1) || c.NewValue != common.BigToHash(big.NewInt(0x42)).Hex() {
+		t.Errorf("wrong storage change: %+v", c)
+	}
+	// The reverted write of the second one must not
+	if changes := find(reverter, stateChangeStorage); len(changes) != 0 {
+		t.Errorf("reverted storage change recorded: %+v", changes[0])
+	}
+	// The sender pays gas in both transactions and bumps its nonce
+	if changes := find(sender, stateChangeBalance); len(changes) != 2 {
+		t.Errorf("wrong number of sender balance changes: have %d, want 2", len(changes))
+	}
+	if changes := find(sender, stateChangeNonce); len(changes) != 2 || changes[1].PrevValue != "1" || changes[1].NewValue != "2" {
+		t.Errorf("wrong sender nonce changes: %v", changes)
+	}
+	// The beacon root system call and withdrawal are recorded without a transaction
+	if changes := find(params.BeaconRootsAddress, stateChangeStorage); len(changes) == 0 {
+		t.Error("missing beacon root system call changes")
+	} else if c := changes[0]; c.TransactionHash != nil || c.Source != stateSourceSystemCall {
+		t.Errorf("wrong system call change: %+v", c)
+	}
+	want := new(big.Int).Mul(big.NewInt(1337), big.NewInt(params.GWei)).String()
+	if changes := find(receiver, stateChangeBalance); len(changes) != 1 {
+		t.Fatalf("wrong number of withdrawal changes: have %d, want 1", len(changes))
+	} else if c := changes[0]; c.TransactionHash != nil || c.Source != stateSourceWithdrawal || c.PrevValue != "0" || c.NewValue != want {
+		t.Errorf("wrong withdrawal change: %+v", c)
+	}
+}
+
+// Tests that contract creations, including nested ones and those destroyed in
+// the same transaction, end up in the account rows, while reverted ones don't.
+func TestIndexerAccounts(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		factory  = common.Address{0xaa}
+		reverter = common.Address{0xbb}
+		killer   = common.Address{0xcc}
+		config   = *params.AllEthashProtocolChanges
+
+		initcode = program.New().ReturnViaCodeCopy([]byte{0x00}).Bytes()
+		suicidal = program.New().Selfdestruct(0).Bytes()
+
+		gspec = &Genesis{
+			Config: &config,
+			Alloc: types.GenesisAlloc{
+				sender:                    {Balance: big.NewInt(params.Ether)},
+				factory:                   {Code: program.New().Create2(initcode, 1).Bytes()},
+				reverter:                  {Code: program.New().Create2(initcode, 1).Push(0).Push(0).Op(vm.REVERT).Bytes()},
+				killer:                    {Code: program.New().Create2(suicidal, 1).Bytes()},
+				params.BeaconRootsAddress: {Code: params.BeaconRootsCode},
+			},
+			BaseFee:    big.NewInt(params.InitialBaseFee),
+			Difficulty: common.Big1,
+		}
+	)
+	config.TerminalTotalDifficulty = common.Big0
+	config.ShanghaiTime = u64(0)
+	config.CancunTime = u64(0)
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, beacon.NewFaker(), 1, func(i int, gen *BlockGen) {
+		gen.SetParentBeaconRoot(common.Hash{0x01})
+		for _, to := range []common.Address{factory, reverter, killer} {
+			gen.AddTx(types.MustSignNewTx(key, gen.Signer(), &types.LegacyTx{
+				Nonce:    gen.TxNonce(sender),
+				To:       &to,
+				Gas:      200000,
+				GasPrice: gen.BaseFee(),
+			}))
+		}
+		gen.AddTx(types.MustSignNewTx(key, gen.Signer(), &types.LegacyTx{
+			Nonce:    gen.TxNonce(sender),
+			Gas:      200000,
+			GasPrice: gen.BaseFee(),
+			Data:     initcode,
+		}))
+	})
+	tracer := newIndexerTracer()
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, beacon.NewFaker(), vm.Config{Tracer: tracer.hooks()}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	var (
+		block    = blocks[0]
+		txs      = block.Transactions()
+		trace    = tracer.trace(block.Hash())
+		receipts = chain.GetReceiptsByHash(block.Hash())
+	)
+	statedb, err := chain.StateAt(block.Root())
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	rows, err := newAccounts(statedb, types.LatestSigner(gspec.Config), block, receipts, trace)
+	if err != nil {
+		t.Fatalf("failed to assemble accounts: %v", err)
+	}
+	accounts := make(map[common.Address]*Account)
+	for _, row := range rows {
+		accounts[row.Address] = row
+	}
+	var (
+		child    = crypto.CreateAddress2(factory, common.BigToHash(common.Big1), crypto.Keccak256(initcode))
+		orphan   = crypto.CreateAddress2(reverter, common.BigToHash(common.Big1), crypto.Keccak256(initcode))
+		ghost    = crypto.CreateAddress2(killer, common.BigToHash(common.Big1), crypto.Keccak256(suicidal))
+		deployed = crypto.CreateAddress(sender, 3)
+	)
+	check := func(addr, creator common.Address, tx *types.Transaction, destructed bool) {
+		t.Helper()
+		account := accounts[addr]
+		if account == nil {
+			t.Fatalf("missing account %s", addr)
+		}
+		if account.CreatorAddress == nil || *account.CreatorAddress != creator || account.CreatorTxHash == nil || *account.CreatorTxHash != tx.Hash() {
+			t.Errorf("wrong creator of %s: have %x in %x", addr, account.CreatorAddress, account.CreatorTxHash)
+		}
+		if !account.CreatedAt.Valid || account.CreatedAt.Time.Unix() != int64(block.Time()) {
+			t.Errorf("wrong creation time of %s: %v", addr, account.CreatedAt)
+		}
+		if account.SelfDestructed != destructed {
+			t.Errorf("wrong self-destruct flag of %s: have %t, want %t", addr, account.SelfDestructed, destructed)
+		}
+	}
+	check(child, factory, txs[0], false)
+	check(ghost, killer, txs[2], true)
+	check(deployed, sender, txs[3], false)
+
+	if account := accounts[child]; !bytes.Equal(account.Code, []byte{0x00}) || account.Nonce != 1 {
+		t.Errorf("wrong child state: code %x, nonce %d", account.Code, account.Nonce)
+	}
+	if account, ok := accounts[orphan]; ok {
+		t.Errorf("reverted creation recorded: %+v", account)
+	}
+	if account := accounts[sender]; account == nil || account.Nonce != 4 || account.CreatorTxHash != nil {
+		t.Errorf("wrong sender account: %+v", account)
+	}
+}
+
+// Tests that the call frames of transactions are indexed if enabled, numbered
+// in execution order and skipping calls into precompiles.
+func TestIndexerTraces(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		router   = common.HexToAddress("0xa0a0")
+		receiver = common.HexToAddress("0xb0b0")
+		reverter = common.HexToAddress("0xdeadbeef")
+		identity = common.BytesToAddress([]byte{0x04})
+
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender:   {Balance: big.NewInt(params.Ether)},
+				router:   {Code: program.New().Call(nil, identity, 0, 0, 0, 0, 0).Call(nil, receiver, 7, 0, 0, 0, 0).Call(nil, reverter, 0, 0, 0, 0, 0).Bytes()},
+				reverter: {Code: program.New().Push(0).Push(0).Op(vm.REVERT).Bytes()},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 1, func(i int, gen *BlockGen) {
+		gen.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    0,
+			To:       &router,
+			Value:    big.NewInt(100),
+			Gas:      200000,
+			GasPrice: gen.header.BaseFee,
+		}))
+	})
+	db := newTestIndexerDB(t)
+	plugin := NewIndexerPlugin(db)
+	plugin.EnableTraces()
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, plugin)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed(t, db, blocks[0])
+
+	var traces []Trace
+	if err := db.db.Select(&traces, `SELECT * FROM traces ORDER BY trace_index`); err != nil {
+		t.Fatalf("failed to read traces: %v", err)
+	}
+	if len(traces) != 3 {
+		t.Fatalf("trace count mismatch: have %d, want 3", len(traces))
+	}
+	receipt := chain.GetReceiptsByHash(blocks[0].Hash())[0]
+	want := []struct {
+		address []int64
+		from    common.Address
+		to      common.Address
+		value   int64
+		err     string
+	}{
+		{[]int64{}, sender, router, 100, ""},
+		{[]int64{0}, router, receiver, 7, ""},
+		{[]int64{1}, router, reverter, 0, "execution reverted"},
+	}
+	for i, trace := range traces {
+		if trace.TransactionHash != receipt.TxHash || trace.BlockNumber != 1 || trace.TraceIndex != uint64(i) || trace.Type != "CALL" {
+			t.Errorf("trace %d: wrong position: %+v", i, trace)
+		}
+		if !slices.Equal(trace.TraceAddress, want[i].address) {
+			t.Errorf("trace %d: wrong trace address: have %v, want %v", i, trace.TraceAddress, want[i].address)
+		}
+		if trace.From != want[i].from || trace.To == nil || *trace.To != want[i].to || trace.Value.Int64() != want[i].value {
+			t.Errorf("trace %d: wrong call: %x -> %x, value %v", i, trace.From, trace.To, trace.Value)
+		}
+		if trace.Error.String != want[i].err {
+			t.Errorf("trace %d: wrong error: have %q, want %q", i, trace.Error.String, want[i].err)
+		}
+	}
+	if traces[0].Gas != 200000 || traces[0].GasUsed != receipt.GasUsed {
+		t.Errorf("wrong top-level gas: have %d/%d, want %d/%d", traces[0].Gas, traces[0].GasUsed, 200000, receipt.GasUsed)
+	}
+	if traces[1].GasUsed != 0 || traces[2].Gas == 0 {
+		t.Errorf("wrong inner gas: transfer used %d, revert got %d", traces[1].GasUsed, traces[2].Gas)
+	}
+}
@@ -0,0 +1,433 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/jmoiron/sqlx"
+)
+
+// Kinds of divergences between the indexer database and the canonical chain.
+const (
+	DivergenceMissing      = "missing"      // canonical block not indexed
+	DivergenceHash         = "hash"         // indexed block is not canonical
+	DivergenceOrphaned     = "orphaned"     // rows of a block that isn't indexed or canonical
+	DivergenceFinalized    = "finalized"    // finalized flag not backed by the chain
+	DivergenceTransactions = "transactions" // transactions differ from the block body
+	DivergenceReceipts     = "receipts"     // receipts missing or of a different status
+	DivergenceLogs         = "logs"         // log count differs from the receipts
+)
+
+// IndexerDivergence is a mismatch between the indexed rows of a block and the
+// canonical chain.
+type IndexerDivergence struct {
+	Number uint64
+	Kind   string
+	Detail string
+}
+
+// String implements fmt.Stringer.
+func (d *IndexerDivergence) String() string {
+	return fmt.Sprintf("block %d: %s: %s", d.Number, d.Kind, d.Detail)
+}
+
+// VerifyReport is the outcome of checking a range of the indexer database
+// against the canonical chain.
+type VerifyReport struct {
+	From         uint64
+	To           uint64
+	Blocks       uint64 // canonical blocks checked
+	StateChanges uint64 // state changes indexed for the canonical blocks
+	Divergences  []*IndexerDivergence
+}
+
+// Ranges returns the inclusive block ranges containing divergences, in
+// ascending order.
+func (r *VerifyReport) Ranges() [][2]uint64 {
+	var ranges [][2]uint64
+	for _, d := range r.Divergences {
+		if n := len(ranges); n > 0 && d.Number <= ranges[n-1][1]+1 {
+			ranges[n-1][1] = max(ranges[n-1][1], d.Number)
+			continue
+		}
+		ranges = append(ranges, [2]uint64{d.Number, d.Number})
+	}
+	return ranges
+}
+
+// Verify compares the indexed blocks in the given inclusive range against the
+// canonical chain: block hashes, transactions, receipt statuses, log counts and
+// finalized flags. Rows left behind at a height by non-canonical or dropped
+// blocks are reported as orphaned. If the range ends at the chain head, rows
+// indexed beyond it are checked as well. State changes can't be derived from
+// the chain without re-execution, their totals are only reported.
+func (p *IndexerPlugin) Verify(ctx context.Context, from, to uint64) (*VerifyReport, error) {
+	if p.db == nil || p.chain == nil {
+		return nil, errIndexerDisabled
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid range: from %d > to %d", from, to)
+	}
+	head := p.chain.CurrentBlock().Number.Uint64()
+	if to > head {
+		return nil, fmt.Errorf("range end %d beyond head block %d", to, head)
+	}
+	last := to
+	if to == head {
+		latest, err := p.db.GetLatestBlock()
+		if err != nil {
+			return nil, err
+		}
+		last = max(to, latest)
+	}
+	var final uint64
+	if header := p.chain.CurrentFinalBlock(); header != nil {
+		final = header.Number.Uint64()
+	}
+	report := &VerifyReport{From: from, To: to}
+	batch := p.batchSize
+	if batch == 0 {
+		batch = defaultBackfillBatch
+	}
+	for first := from; first <= last; first += batch {
+		end := min(first+batch-1, last)
+		summaries, err := p.db.GetBlockSummaries(ctx, first, end)
+		if err != nil {
+			return nil, err
+		}
+		for number := first; number <= end; number++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if number > head {
+				if s := summaries[number]; s != nil {
+					report.add(number, DivergenceOrphaned, "rows indexed beyond the chain head %d", head)
+				}
+				continue
+			}
+			if err := p.verifyBlock(report, number, summaries[number], final); err != nil {
+				return nil, err
+			}
+		}
+		if end == last {
+			break // avoid overflowing at the top of the number space
+		}
+	}
+	return report, nil
+}
+
+// verifyBlock checks the summary of the rows indexed at a canonical height,
+// nil if there are none.
+func (p *IndexerPlugin) verifyBlock(report *VerifyReport, number uint64, s *BlockSummary, final uint64) error {
+	hash := p.chain.GetCanonicalHash(number)
+	block := p.chain.GetBlock(hash, number)
+	if block == nil {
+		return fmt.Errorf("canonical block %d not found", number)
+	}
+	report.Blocks++
+
+	switch {
+	case s == nil || s.Hash == nil:
+		// The genesis and blocks before the configured start aren't indexed
+		if number == 0 || number < p.startBlock {
+			if s != nil {
+				report.add(number, DivergenceOrphaned, "rows of a block which is not indexed")
+			}
+			return nil
+		}
+		report.add(number, DivergenceMissing, "canonical block %x not indexed", hash)
+		if s != nil {
+			report.add(number, DivergenceOrphaned, "rows of a block which is not indexed")
+		}
+		return nil
+	case *s.Hash != hash:
+		report.add(number, DivergenceHash, "have %x, want %x", *s.Hash, hash)
+		return nil
+	}
+	report.StateChanges += s.StateChanges
+	if s.Finalized && number > final {
+		report.add(number, DivergenceFinalized, "flagged finalized beyond the finalized block %d", final)
+	}
+	// Transactions must match the body in order
+	var (
+		txs       = make([]common.Hash, len(block.Transactions()))
+		canonical = make(map[common.Hash]bool)
+	)
+	for i, tx := range block.Transactions() {
+		txs[i] = tx.Hash()
+		canonical[txs[i]] = true
+	}
+	if !slices.Equal(s.Transactions, txs) {
+		report.add(number, DivergenceTransactions, "have %d, want %d", len(s.Transactions), len(txs))
+	}
+	// Receipts must reference the block and carry the canonical status
+	receipts := p.chain.GetReceiptsByHash(hash)
+	if len(receipts) != len(txs) {
+		return fmt.Errorf("receipt count mismatch for block %d: have %d, want %d", number, len(receipts), len(txs))
+	}
+	var (
+		logs    uint64
+		missing int
+		status  int
+		orphans int
+	)
+	for _, receipt := range receipts {
+		logs += uint64(len(receipt.Logs))
+		have, ok := s.Receipts[receipt.TxHash]
+		switch {
+		case !ok || s.ReceiptHash[receipt.TxHash] != hash:
+			missing++
+		case have != receipt.Status:
+			status++
+		}
+	}
+	for txHash := range s.Receipts {
+		if !canonical[txHash] {
+			orphans++
+		}
+	}
+	if missing > 0 {
+		report.add(number, DivergenceReceipts, "%d of %d missing", missing, len(receipts))
+	}
+	if status > 0 {
+		report.add(number, DivergenceReceipts, "%d with mismatching status", status)
+	}
+	if orphans > 0 {
+		report.add(number, DivergenceOrphaned, "%d receipts of non-canonical transactions", orphans)
+	}
+	// Logs of the canonical block must all be live, others flagged removed
+	if have := s.Logs[hash]; have != logs {
+		report.add(number, DivergenceLogs, "have %d, want %d", have, logs)
+	}
+	for other, count := range s.Logs {
+		if other != hash {
+			report.add(number, DivergenceOrphaned, "%d live logs of non-canonical block %x", count, other)
+		}
+	}
+	return nil
+}
+
+// add records a divergence.
+func (r *VerifyReport) add(number uint64, kind string, format string, args ...any) {
+	r.Divergences = append(r.Divergences, &IndexerDivergence{
+		Number: number,
+		Kind:   kind,
+		Detail: fmt.Sprintf(format, args...),
+	})
+}
+
+// Repair brings the blocks in the given inclusive range in line with the
+// chain. Missing blocks and blocks with a non-canonical hash are re-indexed;
+// divergent flags, transactions, receipts and logs of otherwise canonical
+// blocks are fixed in place. Heights beyond the chain head or before the
+// start block are cleared. Blocks are committed in batches of the configured
+// size.
+//
+// Like a backfill, only what's stored in the chain can be re-indexed: state
+// changes and traces of re-indexed blocks are lost unless the blocks were
+// processed since the plugin was attached. Blocks fixed in place keep theirs,
+// except for those of transactions which don't belong to the block.
+func (p *IndexerPlugin) Repair(ctx context.Context, from, to uint64) error {
+	if p.db == nil || p.chain == nil {
+		return errIndexerDisabled
+	}
+	if from > to {
+		return fmt.Errorf("invalid range: from %d > to %d", from, to)
+	}
+	batch := p.batchSize
+	if batch == 0 {
+		batch = defaultBackfillBatch
+	}
+	for first := from; first <= to; first += batch {
+		last := min(first+batch-1, to)
+		if err := p.repairBatch(ctx, first, last); err != nil {
+			return err
+		}
+		if last == to {
+			break // avoid overflowing at the top of the number space
+		}
+	}
+	return nil
+}
+
+// repairBatch repairs the blocks [first, last] in a single database
+// transaction.
+func (p *IndexerPlugin) repairBatch(ctx context.Context, first, last uint64) error {
+	release := p.writing.acquire(first, last)
+	defer release()
+
+	summaries, err := p.db.GetBlockSummaries(ctx, first, last)
+	if err != nil {
+		return err
+	}
+	var final uint64
+	if header := p.chain.CurrentFinalBlock(); header != nil {
+		final = header.Number.Uint64()
+	}
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for blocks %d-%d: %v", first, last, err)
+	}
+	defer tx.Rollback()
+
+	var writes []sinkWrite
+	for number := first; number <= last; number++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hash := p.chain.GetCanonicalHash(number)
+		header := p.chain.GetHeader(hash, number)
+		if header == nil || number == 0 || number < p.startBlock {
+			// Nothing to index, only clear what's left
+			if err := p.db.DeleteBlockWithTx(tx, number); err != nil {
+				return fmt.Errorf("failed to clear block %d: %v", number, err)
+			}
+			continue
+		}
+		s := summaries[number]
+		report := new(VerifyReport)
+		if err := p.verifyBlock(report, number, s, final); err != nil {
+			return err
+		}
+		kinds := make(map[string]bool)
+		for _, d := range report.Divergences {
+			kinds[d.Kind] = true
+		}
+		if len(kinds) == 0 {
+			continue
+		}
+		if !kinds[DivergenceMissing] && !kinds[DivergenceHash] {
+			if err := p.repairBlock(tx, header, s, kinds); err != nil {
+				return err
+			}
+			continue
+		}
+		// The indexed block is missing or stale, replace it entirely
+		if kinds[DivergenceHash] {
+			reorg, err := p.deleteStaleBlock(tx, header, *s.Hash)
+			if err != nil {
+				return err
+			}
+			writes = append(writes, sinkWrite{reorg: reorg})
+		} else if err := p.db.DeleteBlockWithTx(tx, number); err != nil {
+			return fmt.Errorf("failed to delete block %d: %v", number, err)
+		}
+		// Logs of the canonical block are reinserted, don't keep the old ones
+		if err := p.db.DeleteLogsWithTx(tx, hash); err != nil {
+			return fmt.Errorf("failed to delete logs of block %d: %v", number, err)
+		}
+		records, err := p.indexBlock(tx, header)
+		if err != nil {
+			return err
+		}
+		writes = append(writes, sinkWrite{block: records})
+	}
+	if err := commitIndexerTx(tx); err != nil {
+		return fmt.Errorf("failed to commit blocks %d-%d: %v", first, last, err)
+	}
+	if err := p.export(writes...); err != nil {
+		return err
+	}
+	// Restore the finalized flag, lost if the finalized block was replaced
+	if final >= first && final <= last && final > 0 {
+		if err := p.db.MarkBlockFinalized(final); err != nil {
+			return err
+		}
+	}
+	log.Info("Repaired blocks", "from", first, "to", last)
+	return nil
+}
+
+// repairBlock fixes the given kinds of divergences of an indexed canonical
+// block in place, keeping its state changes and traces. Receipts and logs are
+// rewritten from the chain, transactions only where they differ.
+func (p *IndexerPlugin) repairBlock(tx *sqlx.Tx, header *types.Header, s *BlockSummary, kinds map[string]bool) error {
+	var (
+		number   = header.Number.Uint64()
+		hash     = header.Hash()
+		block    = p.chain.GetBlock(hash, number)
+		receipts = p.chain.GetReceiptsByHash(hash)
+	)
+	if kinds[DivergenceFinalized] {
+		if err := p.db.UnmarkBlockFinalizedWithTx(tx, number); err != nil {
+			return fmt.Errorf("failed to clear finalized flag of block %d: %v", number, err)
+		}
+	}
+	if kinds[DivergenceTransactions] {
+		txs := make([]common.Hash, len(block.Transactions()))
+		for i, transaction := range block.Transactions() {
+			txs[i] = transaction.Hash()
+		}
+		if err := p.db.RepairTransactionsWithTx(tx, number, txs); err != nil {
+			return fmt.Errorf("failed to repair transactions of block %d: %v", number, err)
+		}
+		signer := types.MakeSigner(p.chain.Config(), header.Number, header.Time)
+		for i, transaction := range block.Transactions() {
+			if slices.Contains(s.Transactions, txs[i]) {
+				continue
+			}
+			t, err := newTransaction(signer, header, transaction, receipts[i], nil)
+			if err != nil {
+				return err
+			}
+			if err := p.db.InsertTransactionWithTx(tx, t); err != nil {
+				return fmt.Errorf("failed to index transaction %s in block %d: %v", t.Hash, number, err)
+			}
+			for _, blob := range newBlobHashes(t, transaction) {
+				if err := p.db.InsertBlobHashWithTx(tx, blob); err != nil {
+					return fmt.Errorf("failed to index blob %d of %s in block %d: %v", blob.BlobIndex, t.Hash, number, err)
+				}
+			}
+		}
+	}
+	if kinds[DivergenceReceipts] || kinds[DivergenceOrphaned] || kinds[DivergenceTransactions] {
+		if err := p.db.DeleteReceiptsWithTx(tx, number); err != nil {
+			return fmt.Errorf("failed to delete receipts of block %d: %v", number, err)
+		}
+		for i, receipt := range receipts {
+			r := newReceipt(number, hash, block.Transactions()[i], receipt, i)
+			if err := p.db.InsertReceiptWithTx(tx, r); err != nil {
+				return fmt.Errorf("failed to index receipt %s in block %d: %v", receipt.TxHash, number, err)
+			}
+		}
+	}
+	if kinds[DivergenceOrphaned] {
+		if err := p.db.MarkLogsRemovedWithTx(tx, number, hash); err != nil {
+			return fmt.Errorf("failed to flag orphaned logs of block %d: %v", number, err)
+		}
+	}
+	if kinds[DivergenceLogs] || kinds[DivergenceTransactions] {
+		if err := p.db.DeleteLogsWithTx(tx, hash); err != nil {
+			return fmt.Errorf("failed to delete logs of block %d: %v", number, err)
+		}
+		for _, receipt := range receipts {
+			for _, logEntry := range receipt.Logs {
+				l := newLog(number, hash, receipt.TxHash, logEntry)
+				if err := p.db.InsertLogWithTx(tx, l); err != nil {
+					return fmt.Errorf("failed to index log %d of %s in block %d: %v", l.LogIndex, receipt.TxHash, number, err)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the divergences of a damaged indexer database are reported and
+// fixed by re-indexing the affected ranges.
+func TestIndexerVerify(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		emitter = common.HexToAddress("0xe1e1")
+
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender:  {Balance: big.NewInt(params.Ether)},
+				emitter: {Code: program.New().Push(0).Push(0).Op(vm.LOG0).Bytes()},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 5, func(i int, gen *BlockGen) {
+		gen.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    gen.TxNonce(sender),
+			To:       &emitter,
+			Gas:      100000,
+			GasPrice: gen.header.BaseFee,
+		}))
+	})
+	db := newTestIndexerDB(t)
+	plugin := NewIndexerPlugin(db)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, plugin)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed(t, db, blocks[4])
+
+	report, err := plugin.Verify(context.Background(), 1, 5)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if len(report.Divergences) != 0 || report.Blocks != 5 || report.StateChanges == 0 {
+		t.Fatalf("intact database reported as %+v, divergences %v", report, report.Divergences)
+	}
+	// Damage the database in all the detectable ways
+	for _, query := range []string{
+		`DELETE FROM state_changes WHERE block_number = 2`,
+		`UPDATE logs SET removed = TRUE WHERE block_number = 2`,
+		`DELETE FROM receipts WHERE block_number = 2`,
+		`DELETE FROM transactions WHERE block_number = 2`,
+		`DELETE FROM blocks WHERE number = 2`,
+		`UPDATE receipts SET status = 0 WHERE block_number = 3`,
+		`UPDATE blocks SET finalized = TRUE WHERE number = 4`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatalf("failed to damage database: %v", err)
+		}
+	}
+	if _, err := db.db.Exec(`INSERT INTO logs (transaction_hash, block_number, block_hash, address, topics, data, log_index) VALUES (?, 5, ?, ?, '{}', x'', 7)`,
+		common.Hash{0x01}, common.Hash{0x02}, emitter); err != nil {
+		t.Fatalf("failed to insert orphaned log: %v", err)
+	}
+	if err := db.InsertBlock(&Block{Number: 7, Hash: common.Hash{0x07}, Nonce: []byte{}, Difficulty: NewBigInt(common.Big0), ExtraData: []byte{}, LogsBloom: []byte{}}); err != nil {
+		t.Fatalf("failed to insert block beyond the head: %v", err)
+	}
+	report, err = plugin.Verify(context.Background(), 1, 5)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	var have []string
+	for _, d := range report.Divergences {
+		have = append(have, d.Kind)
+	}
+	want := []string{DivergenceMissing, DivergenceReceipts, DivergenceFinalized, DivergenceOrphaned, DivergenceOrphaned}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("divergences mismatch: have %v, want %v", report.Divergences, want)
+	}
+	if ranges := report.Ranges(); !reflect.DeepEqual(ranges, [][2]uint64{{2, 5}, {7, 7}}) {
+		t.Fatalf("divergent ranges mismatch: have %v", ranges)
+	}
+	// Re-indexing the affected ranges must restore the database
+	for _, r := range report.Ranges() {
+		if err := plugin.Repair(context.Background(), r[0], r[1]); err != nil {
+			t.Fatalf("failed to repair blocks %d-%d: %v", r[0], r[1], err)
+		}
+	}
+	report, err = plugin.Verify(context.Background(), 1, 5)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if len(report.Divergences) != 0 {
+		t.Errorf("repaired database diverges: %v", report.Divergences)
+	}
+}
+
+// Tests that divergent flags, receipts and logs of canonical blocks are fixed
+// in place, keeping the state changes and traces of the repaired blocks.
+func TestIndexerRepairInPlace(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		emitter = common.HexToAddress("0xe1e1")
+
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender:  {Balance: big.NewInt(params.Ether)},
+				emitter: {Code: program.New().Push(0).Push(0).Op(vm.LOG0).Bytes()},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		engine = ethash.NewFaker()
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 5, func(i int, gen *BlockGen) {
+		gen.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    gen.TxNonce(sender),
+			To:       &emitter,
+			Gas:      100000,
+			GasPrice: gen.header.BaseFee,
+		}))
+	})
+	db := newTestIndexerDB(t)
+	plugin := NewIndexerPlugin(db)
+	plugin.EnableTraces()
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, plugin)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed(t, db, blocks[4])
+
+	counts := func() (stateChanges, traces int) {
+		if err := db.db.Get(&stateChanges, `SELECT COUNT(*) FROM state_changes WHERE block_number BETWEEN 3 AND 5`); err != nil {
+			t.Fatalf("failed to count state changes: %v", err)
+		}
+		if err := db.db.Get(&traces, `SELECT COUNT(*) FROM traces WHERE block_number BETWEEN 3 AND 5`); err != nil {
+			t.Fatalf("failed to count traces: %v", err)
+		}
+		return stateChanges, traces
+	}
+	stateChanges, traces := counts()
+	if stateChanges == 0 || traces == 0 {
+		t.Fatalf("nothing to preserve: %d state changes, %d traces", stateChanges, traces)
+	}
+	// Forget the traces, as if the node restarted since the blocks were processed
+	plugin.tracer.traces.Purge()
+
+	// Damage everything but the block hashes
+	for _, query := range []string{
+		`UPDATE receipts SET status = 0 WHERE block_number = 3`,
+		`DELETE FROM logs WHERE block_number = 3`,
+		`UPDATE blocks SET finalized = TRUE WHERE number = 4`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatalf("failed to damage database: %v", err)
+		}
+	}
+	if _, err := db.db.Exec(`INSERT INTO logs (transaction_hash, block_number, block_hash, address, topics, data, log_index) VALUES (?, 5, ?, ?, '{}', x'', 7)`,
+		common.Hash{0x01}, common.Hash{0x02}, emitter); err != nil {
+		t.Fatalf("failed to insert orphaned log: %v", err)
+	}
+	report, err := plugin.Verify(context.Background(), 1, 5)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	var have []string
+	for _, d := range report.Divergences {
+		have = append(have, d.Kind)
+	}
+	want := []string{DivergenceReceipts, DivergenceLogs, DivergenceFinalized, DivergenceOrphaned}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("divergences mismatch: have %v, want %v", report.Divergences, want)
+	}
+	if err := plugin.Repair(context.Background(), 3, 5); err != nil {
+		t.Fatalf("failed to repair: %v", err)
+	}
+	report, err = plugin.Verify(context.Background(), 1, 5)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if len(report.Divergences) != 0 {
+		t.Errorf("repaired database diverges: %v", report.Divergences)
+	}
+	if haveChanges, haveTraces := counts(); haveChanges != stateChanges || haveTraces != traces {
+		t.Errorf("repair dropped rows: have %d state changes and %d traces, want %d and %d", haveChanges, haveTraces, stateChanges, traces)
+	}
+}
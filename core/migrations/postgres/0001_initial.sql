@@ -1,3 +1,6 @@
+-- Initial indexer schema. Tables may already exist if the database was created
+-- before schema versioning, hence everything is idempotent.
+
 CREATE TABLE IF NOT EXISTS blocks (
     number BIGINT PRIMARY KEY,
     hash VARCHAR(66) NOT NULL UNIQUE,
@@ -101,10 +104,29 @@ CREATE TABLE IF NOT EXISTS receipts (
     UNIQUE(transaction_hash)
 );
 
+
 CREATE INDEX IF NOT EXISTS idx_logs_address ON logs(address);
 CREATE INDEX IF NOT EXISTS idx_logs_topics ON logs USING gin(topics);
 CREATE INDEX IF NOT EXISTS idx_state_changes_address ON state_changes(address);
 CREATE INDEX IF NOT EXISTS idx_access_lists_address ON access_lists(address);
 CREATE INDEX IF NOT EXISTS idx_accounts_creator ON accounts(creator_address);
 CREATE INDEX IF NOT EXISTS idx_receipts_block ON receipts(block_number);
-CREATE INDEX IF NOT EXISTS idx_receipts_contract ON receipts(contract_address);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_receipts_contract ON receipts(contract_address);
+
+-- Missing from databases created by early versions
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS finalized BOOLEAN DEFAULT FALSE;
+
+-- Announce new blocks to LISTEN new_block subscribers
+CREATE OR REPLACE FUNCTION notify_new_block()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('new_block', row_to_json(NEW)::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS blocks_notify_trigger ON blocks;
+CREATE TRIGGER blocks_notify_trigger
+    AFTER INSERT ON blocks
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_new_block();
@@ -0,0 +1,19 @@
+-- State changes attributed to system calls, withdrawals and rewards have no
+-- transaction, and are tagged with their source instead.
+ALTER TABLE state_changes ALTER COLUMN transaction_hash DROP NOT NULL;
+ALTER TABLE state_changes ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'transaction';
+ALTER TABLE state_changes ALTER COLUMN source DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_state_changes_storage ON state_changes(address, storage_key, block_number);
+
+-- Accounts track the block of their last snapshot, so that a backfill never
+-- overwrites newer state
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS self_destructed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS updated_block BIGINT NOT NULL DEFAULT 0;
+
+-- Progress of long running jobs like backfills
+CREATE TABLE IF NOT EXISTS checkpoints (
+    name VARCHAR(64) PRIMARY KEY,
+    first_block BIGINT NOT NULL,
+    last_block BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
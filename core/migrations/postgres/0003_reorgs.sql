@@ -0,0 +1,22 @@
+-- Logs of blocks dropped by a reorg outlive their block and transaction rows,
+-- flagged as removed, and are tied to the block by hash instead.
+ALTER TABLE logs DROP CONSTRAINT IF EXISTS logs_transaction_hash_fkey;
+ALTER TABLE logs DROP CONSTRAINT IF EXISTS logs_block_number_fkey;
+ALTER TABLE logs ADD COLUMN IF NOT EXISTS block_hash VARCHAR(66);
+UPDATE logs SET block_hash = blocks.hash FROM blocks
+    WHERE logs.block_hash IS NULL AND logs.block_number = blocks.number;
+ALTER TABLE logs ALTER COLUMN block_hash SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_logs_block ON logs(block_number);
+
+-- Audit trail of chain reorganizations
+CREATE TABLE IF NOT EXISTS reorgs (
+    id BIGSERIAL PRIMARY KEY,
+    detected_at TIMESTAMP NOT NULL,
+    ancestor_number BIGINT NOT NULL,
+    ancestor_hash VARCHAR(66) NOT NULL,
+    depth BIGINT NOT NULL,
+    old_head VARCHAR(66),
+    new_head VARCHAR(66),
+    old_hashes TEXT[] NOT NULL,
+    new_hashes TEXT[] NOT NULL
+);
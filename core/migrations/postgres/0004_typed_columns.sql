@@ -0,0 +1,113 @@
+-- Store amounts as NUMERIC(78,0), which fits any uint256, gas as BIGINT and
+-- hashes, addresses and binary data as BYTEA instead of their text encodings,
+-- so that they can be compared, summed and indexed natively.
+
+CREATE FUNCTION pg_temp.hex_to_bytea(s TEXT) RETURNS BYTEA AS $$
+    SELECT decode(substring(s FROM 3), 'hex')
+$$ LANGUAGE SQL IMMUTABLE STRICT;
+
+CREATE FUNCTION pg_temp.hex_array_to_bytea(a TEXT[]) RETURNS BYTEA[] AS $$
+    SELECT COALESCE(array_agg(pg_temp.hex_to_bytea(e) ORDER BY i), '{}')
+    FROM unnest(a) WITH ORDINALITY AS t(e, i)
+$$ LANGUAGE SQL IMMUTABLE STRICT;
+
+-- Foreign keys must match the type of the referenced key, restore them once
+-- both sides are converted
+ALTER TABLE receipts DROP CONSTRAINT IF EXISTS receipts_transaction_hash_fkey;
+ALTER TABLE state_changes DROP CONSTRAINT IF EXISTS state_changes_transaction_hash_fkey;
+ALTER TABLE access_lists DROP CONSTRAINT IF EXISTS access_lists_transaction_hash_fkey;
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS accounts_creator_tx_hash_fkey;
+
+-- Earlier versions stored an unparseable rendering of the block nonce and never
+-- filled in the size and rewards, these are left empty.
+ALTER TABLE blocks
+    ALTER COLUMN nonce DROP NOT NULL,
+    ALTER COLUMN size DROP NOT NULL,
+    ALTER COLUMN block_reward DROP NOT NULL,
+    ALTER COLUMN uncle_reward DROP NOT NULL;
+
+ALTER TABLE blocks
+    ALTER COLUMN hash TYPE BYTEA USING pg_temp.hex_to_bytea(hash),
+    ALTER COLUMN parent_hash TYPE BYTEA USING pg_temp.hex_to_bytea(parent_hash),
+    ALTER COLUMN nonce TYPE BYTEA USING NULL,
+    ALTER COLUMN base_fee_per_gas TYPE NUMERIC(78,0) USING NULLIF(base_fee_per_gas, '')::NUMERIC,
+    ALTER COLUMN blob_gas_used TYPE BIGINT USING NULLIF(blob_gas_used, '')::BIGINT,
+    ALTER COLUMN difficulty TYPE NUMERIC(78,0) USING difficulty::NUMERIC,
+    ALTER COLUMN excess_blob_gas TYPE BIGINT USING NULLIF(excess_blob_gas, '')::BIGINT,
+    ALTER COLUMN extra_data TYPE BYTEA USING pg_temp.hex_to_bytea(extra_data),
+    ALTER COLUMN gas_limit TYPE BIGINT USING gas_limit::BIGINT,
+    ALTER COLUMN gas_used TYPE BIGINT USING gas_used::BIGINT,
+    ALTER COLUMN logs_bloom TYPE BYTEA USING pg_temp.hex_to_bytea(logs_bloom),
+    ALTER COLUMN miner TYPE BYTEA USING pg_temp.hex_to_bytea(miner),
+    ALTER COLUMN mix_hash TYPE BYTEA USING pg_temp.hex_to_bytea(mix_hash),
+    ALTER COLUMN parent_beacon_block_root TYPE BYTEA USING pg_temp.hex_to_bytea(parent_beacon_block_root),
+    ALTER COLUMN receipts_root TYPE BYTEA USING pg_temp.hex_to_bytea(receipts_root),
+    ALTER COLUMN sha3_uncles TYPE BYTEA USING pg_temp.hex_to_bytea(sha3_uncles),
+    ALTER COLUMN size TYPE BIGINT USING NULLIF(size, '')::BIGINT,
+    ALTER COLUMN state_root TYPE BYTEA USING pg_temp.hex_to_bytea(state_root),
+    ALTER COLUMN total_difficulty TYPE NUMERIC(78,0) USING NULLIF(total_difficulty, '')::NUMERIC,
+    ALTER COLUMN transactions_root TYPE BYTEA USING pg_temp.hex_to_bytea(transactions_root),
+    ALTER COLUMN withdrawals_root TYPE BYTEA USING pg_temp.hex_to_bytea(withdrawals_root),
+    ALTER COLUMN transactions TYPE BYTEA[] USING pg_temp.hex_array_to_bytea(transactions),
+    ALTER COLUMN uncles TYPE BYTEA[] USING pg_temp.hex_array_to_bytea(uncles),
+    ALTER COLUMN block_reward TYPE NUMERIC(78,0) USING NULLIF(block_reward, '')::NUMERIC,
+    ALTER COLUMN uncle_reward TYPE NUMERIC(78,0) USING NULLIF(uncle_reward, '')::NUMERIC;
+
+ALTER TABLE transactions
+    ALTER COLUMN hash TYPE BYTEA USING pg_temp.hex_to_bytea(hash),
+    ALTER COLUMN "from" TYPE BYTEA USING pg_temp.hex_to_bytea("from"),
+    ALTER COLUMN "to" TYPE BYTEA USING pg_temp.hex_to_bytea("to"),
+    ALTER COLUMN value TYPE NUMERIC(78,0) USING value::NUMERIC,
+    ALTER COLUMN gas_price TYPE NUMERIC(78,0) USING gas_price::NUMERIC,
+    ALTER COLUMN input TYPE BYTEA USING pg_temp.hex_to_bytea(input),
+    ALTER COLUMN max_fee_per_gas TYPE NUMERIC(78,0) USING NULLIF(max_fee_per_gas, '')::NUMERIC,
+    ALTER COLUMN max_priority_fee TYPE NUMERIC(78,0) USING NULLIF(max_priority_fee, '')::NUMERIC,
+    ALTER COLUMN blob_gas_used TYPE BIGINT USING NULLIF(blob_gas_used, '')::BIGINT,
+    ALTER COLUMN blob_gas_price TYPE NUMERIC(78,0) USING NULLIF(blob_gas_price, '')::NUMERIC;
+
+ALTER TABLE logs
+    ALTER COLUMN transaction_hash TYPE BYTEA USING pg_temp.hex_to_bytea(transaction_hash),
+    ALTER COLUMN block_hash TYPE BYTEA USING pg_temp.hex_to_bytea(block_hash),
+    ALTER COLUMN address TYPE BYTEA USING pg_temp.hex_to_bytea(address),
+    ALTER COLUMN topics TYPE BYTEA[] USING pg_temp.hex_array_to_bytea(topics),
+    ALTER COLUMN data TYPE BYTEA USING pg_temp.hex_to_bytea(data);
+
+ALTER TABLE state_changes
+    ALTER COLUMN transaction_hash TYPE BYTEA USING pg_temp.hex_to_bytea(transaction_hash),
+    ALTER COLUMN address TYPE BYTEA USING pg_temp.hex_to_bytea(address),
+    ALTER COLUMN storage_key TYPE BYTEA USING pg_temp.hex_to_bytea(storage_key);
+
+ALTER TABLE access_lists
+    ALTER COLUMN transaction_hash TYPE BYTEA USING pg_temp.hex_to_bytea(transaction_hash),
+    ALTER COLUMN address TYPE BYTEA USING pg_temp.hex_to_bytea(address),
+    ALTER COLUMN storage_key TYPE BYTEA USING pg_temp.hex_to_bytea(storage_key);
+
+ALTER TABLE accounts
+    ALTER COLUMN address TYPE BYTEA USING pg_temp.hex_to_bytea(address),
+    ALTER COLUMN balance TYPE NUMERIC(78,0) USING balance::NUMERIC,
+    ALTER COLUMN code TYPE BYTEA USING pg_temp.hex_to_bytea(code),
+    ALTER COLUMN creator_address TYPE BYTEA USING pg_temp.hex_to_bytea(creator_address),
+    ALTER COLUMN creator_tx_hash TYPE BYTEA USING pg_temp.hex_to_bytea(creator_tx_hash);
+
+-- Receipts used to carry the zero address if no contract was created
+ALTER TABLE receipts
+    ALTER COLUMN block_hash TYPE BYTEA USING pg_temp.hex_to_bytea(block_hash),
+    ALTER COLUMN transaction_hash TYPE BYTEA USING pg_temp.hex_to_bytea(transaction_hash),
+    ALTER COLUMN contract_address TYPE BYTEA USING pg_temp.hex_to_bytea(
+        NULLIF(NULLIF(contract_address, ''), '0x0000000000000000000000000000000000000000'));
+
+ALTER TABLE reorgs
+    ALTER COLUMN ancestor_hash TYPE BYTEA USING pg_temp.hex_to_bytea(ancestor_hash),
+    ALTER COLUMN old_head TYPE BYTEA USING pg_temp.hex_to_bytea(old_head),
+    ALTER COLUMN new_head TYPE BYTEA USING pg_temp.hex_to_bytea(new_head),
+    ALTER COLUMN old_hashes TYPE BYTEA[] USING pg_temp.hex_array_to_bytea(old_hashes),
+    ALTER COLUMN new_hashes TYPE BYTEA[] USING pg_temp.hex_array_to_bytea(new_hashes);
+
+ALTER TABLE receipts ADD CONSTRAINT receipts_transaction_hash_fkey
+    FOREIGN KEY (transaction_hash) REFERENCES transactions(hash);
+ALTER TABLE state_changes ADD CONSTRAINT state_changes_transaction_hash_fkey
+    FOREIGN KEY (transaction_hash) REFERENCES transactions(hash);
+ALTER TABLE access_lists ADD CONSTRAINT access_lists_transaction_hash_fkey
+    FOREIGN KEY (transaction_hash) REFERENCES transactions(hash);
+ALTER TABLE accounts ADD CONSTRAINT accounts_creator_tx_hash_fkey
+    FOREIGN KEY (creator_tx_hash) REFERENCES transactions(hash);
@@ -0,0 +1,9 @@
+-- Transactions carry their position in the block, so that the activity of an
+-- address can be paginated in chain order.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS transaction_index BIGINT NOT NULL DEFAULT 0;
+UPDATE transactions SET transaction_index = receipts.transaction_index FROM receipts
+    WHERE receipts.transaction_hash = transactions.hash;
+ALTER TABLE transactions ALTER COLUMN transaction_index DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_from ON transactions("from", block_number, transaction_index);
+CREATE INDEX IF NOT EXISTS idx_transactions_to ON transactions("to", block_number, transaction_index);
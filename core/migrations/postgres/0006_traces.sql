@@ -0,0 +1,23 @@
+-- Call frames executed by transactions, the internal transactions of Parity
+-- style traces. Only filled in if trace indexing is enabled.
+CREATE TABLE IF NOT EXISTS traces (
+    id BIGSERIAL PRIMARY KEY,
+    block_number BIGINT NOT NULL REFERENCES blocks(number),
+    transaction_hash BYTEA NOT NULL REFERENCES transactions(hash),
+    trace_index BIGINT NOT NULL,
+    trace_address BIGINT[] NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    "from" BYTEA NOT NULL,
+    "to" BYTEA,
+    value NUMERIC(78,0),
+    gas BIGINT NOT NULL,
+    gas_used BIGINT NOT NULL,
+    input BYTEA NOT NULL,
+    output BYTEA,
+    error TEXT,
+    UNIQUE(transaction_hash, trace_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_traces_block ON traces(block_number);
+CREATE INDEX IF NOT EXISTS idx_traces_from ON traces("from", block_number);
+CREATE INDEX IF NOT EXISTS idx_traces_to ON traces("to", block_number);
@@ -0,0 +1,38 @@
+-- Logs decoded into the events of known contract ABIs. Only filled in if event
+-- decoding is enabled.
+CREATE TABLE IF NOT EXISTS decoded_events (
+    id BIGSERIAL PRIMARY KEY,
+    block_number BIGINT NOT NULL REFERENCES blocks(number),
+    transaction_hash BYTEA NOT NULL REFERENCES transactions(hash),
+    log_index BIGINT NOT NULL,
+    address BYTEA NOT NULL,
+    contract TEXT NOT NULL,
+    event TEXT NOT NULL,
+    signature TEXT NOT NULL,
+    args JSONB NOT NULL,
+    UNIQUE(block_number, log_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_decoded_events_address ON decoded_events(address, event, block_number);
+CREATE INDEX IF NOT EXISTS idx_decoded_events_event ON decoded_events(contract, event, block_number);
+
+-- Tokens moved by ERC-20, ERC-721 and ERC-1155 transfer events
+CREATE TABLE IF NOT EXISTS token_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    block_number BIGINT NOT NULL REFERENCES blocks(number),
+    transaction_hash BYTEA NOT NULL REFERENCES transactions(hash),
+    log_index BIGINT NOT NULL,
+    batch_index BIGINT NOT NULL,
+    token BYTEA NOT NULL,
+    standard VARCHAR(8) NOT NULL,
+    operator BYTEA,
+    "from" BYTEA NOT NULL,
+    "to" BYTEA NOT NULL,
+    token_id NUMERIC(78,0),
+    value NUMERIC(78,0) NOT NULL,
+    UNIQUE(block_number, log_index, batch_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_transfers_token ON token_transfers(token, block_number);
+CREATE INDEX IF NOT EXISTS idx_token_transfers_from ON token_transfers("from", block_number);
+CREATE INDEX IF NOT EXISTS idx_token_transfers_to ON token_transfers("to", block_number);
@@ -0,0 +1,7 @@
+-- Logs are identified by their block and position, so re-inserting the logs of
+-- a block replaces them instead of adding duplicates. Duplicates left behind by
+-- earlier versions are dropped first, keeping the latest copy.
+DELETE FROM logs a USING logs b
+    WHERE a.block_hash = b.block_hash AND a.log_index = b.log_index AND a.id < b.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_logs_block_hash_index ON logs(block_hash, log_index);
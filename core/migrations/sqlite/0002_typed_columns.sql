@@ -0,0 +1,197 @@
+-- Store gas as INTEGER and hashes, addresses and binary data as BLOB instead of
+-- their text encodings. SQLite has no exact integer type wider than 64 bits,
+-- amounts are kept as decimal text. Column types can't be altered in place, so
+-- every table is rebuilt; foreign keys are checked once all of them are done.
+
+CREATE TABLE blocks_new (
+    number BIGINT PRIMARY KEY,
+    hash BLOB NOT NULL UNIQUE,
+    parent_hash BLOB NOT NULL,
+    timestamp TIMESTAMP NOT NULL,
+    nonce BLOB,
+    base_fee_per_gas TEXT,
+    blob_gas_used BIGINT,
+    difficulty TEXT NOT NULL,
+    excess_blob_gas BIGINT,
+    extra_data BLOB NOT NULL,
+    gas_limit BIGINT NOT NULL,
+    gas_used BIGINT NOT NULL,
+    logs_bloom BLOB,
+    miner BLOB NOT NULL,
+    mix_hash BLOB NOT NULL,
+    parent_beacon_block_root BLOB,
+    receipts_root BLOB NOT NULL,
+    sha3_uncles BLOB NOT NULL,
+    size BIGINT,
+    state_root BLOB NOT NULL,
+    total_difficulty TEXT,
+    transactions_root BLOB NOT NULL,
+    withdrawals_root BLOB,
+    seal_fields TEXT,
+    transactions TEXT,
+    uncles TEXT,
+    block_reward TEXT,
+    uncle_reward TEXT,
+    finalized BOOLEAN DEFAULT FALSE
+);
+-- Earlier versions stored an unparseable rendering of the block nonce and never
+-- filled in the size and rewards, these are left empty.
+INSERT INTO blocks_new SELECT
+    number, unhex(substr(hash, 3)), unhex(substr(parent_hash, 3)), timestamp, NULL,
+    NULLIF(base_fee_per_gas, ''), CAST(NULLIF(blob_gas_used, '') AS INTEGER), difficulty,
+    CAST(NULLIF(excess_blob_gas, '') AS INTEGER), unhex(substr(extra_data, 3)),
+    CAST(gas_limit AS INTEGER), CAST(gas_used AS INTEGER), unhex(substr(logs_bloom, 3)),
+    unhex(substr(miner, 3)), unhex(substr(mix_hash, 3)), unhex(substr(parent_beacon_block_root, 3)),
+    unhex(substr(receipts_root, 3)), unhex(substr(sha3_uncles, 3)), CAST(NULLIF(size, '') AS INTEGER),
+    unhex(substr(state_root, 3)), NULLIF(total_difficulty, ''), unhex(substr(transactions_root, 3)),
+    unhex(substr(withdrawals_root, 3)), seal_fields,
+    replace(transactions, '"0x', '"\\x'), replace(uncles, '"0x', '"\\x'),
+    NULLIF(block_reward, ''), NULLIF(uncle_reward, ''), finalized
+FROM blocks;
+DROP TABLE blocks;
+ALTER TABLE blocks_new RENAME TO blocks;
+
+CREATE TABLE transactions_new (
+    hash BLOB PRIMARY KEY,
+    block_number BIGINT NOT NULL REFERENCES blocks(number),
+    "from" BLOB NOT NULL,
+    "to" BLOB,
+    value TEXT NOT NULL,
+    nonce BIGINT NOT NULL,
+    gas_price TEXT NOT NULL,
+    gas_limit BIGINT NOT NULL,
+    gas_used BIGINT NOT NULL,
+    input BLOB NOT NULL,
+    status SMALLINT NOT NULL,
+    type SMALLINT NOT NULL,
+    max_fee_per_gas TEXT,
+    max_priority_fee TEXT,
+    blob_gas_used BIGINT,
+    blob_gas_price TEXT,
+    error TEXT
+);
+INSERT INTO transactions_new SELECT
+    unhex(substr(hash, 3)), block_number, unhex(substr("from", 3)), unhex(substr("to", 3)),
+    value, nonce, gas_price, gas_limit, gas_used, unhex(substr(input, 3)), status, type,
+    NULLIF(max_fee_per_gas, ''), NULLIF(max_priority_fee, ''),
+    CAST(NULLIF(blob_gas_used, '') AS INTEGER), NULLIF(blob_gas_price, ''), error
+FROM transactions;
+DROP TABLE transactions;
+ALTER TABLE transactions_new RENAME TO transactions;
+
+CREATE TABLE logs_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    transaction_hash BLOB NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash BLOB NOT NULL,
+    address BLOB NOT NULL,
+    topics TEXT NOT NULL,
+    data BLOB NOT NULL,
+    log_index BIGINT NOT NULL,
+    removed BOOLEAN NOT NULL DEFAULT FALSE
+);
+INSERT INTO logs_new SELECT
+    id, unhex(substr(transaction_hash, 3)), block_number, unhex(substr(block_hash, 3)),
+    unhex(substr(address, 3)), replace(topics, '"0x', '"\\x'), unhex(substr(data, 3)),
+    log_index, removed
+FROM logs;
+DROP TABLE logs;
+ALTER TABLE logs_new RENAME TO logs;
+
+CREATE TABLE state_changes_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    block_number BIGINT NOT NULL REFERENCES blocks(number),
+    transaction_hash BLOB REFERENCES transactions(hash),
+    address BLOB NOT NULL,
+    storage_key BLOB,
+    prev_value TEXT NOT NULL,
+    new_value TEXT NOT NULL,
+    change_type VARCHAR(20) NOT NULL,
+    source VARCHAR(20) NOT NULL
+);
+INSERT INTO state_changes_new SELECT
+    id, block_number, unhex(substr(transaction_hash, 3)), unhex(substr(address, 3)),
+    unhex(substr(storage_key, 3)), prev_value, new_value, change_type, source
+FROM state_changes;
+DROP TABLE state_changes;
+ALTER TABLE state_changes_new RENAME TO state_changes;
+
+CREATE TABLE access_lists_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    transaction_hash BLOB NOT NULL REFERENCES transactions(hash),
+    address BLOB NOT NULL,
+    storage_key BLOB NOT NULL
+);
+INSERT INTO access_lists_new SELECT
+    id, unhex(substr(transaction_hash, 3)), unhex(substr(address, 3)), unhex(substr(storage_key, 3))
+FROM access_lists;
+DROP TABLE access_lists;
+ALTER TABLE access_lists_new RENAME TO access_lists;
+
+CREATE TABLE accounts_new (
+    address BLOB PRIMARY KEY,
+    balance TEXT NOT NULL,
+    nonce BIGINT NOT NULL,
+    code BLOB,
+    creator_address BLOB,
+    creator_tx_hash BLOB REFERENCES transactions(hash),
+    created_at TIMESTAMP,
+    self_destructed BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_block BIGINT NOT NULL DEFAULT 0
+);
+INSERT INTO accounts_new SELECT
+    unhex(substr(address, 3)), balance, nonce, unhex(substr(code, 3)),
+    unhex(substr(creator_address, 3)), unhex(substr(creator_tx_hash, 3)),
+    created_at, self_destructed, updated_block
+FROM accounts;
+DROP TABLE accounts;
+ALTER TABLE accounts_new RENAME TO accounts;
+
+-- Receipts used to carry the zero address if no contract was created
+CREATE TABLE receipts_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    block_number BIGINT NOT NULL REFERENCES blocks(number),
+    block_hash BLOB NOT NULL,
+    transaction_hash BLOB NOT NULL REFERENCES transactions(hash),
+    transaction_index BIGINT NOT NULL,
+    contract_address BLOB,
+    gas_used BIGINT NOT NULL,
+    status SMALLINT NOT NULL,
+    UNIQUE(transaction_hash)
+);
+INSERT INTO receipts_new SELECT
+    id, block_number, unhex(substr(block_hash, 3)), unhex(substr(transaction_hash, 3)),
+    transaction_index,
+    unhex(substr(NULLIF(NULLIF(contract_address, ''), '0x0000000000000000000000000000000000000000'), 3)),
+    gas_used, status
+FROM receipts;
+DROP TABLE receipts;
+ALTER TABLE receipts_new RENAME TO receipts;
+
+CREATE TABLE reorgs_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    detected_at TIMESTAMP NOT NULL,
+    ancestor_number BIGINT NOT NULL,
+    ancestor_hash BLOB NOT NULL,
+    depth BIGINT NOT NULL,
+    old_head BLOB,
+    new_head BLOB,
+    old_hashes TEXT NOT NULL,
+    new_hashes TEXT NOT NULL
+);
+INSERT INTO reorgs_new SELECT
+    id, detected_at, ancestor_number, unhex(substr(ancestor_hash, 3)), depth,
+    unhex(substr(old_head, 3)), unhex(substr(new_head, 3)),
+    replace(old_hashes, '"0x', '"\\x'), replace(new_hashes, '"0x', '"\\x')
+FROM reorgs;
+DROP TABLE reorgs;
+ALTER TABLE reorgs_new RENAME TO reorgs;
+
+CREATE INDEX idx_logs_address ON logs(address);
+CREATE INDEX idx_logs_block ON logs(block_number);
+CREATE INDEX idx_state_changes_address ON state_changes(address);
+CREATE INDEX idx_state_changes_storage ON state_changes(address, storage_key, block_number);
+CREATE INDEX idx_access_lists_address ON access_lists(address);
+CREATE INDEX idx_accounts_creator ON accounts(creator_address);
+CREATE INDEX idx_receipts_block ON receipts(block_number);
+CREATE INDEX idx_receipts_contract ON receipts(contract_address);
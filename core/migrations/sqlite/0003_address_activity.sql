@@ -0,0 +1,8 @@
+-- Transactions carry their position in the block, so that the activity of an
+-- address can be paginated in chain order.
+ALTER TABLE transactions ADD COLUMN transaction_index BIGINT NOT NULL DEFAULT 0;
+UPDATE transactions SET transaction_index = COALESCE(
+    (SELECT transaction_index FROM receipts WHERE receipts.transaction_hash = transactions.hash), 0);
+
+CREATE INDEX idx_transactions_from ON transactions("from", block_number, transaction_index);
+CREATE INDEX idx_transactions_to ON transactions("to", block_number, transaction_index);
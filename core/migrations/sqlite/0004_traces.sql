@@ -0,0 +1,23 @@
+-- Call frames executed by transactions, the internal transactions of Parity
+-- style traces. Only filled in if trace indexing is enabled.
+CREATE TABLE traces (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    block_number BIGINT NOT NULL REFERENCES blocks(number),
+    transaction_hash BLOB NOT NULL REFERENCES transactions(hash),
+    trace_index BIGINT NOT NULL,
+    trace_address TEXT NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    "from" BLOB NOT NULL,
+    "to" BLOB,
+    value TEXT,
+    gas BIGINT NOT NULL,
+    gas_used BIGINT NOT NULL,
+    input BLOB NOT NULL,
+    output BLOB,
+    error TEXT,
+    UNIQUE(transaction_hash, trace_index)
+);
+
+CREATE INDEX idx_traces_block ON traces(block_number);
+CREATE INDEX idx_traces_from ON traces("from", block_number);
+CREATE INDEX idx_traces_to ON traces("to", block_number);
@@ -0,0 +1,38 @@
+-- Logs decoded into the events of known contract ABIs. Only filled in if event
+-- decoding is enabled.
+CREATE TABLE decoded_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    block_number BIGINT NOT NULL REFERENCES blocks(number),
+    transaction_hash BLOB NOT NULL REFERENCES transactions(hash),
+    log_index BIGINT NOT NULL,
+    address BLOB NOT NULL,
+    contract TEXT NOT NULL,
+    event TEXT NOT NULL,
+    signature TEXT NOT NULL,
+    args TEXT NOT NULL,
+    UNIQUE(block_number, log_index)
+);
+
+CREATE INDEX idx_decoded_events_address ON decoded_events(address, event, block_number);
+CREATE INDEX idx_decoded_events_event ON decoded_events(contract, event, block_number);
+
+-- Tokens moved by ERC-20, ERC-721 and ERC-1155 transfer events
+CREATE TABLE token_transfers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    block_number BIGINT NOT NULL REFERENCES blocks(number),
+    transaction_hash BLOB NOT NULL REFERENCES transactions(hash),
+    log_index BIGINT NOT NULL,
+    batch_index BIGINT NOT NULL,
+    token BLOB NOT NULL,
+    standard VARCHAR(8) NOT NULL,
+    operator BLOB,
+    "from" BLOB NOT NULL,
+    "to" BLOB NOT NULL,
+    token_id TEXT,
+    value TEXT NOT NULL,
+    UNIQUE(block_number, log_index, batch_index)
+);
+
+CREATE INDEX idx_token_transfers_token ON token_transfers(token, block_number);
+CREATE INDEX idx_token_transfers_from ON token_transfers("from", block_number);
+CREATE INDEX idx_token_transfers_to ON token_transfers("to", block_number);
@@ -0,0 +1,27 @@
+-- Validator withdrawals (EIP-4895) credited by the blocks, amounts in Gwei
+CREATE TABLE withdrawals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    block_number BIGINT NOT NULL REFERENCES blocks(number),
+    withdrawal_index BIGINT NOT NULL,
+    validator_index BIGINT NOT NULL,
+    address BLOB NOT NULL,
+    amount BIGINT NOT NULL,
+    UNIQUE(block_number, withdrawal_index)
+);
+
+CREATE INDEX idx_withdrawals_validator ON withdrawals(validator_index, block_number);
+CREATE INDEX idx_withdrawals_address ON withdrawals(address, block_number);
+
+-- Versioned hashes of the blobs carried by blob transactions (EIP-4844)
+CREATE TABLE blob_hashes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    block_number BIGINT NOT NULL REFERENCES blocks(number),
+    transaction_hash BLOB NOT NULL REFERENCES transactions(hash),
+    blob_index BIGINT NOT NULL,
+    versioned_hash BLOB NOT NULL,
+    blob_gas_price TEXT,
+    UNIQUE(transaction_hash, blob_index)
+);
+
+CREATE INDEX idx_blob_hashes_block ON blob_hashes(block_number);
+CREATE INDEX idx_blob_hashes_versioned_hash ON blob_hashes(versioned_hash);
@@ -0,0 +1,8 @@
+-- Logs are identified by their block and position, so re-inserting the logs of
+-- a block replaces them instead of adding duplicates. Duplicates left behind by
+-- earlier versions are dropped first, keeping the latest copy.
+DELETE FROM logs WHERE id NOT IN (
+    SELECT MAX(id) FROM logs GROUP BY block_hash, log_index
+);
+
+CREATE UNIQUE INDEX idx_logs_block_hash_index ON logs(block_hash, log_index);
@@ -0,0 +1,4 @@
+-- Logs are mostly filtered by their event signature, the first topic. Topics
+-- are stored as a bytea array literal, {"\\x<hex>",...}, so the first one is
+-- indexed by its hex digits. Queries must use the same expression to hit it.
+CREATE INDEX idx_logs_topic0 ON logs(substr(topics, 6, 64), block_number);
@@ -2,57 +2,474 @@ package core
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"runtime/debug"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
-// Plugin defines the interface for blockchain plugins
+// Plugin defines the interface for blockchain plugins. Plugins are attached to
+// a BlockChain and receive lifecycle and chain events in registration order.
+// Errors and panics raised by a plugin are logged and contained, they never
+// interrupt block processing or the remaining plugins.
+//
+// OnReorg is invoked with the headers dropped from and added to the canonical
+// chain, both in ascending order. The added ones include the new head, which
+// is announced via OnHead as well. When the chain is extended by several blocks
+// at once, e.g. by a forkchoice update, OnReorg is invoked with no dropped
+// headers.
 type Plugin interface {
-	OnInit(chain *BlockChain)
-	OnHead(header *types.Header)
-	OnFinal(header *types.Header)
-	OnClose()
-	OnReorg(oldHeaders, newHeaders []*types.Header)
+	OnInit(chain *BlockChain) error
+	OnHead(header *types.Header) error
+	OnFinal(header *types.Header) error
+	OnClose() error
+	OnReorg(oldHeaders, newHeaders []*types.Header) error
+}
+
+// TracingPlugin is implemented by plugins which additionally want to observe
+// block execution. The returned hooks are merged with the chain's configured
+// live tracer when the plugin is registered and are invoked inline while
+// blocks are processed.
+type TracingPlugin interface {
+	Plugin
+	Hooks() *tracing.Hooks
+}
+
+// pluginRegistry is the ordered set of plugins attached to a blockchain.
+type pluginRegistry struct {
+	plugins []Plugin
+	lock    sync.RWMutex
+
+	dispatching int      // Dispatches running under the chain lock
+	pending     []Plugin // Registrations deferred until the dispatches are done
+	pendingLock sync.Mutex
+}
+
+// register initializes the given plugin and, if that succeeds, appends it to
+// the set of plugins receiving chain events.
+func (r *pluginRegistry) register(chain *BlockChain, plugin Plugin) error {
+	if plugin == nil {
+		return errors.New("nil plugin")
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if slices.Contains(r.plugins, plugin) {
+		return fmt.Errorf("plugin %T already registered", plugin)
+	}
+	if err := callPlugin(plugin, "init", func() error { return plugin.OnInit(chain) }); err != nil {
+		return err
+	}
+	r.plugins = append(r.plugins, plugin)
+	return nil
+}
+
+// list returns a copy of the registered plugins.
+func (r *pluginRegistry) list() []Plugin {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return slices.Clone(r.plugins)
+}
+
+// each invokes the given hook on every registered plugin in order. Failures are
+// logged and do not prevent the hook from reaching the remaining plugins.
+// The hooks run on a snapshot of the plugins, plugins registered meanwhile only
+// receive later events.
+func (r *pluginRegistry) each(hook string, fn func(p Plugin) error) {
+	for _, plugin := range r.list() {
+		if err := callPlugin(plugin, hook, func() error { return fn(plugin) }); err != nil {
+			log.Error("Plugin hook failed", "plugin", fmt.Sprintf("%T", plugin), "hook", hook, "err", err)
+		}
+	}
+}
+
+func (r *pluginRegistry) onHead(header *types.Header) {
+	r.each("head", func(p Plugin) error { return p.OnHead(header) })
+}
+
+func (r *pluginRegistry) onFinal(header *types.Header) {
+	r.each("final", func(p Plugin) error { return p.OnFinal(header) })
+}
+
+func (r *pluginRegistry) onReorg(oldHeaders, newHeaders []*types.Header) {
+	r.each("reorg", func(p Plugin) error { return p.OnReorg(oldHeaders, newHeaders) })
+}
+
+// close delivers the close event to all plugins and detaches them.
+func (r *pluginRegistry) close() {
+	r.each("close", func(p Plugin) error { return p.OnClose() })
+
+	r.lock.Lock()
+	r.plugins = nil
+	r.lock.Unlock()
+}
+
+// RegisterPlugin attaches a plugin to the blockchain. The plugin's OnInit hook
+// is invoked immediately; if it fails, the plugin is not registered. Plugins
+// receive chain events in the order they were registered.
+//
+// While chain events are dispatched under the chain lock, i.e. OnHead and
+// OnReorg, registering can't wait for the lock: the hooks themselves may be
+// the ones registering. The registration is deferred until the dispatch is
+// done instead, and a failing OnInit is only logged.
+func (bc *BlockChain) RegisterPlugin(plugin Plugin) error {
+	if plugin == nil {
+		return errors.New("nil plugin")
+	}
+	bc.plugins.pendingLock.Lock()
+	if bc.plugins.dispatching > 0 {
+		bc.plugins.pending = append(bc.plugins.pending, plugin)
+		bc.plugins.pendingLock.Unlock()
+		return nil
+	}
+	bc.plugins.pendingLock.Unlock()
+
+	// Hold the chain lock so hooks aren't swapped out under block processing.
+	// Taking it waits for any running import, it only fails once the chain is
+	// stopped.
+	if bc.stopping.Load() || !bc.chainmu.TryLock() {
+		return errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	return bc.registerPlugin(plugin)
+}
+
+// registerPlugin attaches a plugin to the blockchain, merging its tracing hooks
+// into the VM config. The chain lock must be held.
+func (bc *BlockChain) registerPlugin(plugin Plugin) error {
+	if err := bc.plugins.register(bc, plugin); err != nil {
+		return err
+	}
+	if tp, ok := plugin.(TracingPlugin); ok {
+		if hooks := tp.Hooks(); hooks != nil {
+			bc.logger = combineHooks(bc.logger, hooks)
+			bc.vmConfig.Tracer = bc.logger
+
+			// Readers outside the chain lock get a copy, don't modify theirs
+			config := bc.vmConfig
+			bc.publicVMConfig.Store(&config)
+		}
+	}
+	return nil
+}
+
+// dispatchLocked runs a plugin event dispatch while the chain lock is held.
+// Plugins registered meanwhile are registered once it's done.
+func (bc *BlockChain) dispatchLocked(dispatch func(r *pluginRegistry)) {
+	r := &bc.plugins
+
+	r.pendingLock.Lock()
+	r.dispatching++
+	r.pendingLock.Unlock()
+
+	dispatch(r)
+
+	// Registrations made by OnInit of the deferred plugins are deferred too,
+	// drain them all before leaving
+	for {
+		r.pendingLock.Lock()
+		if r.dispatching > 1 || len(r.pending) == 0 {
+			r.dispatching--
+			r.pendingLock.Unlock()
+			return
+		}
+		pending := r.pending
+		r.pending = nil
+		r.pendingLock.Unlock()
+
+		for _, plugin := range pending {
+			if err := bc.registerPlugin(plugin); err != nil {
+				log.Error("Deferred plugin registration failed", "plugin", fmt.Sprintf("%T", plugin), "err", err)
+			}
+		}
+	}
+}
+
+// Plugins returns the plugins currently attached to the blockchain.
+func (bc *BlockChain) Plugins() []Plugin {
+	return bc.plugins.list()
+}
+
+// callPlugin runs a single plugin hook, converting a panic into an error.
+func callPlugin(plugin Plugin, hook string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Plugin hook panicked", "plugin", fmt.Sprintf("%T", plugin), "hook", hook, "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("plugin %T panicked in %s: %v", plugin, hook, r)
+		}
+	}()
+	return fn()
+}
+
+// indexerRanges serializes the writers of the indexer database by the block
+// ranges they touch. Backfill batches run alongside each other and the live
+// indexer, but never on overlapping blocks, so every writer sees the blocks it
+// is about to replace as committed by the previous one.
+type indexerRanges struct {
+	held [][2]uint64 // Inclusive ranges currently being written
+	lock sync.Mutex
+	cond *sync.Cond
+}
+
+// acquire blocks until no held range overlaps [first, last] and takes it,
+// returning the function releasing it.
+func (r *indexerRanges) acquire(first, last uint64) func() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.cond == nil {
+		r.cond = sync.NewCond(&r.lock)
+	}
+	for r.overlaps(first, last) {
+		r.cond.Wait()
+	}
+	span := [2]uint64{first, last}
+	r.held = append(r.held, span)
+
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		for i, held := range r.held {
+			if held == span {
+				r.held = append(r.held[:i], r.held[i+1:]...)
+				break
+			}
+		}
+		r.cond.Broadcast()
+	}
+}
+
+// overlaps reports whether a held range overlaps [first, last].
+//
+// Note, this function assumes that the lock is held!
+func (r *indexerRanges) overlaps(first, last uint64) bool {
+	for _, held := range r.held {
+		if first <= held[1] && held[0] <= last {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeadlock reports whether a database error aborted a transaction to break a
+// deadlock, in which case retrying it is expected to succeed.
+func isDeadlock(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "40P01"
 }
 
 // IndexerPlugin implements blockchain indexing functionality
 type IndexerPlugin struct {
-	db    *IndexerDB
-	chain *BlockChain
+	db      IndexerDB
+	chain   *BlockChain
+	tracer  *indexerTracer // Execution details of processed blocks
+	decoder *EventDecoder  // Decoder of the logs into known events, nil if disabled
+	sink    IndexerSink    // Export of the indexed records, nil if disabled
+
+	decodedEvents  bool   // Whether decoded events are stored
+	tokenTransfers bool   // Whether token transfers are stored
+	startBlock     uint64 // First block indexed, older chain events are ignored
+	batchSize      uint64 // Default batch size of backfills
+	maxLag         uint64 // Blocks the indexer may trail the chain head while healthy
+	retries        int    // Attempts at a chain event before its blocks are deferred
+
+	queue *indexerQueue  // Chain events waiting to be indexed
+	quit  chan struct{}  // Termination channel of the queue processor
+	wg    sync.WaitGroup // Tracker of the queue processor
+
+	backfill     *backfillTask // Running or last historical backfill
+	backfillLock sync.Mutex
+
+	health  indexerHealth // Progress and failures reported by the healthcheck
+	writing indexerRanges // Block ranges being written, to serialize overlapping writers
+
+	exports    []sinkWrite // Writes of committed transactions not yet exported
+	exportLock sync.Mutex
+
+	indexedFeed event.Feed // Announces blocks committed to the database
 }
 
 // NewIndexerPlugin creates a new indexer plugin instance
-func NewIndexerPlugin(db *IndexerDB) *IndexerPlugin {
+func NewIndexerPlugin(db IndexerDB) *IndexerPlugin {
 	if db == nil {
 		log.Info("Creating indexer plugin without database connection")
 		return &IndexerPlugin{}
 	}
 	log.Info("Creating new indexer plugin with database connection")
 	return &IndexerPlugin{
-		db: db,
+		db:      db,
+		tracer:  newIndexerTracer(),
+		maxLag:  defaultIndexerMaxLag,
+		retries: indexerRetryLimit,
+	}
+}
+
+// SubscribeIndexedBlocks registers a subscription of IndexedBlockEvent, posted
+// once the new canonical blocks and finalizations are committed to the database.
+// Blocks indexed by a backfill are not announced.
+func (p *IndexerPlugin) SubscribeIndexedBlocks(ch chan<- IndexedBlockEvent) event.Subscription {
+	return p.indexedFeed.Subscribe(ch)
+}
+
+// EnableTraces makes the plugin index the call frames of all transactions into
+// the traces table. It must be called before the plugin is attached to a chain.
+func (p *IndexerPlugin) EnableTraces() {
+	if p.tracer != nil {
+		p.tracer.calls = true
+	}
+}
+
+// SetEventDecoder makes the plugin decode the logs of all blocks into known
+// events and token transfers.
+func (p *IndexerPlugin) SetEventDecoder(decoder *EventDecoder) {
+	p.decoder = decoder
+	p.decodedEvents, p.tokenTransfers = true, true
+}
+
+// SetSink makes the plugin export the records of all indexed blocks into the
+// given sink, in addition to the database. The sink is closed along with the
+// plugin. It must be called before the plugin is attached to a chain.
+func (p *IndexerPlugin) SetSink(sink IndexerSink) {
+	p.sink = sink
+}
+
+// Configure applies the plugin settings of the indexer config: the optional
+// tables to fill, the ABIs to decode events with, the file sink to export to,
+// the first block to index, the default backfill batch size and the lag
+// tolerated by the healthcheck. It must be called before the plugin is
+// attached to a chain.
+func (p *IndexerPlugin) Configure(config IndexerConfig) error {
+	for _, table := range config.Tables {
+		switch table {
+		case IndexerTableTraces:
+			p.EnableTraces()
+		case IndexerTableDecodedEvents:
+			p.decodedEvents = true
+		case IndexerTableTokenTransfers:
+			p.tokenTransfers = true
+		default:
+			return fmt.Errorf("unknown indexer table %q", table)
+		}
+	}
+	// Configuring ABIs only makes sense if the decoded events are wanted
+	if config.ABIDir != "" {
+		p.decodedEvents = true
 	}
+	if p.decodedEvents || p.tokenTransfers {
+		decoder, err := NewEventDecoder(config.ABIDir)
+		if err != nil {
+			return err
+		}
+		p.decoder = decoder
+	}
+	if config.Sink != "" {
+		sink, err := NewFileSink(config.SinkDir, config.Sink, config.SinkPartition)
+		if err != nil {
+			return err
+		}
+		p.sink = sink
+	}
+	p.startBlock = config.StartBlock
+	p.batchSize = config.BatchSize
+	if config.MaxLag != 0 {
+		p.maxLag = config.MaxLag
+	}
+	return nil
+}
+
+// DB returns the database the plugin indexes into, nil if there's none.
+func (p *IndexerPlugin) DB() IndexerDB {
+	return p.db
+}
+
+// Hooks implements TracingPlugin, capturing the execution results which are
+// not part of the stored receipts.
+func (p *IndexerPlugin) Hooks() *tracing.Hooks {
+	if p.db == nil {
+		return nil
+	}
+	return p.tracer.hooks()
 }
 
 // OnInit implements Plugin
-func (p *IndexerPlugin) OnInit(bc *BlockChain) {
+func (p *IndexerPlugin) OnInit(bc *BlockChain) error {
 	if p.db == nil {
 		log.Info("Initializing indexer plugin without database - indexing disabled")
-		return
+		return nil
 	}
 	log.Info("Initializing indexer plugin", "chainID", bc.Config().ChainID)
 	p.chain = bc
+	p.tracer.config = bc.Config()
+	p.queue = newIndexerQueue(bc.db, indexerQueueLimit)
+	p.quit = make(chan struct{})
+
+	// Resume the health tracking from the database, the queue catches up on
+	// anything missed
+	latest, err := p.db.GetLatestBlock()
+	if err != nil {
+		log.Warn("Failed to retrieve latest indexed block", "err", err)
+	}
+	p.health.indexed(latest)
+	if err := metrics.Register(indexerHealthcheck, p.healthcheck()); err != nil {
+		log.Warn("Failed to register indexer healthcheck", "err", err)
+	}
+
+	p.wg.Add(1)
+	go p.loop()
+	return nil
 }
 
-// OnHead is called whenever a new head block is set
-func (p *IndexerPlugin) OnHead(header *types.Header) {
+// OnHead is called whenever a new head block is set. The block is queued and
+// indexed in the background.
+func (p *IndexerPlugin) OnHead(header *types.Header) error {
 	if p.db == nil {
-		return
+		return nil
+	}
+	p.queue.push(newIndexerJob(indexerJobHead, nil, []*types.Header{header}))
+	return nil
+}
+
+// processHead indexes a new head block. Blocks already indexed are skipped and
+// stale ones at the same height replaced, so events may safely be repeated.
+// Blocks indexed past the chain head are dropped, as a rewind of the chain,
+// e.g. by SetHead, only announces the new lower head.
+func (p *IndexerPlugin) processHead(header *types.Header) error {
+	number := header.Number.Uint64()
+	if number < p.startBlock {
+		return nil
+	}
+	release := p.writing.acquire(number, math.MaxUint64)
+	defer release()
+
+	dropped, err := p.droppedBlocks(max(number, p.chain.CurrentBlock().Number.Uint64()))
+	if err != nil {
+		return err
+	}
+	hashes, err := p.db.GetBlockHashes(number, number)
+	if err != nil {
+		return err
+	}
+	have, indexed := hashes[number]
+	current := indexed && have == header.Hash()
+	if current && dropped == nil {
+		log.Debug("Block already indexed", "number", number, "hash", header.Hash())
+		return nil
 	}
 	log.Info("Indexer processing new head block",
 		"number", header.Number,
@@ -60,182 +477,704 @@ func (p *IndexerPlugin) OnHead(header *types.Header) {
 		"parent", header.ParentHash,
 		"timestamp", time.Unix(int64(header.Time), 0))
 
-	tx, err := p.db.db.Beginx()
+	tx, err := p.db.Begin()
 	if err != nil {
-		log.Error("Failed to begin transaction",
-			"block", header.Number,
-			"hash", header.Hash(),
-			"error", err)
-		return
+		return fmt.Errorf("failed to begin transaction for block %d: %v", header.Number, err)
 	}
 	defer tx.Rollback()
 
+	var writes []sinkWrite
+	if dropped != nil {
+		log.Info("Indexer dropping blocks past the chain head", "head", dropped.AncestorNumber, "dropped", dropped.Depth)
+		if err := p.db.DeleteBlockAndDescendantsWithTx(tx, dropped.AncestorNumber+1); err != nil {
+			return fmt.Errorf("failed to delete blocks from %d: %v", dropped.AncestorNumber+1, err)
+		}
+		if err := p.db.InsertReorgWithTx(tx, dropped); err != nil {
+			return err
+		}
+		writes = append(writes, sinkWrite{reorg: dropped})
+	}
+	if !current {
+		if indexed {
+			reorg, err := p.deleteStaleBlock(tx, header, have)
+			if err != nil {
+				return err
+			}
+			writes = append(writes, sinkWrite{reorg: reorg})
+		}
+		records, err := p.indexBlock(tx, header)
+		if err != nil {
+			return err
+		}
+		writes = append(writes, sinkWrite{block: records})
+	}
+	if err := commitIndexerTx(tx); err != nil {
+		return fmt.Errorf("failed to commit block %d: %v", header.Number, err)
+	}
+	p.health.indexed(number)
+	if !current {
+		p.indexedFeed.Send(IndexedBlockEvent{Header: header})
+	}
+	if head := p.chain.CurrentBlock().Number.Uint64(); head > number {
+		indexerLagGauge.Update(int64(head - number))
+	}
+	log.Info("Successfully indexed block",
+		"number", header.Number,
+		"hash", header.Hash())
+	return p.export(writes...)
+}
+
+// sinkWrite is an export of either the records of a block or a reorg, held back
+// until the database transaction producing it committed.
+type sinkWrite struct {
+	block *BlockRecords
+	reorg *Reorg
+}
+
+// export hands the writes of a committed database transaction to the sink,
+// after the ones left over by earlier failures. Writes are dropped as they
+// succeed, so a failed export resumes where it stopped when called again.
+func (p *IndexerPlugin) export(writes ...sinkWrite) error {
+	if p.sink == nil {
+		return nil
+	}
+	p.exportLock.Lock()
+	defer p.exportLock.Unlock()
+
+	p.exports = append(p.exports, writes...)
+	for len(p.exports) > 0 {
+		if write := p.exports[0]; write.block != nil {
+			if err := p.sink.WriteBlock(write.block); err != nil {
+				return err
+			}
+		} else if err := p.sink.WriteReorg(write.reorg); err != nil {
+			return fmt.Errorf("failed to export reorg: %v", err)
+		}
+		p.exports = p.exports[1:]
+	}
+	return nil
+}
+
+// deleteStaleBlock deletes the block of a side chain left behind at the height
+// of a canonical header, returning the reorg recording it as dropped.
+func (p *IndexerPlugin) deleteStaleBlock(tx *sqlx.Tx, header *types.Header, stale common.Hash) (*Reorg, error) {
+	number := header.Number.Uint64()
+	if err := p.db.DeleteBlockWithTx(tx, number); err != nil {
+		return nil, fmt.Errorf("failed to delete stale block %d: %v", number, err)
+	}
+	reorg := &Reorg{
+		DetectedAt:     time.Now(),
+		AncestorNumber: number - 1,
+		AncestorHash:   header.ParentHash,
+		Depth:          1,
+		OldHead:        &stale,
+		OldHashes:      pq.ByteaArray{stale.Bytes()},
+	}
+	return reorg, nil
+}
+
+// indexBlock writes a canonical block along with its transactions, receipts,
+// logs, state changes and touched accounts using the given database transaction,
+// returning the records to export once it committed. Execution details are only
+// available if the block was processed while the plugin was attached, otherwise
+// only what's stored in the chain is indexed.
+func (p *IndexerPlugin) indexBlock(tx *sqlx.Tx, header *types.Header) (*BlockRecords, error) {
+	defer indexerBlockTimer.UpdateSince(time.Now())
+
+	body := p.chain.GetBlock(header.Hash(), header.Number.Uint64())
+	if body == nil {
+		return nil, fmt.Errorf("block %d [%x] not found", header.Number, header.Hash())
+	}
 	// Create base block record
 	block := &Block{
-		Number:                header.Number.Uint64(),
-		Hash:                  header.Hash().Hex(),
-		ParentHash:            header.ParentHash.Hex(),
-		Timestamp:             time.Unix(int64(header.Time), 0),
-		Nonce:                 fmt.Sprintf("%d", header.Nonce),
-		BaseFeePerGas:         sql.NullString{String: header.BaseFee.String(), Valid: header.BaseFee != nil},
-		BlobGasUsed:           sql.NullString{String: fmt.Sprintf("%d", *header.BlobGasUsed), Valid: header.BlobGasUsed != nil},
-		Difficulty:            header.Difficulty.String(),
-		ExcessBlobGas:         sql.NullString{String: fmt.Sprintf("%d", *header.ExcessBlobGas), Valid: header.ExcessBlobGas != nil},
-		ExtraData:             fmt.Sprintf("0x%x", header.Extra),
-		GasLimit:              fmt.Sprintf("%d", header.GasLimit),
-		GasUsed:               fmt.Sprintf("%d", header.GasUsed),
-		LogsBloom:             sql.NullString{String: fmt.Sprintf("0x%x", header.Bloom), Valid: true},
-		Miner:                 header.Coinbase.Hex(),
-		MixHash:               header.MixDigest.Hex(),
-		ParentBeaconBlockRoot: sql.NullString{String: header.ParentBeaconRoot.String(), Valid: header.ParentBeaconRoot != nil},
-		ReceiptsRoot:          header.ReceiptHash.Hex(),
-		Sha3Uncles:            header.UncleHash.Hex(),
-		StateRoot:             header.Root.Hex(),
-		TransactionsRoot:      header.TxHash.Hex(),
-		WithdrawalsRoot:       sql.NullString{String: header.WithdrawalsHash.String(), Valid: header.WithdrawalsHash != nil},
+		Number:           header.Number.Uint64(),
+		Hash:             header.Hash(),
+		ParentHash:       header.ParentHash,
+		Timestamp:        time.Unix(int64(header.Time), 0),
+		Nonce:            common.CopyBytes(header.Nonce[:]),
+		Difficulty:       NewBigInt(header.Difficulty),
+		ExtraData:        common.CopyBytes(header.Extra),
+		GasLimit:         header.GasLimit,
+		GasUsed:          header.GasUsed,
+		LogsBloom:        header.Bloom.Bytes(),
+		Miner:            header.Coinbase,
+		MixHash:          header.MixDigest,
+		ReceiptsRoot:     header.ReceiptHash,
+		Sha3Uncles:       header.UncleHash,
+		StateRoot:        header.Root,
+		TransactionsRoot: header.TxHash,
+		Transactions:     make(pq.ByteaArray, len(body.Transactions())),
+		Uncles:           make(pq.ByteaArray, len(body.Uncles())),
+	}
+	if block.ExtraData == nil {
+		block.ExtraData = []byte{}
 	}
+	size := body.Size()
+	block.Size = &size
+	for i, transaction := range body.Transactions() {
+		block.Transactions[i] = transaction.Hash().Bytes()
+	}
+	for i, uncle := range body.Uncles() {
+		block.Uncles[i] = uncle.Hash().Bytes()
+	}
+	// Fill in the fields introduced by later forks
+	block.BaseFeePerGas = NewBigInt(header.BaseFee)
+	block.BlobGasUsed = header.BlobGasUsed
+	block.ExcessBlobGas = header.ExcessBlobGas
+	block.ParentBeaconBlockRoot = header.ParentBeaconRoot
+	block.WithdrawalsRoot = header.WithdrawalsHash
 
 	// Insert the block
 	if err := p.db.InsertBlockWithTx(tx, block); err != nil {
-		log.Error("Failed to index block",
-			"number", block.Number,
-			"hash", block.Hash,
-			"error", err)
-		return
+		return nil, fmt.Errorf("failed to index block %d: %v", block.Number, err)
 	}
+	records := &BlockRecords{Block: block}
 
-	// Get receipts from chain
+	// Get the receipts from chain
 	receipts := p.chain.GetReceiptsByHash(header.Hash())
+	if len(receipts) != len(body.Transactions()) {
+		return nil, fmt.Errorf("receipt count mismatch for block %d: have %d, want %d", block.Number, len(receipts), len(body.Transactions()))
+	}
 	log.Debug("Processing receipts",
 		"block", block.Number,
 		"count", len(receipts),
 		"hash", block.Hash)
 
-	for i, receipt := range receipts {
-		txHash := receipt.TxHash.Hex()
+	// Index the transactions first, receipts and logs reference them
+	var (
+		signer = types.MakeSigner(p.chain.Config(), header.Number, header.Time)
+		trace  = p.tracer.trace(header.Hash())
+	)
+	for i, transaction := range body.Transactions() {
+		t, err := newTransaction(signer, header, transaction, receipts[i], trace)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.db.InsertTransactionWithTx(tx, t); err != nil {
+			return nil, fmt.Errorf("failed to index transaction %s in block %d: %v", t.Hash, block.Number, err)
+		}
+		records.Transactions = append(records.Transactions, t)
 
-		// Index the receipt
-		r := &Receipt{
-			BlockNumber:      header.Number.Uint64(),
-			BlockHash:        header.Hash().Hex(),
-			TransactionHash:  txHash,
-			TransactionIndex: uint(i),
-			ContractAddress:  receipt.ContractAddress.Hex(),
-			GasUsed:          receipt.GasUsed,
-			Status:           receipt.Status,
+		for _, blob := range newBlobHashes(t, transaction) {
+			if err := p.db.InsertBlobHashWithTx(tx, blob); err != nil {
+				return nil, fmt.Errorf("failed to index blob %d of %s in block %d: %v", blob.BlobIndex, t.Hash, block.Number, err)
+			}
+			records.BlobHashes = append(records.BlobHashes, blob)
+		}
+		if trace == nil {
+			continue
 		}
+		if txTrace := trace.txs[t.Hash]; txTrace != nil {
+			for _, call := range txTrace.calls {
+				if err := p.db.InsertTraceWithTx(tx, call); err != nil {
+					return nil, fmt.Errorf("failed to index trace %d of %s in block %d: %v", call.TraceIndex, t.Hash, block.Number, err)
+				}
+			}
+			records.Traces = append(records.Traces, txTrace.calls...)
+		}
+	}
+
+	for i, receipt := range receipts {
+		txHash := receipt.TxHash
 
+		// Index the receipt
+		r := newReceipt(block.Number, block.Hash, body.Transactions()[i], receipt, i)
 		if err := p.db.InsertReceiptWithTx(tx, r); err != nil {
-			log.Error("Failed to index receipt",
-				"block", block.Number,
-				"tx", txHash,
-				"error", err)
-			continue
+			return nil, fmt.Errorf("failed to index receipt %s in block %d: %v", txHash, block.Number, err)
 		}
+		records.Receipts = append(records.Receipts, r)
 
 		// Index the logs
 		for _, logEntry := range receipt.Logs {
-			l := &Log{
-				BlockNumber:     header.Number.Uint64(),
-				TransactionHash: txHash,
-				LogIndex:        uint64(logEntry.Index),
-				Address:         logEntry.Address.Hex(),
-				Topics:          make([]string, len(logEntry.Topics)),
-				Data:            hexutil.Encode(logEntry.Data),
+			l := newLog(block.Number, block.Hash, txHash, logEntry)
+			if err := p.db.InsertLogWithTx(tx, l); err != nil {
+				return nil, fmt.Errorf("failed to index log %d of %s in block %d: %v", l.LogIndex, txHash, block.Number, err)
 			}
-			for i, topic := range logEntry.Topics {
-				l.Topics[i] = topic.Hex()
+			records.Logs = append(records.Logs, l)
+			if err := p.indexDecodedLog(tx, logEntry, records); err != nil {
+				return nil, err
 			}
-			if err := p.db.InsertLogWithTx(tx, l); err != nil {
-				log.Error("Failed to index log",
-					"block", block.Number,
-					"txHash", txHash,
-					"logIndex", l.LogIndex,
-					"error", err)
-				continue
+		}
+	}
+
+	// Index the validator withdrawals credited by the block
+	for _, withdrawal := range body.Withdrawals() {
+		w := &Withdrawal{
+			BlockNumber:     block.Number,
+			WithdrawalIndex: withdrawal.Index,
+			ValidatorIndex:  withdrawal.Validator,
+			Address:         withdrawal.Address,
+			Amount:          withdrawal.Amount,
+		}
+		if err := p.db.InsertWithdrawalWithTx(tx, w); err != nil {
+			return nil, fmt.Errorf("failed to index withdrawal %d in block %d: %v", w.WithdrawalIndex, block.Number, err)
+		}
+		records.Withdrawals = append(records.Withdrawals, w)
+	}
+
+	// Index the state changes captured while the block was processed
+	if trace != nil {
+		for _, change := range trace.stateChanges {
+			if err := p.db.InsertStateChangeWithTx(tx, change); err != nil {
+				return nil, fmt.Errorf("failed to index state change of %s in block %d: %v", change.Address, block.Number, err)
 			}
 		}
+		records.StateChanges = trace.stateChanges
+	} else {
+		log.Debug("No execution trace for block, skipping state changes", "number", block.Number, "hash", block.Hash)
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		log.Error("Failed to commit transaction",
-			"block", block.Number,
-			"hash", block.Hash,
-			"error", err)
-		return
+	if err := p.indexAccounts(tx, signer, body, receipts, trace); err != nil {
+		return nil, err
 	}
+	return records, nil
+}
 
-	log.Info("Successfully indexed block",
-		"number", block.Number,
-		"hash", block.Hash,
-		"txCount", len(receipts))
+// indexAccounts updates the accounts touched by a block from its post-state,
+// which is usually gone for historical blocks on a pruned node.
+func (p *IndexerPlugin) indexAccounts(tx *sqlx.Tx, signer types.Signer, block *types.Block, receipts types.Receipts, trace *blockTrace) error {
+	if !p.chain.HasState(block.Root()) {
+		log.Debug("Block state unavailable, skipping account updates", "number", block.Number(), "hash", block.Hash())
+		return nil
+	}
+	statedb, err := p.chain.StateAt(block.Root())
+	if err != nil {
+		return fmt.Errorf("failed to open state of block %d: %v", block.Number(), err)
+	}
+	accounts, err := newAccounts(statedb, signer, block, receipts, trace)
+	if err != nil {
+		return err
+	}
+	// Upsert in address order, so concurrent writers lock the rows in the same
+	// order instead of deadlocking on each other
+	slices.SortFunc(accounts, func(a, b *Account) int { return a.Address.Cmp(b.Address) })
+	for _, account := range accounts {
+		if err := p.db.UpsertAccountWithTx(tx, account); err != nil {
+			return fmt.Errorf("failed to index account %s in block %d: %v", account.Address, block.Number(), err)
+		}
+	}
+	return nil
 }
 
 // OnFinal implements Plugin
-func (p *IndexerPlugin) OnFinal(header *types.Header) {
+func (p *IndexerPlugin) OnFinal(header *types.Header) error {
 	if p.db == nil {
-		return
+		return nil
 	}
+	p.queue.push(newIndexerJob(indexerJobFinal, nil, []*types.Header{header}))
+	return nil
+}
+
+// processFinal marks a block as finalized.
+func (p *IndexerPlugin) processFinal(header *types.Header) error {
 	log.Info("Indexer processing finalized block",
 		"number", header.Number,
 		"hash", header.Hash())
 
 	if err := p.db.MarkBlockFinalized(header.Number.Uint64()); err != nil {
-		log.Error("Failed to mark block as finalized",
-			"block", header.Number,
-			"hash", header.Hash(),
-			"error", err)
-		return
+		return fmt.Errorf("failed to mark block %d as finalized: %v", header.Number, err)
+	}
+	indexerFinalizedGauge.Update(header.Number.Int64())
+	// Only announce the finalization if it applied to the indexed block
+	hashes, err := p.db.GetBlockHashes(header.Number.Uint64(), header.Number.Uint64())
+	if err != nil {
+		return err
+	}
+	if hashes[header.Number.Uint64()] == header.Hash() {
+		if p.sink != nil {
+			if err := p.sink.Finalize(header.Number.Uint64(), header.Hash()); err != nil {
+				return fmt.Errorf("failed to export finalized block %d: %v", header.Number, err)
+			}
+		}
+		p.indexedFeed.Send(IndexedBlockEvent{Header: header, Finalized: true})
 	}
 
 	log.Info("Successfully marked block as finalized",
 		"number", header.Number,
 		"hash", header.Hash())
+	return nil
 }
 
 // OnClose implements Plugin
-func (p *IndexerPlugin) OnClose() {
+func (p *IndexerPlugin) OnClose() error {
+	if p.db == nil {
+		return nil
+	}
 	log.Info("Closing indexer plugin")
+	p.stopBackfill()
+	metrics.Unregister(indexerHealthcheck)
+
+	// Stop processing the queue, pending events are picked up on restart
+	if p.quit != nil {
+		close(p.quit)
+		p.wg.Wait()
+	}
+	if p.sink != nil {
+		if err := p.export(); err != nil {
+			log.Error("Failed to export indexed records", "pending", len(p.exports), "err", err)
+		}
+		if err := p.sink.Close(); err != nil {
+			log.Error("Failed to close indexer sink", "err", err)
+		}
+	}
 	if err := p.db.Close(); err != nil {
-		log.Error("Failed to close database connection", "error", err)
+		return fmt.Errorf("failed to close database connection: %v", err)
 	}
+	return nil
 }
 
 // OnReorg handles chain reorganizations
-func (p *IndexerPlugin) OnReorg(oldHeaders, newHeaders []*types.Header) {
+func (p *IndexerPlugin) OnReorg(oldHeaders, newHeaders []*types.Header) error {
+	if p.db == nil {
+		return nil
+	}
+	p.queue.push(newIndexerJob(indexerJobReorg, oldHeaders, newHeaders))
+	return nil
+}
+
+// processReorg replaces the blocks dropped from the canonical chain with the
+// ones superseding them in a single database transaction, so readers never see
+// a mix of both chains. Logs of the dropped blocks are kept but marked removed,
+// and the reorg is recorded in the audit table. Both header lists are expected
+// in ascending order.
+func (p *IndexerPlugin) processReorg(oldHeaders, newHeaders []*types.Header) error {
+	// Blocks before the start block are not indexed, neither are their reorgs
+	oldHeaders, newHeaders = headersFrom(oldHeaders, p.startBlock), headersFrom(newHeaders, p.startBlock)
+	if len(oldHeaders) == 0 && len(newHeaders) == 0 {
+		return nil
+	}
+	first, last := reorgSpan(oldHeaders, newHeaders)
+
+	// Descendants of the common ancestor are dropped, lock them all
+	release := p.writing.acquire(first, math.MaxUint64)
+	defer release()
+
+	hashes, err := p.db.GetBlockHashes(first, last)
+	if err != nil {
+		return err
+	}
+	if reorgApplied(hashes, oldHeaders, newHeaders) {
+		log.Debug("Chain reorg already indexed", "from", first, "to", last)
+		return nil
+	}
 	log.Info("Indexer handling chain reorg",
 		"oldLen", len(oldHeaders),
-		"newLen", len(newHeaders),
-		"firstOld", oldHeaders[0].Number,
-		"firstNew", newHeaders[0].Number)
+		"newLen", len(newHeaders))
 
-	tx, err := p.db.db.Beginx()
+	tx, err := p.db.Begin()
 	if err != nil {
-		log.Error("Failed to begin reorg transaction", "error", err)
-		return
+		return fmt.Errorf("failed to begin reorg transaction: %v", err)
 	}
 	defer tx.Rollback()
 
-	// Delete old blocks and their data
-	for _, header := range oldHeaders {
-		blockNumber := header.Number.Uint64()
-		if err := p.db.DeleteBlockAndDescendantsWithTx(tx, blockNumber); err != nil {
-			log.Error("Failed to delete reorged block", "number", blockNumber, "error", err)
-			return
+	// Drop everything past the common ancestor and index the new chain on top
+	if err := p.db.DeleteBlockAndDescendantsWithTx(tx, first); err != nil {
+		return fmt.Errorf("failed to delete reorged blocks from %d: %v", first, err)
+	}
+	// Extending the chain without dropping blocks is not audited as a reorg
+	var (
+		reorg  = newReorg(oldHeaders, newHeaders)
+		writes []sinkWrite
+	)
+	if len(oldHeaders) > 0 {
+		writes = append(writes, sinkWrite{reorg: reorg})
+	}
+	for _, header := range newHeaders {
+		records, err := p.indexBlock(tx, header)
+		if err != nil {
+			return err
 		}
+		writes = append(writes, sinkWrite{block: records})
 	}
+	if len(oldHeaders) > 0 {
+		if err := p.db.InsertReorgWithTx(tx, reorg); err != nil {
+			return err
+		}
+	}
+	if err := commitIndexerTx(tx); err != nil {
+		return fmt.Errorf("failed to commit reorg transaction: %v", err)
+	}
+	if len(newHeaders) > 0 {
+		p.health.indexed(newHeaders[len(newHeaders)-1].Number.Uint64())
+	} else {
+		p.health.indexed(first - 1)
+	}
+	for _, header := range newHeaders {
+		p.indexedFeed.Send(IndexedBlockEvent{Header: header})
+	}
+	return p.export(writes...)
+}
 
-	// Insert new blocks
+// reorgSpan returns the range of block numbers touched by a reorg, starting
+// right after the common ancestor of the old and new chains.
+func reorgSpan(oldHeaders, newHeaders []*types.Header) (uint64, uint64) {
+	var first, last uint64
+	if len(oldHeaders) > 0 {
+		first = oldHeaders[0].Number.Uint64()
+		last = oldHeaders[len(oldHeaders)-1].Number.Uint64()
+	}
+	if len(newHeaders) > 0 {
+		if n := newHeaders[0].Number.Uint64(); len(oldHeaders) == 0 || n < first {
+			first = n
+		}
+		if n := newHeaders[len(newHeaders)-1].Number.Uint64(); n > last {
+			last = n
+		}
+	}
+	return first, last
+}
+
+// reorgApplied reports whether the indexed block hashes already reflect the
+// outcome of a reorg, i.e. all new blocks are present and none of the old ones.
+func reorgApplied(hashes map[uint64]common.Hash, oldHeaders, newHeaders []*types.Header) bool {
 	for _, header := range newHeaders {
-		p.OnHead(header)
+		if hashes[header.Number.Uint64()] != header.Hash() {
+			return false
+		}
+	}
+	for _, header := range oldHeaders {
+		if hashes[header.Number.Uint64()] == header.Hash() {
+			return false
+		}
+	}
+	return true
+}
+
+// indexDecodedLog stores the event a log decodes into, if the decoder is
+// enabled and knows it, along with the tokens it transfers. The stored rows are
+// added to the records of the block.
+func (p *IndexerPlugin) indexDecodedLog(tx *sqlx.Tx, log *types.Log, records *BlockRecords) error {
+	if p.decoder == nil {
+		return nil
+	}
+	decoded := p.decoder.decode(log)
+	if decoded == nil {
+		return nil
+	}
+	if p.decodedEvents {
+		event, err := newDecodedEvent(log, decoded)
+		if err != nil {
+			return err
+		}
+		if err := p.db.InsertDecodedEventWithTx(tx, event); err != nil {
+			return fmt.Errorf("failed to index %s event of log %d in block %d: %v", event.Event, log.Index, log.BlockNumber, err)
+		}
+		records.DecodedEvents = append(records.DecodedEvents, event)
+	}
+	if !p.tokenTransfers {
+		return nil
+	}
+	for _, transfer := range newTokenTransfers(log, decoded) {
+		if err := p.db.InsertTokenTransferWithTx(tx, transfer); err != nil {
+			return fmt.Errorf("failed to index token transfer of log %d in block %d: %v", log.Index, log.BlockNumber, err)
+		}
+		records.TokenTransfers = append(records.TokenTransfers, transfer)
 	}
+	return nil
+}
+
+// headersFrom returns the headers of an ascending list starting at the given
+// block number.
+func headersFrom(headers []*types.Header, number uint64) []*types.Header {
+	for i, header := range headers {
+		if header.Number.Uint64() >= number {
+			return headers[i:]
+		}
+	}
+	return nil
+}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		log.Error("Failed to commit reorg transaction", "error", err)
-		return
+// newReorg creates the audit record of a reorg from the dropped and added
+// headers, both in ascending order.
+func newReorg(oldHeaders, newHeaders []*types.Header) *Reorg {
+	reorg := &Reorg{
+		DetectedAt: time.Now(),
+		Depth:      uint64(len(oldHeaders)),
+		OldHashes:  make(pq.ByteaArray, len(oldHeaders)),
+		NewHashes:  make(pq.ByteaArray, len(newHeaders)),
+	}
+	for i, header := range oldHeaders {
+		reorg.OldHashes[i] = header.Hash().Bytes()
+	}
+	for i, header := range newHeaders {
+		reorg.NewHashes[i] = header.Hash().Bytes()
+	}
+	// The parent of the first dropped (or added) block is the common ancestor
+	var first *types.Header
+	if len(oldHeaders) > 0 {
+		first = oldHeaders[0]
+		head := oldHeaders[len(oldHeaders)-1].Hash()
+		reorg.OldHead = &head
+	}
+	if len(newHeaders) > 0 {
+		if first == nil || newHeaders[0].Number.Cmp(first.Number) < 0 {
+			first = newHeaders[0]
+		}
+		head := newHeaders[len(newHeaders)-1].Hash()
+		reorg.NewHead = &head
+	}
+	if first != nil {
+		reorg.AncestorHash = first.ParentHash
+		if n := first.Number.Uint64(); n > 0 {
+			reorg.AncestorNumber = n - 1
+		}
+	}
+	return reorg
+}
+
+// newTransaction converts a transaction and its receipt into a transactions
+// table row. The trace of the containing block is optional and only used to
+// fill in the execution error of failed transactions.
+func newTransaction(signer types.Signer, header *types.Header, tx *types.Transaction, receipt *types.Receipt, trace *blockTrace) (*Transaction, error) {
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender of transaction %s: %v", tx.Hash().Hex(), err)
+	}
+	t := &Transaction{
+		Hash:             tx.Hash(),
+		BlockNumber:      header.Number.Uint64(),
+		TransactionIndex: uint64(receipt.TransactionIndex),
+		From:             from,
+		To:               tx.To(),
+		Value:            NewBigInt(tx.Value()),
+		Nonce:            tx.Nonce(),
+		GasPrice:         NewBigInt(tx.GasPrice()),
+		GasLimit:         tx.Gas(),
+		GasUsed:          receipt.GasUsed,
+		Input:            append([]byte{}, tx.Data()...),
+		Status:           receipt.Status,
+		Type:             uint64(tx.Type()),
+	}
+	if receipt.EffectiveGasPrice != nil {
+		t.GasPrice = NewBigInt(receipt.EffectiveGasPrice)
+	}
+	if tx.Type() != types.LegacyTxType && tx.Type() != types.AccessListTxType {
+		t.MaxFeePerGas = NewBigInt(tx.GasFeeCap())
+		t.MaxPriorityFee = NewBigInt(tx.GasTipCap())
+	}
+	if tx.Type() == types.BlobTxType {
+		blobGasUsed := receipt.BlobGasUsed
+		t.BlobGasUsed = &blobGasUsed
+		t.BlobGasPrice = NewBigInt(receipt.BlobGasPrice)
+	}
+	if trace != nil {
+		if txTrace := trace.txs[tx.Hash()]; txTrace != nil && txTrace.err != "" {
+			t.Error = sql.NullString{String: txTrace.err, Valid: true}
+		}
+	}
+	return t, nil
+}
+
+// newBlobHashes converts the blob hashes carried by a transaction into
+// blob_hashes table rows.
+func newBlobHashes(t *Transaction, tx *types.Transaction) []*BlobHash {
+	var blobs []*BlobHash
+	for i, hash := range tx.BlobHashes() {
+		blobs = append(blobs, &BlobHash{
+			BlockNumber:     t.BlockNumber,
+			TransactionHash: t.Hash,
+			BlobIndex:       uint64(i),
+			VersionedHash:   hash,
+			BlobGasPrice:    t.BlobGasPrice,
+		})
+	}
+	return blobs
+}
+
+// newReceipt converts the receipt of the index-th transaction of a block into
+// a receipts table row.
+func newReceipt(number uint64, hash common.Hash, tx *types.Transaction, receipt *types.Receipt, index int) *Receipt {
+	r := &Receipt{
+		BlockNumber:      number,
+		BlockHash:        hash,
+		TransactionHash:  receipt.TxHash,
+		TransactionIndex: uint(index),
+		GasUsed:          receipt.GasUsed,
+		Status:           receipt.Status,
+	}
+	if tx.To() == nil {
+		r.ContractAddress = &receipt.ContractAddress
+	}
+	return r
+}
+
+// newLog converts a log emitted by a transaction into a logs table row.
+func newLog(number uint64, hash common.Hash, txHash common.Hash, logEntry *types.Log) *Log {
+	return &Log{
+		BlockNumber:     number,
+		BlockHash:       hash,
+		TransactionHash: txHash,
+		LogIndex:        uint64(logEntry.Index),
+		Address:         logEntry.Address,
+		Topics:          hashesValue(logEntry.Topics),
+		Data:            append([]byte{}, logEntry.Data...),
+	}
+}
+
+// newAccounts assembles the accounts table rows of everything touched by a
+// block: transaction senders and recipients, created contracts and accounts
+// with recorded state changes. Balances, nonces and code are read from the
+// post-block state. The trace of the block is optional, without it only the
+// top-level participants and deployments are known.
+func newAccounts(statedb tracing.StateDB, signer types.Signer, block *types.Block, receipts types.Receipts, trace *blockTrace) ([]*Account, error) {
+	var (
+		order    []common.Address
+		accounts = make(map[common.Address]*Account)
+	)
+	touch := func(addr common.Address) *Account {
+		if account, ok := accounts[addr]; ok {
+			return account
+		}
+		account := &Account{Address: addr, UpdatedBlock: block.NumberU64()}
+		accounts[addr] = account
+		order = append(order, addr)
+		return account
+	}
+	create := func(addr common.Address, creator common.Address, txHash common.Hash) {
+		account := touch(addr)
+		account.CreatorAddress = &creator
+		account.CreatorTxHash = &txHash
+		account.CreatedAt = sql.NullTime{Time: time.Unix(int64(block.Time()), 0), Valid: true}
+	}
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to recover sender of transaction %s: %v", tx.Hash().Hex(), err)
+		}
+		touch(from)
+		if to := tx.To(); to != nil {
+			touch(*to)
+		} else if trace == nil && receipts[i].Status == types.ReceiptStatusSuccessful {
+			create(receipts[i].ContractAddress, from, tx.Hash())
+		}
+	}
+	destructed := make(map[common.Address]bool)
+	if trace != nil {
+		for _, change := range trace.stateChanges {
+			touch(change.Address)
+		}
+		for _, creation := range trace.creations {
+			create(creation.address, creation.creator, creation.txHash)
+		}
+		for _, addr := range trace.destructs {
+			touch(addr)
+			destructed[addr] = true
+		}
+	}
+	rows := make([]*Account, 0, len(order))
+	for _, addr := range order {
+		account := accounts[addr]
+		account.Balance = NewBigInt(statedb.GetBalance(addr).ToBig())
+		account.Nonce = statedb.GetNonce(addr)
+		if code := statedb.GetCode(addr); len(code) > 0 {
+			account.Code = common.CopyBytes(code)
+		}
+		// A contract is gone if it self-destructed pre-Cancun, or was created
+		// and destroyed within the same transaction.
+		account.SelfDestructed = destructed[addr] && !statedb.Exist(addr)
+		rows = append(rows, account)
 	}
+	return rows, nil
 }
 
 // Add receipts cache implementation
@@ -0,0 +1,252 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// combineHooks merges two sets of live tracing hooks into one which invokes the
+// hooks of a, then those of b. Hooks only set in one of the inputs are carried
+// over as is, so that unused events retain their zero cost in the EVM.
+func combineHooks(a, b *tracing.Hooks) *tracing.Hooks {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	h := *a
+
+	// VM events
+	if f, g := a.OnTxStart, b.OnTxStart; g != nil {
+		h.OnTxStart = g
+		if f != nil {
+			h.OnTxStart = func(vm *tracing.VMContext, tx *types.Transaction, from common.Address) {
+				f(vm, tx, from)
+				g(vm, tx, from)
+			}
+		}
+	}
+	if f, g := a.OnTxEnd, b.OnTxEnd; g != nil {
+		h.OnTxEnd = g
+		if f != nil {
+			h.OnTxEnd = func(receipt *types.Receipt, err error) {
+				f(receipt, err)
+				g(receipt, err)
+			}
+		}
+	}
+	if f, g := a.OnEnter, b.OnEnter; g != nil {
+		h.OnEnter = g
+		if f != nil {
+			h.OnEnter = func(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+				f(depth, typ, from, to, input, gas, value)
+				g(depth, typ, from, to, input, gas, value)
+			}
+		}
+	}
+	if f, g := a.OnExit, b.OnExit; g != nil {
+		h.OnExit = g
+		if f != nil {
+			h.OnExit = func(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+				f(depth, output, gasUsed, err, reverted)
+				g(depth, output, gasUsed, err, reverted)
+			}
+		}
+	}
+	if f, g := a.OnOpcode, b.OnOpcode; g != nil {
+		h.OnOpcode = g
+		if f != nil {
+			h.OnOpcode = func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+				f(pc, op, gas, cost, scope, rData, depth, err)
+				g(pc, op, gas, cost, scope, rData, depth, err)
+			}
+		}
+	}
+	if f, g := a.OnFault, b.OnFault; g != nil {
+		h.OnFault = g
+		if f != nil {
+			h.OnFault = func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, depth int, err error) {
+				f(pc, op, gas, cost, scope, depth, err)
+				g(pc, op, gas, cost, scope, depth, err)
+			}
+		}
+	}
+	if f, g := a.OnGasChange, b.OnGasChange; g != nil {
+		h.OnGasChange = g
+		if f != nil {
+			h.OnGasChange = func(old, new uint64, reason tracing.GasChangeReason) {
+				f(old, new, reason)
+				g(old, new, reason)
+			}
+		}
+	}
+	// Chain events
+	if f, g := a.OnBlockchainInit, b.OnBlockchainInit; g != nil {
+		h.OnBlockchainInit = g
+		if f != nil {
+			h.OnBlockchainInit = func(config *params.ChainConfig) {
+				f(config)
+				g(config)
+			}
+		}
+	}
+	if f, g := a.OnClose, b.OnClose; g != nil {
+		h.OnClose = g
+		if f != nil {
+			h.OnClose = func() {
+				f()
+				g()
+			}
+		}
+	}
+	if f, g := a.OnBlockStart, b.OnBlockStart; g != nil {
+		h.OnBlockStart = g
+		if f != nil {
+			h.OnBlockStart = func(event tracing.BlockEvent) {
+				f(event)
+				g(event)
+			}
+		}
+	}
+	if f, g := a.OnBlockEnd, b.OnBlockEnd; g != nil {
+		h.OnBlockEnd = g
+		if f != nil {
+			h.OnBlockEnd = func(err error) {
+				f(err)
+				g(err)
+			}
+		}
+	}
+	if f, g := a.OnSkippedBlock, b.OnSkippedBlock; g != nil {
+		h.OnSkippedBlock = g
+		if f != nil {
+			h.OnSkippedBlock = func(event tracing.BlockEvent) {
+				f(event)
+				g(event)
+			}
+		}
+	}
+	if f, g := a.OnGenesisBlock, b.OnGenesisBlock; g != nil {
+		h.OnGenesisBlock = g
+		if f != nil {
+			h.OnGenesisBlock = func(genesis *types.Block, alloc types.GenesisAlloc) {
+				f(genesis, alloc)
+				g(genesis, alloc)
+			}
+		}
+	}
+	// The EVM only invokes the V2 system call hook if present, so if either side
+	// uses it, both have to be routed through it.
+	if a.OnSystemCallStartV2 != nil || b.OnSystemCallStartV2 != nil {
+		f, g := systemCallStartV2(a), systemCallStartV2(b)
+		switch {
+		case f == nil:
+			h.OnSystemCallStartV2 = g
+		case g == nil:
+			h.OnSystemCallStartV2 = f
+		default:
+			h.OnSystemCallStartV2 = func(vm *tracing.VMContext) {
+				f(vm)
+				g(vm)
+			}
+		}
+		h.OnSystemCallStart = nil
+	} else if f, g := a.OnSystemCallStart, b.OnSystemCallStart; g != nil {
+		h.OnSystemCallStart = g
+		if f != nil {
+			h.OnSystemCallStart = func() {
+				f()
+				g()
+			}
+		}
+	}
+	if f, g := a.OnSystemCallEnd, b.OnSystemCallEnd; g != nil {
+		h.OnSystemCallEnd = g
+		if f != nil {
+			h.OnSystemCallEnd = func() {
+				f()
+				g()
+			}
+		}
+	}
+	// State events
+	if f, g := a.OnBalanceChange, b.OnBalanceChange; g != nil {
+		h.OnBalanceChange = g
+		if f != nil {
+			h.OnBalanceChange = func(addr common.Address, prev, new *big.Int, reason tracing.BalanceChangeReason) {
+				f(addr, prev, new, reason)
+				g(addr, prev, new, reason)
+			}
+		}
+	}
+	if f, g := a.OnNonceChange, b.OnNonceChange; g != nil {
+		h.OnNonceChange = g
+		if f != nil {
+			h.OnNonceChange = func(addr common.Address, prev, new uint64) {
+				f(addr, prev, new)
+				g(addr, prev, new)
+			}
+		}
+	}
+	if f, g := a.OnCodeChange, b.OnCodeChange; g != nil {
+		h.OnCodeChange = g
+		if f != nil {
+			h.OnCodeChange = func(addr common.Address, prevCodeHash common.Hash, prevCode []byte, codeHash common.Hash, code []byte) {
+				f(addr, prevCodeHash, prevCode, codeHash, code)
+				g(addr, prevCodeHash, prevCode, codeHash, code)
+			}
+		}
+	}
+	if f, g := a.OnStorageChange, b.OnStorageChange; g != nil {
+		h.OnStorageChange = g
+		if f != nil {
+			h.OnStorageChange = func(addr common.Address, slot common.Hash, prev, new common.Hash) {
+				f(addr, slot, prev, new)
+				g(addr, slot, prev, new)
+			}
+		}
+	}
+	if f, g := a.OnLog, b.OnLog; g != nil {
+		h.OnLog = g
+		if f != nil {
+			h.OnLog = func(log *types.Log) {
+				f(log)
+				g(log)
+			}
+		}
+	}
+	return &h
+}
+
+// systemCallStartV2 returns the system call start hook of h in its V2 form,
+// adapting the legacy variant if that is the only one set.
+func systemCallStartV2(h *tracing.Hooks) tracing.OnSystemCallStartHookV2 {
+	if h.OnSystemCallStartV2 != nil {
+		return h.OnSystemCallStartV2
+	}
+	if f := h.OnSystemCallStart; f != nil {
+		return func(*tracing.VMContext) { f() }
+	}
+	return nil
+}
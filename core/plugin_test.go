@@ -0,0 +1,483 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// recordingPlugin is a Plugin that records every event it receives into a
+// shared journal, optionally failing or panicking on selected hooks.
+type recordingPlugin struct {
+	name    string
+	journal *[]string
+	failOn  string
+	panicOn string
+}
+
+func (p *recordingPlugin) record(hook string, detail string) error {
+	*p.journal = append(*p.journal, fmt.Sprintf("%s:%s:%s", p.name, hook, detail))
+	if p.panicOn == hook {
+		panic("boom")
+	}
+	if p.failOn == hook {
+		return errors.New("failure")
+	}
+	return nil
+}
+
+func (p *recordingPlugin) OnInit(chain *BlockChain) error {
+	return p.record("init", "")
+}
+
+func (p *recordingPlugin) OnHead(header *types.Header) error {
+	return p.record("head", header.Number.String())
+}
+
+func (p *recordingPlugin) OnFinal(header *types.Header) error {
+	return p.record("final", header.Number.String())
+}
+
+func (p *recordingPlugin) OnClose() error {
+	return p.record("close", "")
+}
+
+func (p *recordingPlugin) OnReorg(oldHeaders, newHeaders []*types.Header) error {
+	return p.record("reorg", fmt.Sprintf("%d/%d", len(oldHeaders), len(newHeaders)))
+}
+
+// Tests that plugins receive chain events in registration order and that a
+// failing or panicking plugin does not affect the chain or the other plugins.
+func TestPluginRegistry(t *testing.T) {
+	var (
+		journal []string
+		first   = &recordingPlugin{name: "first", journal: &journal, panicOn: "head"}
+		second  = &recordingPlugin{name: "second", journal: &journal, failOn: "final"}
+		broken  = &recordingPlugin{name: "broken", journal: &journal, failOn: "init"}
+		gspec   = &Genesis{Config: params.TestChainConfig}
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2, nil)
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, first, broken, second)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if plugins := chain.Plugins(); len(plugins) != 2 || plugins[0] != first || plugins[1] != second {
+		t.Fatalf("unexpected plugin set: %v", plugins)
+	}
+	if err := chain.RegisterPlugin(first); err == nil {
+		t.Fatal("duplicate plugin registration succeeded")
+	}
+	journal = journal[:0]
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	if chain.CurrentBlock().Number.Uint64() != 2 {
+		t.Fatalf("chain head mismatch: have %d, want 2", chain.CurrentBlock().Number)
+	}
+	// Every block is announced exactly once to each plugin
+	want := []string{"first:head:1", "second:head:1", "first:head:2", "second:head:2"}
+	if fmt.Sprint(journal) != fmt.Sprint(want) {
+		t.Fatalf("unexpected head events: have %v, want %v", journal, want)
+	}
+	journal = journal[:0]
+
+	chain.SetFinalized(blocks[0].Header())
+	chain.Stop()
+
+	want = []string{"first:final:1", "second:final:1", "first:close:", "second:close:"}
+	if fmt.Sprint(journal) != fmt.Sprint(want) {
+		t.Fatalf("unexpected events: have %v, want %v", journal, want)
+	}
+	if len(chain.Plugins()) != 0 {
+		t.Fatal("plugins still attached after stop")
+	}
+}
+
+// registeringPlugin is a Plugin registering another plugin when a block is
+// finalized.
+type registeringPlugin struct {
+	recordingPlugin
+	chain *BlockChain
+	other Plugin
+}
+
+func (p *registeringPlugin) OnInit(chain *BlockChain) error {
+	p.chain = chain
+	return p.recordingPlugin.OnInit(chain)
+}
+
+func (p *registeringPlugin) OnFinal(header *types.Header) error {
+	return p.chain.RegisterPlugin(p.other)
+}
+
+// Tests that plugin hooks may register further plugins.
+func TestPluginRegisterFromHook(t *testing.T) {
+	var (
+		journal []string
+		other   = &recordingPlugin{name: "other", journal: &journal}
+		plugin  = &registeringPlugin{recordingPlugin: recordingPlugin{name: "registering", journal: &journal}, other: other}
+		gspec   = &Genesis{Config: params.TestChainConfig}
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, nil)
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, plugin)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	chain.SetFinalized(blocks[0].Header())
+
+	if plugins := chain.Plugins(); len(plugins) != 2 || plugins[1] != other {
+		t.Fatalf("unexpected plugin set: %v", plugins)
+	}
+}
+
+// headRegisteringPlugin is a Plugin registering another plugin on the first
+// head event, which is dispatched under the chain lock.
+type headRegisteringPlugin struct {
+	registeringPlugin
+	done bool
+}
+
+func (p *headRegisteringPlugin) OnHead(header *types.Header) error {
+	if p.done {
+		return nil
+	}
+	p.done = true
+	return p.chain.RegisterPlugin(p.other)
+}
+
+// Tests that plugins registered from hooks running under the chain lock are
+// attached once the hooks return instead of deadlocking block import.
+func TestPluginRegisterFromHeadHook(t *testing.T) {
+	var (
+		journal []string
+		other   = &recordingPlugin{name: "other", journal: &journal}
+		plugin  = &headRegisteringPlugin{registeringPlugin: registeringPlugin{recordingPlugin: recordingPlugin{name: "registering", journal: &journal}, other: other}}
+		gspec   = &Genesis{Config: params.TestChainConfig}
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2, nil)
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, plugin)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := chain.InsertChain(blocks)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("failed to insert blocks: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("block import deadlocked")
+	}
+	if plugins := chain.Plugins(); len(plugins) != 2 || plugins[1] != other {
+		t.Fatalf("unexpected plugin set: %v", plugins)
+	}
+	// The deferred plugin only sees the events after its registration
+	want := []string{"registering:init:", "other:init:", "other:head:2"}
+	if !slices.Equal(journal, want) {
+		t.Fatalf("journal mismatch: have %v, want %v", journal, want)
+	}
+}
+
+// hookedPlugin is a TracingPlugin counting the blocks it saw processed.
+type hookedPlugin struct {
+	recordingPlugin
+	blocks int
+}
+
+func (p *hookedPlugin) Hooks() *tracing.Hooks {
+	return &tracing.Hooks{OnBlockStart: func(event tracing.BlockEvent) { p.blocks++ }}
+}
+
+// Tests that the tracing hooks of late plugins are published through a fresh
+// VM config, leaving the ones handed out before untouched.
+func TestPluginHooksPublished(t *testing.T) {
+	var (
+		journal []string
+		plugin  = &hookedPlugin{recordingPlugin: recordingPlugin{name: "hooked", journal: &journal}}
+		gspec   = &Genesis{Config: params.TestChainConfig}
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, nil)
+
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	before := chain.GetVMConfig()
+	if err := chain.RegisterPlugin(plugin); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+	if before.Tracer != nil {
+		t.Error("previously returned config modified")
+	}
+	if after := chain.GetVMConfig(); after.Tracer == nil || after.Tracer.OnBlockStart == nil {
+		t.Error("plugin hooks not published")
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	if plugin.blocks != 1 {
+		t.Errorf("processed block count mismatch: have %d, want 1", plugin.blocks)
+	}
+}
+
+// Tests that registering a plugin waits for a running chain modification
+// instead of failing, and only fails once the chain is stopped.
+func TestPluginRegisterWhileLocked(t *testing.T) {
+	var (
+		journal []string
+		plugin  = &recordingPlugin{name: "plugin", journal: &journal}
+		gspec   = &Genesis{Config: params.TestChainConfig}
+	)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	// Hold the chain lock as a block import would
+	chain.chainmu.MustLock()
+
+	errc := make(chan error, 1)
+	go func() { errc <- chain.RegisterPlugin(plugin) }()
+	select {
+	case err := <-errc:
+		t.Fatalf("registration didn't wait for the chain lock: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	chain.chainmu.Unlock()
+	if err := <-errc; err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+	chain.Stop()
+
+	if err := chain.RegisterPlugin(&recordingPlugin{name: "late", journal: &journal}); !errors.Is(err, errChainStopped) {
+		t.Fatalf("registration after stop: have %v, want %v", err, errChainStopped)
+	}
+}
+
+// Tests that merged tracing hooks are invoked in order and that the legacy and
+// V2 system call hooks are both served when mixed.
+func TestCombineHooks(t *testing.T) {
+	var journal []string
+	a := &tracing.Hooks{
+		OnNonceChange:     func(common.Address, uint64, uint64) { journal = append(journal, "a:nonce") },
+		OnSystemCallStart: func() { journal = append(journal, "a:syscall") },
+	}
+	b := &tracing.Hooks{
+		OnNonceChange:       func(common.Address, uint64, uint64) { journal = append(journal, "b:nonce") },
+		OnSystemCallStartV2: func(*tracing.VMContext) { journal = append(journal, "b:syscall") },
+		OnLog:               func(*types.Log) { journal = append(journal, "b:log") },
+	}
+	h := combineHooks(a, b)
+	if h.OnSystemCallStart != nil || h.OnBalanceChange != nil {
+		t.Fatal("unexpected hooks set")
+	}
+	h.OnNonceChange(common.Address{}, 0, 1)
+	h.OnSystemCallStartV2(nil)
+	h.OnLog(nil)
+
+	want := []string{"a:nonce", "b:nonce", "a:syscall", "b:syscall", "b:log"}
+	if fmt.Sprint(journal) != fmt.Sprint(want) {
+		t.Fatalf("unexpected events: have %v, want %v", journal, want)
+	}
+}
+
+// reorgPlugin is a Plugin capturing the headers of every reorg event.
+type reorgPlugin struct {
+	recordingPlugin
+	old, new [][]*types.Header
+}
+
+func (p *reorgPlugin) OnReorg(oldHeaders, newHeaders []*types.Header) error {
+	p.old = append(p.old, oldHeaders)
+	p.new = append(p.new, newHeaders)
+	return nil
+}
+
+// Tests that a reorg is announced to plugins as a single event carrying both
+// the dropped and the added headers in ascending order.
+func TestPluginReorg(t *testing.T) {
+	var (
+		journal []string
+		plugin  = &reorgPlugin{recordingPlugin: recordingPlugin{name: "reorg", journal: &journal}}
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		engine  = ethash.NewFaker()
+	)
+	db, blocks, _ := GenerateChainWithGenesis(gspec, engine, 3, nil)
+	forks, _ := GenerateChain(gspec.Config, blocks[0], engine, db, 3, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x1})
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, plugin)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert canonical chain: %v", err)
+	}
+	if len(plugin.old) != 0 {
+		t.Fatalf("unexpected reorg while extending the chain: %d events", len(plugin.old))
+	}
+	if _, err := chain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if len(plugin.old) != 1 {
+		t.Fatalf("reorg event count mismatch: have %d, want 1", len(plugin.old))
+	}
+	check := func(kind string, have []*types.Header, want []*types.Block) {
+		if len(have) != len(want) {
+			t.Fatalf("%s header count mismatch: have %d, want %d", kind, len(have), len(want))
+		}
+		for i := range want {
+			if have[i].Hash() != want[i].Hash() {
+				t.Errorf("%s header %d mismatch: have #%d [%x], want #%d [%x]", kind, i, have[i].Number, have[i].Hash(), want[i].Number(), want[i].Hash())
+			}
+		}
+	}
+	check("old", plugin.old[0], blocks[1:])
+	// The fork becomes canonical with its first block, the rest extend it
+	check("new", plugin.new[0], forks[:1])
+
+	// The audit record must be anchored at the common ancestor
+	reorg := newReorg(plugin.old[0], plugin.new[0])
+	if reorg.AncestorNumber != 1 || reorg.AncestorHash != blocks[0].Hash() {
+		t.Errorf("ancestor mismatch: have #%d [%x], want #1 [%x]", reorg.AncestorNumber, reorg.AncestorHash, blocks[0].Hash())
+	}
+	if reorg.Depth != 2 {
+		t.Errorf("depth mismatch: have %d, want 2", reorg.Depth)
+	}
+	if reorg.OldHead == nil || *reorg.OldHead != blocks[2].Hash() || reorg.NewHead == nil || *reorg.NewHead != forks[0].Hash() {
+		t.Errorf("head mismatch: have %x -> %x", reorg.OldHead, reorg.NewHead)
+	}
+	if first, last := reorgSpan(plugin.old[0], plugin.new[0]); first != 2 || last != 3 {
+		t.Errorf("span mismatch: have %d-%d, want 2-3", first, last)
+	}
+}
+
+// Tests that extending the chain by several blocks at once, as a forkchoice
+// update does, announces the skipped blocks to the plugins as a reorg without
+// dropped blocks, so the indexer doesn't leave a gap.
+func TestPluginReorgExtend(t *testing.T) {
+	var (
+		journal []string
+		plugin  = &reorgPlugin{recordingPlugin: recordingPlugin{name: "reorg", journal: &journal}}
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		engine  = ethash.NewFaker()
+		db      = newTestIndexerDB(t)
+		indexer = NewIndexerPlugin(db)
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 4, nil)
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, plugin, indexer)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[:1]); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+	for _, block := range blocks[1:] {
+		if _, err := chain.InsertBlockWithoutSetHead(block, false); err != nil {
+			t.Fatalf("failed to insert block #%d: %v", block.NumberU64(), err)
+		}
+	}
+	journal = journal[:0]
+	if _, err := chain.SetCanonical(blocks[3]); err != nil {
+		t.Fatalf("failed to set canonical head: %v", err)
+	}
+	if len(plugin.old) != 1 || len(plugin.old[0]) != 0 {
+		t.Fatalf("unexpected extension events: %v", plugin.old)
+	}
+	if len(plugin.new[0]) != 3 {
+		t.Fatalf("extension header count mismatch: have %d, want 3", len(plugin.new[0]))
+	}
+	for i, header := range plugin.new[0] {
+		if header.Hash() != blocks[i+1].Hash() {
+			t.Errorf("extension header %d mismatch: have #%d, want #%d", i, header.Number, blocks[i+1].Number())
+		}
+	}
+	if want := []string{"reorg:head:4"}; fmt.Sprint(journal) != fmt.Sprint(want) {
+		t.Fatalf("unexpected events: have %v, want %v", journal, want)
+	}
+	for _, block := range blocks {
+		waitIndexed(t, db, block)
+	}
+	var reorgs []Reorg
+	if err := db.db.Select(&reorgs, `SELECT * FROM reorgs`); err != nil {
+		t.Fatalf("failed to read reorgs: %v", err)
+	}
+	if len(reorgs) != 0 {
+		t.Fatalf("chain extension audited as a reorg: %v", reorgs)
+	}
+}
+
+// Tests that replayed reorg events are detected from the indexed block hashes.
+func TestIndexerReorgApplied(t *testing.T) {
+	gspec := &Genesis{Config: params.TestChainConfig}
+	db, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 3, nil)
+	forks, _ := GenerateChain(gspec.Config, blocks[0], ethash.NewFaker(), db, 3, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x1})
+	})
+	var (
+		oldHeaders = []*types.Header{blocks[1].Header(), blocks[2].Header()}
+		newHeaders = []*types.Header{forks[0].Header(), forks[1].Header(), forks[2].Header()}
+		indexed    = func(blocks ...*types.Block) map[uint64]common.Hash {
+			hashes := make(map[uint64]common.Hash)
+			for _, block := range blocks {
+				hashes[block.NumberU64()] = block.Hash()
+			}
+			return hashes
+		}
+	)
+	if reorgApplied(indexed(blocks...), oldHeaders, newHeaders) {
+		t.Error("pending reorg reported as applied")
+	}
+	if reorgApplied(indexed(blocks[0], forks[0], forks[1]), oldHeaders, newHeaders) {
+		t.Error("partially indexed new chain reported as applied")
+	}
+	if !reorgApplied(indexed(append([]*types.Block{blocks[0]}, forks...)...), oldHeaders, newHeaders) {
+		t.Error("applied reorg reported as pending")
+	}
+}
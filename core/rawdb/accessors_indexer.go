@@ -0,0 +1,88 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ReadIndexerQueueRange returns the sequence numbers of the first queued indexer
+// job and the one following the last. Both are zero if the queue is empty.
+func ReadIndexerQueueRange(db ethdb.Iteratee) (uint64, uint64) {
+	it := db.NewIterator(indexerQueuePrefix, nil)
+	defer it.Release()
+
+	var first, next uint64
+	for count := 0; it.Next(); count++ {
+		key := it.Key()
+		if len(key) != len(indexerQueuePrefix)+8 {
+			continue
+		}
+		seq := binary.BigEndian.Uint64(key[len(indexerQueuePrefix):])
+		if count == 0 {
+			first = seq
+		}
+		next = seq + 1
+	}
+	return first, next
+}
+
+// ReadIndexerJob retrieves the serialized indexer job with the given sequence
+// number.
+func ReadIndexerJob(db ethdb.KeyValueReader, seq uint64) []byte {
+	data, _ := db.Get(indexerJobKey(seq))
+	return data
+}
+
+// WriteIndexerJob stores a serialized indexer job into the queue.
+func WriteIndexerJob(db ethdb.KeyValueWriter, seq uint64, job []byte) {
+	if err := db.Put(indexerJobKey(seq), job); err != nil {
+		log.Crit("Failed to store indexer job", "err", err)
+	}
+}
+
+// DeleteIndexerJob removes a processed indexer job from the queue.
+func DeleteIndexerJob(db ethdb.KeyValueWriter, seq uint64) {
+	if err := db.Delete(indexerJobKey(seq)); err != nil {
+		log.Crit("Failed to delete indexer job", "err", err)
+	}
+}
+
+// ReadIndexerQueueOverflow retrieves the serialized overflow marker of the
+// indexer queue.
+func ReadIndexerQueueOverflow(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(indexerQueueOverflowKey)
+	return data
+}
+
+// WriteIndexerQueueOverflow stores the serialized overflow marker of the indexer
+// queue.
+func WriteIndexerQueueOverflow(db ethdb.KeyValueWriter, overflow []byte) {
+	if err := db.Put(indexerQueueOverflowKey, overflow); err != nil {
+		log.Crit("Failed to store indexer queue overflow", "err", err)
+	}
+}
+
+// DeleteIndexerQueueOverflow removes the overflow marker of the indexer queue.
+func DeleteIndexerQueueOverflow(db ethdb.KeyValueWriter) {
+	if err := db.Delete(indexerQueueOverflowKey); err != nil {
+		log.Crit("Failed to delete indexer queue overflow", "err", err)
+	}
+}
@@ -18,6 +18,7 @@ package core
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
 	"math"
 	"math/big"
 	"testing"
@@ -445,3 +446,83 @@ func GenerateBadBlock(parent *types.Block, engine consensus.Engine, txs types.Tr
 	}
 	return types.NewBlock(header, body, receipts, trie.NewStackTrie(nil))
 }
+
+// recorder is a stateful precompile storing and returning the sender of the
+// last call.
+type recorder struct{}
+
+func (recorder) RequiredGas(input []byte) uint64 { return 1000 }
+
+func (recorder) Run(input []byte) ([]byte, error) { return nil, vm.ErrStatefulPrecompile }
+
+func (recorder) RunStateful(env *vm.PrecompileEnvironment, input []byte) ([]byte, error) {
+	caller := common.BytesToHash(env.Caller.Bytes())
+	env.StateDB().SetState(env.Address, common.Hash{}, caller)
+	if err := env.AddLog([]common.Hash{caller}, nil); err != nil {
+		return nil, err
+	}
+	return caller.Bytes(), nil
+}
+
+func init() {
+	if err := vm.RegisterPrecompile("core-test-recorder", recorder{}); err != nil {
+		panic(err)
+	}
+}
+
+// Tests that precompiles declared by the genesis are executed by block import
+// from their activation on.
+func TestStatefulPrecompileImport(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		precomp = common.HexToAddress("0x0300000000000000000000000000000000000001")
+		config  = *params.TestChainConfig
+	)
+	if err := json.Unmarshal([]byte(`[{"address": "0x0300000000000000000000000000000000000001", "time": 25, "contract": "core-test-recorder"}]`), &config.Precompiles); err != nil {
+		t.Fatalf("failed to decode precompile upgrades: %v", err)
+	}
+	gspec := &Genesis{
+		Config: &config,
+		Alloc: types.GenesisAlloc{
+			sender:  {Balance: big.NewInt(params.Ether)},
+			precomp: {Nonce: 1}, // keep the storage from being cleared as empty
+		},
+	}
+	signer := types.LatestSigner(gspec.Config)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 3, func(i int, gen *BlockGen) {
+		gen.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    gen.TxNonce(sender),
+			To:       &precomp,
+			Gas:      100000,
+			GasPrice: gen.header.BaseFee,
+		}))
+	})
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	// Only the block past the activation runs the precompile
+	for i, block := range blocks {
+		if logs := len(receipts[i][0].Logs); (block.Time() >= 25) != (logs == 1) {
+			t.Errorf("block %d at time %d: have %d logs", block.NumberU64(), block.Time(), logs)
+		}
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	if have := statedb.GetState(precomp, common.Hash{}); have != common.BytesToHash(sender.Bytes()) {
+		t.Errorf("precompile state mismatch: have %x, want %x", have, sender)
+	}
+	// Chains installing unknown contracts are rejected
+	config.Precompiles = []params.PrecompileUpgrade{{Address: precomp, Contract: "core-test-missing"}}
+	if _, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil); err == nil {
+		t.Error("unregistered precompile accepted")
+	}
+}
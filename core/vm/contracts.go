@@ -174,22 +174,24 @@ func init() {
 }
 
 func activePrecompiledContracts(rules params.Rules) PrecompiledContracts {
+	var precompiles PrecompiledContracts
 	switch {
 	case rules.IsVerkle:
-		return PrecompiledContractsVerkle
+		precompiles = PrecompiledContractsVerkle
 	case rules.IsPrague:
-		return PrecompiledContractsPrague
+		precompiles = PrecompiledContractsPrague
 	case rules.IsCancun:
-		return PrecompiledContractsCancun
+		precompiles = PrecompiledContractsCancun
 	case rules.IsBerlin:
-		return PrecompiledContractsBerlin
+		precompiles = PrecompiledContractsBerlin
 	case rules.IsIstanbul:
-		return PrecompiledContractsIstanbul
+		precompiles = PrecompiledContractsIstanbul
 	case rules.IsByzantium:
-		return PrecompiledContractsByzantium
+		precompiles = PrecompiledContractsByzantium
 	default:
-		return PrecompiledContractsHomestead
+		precompiles = PrecompiledContractsHomestead
 	}
+	return withRegisteredPrecompiles(rules, precompiles)
 }
 
 // ActivePrecompiledContracts returns a copy of precompiled contracts enabled with the current configuration.
@@ -199,20 +201,22 @@ func ActivePrecompiledContracts(rules params.Rules) PrecompiledContracts {
 
 // ActivePrecompiles returns the precompile addresses enabled with the current configuration.
 func ActivePrecompiles(rules params.Rules) []common.Address {
+	var addrs []common.Address
 	switch {
 	case rules.IsPrague:
-		return PrecompiledAddressesPrague
+		addrs = PrecompiledAddressesPrague
 	case rules.IsCancun:
-		return PrecompiledAddressesCancun
+		addrs = PrecompiledAddressesCancun
 	case rules.IsBerlin:
-		return PrecompiledAddressesBerlin
+		addrs = PrecompiledAddressesBerlin
 	case rules.IsIstanbul:
-		return PrecompiledAddressesIstanbul
+		addrs = PrecompiledAddressesIstanbul
 	case rules.IsByzantium:
-		return PrecompiledAddressesByzantium
+		addrs = PrecompiledAddressesByzantium
 	default:
-		return PrecompiledAddressesHomestead
+		addrs = PrecompiledAddressesHomestead
 	}
+	return withRegisteredAddresses(rules, addrs)
 }
 
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
@@ -0,0 +1,224 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// ErrStatefulPrecompile may be returned by the Run method of stateful
+// precompiles, which can't be executed without an EVM.
+var ErrStatefulPrecompile = errors.New("stateful precompile requires an execution environment")
+
+// StatefulPrecompiledContract is a native Go contract with access to the state
+// and the context of the call it's executed in, e.g. the native contracts of a
+// permissioned network. The EVM invokes RunStateful instead of Run, which is
+// only used if the contract is executed outside of an EVM.
+//
+// State modifications are reverted along with the calling frame if the call
+// fails. The contract must not modify the state if the environment is
+// read-only. Like any account, the precompile's is deleted along with its
+// storage at the end of a transaction if it's empty, so a contract keeping
+// state needs a nonce or balance, e.g. allocated in the genesis.
+type StatefulPrecompiledContract interface {
+	PrecompiledContract
+
+	// RunStateful runs the contract in the given environment.
+	RunStateful(env *PrecompileEnvironment, input []byte) ([]byte, error)
+}
+
+// PrecompileEnvironment is the context a stateful precompile is executed in.
+type PrecompileEnvironment struct {
+	evm *EVM
+
+	Caller   common.Address // Sender of the call, the caller's caller for DELEGATECALL
+	Address  common.Address // Account the contract executes as, the caller's for CALLCODE and DELEGATECALL
+	Value    *uint256.Int   // Value transferred with the call, or inherited by DELEGATECALL
+	ReadOnly bool           // Whether state modifications are forbidden
+}
+
+// StateDB returns the state the contract executes on.
+func (env *PrecompileEnvironment) StateDB() StateDB {
+	return env.evm.StateDB
+}
+
+// BlockContext returns the context of the block the contract executes in.
+func (env *PrecompileEnvironment) BlockContext() BlockContext {
+	return env.evm.Context
+}
+
+// Origin returns the sender of the transaction.
+func (env *PrecompileEnvironment) Origin() common.Address {
+	return env.evm.Origin
+}
+
+// Rules returns the chain rules the contract executes under.
+func (env *PrecompileEnvironment) Rules() params.Rules {
+	return env.evm.chainRules
+}
+
+// AddLog emits a log from the account the contract executes as.
+func (env *PrecompileEnvironment) AddLog(topics []common.Hash, data []byte) error {
+	if env.ReadOnly {
+		return ErrWriteProtection
+	}
+	env.evm.StateDB.AddLog(&types.Log{
+		Address: env.Address,
+		Topics:  slices.Clone(topics),
+		Data:    common.CopyBytes(data),
+		// This is a non-consensus field, but assigned here because
+		// core/state doesn't know the current block number.
+		BlockNumber: env.evm.Context.BlockNumber.Uint64(),
+	})
+	return nil
+}
+
+// RunStatefulPrecompiledContract runs and evaluates the output of a stateful
+// precompiled contract, like RunPrecompiledContract.
+func RunStatefulPrecompiledContract(p StatefulPrecompiledContract, env *PrecompileEnvironment, input []byte, suppliedGas uint64, logger *tracing.Hooks) (ret []byte, remainingGas uint64, err error) {
+	gasCost := p.RequiredGas(input)
+	if suppliedGas < gasCost {
+		return nil, 0, ErrOutOfGas
+	}
+	if logger != nil && logger.OnGasChange != nil {
+		logger.OnGasChange(suppliedGas, suppliedGas-gasCost, tracing.GasChangeCallPrecompiledContract)
+	}
+	suppliedGas -= gasCost
+	output, err := p.RunStateful(env, input)
+	return output, suppliedGas, err
+}
+
+// runPrecompile runs a precompiled contract, providing the call context to the
+// stateful ones.
+func (evm *EVM) runPrecompile(p PrecompiledContract, caller, addr common.Address, input []byte, gas uint64, value *uint256.Int, readOnly bool) ([]byte, uint64, error) {
+	sp, ok := p.(StatefulPrecompiledContract)
+	if !ok {
+		return RunPrecompiledContract(p, input, gas, evm.Config.Tracer)
+	}
+	if value == nil {
+		value = new(uint256.Int)
+	}
+	env := &PrecompileEnvironment{
+		evm:      evm,
+		Caller:   caller,
+		Address:  addr,
+		Value:    value,
+		ReadOnly: readOnly,
+	}
+	return RunStatefulPrecompiledContract(sp, env, input, gas, evm.Config.Tracer)
+}
+
+var (
+	// registeredPrecompiles holds the native contracts chain configs can
+	// install, by name.
+	registeredPrecompiles     = make(map[string]PrecompiledContract)
+	registeredPrecompilesLock sync.RWMutex
+)
+
+// RegisterPrecompile makes a native contract available under a name, for the
+// precompile upgrades of chain configs to install at an address. Names are
+// unique, a contract can't be replaced once registered.
+//
+// As the precompiles are part of the consensus rules of a chain, they must be
+// registered before any block is processed, usually from an init function.
+func RegisterPrecompile(name string, contract PrecompiledContract) error {
+	if name == "" || contract == nil {
+		return errors.New("missing precompile name or contract")
+	}
+	registeredPrecompilesLock.Lock()
+	defer registeredPrecompilesLock.Unlock()
+
+	if _, ok := registeredPrecompiles[name]; ok {
+		return fmt.Errorf("precompile %q already registered", name)
+	}
+	registeredPrecompiles[name] = contract
+	return nil
+}
+
+// CheckPrecompiles verifies that all the contracts installed by the precompile
+// upgrades of a chain config are registered.
+func CheckPrecompiles(config *params.ChainConfig) error {
+	registeredPrecompilesLock.RLock()
+	defer registeredPrecompilesLock.RUnlock()
+
+	for _, upgrade := range config.Precompiles {
+		if upgrade.Contract == "" {
+			continue
+		}
+		if _, ok := registeredPrecompiles[upgrade.Contract]; !ok {
+			return fmt.Errorf("precompile %q installed at %v not registered", upgrade.Contract, upgrade.Address)
+		}
+	}
+	return nil
+}
+
+// withRegisteredPrecompiles returns the given precompiles along with the ones
+// installed by the chain config at the time of the rules. The base set is
+// returned if there are none.
+func withRegisteredPrecompiles(rules params.Rules, base PrecompiledContracts) PrecompiledContracts {
+	if len(rules.Precompiles) == 0 || rules.Precompiles[0].Time > rules.Time {
+		return base
+	}
+	registeredPrecompilesLock.RLock()
+	defer registeredPrecompilesLock.RUnlock()
+
+	precompiles := maps.Clone(base)
+	for _, upgrade := range rules.Precompiles {
+		if upgrade.Time > rules.Time {
+			break
+		}
+		// Contracts missing from the registry are rejected when the chain is
+		// set up, treat them as removed
+		if contract := registeredPrecompiles[upgrade.Contract]; contract != nil {
+			precompiles[upgrade.Address] = contract
+		} else {
+			delete(precompiles, upgrade.Address)
+		}
+	}
+	return precompiles
+}
+
+// withRegisteredAddresses returns the given precompile addresses along with the
+// ones installed by the chain config at the time of the rules, in ascending
+// order.
+func withRegisteredAddresses(rules params.Rules, base []common.Address) []common.Address {
+	if len(rules.Precompiles) == 0 || rules.Precompiles[0].Time > rules.Time {
+		return base
+	}
+	precompiles := make(PrecompiledContracts, len(base))
+	for _, addr := range base {
+		precompiles[addr] = nil
+	}
+	precompiles = withRegisteredPrecompiles(rules, precompiles)
+
+	addrs := make([]common.Address, 0, len(precompiles))
+	for addr := range precompiles {
+		addrs = append(addrs, addr)
+	}
+	slices.SortFunc(addrs, common.Address.Cmp)
+	return addrs
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// allowlist is a stateful precompile flagging the address in its input as
+// allowed, returning the account the call was executed as and its sender.
+type allowlist struct {
+	env *PrecompileEnvironment // Environment of the last call
+}
+
+func (a *allowlist) RequiredGas(input []byte) uint64 { return 100 }
+
+func (a *allowlist) Run(input []byte) ([]byte, error) { return nil, ErrStatefulPrecompile }
+
+func (a *allowlist) RunStateful(env *PrecompileEnvironment, input []byte) ([]byte, error) {
+	a.env = env
+	if env.ReadOnly {
+		return nil, ErrWriteProtection
+	}
+	slot := common.BytesToHash(input)
+	env.StateDB().SetState(env.Address, slot, common.Hash{31: 1})
+	if err := env.AddLog([]common.Hash{slot}, nil); err != nil {
+		return nil, err
+	}
+	return append(env.Address.Bytes(), env.Caller.Bytes()...), nil
+}
+
+// testAllowlist is the allowlist installed by the chain configs of the tests.
+var testAllowlist = new(allowlist)
+
+func init() {
+	if err := RegisterPrecompile("vm-test-allowlist", testAllowlist); err != nil {
+		panic(err)
+	}
+}
+
+// Tests that stateful precompiles installed by the chain config are activated
+// at their timestamp and executed with the context of the call.
+func TestStatefulPrecompile(t *testing.T) {
+	var (
+		config  = *params.AllEthashProtocolChanges
+		addr    = common.HexToAddress("0x0200000000000000000000000000000000000001")
+		low     = common.HexToAddress("0x0100")
+		sender  = common.HexToAddress("0xaaaa")
+		proxy   = common.HexToAddress("0xbbbb")
+		member  = common.HexToAddress("0xcccc")
+		precomp = testAllowlist
+	)
+	if err := RegisterPrecompile("vm-test-allowlist", new(allowlist)); err == nil {
+		t.Error("duplicate registration accepted")
+	}
+	config.Precompiles = []params.PrecompileUpgrade{
+		{Address: addr, Time: 100, Contract: "vm-test-allowlist"},
+		{Address: low, Time: 100, Contract: "vm-test-allowlist"},
+		{Address: addr, Time: 200},
+	}
+	if err := CheckPrecompiles(&config); err != nil {
+		t.Fatalf("registered precompiles rejected: %v", err)
+	}
+	missing := config
+	missing.Precompiles = []params.PrecompileUpgrade{{Address: addr, Contract: "vm-test-missing"}}
+	if err := CheckPrecompiles(&missing); err == nil {
+		t.Error("unregistered precompile accepted")
+	}
+	newEVM := func(time uint64) *EVM {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+		vmctx := BlockContext{
+			CanTransfer: func(StateDB, common.Address, *uint256.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *uint256.Int) {},
+			BlockNumber: big.NewInt(1),
+			Time:        time,
+		}
+		return NewEVM(vmctx, statedb, &config, Config{})
+	}
+	// The precompile is only active between its activation and deactivation
+	for _, tt := range []struct {
+		time   uint64
+		active bool
+	}{{99, false}, {100, true}, {199, true}, {200, false}} {
+		rules := config.Rules(common.Big1, false, tt.time)
+		addrs := ActivePrecompiles(rules)
+		if active := slices.Contains(addrs, addr); active != tt.active {
+			t.Errorf("time %d: active mismatch: have %v, want %v", tt.time, active, tt.active)
+		}
+		if tt.time >= 100 && !slices.IsSortedFunc(addrs, common.Address.Cmp) {
+			t.Errorf("time %d: active addresses not sorted: %v", tt.time, addrs)
+		}
+		if _, ok := newEVM(tt.time).precompile(addr); ok != tt.active {
+			t.Errorf("time %d: EVM precompile mismatch: have %v, want %v", tt.time, ok, tt.active)
+		}
+	}
+	if _, ok := ActivePrecompiledContracts(config.Rules(common.Big1, false, 100))[addr]; !ok {
+		t.Error("precompile missing from the active contracts")
+	}
+	if _, ok := ActivePrecompiledContracts(params.AllEthashProtocolChanges.Rules(common.Big1, false, 100))[addr]; ok {
+		t.Error("precompile active on a chain not installing it")
+	}
+	// Regular calls modify the state of the precompile and emit logs
+	evm := newEVM(150)
+	ret, gas, err := evm.Call(AccountRef(sender), addr, member.Bytes(), 1000, uint256.NewInt(7))
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if gas != 900 {
+		t.Errorf("gas mismatch: have %d, want 900", gas)
+	}
+	if want := append(addr.Bytes(), sender.Bytes()...); !slices.Equal(ret, want) {
+		t.Errorf("output mismatch: have %x, want %x", ret, want)
+	}
+	if precomp.env.Value.Uint64() != 7 || precomp.env.ReadOnly {
+		t.Errorf("environment mismatch: value %v, read-only %v", precomp.env.Value, precomp.env.ReadOnly)
+	}
+	if evm.StateDB.GetState(addr, common.BytesToHash(member.Bytes())) != (common.Hash{31: 1}) {
+		t.Error("precompile state not updated")
+	}
+	if logs := evm.StateDB.(*state.StateDB).Logs(); len(logs) != 1 || logs[0].Address != addr {
+		t.Errorf("logs mismatch: %v", logs)
+	}
+	// Static calls are read-only, failures revert the state
+	evm = newEVM(150)
+	if _, _, err := evm.StaticCall(AccountRef(sender), addr, member.Bytes(), 1000); !errors.Is(err, ErrWriteProtection) {
+		t.Errorf("static call error mismatch: have %v, want %v", err, ErrWriteProtection)
+	}
+	if !precomp.env.ReadOnly {
+		t.Error("static call not read-only")
+	}
+	// Delegate calls execute as the caller, on behalf of its sender
+	evm = newEVM(150)
+	caller := NewContract(AccountRef(sender), AccountRef(proxy), uint256.NewInt(3), 1000)
+	ret, _, err = evm.DelegateCall(caller, addr, member.Bytes(), 1000)
+	if err != nil {
+		t.Fatalf("delegate call failed: %v", err)
+	}
+	if want := append(proxy.Bytes(), sender.Bytes()...); !slices.Equal(ret, want) {
+		t.Errorf("delegate call output mismatch: have %x, want %x", ret, want)
+	}
+	if precomp.env.Value.Uint64() != 3 {
+		t.Errorf("delegate call value mismatch: have %v, want 3", precomp.env.Value)
+	}
+	if evm.StateDB.GetState(proxy, common.BytesToHash(member.Bytes())) != (common.Hash{31: 1}) {
+		t.Error("delegate call state not updated in the caller")
+	}
+}
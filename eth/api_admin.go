@@ -141,3 +141,27 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	}
 	return true, nil
 }
+
+// IndexerBackfill starts indexing the canonical blocks in the inclusive range
+// [first, last] into the indexer database in the background. Its progress can
+// be followed via IndexerBackfillStatus.
+func (api *AdminAPI) IndexerBackfill(first uint64, last uint64) (bool, error) {
+	indexer := api.eth.Indexer()
+	if indexer == nil {
+		return false, errors.New("indexer not enabled")
+	}
+	if err := indexer.StartBackfill(first, last, core.BackfillConfig{}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// IndexerBackfillStatus returns the progress of the running or last indexer
+// backfill.
+func (api *AdminAPI) IndexerBackfillStatus() (core.BackfillStatus, error) {
+	indexer := api.eth.Indexer()
+	if indexer == nil {
+		return core.BackfillStatus{}, errors.New("indexer not enabled")
+	}
+	return indexer.BackfillStatus(), nil
+}
@@ -0,0 +1,319 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	defaultIndexerPageSize = 100  // Number of items per page if not requested otherwise
+	maxIndexerPageSize     = 1000 // Maximum number of items per page
+)
+
+// IndexerAPI serves the address activity recorded by the database indexer,
+// which can't be looked up in the chain without scanning it.
+type IndexerAPI struct {
+	eth     *Ethereum
+	indexer *core.IndexerPlugin
+}
+
+// NewIndexerAPI creates a new instance of IndexerAPI.
+func NewIndexerAPI(eth *Ethereum, indexer *core.IndexerPlugin) *IndexerAPI {
+	return &IndexerAPI{eth: eth, indexer: indexer}
+}
+
+// IndexerPageArgs selects a page of a paginated query.
+type IndexerPageArgs struct {
+	Cursor string               `json:"cursor"` // Cursor returned with the previous page, empty for the first one
+	Limit  *math.HexOrDecimal64 `json:"limit"`  // Maximum number of items on the page
+}
+
+// limit returns the requested page size, capped at the maximum.
+func (args *IndexerPageArgs) limit() (int, error) {
+	if args == nil || args.Limit == nil {
+		return defaultIndexerPageSize, nil
+	}
+	limit := uint64(*args.Limit)
+	if limit == 0 || limit > maxIndexerPageSize {
+		return 0, fmt.Errorf("page limit must be between 1 and %d", maxIndexerPageSize)
+	}
+	return int(limit), nil
+}
+
+// cursor returns the requested page cursor.
+func (args *IndexerPageArgs) cursor() string {
+	if args == nil {
+		return ""
+	}
+	return args.Cursor
+}
+
+// IndexerTransaction is a transaction of the address activity.
+type IndexerTransaction struct {
+	Hash             common.Hash     `json:"hash"`
+	BlockNumber      hexutil.Uint64  `json:"blockNumber"`
+	TransactionIndex hexutil.Uint64  `json:"transactionIndex"`
+	From             common.Address  `json:"from"`
+	To               *common.Address `json:"to"`
+	Value            *hexutil.Big    `json:"value"`
+	Nonce            hexutil.Uint64  `json:"nonce"`
+	GasPrice         *hexutil.Big    `json:"gasPrice"`
+	Gas              hexutil.Uint64  `json:"gas"`
+	GasUsed          hexutil.Uint64  `json:"gasUsed"`
+	Input            hexutil.Bytes   `json:"input"`
+	Status           hexutil.Uint64  `json:"status"`
+	Type             hexutil.Uint64  `json:"type"`
+	Error            string          `json:"error,omitempty"`
+}
+
+// IndexerTransactionPage is a page of transactions, continued by the next cursor
+// if it's not empty.
+type IndexerTransactionPage struct {
+	Transactions []*IndexerTransaction `json:"transactions"`
+	Next         string                `json:"next,omitempty"`
+}
+
+// IndexerContract is a contract created by an address.
+type IndexerContract struct {
+	Address         common.Address `json:"address"`
+	Creator         common.Address `json:"creator"`
+	TransactionHash common.Hash    `json:"transactionHash"`
+	Timestamp       hexutil.Uint64 `json:"timestamp"`
+	Balance         *hexutil.Big   `json:"balance"`
+	Nonce           hexutil.Uint64 `json:"nonce"`
+	SelfDestructed  bool           `json:"selfDestructed"`
+}
+
+// IndexerContractPage is a page of contracts, continued by the next cursor if
+// it's not empty.
+type IndexerContractPage struct {
+	Contracts []*IndexerContract `json:"contracts"`
+	Next      string             `json:"next,omitempty"`
+}
+
+// IndexerStateChange is the net change of an account field or storage slot
+// made by a transaction, system call or block level operation.
+type IndexerStateChange struct {
+	BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+	TransactionHash *common.Hash   `json:"transactionHash"`
+	Address         common.Address `json:"address"`
+	Slot            *common.Hash   `json:"slot,omitempty"`
+	Kind            string         `json:"kind"`
+	Source          string         `json:"source"`
+	Previous        string         `json:"previous"`
+	Value           string         `json:"value"`
+}
+
+// IndexerStateChangePage is a page of state changes, continued by the next
+// cursor if it's not empty.
+type IndexerStateChangePage struct {
+	StateChanges []*IndexerStateChange `json:"stateChanges"`
+	Next         string                `json:"next,omitempty"`
+}
+
+// IndexerSyncStatus reports how far the indexer database follows the chain.
+type IndexerSyncStatus struct {
+	IndexedBlock   hexutil.Uint64      `json:"indexedBlock"`   // Highest indexed block
+	FinalizedBlock hexutil.Uint64      `json:"finalizedBlock"` // Highest indexed block marked final
+	HeadBlock      hexutil.Uint64      `json:"headBlock"`      // Current head of the chain
+	Synced         bool                `json:"synced"`         // Whether the chain head is indexed
+	Backfill       core.BackfillStatus `json:"backfill"`       // Progress of the last historical backfill
+}
+
+// GetTransactionsByAddress returns the transactions sent from or to an address
+// in chain order, a page at a time.
+func (api *IndexerAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, page *IndexerPageArgs) (*IndexerTransactionPage, error) {
+	limit, err := page.limit()
+	if err != nil {
+		return nil, err
+	}
+	txs, next, err := api.indexer.DB().GetTransactionsByAddress(ctx, address, page.cursor(), limit)
+	if err != nil {
+		return nil, err
+	}
+	result := &IndexerTransactionPage{
+		Transactions: make([]*IndexerTransaction, len(txs)),
+		Next:         next,
+	}
+	for i, tx := range txs {
+		result.Transactions[i] = &IndexerTransaction{
+			Hash:             tx.Hash,
+			BlockNumber:      hexutil.Uint64(tx.BlockNumber),
+			TransactionIndex: hexutil.Uint64(tx.TransactionIndex),
+			From:             tx.From,
+			To:               tx.To,
+			Value:            (*hexutil.Big)(tx.Value.ToBig()),
+			Nonce:            hexutil.Uint64(tx.Nonce),
+			GasPrice:         (*hexutil.Big)(tx.GasPrice.ToBig()),
+			Gas:              hexutil.Uint64(tx.GasLimit),
+			GasUsed:          hexutil.Uint64(tx.GasUsed),
+			Input:            tx.Input,
+			Status:           hexutil.Uint64(tx.Status),
+			Type:             hexutil.Uint64(tx.Type),
+			Error:            tx.Error.String,
+		}
+	}
+	return result, nil
+}
+
+// GetContractsCreatedBy returns the contracts created by an address, directly
+// or through a factory, in the order of their creation, a page at a time.
+func (api *IndexerAPI) GetContractsCreatedBy(ctx context.Context, creator common.Address, page *IndexerPageArgs) (*IndexerContractPage, error) {
+	limit, err := page.limit()
+	if err != nil {
+		return nil, err
+	}
+	accounts, next, err := api.indexer.DB().GetContractsCreatedBy(ctx, creator, page.cursor(), limit)
+	if err != nil {
+		return nil, err
+	}
+	result := &IndexerContractPage{
+		Contracts: make([]*IndexerContract, len(accounts)),
+		Next:      next,
+	}
+	for i, account := range accounts {
+		result.Contracts[i] = &IndexerContract{
+			Address:         account.Address,
+			Creator:         *account.CreatorAddress,
+			TransactionHash: *account.CreatorTxHash,
+			Timestamp:       hexutil.Uint64(account.CreatedAt.Time.Unix()),
+			Balance:         (*hexutil.Big)(account.Balance.ToBig()),
+			Nonce:           hexutil.Uint64(account.Nonce),
+			SelfDestructed:  account.SelfDestructed,
+		}
+	}
+	return result, nil
+}
+
+// GetStateChanges returns the state changes of an address in the inclusive
+// block range in chain order, a page at a time. If a storage slot is given,
+// only its changes are returned. The range defaults to the whole chain.
+func (api *IndexerAPI) GetStateChanges(ctx context.Context, address common.Address, slot *common.Hash, fromBlock *hexutil.Uint64, toBlock *hexutil.Uint64, page *IndexerPageArgs) (*IndexerStateChangePage, error) {
+	limit, err := page.limit()
+	if err != nil {
+		return nil, err
+	}
+	from, to := uint64(0), api.eth.BlockChain().CurrentBlock().Number.Uint64()
+	if fromBlock != nil {
+		from = uint64(*fromBlock)
+	}
+	if toBlock != nil {
+		to = uint64(*toBlock)
+	}
+	if from > to {
+		return nil, errors.New("invalid block range")
+	}
+	changes, next, err := api.indexer.DB().GetStateChanges(ctx, address, slot, from, to, page.cursor(), limit)
+	if err != nil {
+		return nil, err
+	}
+	result := &IndexerStateChangePage{
+		StateChanges: make([]*IndexerStateChange, len(changes)),
+		Next:         next,
+	}
+	for i, change := range changes {
+		result.StateChanges[i] = &IndexerStateChange{
+			BlockNumber:     hexutil.Uint64(change.BlockNumber),
+			TransactionHash: change.TransactionHash,
+			Address:         change.Address,
+			Slot:            change.StorageKey,
+			Kind:            change.ChangeType,
+			Source:          change.Source,
+			Previous:        change.PrevValue,
+			Value:           change.NewValue,
+		}
+	}
+	return result, nil
+}
+
+// GetSyncStatus reports how far the indexer database follows the chain.
+func (api *IndexerAPI) GetSyncStatus() (*IndexerSyncStatus, error) {
+	db := api.indexer.DB()
+	indexed, err := db.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	finalized, err := db.GetLatestFinalizedBlock()
+	if err != nil {
+		return nil, err
+	}
+	head := api.eth.BlockChain().CurrentBlock().Number.Uint64()
+	return &IndexerSyncStatus{
+		IndexedBlock:   hexutil.Uint64(indexed),
+		FinalizedBlock: hexutil.Uint64(finalized),
+		HeadBlock:      hexutil.Uint64(head),
+		Synced:         indexed >= head,
+		Backfill:       api.indexer.BackfillStatus(),
+	}, nil
+}
+
+// IndexedBlocksArgs configures an indexedBlocks subscription.
+type IndexedBlocksArgs struct {
+	Finalized bool `json:"finalized"` // Whether to also announce finalizations
+}
+
+// IndexedBlock is the notification of a block committed to the indexer
+// database. Blocks replaced by a reorg are announced again with their new hash.
+type IndexedBlock struct {
+	Number    hexutil.Uint64 `json:"number"`
+	Hash      common.Hash    `json:"hash"`
+	Finalized bool           `json:"finalized"`
+}
+
+// IndexedBlocks creates a subscription that fires once a new canonical block,
+// and optionally its finalization, has been committed to the indexer database,
+// so that its rows are guaranteed to be readable when the notification arrives.
+func (api *IndexerAPI) IndexedBlocks(ctx context.Context, args *IndexedBlocksArgs) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	finalized := args != nil && args.Finalized
+
+	rpcSub := notifier.CreateSubscription()
+	go func() {
+		events := make(chan core.IndexedBlockEvent, 128)
+		sub := api.indexer.SubscribeIndexedBlocks(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				if ev.Finalized && !finalized {
+					continue
+				}
+				notifier.Notify(rpcSub.ID, &IndexedBlock{
+					Number:    hexutil.Uint64(ev.Header.Number.Uint64()),
+					Hash:      ev.Header.Hash(),
+					Finalized: ev.Finalized,
+				})
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
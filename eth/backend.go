@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math/big"
 	"runtime"
+	"slices"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/accounts"
@@ -102,7 +103,7 @@ type Ethereum struct {
 
 // New creates a new Ethereum object (including the initialisation of the common Ethereum object),
 // whose lifecycle will be managed by the provided node.
-func New(stack *node.Node, config *ethconfig.Config, plugin *core.IndexerPlugin) (*Ethereum, error) {
+func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	// Ensure configuration values are compatible and sane
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
@@ -218,8 +219,16 @@ func New(stack *node.Node, config *ethconfig.Config, plugin *core.IndexerPlugin)
 		overrides.OverrideVerkle = config.OverrideVerkle
 	}
 
+	// Collect the chain plugins from the config and from any services already
+	// registered on the node which also implement the plugin interface.
+	plugins := slices.Clone(config.Plugins)
+	for _, lifecycle := range stack.Lifecycles() {
+		if plugin, ok := lifecycle.(core.Plugin); ok && !slices.Contains(plugins, plugin) {
+			plugins = append(plugins, plugin)
+		}
+	}
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, config.Genesis,
-		&overrides, eth.engine, vmConfig, &config.TransactionHistory, plugin)
+		&overrides, eth.engine, vmConfig, &config.TransactionHistory, plugins...)
 	if err != nil {
 		return nil, err
 	}
@@ -303,6 +312,14 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the queries of the database indexer if it's enabled
+	if indexer := s.Indexer(); indexer != nil && indexer.DB() != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "indexer",
+			Service:   NewIndexerAPI(s, indexer),
+		})
+	}
+
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -324,6 +341,16 @@ func (s *Ethereum) APIs() []rpc.API {
 	}...)
 }
 
+// Indexer returns the database indexer attached to the blockchain, if any.
+func (s *Ethereum) Indexer() *core.IndexerPlugin {
+	for _, plugin := range s.blockchain.Plugins() {
+		if indexer, ok := plugin.(*core.IndexerPlugin); ok {
+			return indexer
+		}
+	}
+	return nil
+}
+
 func (s *Ethereum) ResetWithGenesisBlock(gb *types.Block) {
 	s.blockchain.ResetWithGenesisBlock(gb)
 }
@@ -66,6 +66,7 @@ var Defaults = Config{
 	RPCEVMTimeout:      5 * time.Second,
 	GPO:                FullNodeGPO,
 	RPCTxFeeCap:        1, // 1 ether
+	Indexer:            core.DefaultIndexerConfig,
 }
 
 //go:generate go run github.com/fjl/gencodec -type Config -formats toml -out gen_config.go
@@ -154,8 +155,12 @@ type Config struct {
 	// OverrideVerkle (TODO: remove after the fork)
 	OverrideVerkle *uint64 `toml:",omitempty"`
 
-	// Indexer configuration
-	IndexerConfig *core.IndexerConfig
+	// Indexer database and plugin options
+	Indexer core.IndexerConfig
+
+	// Plugins are attached to the blockchain on startup and receive chain
+	// events in the given order.
+	Plugins []core.Plugin `toml:"-"`
 }
 
 // CreateConsensusEngine creates a consensus engine for the given chain config.
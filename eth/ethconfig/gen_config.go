@@ -50,6 +50,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		RPCTxFeeCap             float64
 		OverrideCancun          *uint64 `toml:",omitempty"`
 		OverrideVerkle          *uint64 `toml:",omitempty"`
+		Indexer                 core.IndexerConfig
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -86,6 +87,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
 	enc.OverrideCancun = c.OverrideCancun
 	enc.OverrideVerkle = c.OverrideVerkle
+	enc.Indexer = c.Indexer
 	return &enc, nil
 }
 
@@ -126,6 +128,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		RPCTxFeeCap             *float64
 		OverrideCancun          *uint64 `toml:",omitempty"`
 		OverrideVerkle          *uint64 `toml:",omitempty"`
+		Indexer                 *core.IndexerConfig
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -233,5 +236,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.OverrideVerkle != nil {
 		c.OverrideVerkle = dec.OverrideVerkle
 	}
+	if dec.Indexer != nil {
+		c.Indexer = *dec.Indexer
+	}
 	return nil
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -148,8 +149,39 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		return nil, err
 	}
 
-	logChan, errChan := f.rangeLogsAsync(ctx)
+	// Serve the indexed part of the range from the log index if possible,
+	// falling back to the bloombits and block iteration for the rest
+	first, last, indexed, ok, err := f.logIndexLogs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return f.rangeLogs(ctx)
+	}
+	var (
+		end  = f.end
+		logs []*types.Log
+	)
+	if first > f.begin {
+		f.end = first - 1
+		if logs, err = f.rangeLogs(ctx); err != nil {
+			return logs, err
+		}
+	}
+	logs = append(logs, indexed...)
+	if last < end {
+		f.begin, f.end = last+1, end
+		tail, err := f.rangeLogs(ctx)
+		return append(logs, tail...), err
+	}
+	return logs, nil
+}
+
+// rangeLogs retrieves the logs of the block range matching the filter criteria
+// from the bloombits and the blocks themselves.
+func (f *Filter) rangeLogs(ctx context.Context) ([]*types.Log, error) {
 	var logs []*types.Log
+	logChan, errChan := f.rangeLogsAsync(ctx)
 	for {
 		select {
 		case log := <-logChan:
@@ -160,6 +192,41 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	}
 }
 
+// logIndexLogs retrieves the logs of the first part of the range that is
+// available in the external log index, along with its first and last block.
+// Without an index or if it doesn't follow the local chain, nothing is
+// retrieved.
+func (f *Filter) logIndexLogs(ctx context.Context) (int64, int64, []*types.Log, bool, error) {
+	index := f.sys.cfg.LogIndex
+	if index == nil || f.begin < 0 || f.begin > f.end {
+		return 0, 0, nil, false, nil
+	}
+	first, last, hash, ok, err := index.GetIndexedRange(uint64(f.begin), uint64(f.end))
+	if err != nil {
+		log.Warn("Failed to query log index", "err", err)
+		return 0, 0, nil, false, nil
+	}
+	if !ok {
+		return 0, 0, nil, false, nil
+	}
+	// The index is updated asynchronously and reorgs are applied to it at once,
+	// so it's consistent with the chain if the last block is
+	header, err := f.sys.backend.HeaderByNumber(ctx, rpc.BlockNumber(last))
+	if err != nil {
+		return 0, 0, nil, false, err
+	}
+	if header == nil || header.Hash() != hash {
+		log.Debug("Log index behind the chain", "number", last, "indexed", hash)
+		return 0, 0, nil, false, nil
+	}
+	logs, err := index.GetLogs(ctx, first, last, f.addresses, f.topics)
+	if err != nil {
+		log.Warn("Failed to retrieve logs from index", "err", err)
+		return 0, 0, nil, false, nil
+	}
+	return int64(first), int64(last), logs, true, nil
+}
+
 // rangeLogsAsync retrieves block-range logs that match the filter criteria asynchronously,
 // it creates and returns two channels: one for delivering log data, and one for reporting errors.
 func (f *Filter) rangeLogsAsync(ctx context.Context) (chan *types.Log, chan error) {
@@ -0,0 +1,47 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build postgres
+
+package filters
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core"
+)
+
+// Tests that logs served from a PostgreSQL indexer database, which matches
+// topics natively on arrays, are identical to the ones found by scanning the
+// chain. The database is given by the GETH_INDEXER_TEST_DSN environment
+// variable and emptied by the test.
+func TestIndexedLogsPostgres(t *testing.T) {
+	dsn := os.Getenv("GETH_INDEXER_TEST_DSN")
+	if dsn == "" {
+		t.Skip("GETH_INDEXER_TEST_DSN not set")
+	}
+	index, err := core.NewDB(core.IndexerConfig{Driver: core.DriverPostgres, DSN: dsn})
+	if err != nil {
+		t.Fatalf("failed to open indexer database: %v", err)
+	}
+	defer index.Close()
+
+	if err := index.DeleteBlockAndDescendants(0); err != nil {
+		t.Fatalf("failed to empty indexer database: %v", err)
+	}
+	testIndexedLogs(t, index)
+}
@@ -42,6 +42,7 @@ import (
 type Config struct {
 	LogCacheSize int           // maximum number of cached blocks (default: 32)
 	Timeout      time.Duration // how long filters stay active (default: 5min)
+	LogIndex     LogIndex      // external log database serving range queries (optional)
 }
 
 func (cfg Config) withDefaults() Config {
@@ -73,6 +74,21 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// LogIndex is an external database of the canonical chain's logs, like the SQL
+// indexer, able to answer range queries without scanning the chain block by
+// block. It may lag behind the chain or only cover parts of it.
+type LogIndex interface {
+	// GetIndexedRange returns the first gapless run of indexed blocks within
+	// the inclusive range: the numbers of its first and last blocks, and the
+	// hash of the last one. It reports false if no block of the range is
+	// indexed.
+	GetIndexedRange(from, to uint64) (uint64, uint64, common.Hash, bool, error)
+
+	// GetLogs returns the logs of the indexed blocks in the inclusive range
+	// matching the addresses and topics, ordered as in the chain.
+	GetLogs(ctx context.Context, from, to uint64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error)
+}
+
 // FilterSystem holds resources shared by all filters.
 type FilterSystem struct {
 	backend   Backend
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"math/big"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -28,9 +29,11 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	_ "github.com/ethereum/go-ethereum/core/indexer/sqlite" // SQLite indexer database
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -387,3 +390,156 @@ func TestFilters(t *testing.T) {
 		}
 	})
 }
+
+// servedLogIndex counts the log queries served from a log index.
+type servedLogIndex struct {
+	LogIndex
+	queries int
+}
+
+func (i *servedLogIndex) GetLogs(ctx context.Context, from, to uint64, addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error) {
+	i.queries++
+	return i.LogIndex.GetLogs(ctx, from, to, addresses, topics)
+}
+
+// Tests that logs served from the indexer database are identical to the ones
+// found by scanning the chain, also for ranges extending past the index.
+func TestIndexedLogs(t *testing.T) {
+	index, err := core.NewDB(core.IndexerConfig{Driver: core.DriverSQLite, Path: filepath.Join(t.TempDir(), "indexer.sqlite")})
+	if err != nil {
+		t.Fatalf("failed to open indexer database: %v", err)
+	}
+	defer index.Close()
+
+	testIndexedLogs(t, index)
+}
+
+// testIndexedLogs indexes a chain into the given empty indexer database and
+// checks that the logs served from it are identical to the ones found by
+// scanning the chain.
+func testIndexedLogs(t *testing.T, index core.IndexerDB) {
+	var (
+		key, _ = crypto.GenerateKey()
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+
+		topicA = common.Hash{0xa}
+		topicB = common.Hash{0xb}
+		topicC = common.Hash{0xc}
+
+		emitters = []common.Address{{0xe1}, {0xe2}, {0xe3}}
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				emitters[0]: {Code: program.New().Mstore([]byte{0x12, 0x34}, 0).
+					Push(topicB).Push(topicA).Push(2).Push(0).Op(vm.LOG2).
+					Push(0).Push(0).Op(vm.LOG0).Bytes()},
+				emitters[1]: {Code: program.New().
+					Push(topicB).Push(0).Push(0).Op(vm.LOG1).
+					Push(topicB).Push(topicC).Push(topicA).Push(0).Push(0).Op(vm.LOG3).Bytes()},
+				emitters[2]: {Code: program.New().Mstore([]byte{0xff}, 0).
+					Push(1).Push(31).Op(vm.LOG0).Bytes()},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 20, func(i int, gen *core.BlockGen) {
+		for j, emitter := range emitters {
+			if (i+j)%2 == 0 {
+				gen.AddTx(types.MustSignNewTx(key, signer, &types.LegacyTx{
+					Nonce:    gen.TxNonce(sender),
+					To:       &emitter,
+					Gas:      100000,
+					GasPrice: gen.BaseFee(),
+				}))
+			}
+		}
+	})
+	db := rawdb.NewMemoryDatabase()
+	chain, err := core.NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, core.NewIndexerPlugin(index))
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if first, last, _, ok, _ := index.GetIndexedRange(1, 20); ok && first == 1 && last == 20 {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("chain not indexed")
+		}
+	}
+	var (
+		_, native  = newTestFilterSystem(t, db, Config{})
+		served     = &servedLogIndex{LogIndex: index}
+		_, indexed = newTestFilterSystem(t, db, Config{LogIndex: served})
+	)
+	check := func(name string) {
+		t.Helper()
+
+		for i, tc := range []struct {
+			begin, end int64
+			addresses  []common.Address
+			topics     [][]common.Hash
+		}{
+			{0, 20, nil, nil}, // genesis is not indexed
+			{1, 20, nil, nil},
+			{5, 5, nil, nil},
+			{3, 17, []common.Address{emitters[0]}, nil},
+			{1, 20, []common.Address{emitters[1], emitters[2]}, nil},
+			{1, 20, nil, [][]common.Hash{{topicA}}},
+			{1, 20, nil, [][]common.Hash{{}, {topicB}}},
+			{1, 20, nil, [][]common.Hash{{topicA, topicB}, {topicB, topicC}}},
+			{1, 20, nil, [][]common.Hash{{}}},
+			{1, 20, nil, [][]common.Hash{{}, {}, {topicB}}},
+			{1, 20, []common.Address{emitters[1]}, [][]common.Hash{{topicB}}},
+			{1, 20, []common.Address{emitters[2]}, [][]common.Hash{{topicA}}},
+			{0, 20, []common.Address{emitters[0], emitters[1]}, [][]common.Hash{{topicA, topicB}, {}, {topicA}}},
+			{8, int64(rpc.LatestBlockNumber), nil, [][]common.Hash{{topicA}}},
+		} {
+			want, err := native.NewRangeFilter(tc.begin, tc.end, tc.addresses, tc.topics).Logs(context.Background())
+			if err != nil {
+				t.Fatalf("%s: query %d: failed to filter chain: %v", name, i, err)
+			}
+			queries := served.queries
+			have, err := indexed.NewRangeFilter(tc.begin, tc.end, tc.addresses, tc.topics).Logs(context.Background())
+			if err != nil {
+				t.Fatalf("%s: query %d: failed to filter index: %v", name, i, err)
+			}
+			if served.queries == queries {
+				t.Errorf("%s: query %d: not served from the index", name, i)
+			}
+			haveJSON, _ := json.Marshal(have)
+			wantJSON, _ := json.Marshal(want)
+			if string(haveJSON) != string(wantJSON) {
+				t.Errorf("%s: query %d: log mismatch\nhave %s\nwant %s", name, i, haveJSON, wantJSON)
+			}
+		}
+	}
+	check("full index")
+
+	// Drop the head of the index, leaving a tail to be served from the chain
+	if err := index.DeleteBlockAndDescendants(14); err != nil {
+		t.Fatalf("failed to truncate index: %v", err)
+	}
+	check("partial index")
+
+	// Drop the first blocks of the index too, as if it started later
+	tx, err := index.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	for number := uint64(1); number <= 3; number++ {
+		if err := index.DeleteBlockWithTx(tx, number); err != nil {
+			t.Fatalf("failed to delete block %d: %v", number, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit deletion: %v", err)
+	}
+	check("index within range")
+}
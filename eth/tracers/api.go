@@ -67,6 +67,10 @@ const (
 	// for tracing. The creation of trace state will be paused if the unused
 	// trace states exceed this limit.
 	maximumPendingTraceStates = 128
+
+	// maxTraceCallManyCalls is the maximum number of calls, across all bundles,
+	// traced by a single TraceCallMany request.
+	maxTraceCallManyCalls = 1000
 )
 
 var errTxNotFound = errors.New("transaction not found")
@@ -905,6 +909,163 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 // the trace will be conducted on the state after executing the specified transaction
 // within the specified block.
 func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
+	block, statedb, release, err := api.callState(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+	// Apply the customization rules if required.
+	if config != nil {
+		if err := api.applyOverrides(&vmctx, statedb, config.BlockOverrides, config.StateOverrides); err != nil {
+			return nil, err
+		}
+	}
+	// Execute the trace
+	msg, tx, vmctx, err := api.callMessage(&args, vmctx)
+	if err != nil {
+		return nil, err
+	}
+	var traceConfig *TraceConfig
+	if config != nil {
+		traceConfig = &config.TraceConfig
+	}
+	return api.traceTx(ctx, tx, msg, new(Context), vmctx, statedb, traceConfig)
+}
+
+// Bundle is a list of calls executed one after the other in the same block
+// context, with optional overrides of the block and of the state.
+type Bundle struct {
+	Calls          []ethapi.TransactionArgs `json:"calls"`
+	StateOverrides *override.StateOverride  `json:"stateOverrides"`
+	BlockOverrides *override.BlockOverrides `json:"blockOverrides"`
+}
+
+// callTraceResult is the result of a single call trace of a bundle.
+type callTraceResult struct {
+	Result interface{} `json:"result,omitempty"` // Trace results produced by the tracer
+	Error  string      `json:"error,omitempty"`  // Trace failure produced by the tracer
+}
+
+// TraceCallMany lets you trace a list of call bundles on top of the provided
+// block. The calls are executed in order, each one on top of the state left by
+// the previous ones, and the trace of every call is returned, grouped by bundle.
+//
+// The overrides of the config are applied before the first bundle, and the block
+// overrides of each bundle on top of them. A call failing to execute or to be
+// traced, e.g. by timing out, doesn't abort the bundles, its error is reported
+// in its result and its state changes are discarded. At most
+// maxTraceCallManyCalls calls are traced per request.
+func (api *API) TraceCallMany(ctx context.Context, bundles []Bundle, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) ([][]*callTraceResult, error) {
+	if len(bundles) == 0 {
+		return nil, errors.New("empty bundle list")
+	}
+	var calls int
+	for _, bundle := range bundles {
+		calls += len(bundle.Calls)
+	}
+	if calls > maxTraceCallManyCalls {
+		return nil, fmt.Errorf("too many calls: %d, limit %d", calls, maxTraceCallManyCalls)
+	}
+	block, statedb, release, err := api.callState(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var (
+		blockctx    = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		traceConfig *TraceConfig
+		txIndex     int
+	)
+	if config != nil {
+		if err := api.applyOverrides(&blockctx, statedb, config.BlockOverrides, config.StateOverrides); err != nil {
+			return nil, err
+		}
+		traceConfig = &config.TraceConfig
+		if config.TxIndex != nil {
+			txIndex = int(*config.TxIndex)
+		}
+	}
+	// All calls are traced on the same state, copied only to checkpoint it. The
+	// changes of a call failing to execute are reverted to its snapshot. Once a
+	// call is applied the state is finalised, so if its tracer fails the state
+	// is rebuilt from the checkpoint by replaying the changes made since.
+	var (
+		checkpoint = statedb.Copy()
+		replay     []func(*state.StateDB) error
+	)
+	results := make([][]*callTraceResult, len(bundles))
+	for i, bundle := range bundles {
+		vmctx := blockctx
+		if err := api.applyOverrides(&vmctx, statedb, bundle.BlockOverrides, bundle.StateOverrides); err != nil {
+			return nil, fmt.Errorf("bundle %d: %v", i, err)
+		}
+		if bundle.StateOverrides != nil {
+			replay = append(replay, func(statedb *state.StateDB) error {
+				vmctx := blockctx
+				return api.applyOverrides(&vmctx, statedb, bundle.BlockOverrides, bundle.StateOverrides)
+			})
+		}
+		results[i] = make([]*callTraceResult, len(bundle.Calls))
+		for j := range bundle.Calls {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			msg, tx, callctx, err := api.callMessage(&bundle.Calls[j], vmctx)
+			if err != nil {
+				return nil, fmt.Errorf("bundle %d call %d: %v", i, j, err)
+			}
+			txctx := &Context{
+				BlockHash:   block.Hash(),
+				BlockNumber: vmctx.BlockNumber,
+				TxIndex:     txIndex,
+				TxHash:      tx.Hash(),
+			}
+			snapshot := statedb.Snapshot()
+			res, applied, err := api.traceTxApplied(ctx, tx, msg, txctx, callctx, statedb, traceConfig)
+			switch {
+			case err == nil:
+				results[i][j] = &callTraceResult{Result: res}
+				replay = append(replay, func(statedb *state.StateDB) error {
+					return api.applyTx(tx, msg, txctx, callctx, statedb)
+				})
+				txIndex++
+
+			case !applied:
+				results[i][j] = &callTraceResult{Error: err.Error()}
+				statedb.RevertToSnapshot(snapshot)
+
+			default:
+				results[i][j] = &callTraceResult{Error: err.Error()}
+				statedb = checkpoint
+				for _, apply := range replay {
+					if err := apply(statedb); err != nil {
+						return nil, fmt.Errorf("bundle %d call %d: failed to restore state: %v", i, j, err)
+					}
+				}
+				checkpoint, replay = statedb.Copy(), nil
+			}
+		}
+	}
+	return results, nil
+}
+
+// applyTx applies a transaction to the state without tracing it.
+func (api *API) applyTx(tx *types.Transaction, message *core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB) error {
+	var (
+		evm     = vm.NewEVM(vmctx, statedb, api.backend.ChainConfig(), vm.Config{NoBaseFee: true})
+		usedGas uint64
+	)
+	statedb.SetTxContext(txctx.TxHash, txctx.TxIndex)
+	_, err := core.ApplyTransactionWithEVM(message, new(core.GasPool).AddGas(message.GasLimit), statedb, vmctx.BlockNumber, txctx.BlockHash, tx, &usedGas, evm)
+	return err
+}
+
+// callState returns the block to trace calls on top of, along with its state
+// after the transaction index of the config, if any.
+func (api *API) callState(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (*types.Block, *state.StateDB, StateReleaseFunc, error) {
 	// Try to retrieve the specified block
 	var (
 		err     error
@@ -921,14 +1082,14 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 			// more flexibility and stability than trying to trace on 'pending', since
 			// the contents of 'pending' is unstable and probably not a true representation
 			// of what the next actual block is likely to contain.
-			return nil, errors.New("tracing on top of pending is not supported")
+			return nil, nil, nil, errors.New("tracing on top of pending is not supported")
 		}
 		block, err = api.blockByNumber(ctx, number)
 	} else {
-		return nil, errors.New("invalid arguments; neither block nor hash specified")
+		return nil, nil, nil, errors.New("invalid arguments; neither block nor hash specified")
 	}
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	// try to recompute the state
 	reexec := defaultTraceReexec
@@ -942,29 +1103,32 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 		statedb, release, err = api.backend.StateAtBlock(ctx, block, reexec, nil, true, false)
 	}
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
-	defer release()
+	return block, statedb, release, nil
+}
 
-	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
-	// Apply the customization rules if required.
-	if config != nil {
-		config.BlockOverrides.Apply(&vmctx)
-		rules := api.backend.ChainConfig().Rules(vmctx.BlockNumber, vmctx.Random != nil, vmctx.Time)
+// applyOverrides applies the block overrides to the block context, then the
+// state overrides to the state, with the precompiles active in the overridden
+// block.
+func (api *API) applyOverrides(vmctx *vm.BlockContext, statedb *state.StateDB, blockOverrides *override.BlockOverrides, stateOverrides *override.StateOverride) error {
+	blockOverrides.Apply(vmctx)
+	rules := api.backend.ChainConfig().Rules(vmctx.BlockNumber, vmctx.Random != nil, vmctx.Time)
 
-		precompiles := vm.ActivePrecompiledContracts(rules)
-		if err := config.StateOverrides.Apply(statedb, precompiles); err != nil {
-			return nil, err
-		}
-	}
-	// Execute the trace
+	precompiles := vm.ActivePrecompiledContracts(rules)
+	return stateOverrides.Apply(statedb, precompiles)
+}
+
+// callMessage fills in the defaults of the call arguments and converts them to
+// a message and a transaction to trace in the given block context. The returned
+// block context is adjusted for calls without fees.
+func (api *API) callMessage(args *ethapi.TransactionArgs, vmctx vm.BlockContext) (*core.Message, *types.Transaction, vm.BlockContext, error) {
 	if err := args.CallDefaults(api.backend.RPCGasCap(), vmctx.BaseFee, api.backend.ChainConfig().ChainID); err != nil {
-		return nil, err
+		return nil, nil, vmctx, err
 	}
 	var (
-		msg         = args.ToMessage(vmctx.BaseFee, true, true)
-		tx          = args.ToTransaction(types.LegacyTxType)
-		traceConfig *TraceConfig
+		msg = args.ToMessage(vmctx.BaseFee, true, true)
+		tx  = args.ToTransaction(types.LegacyTxType)
 	)
 	// Lower the basefee to 0 to avoid breaking EVM
 	// invariants (basefee < feecap).
@@ -974,16 +1138,21 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 	if msg.BlobGasFeeCap != nil && msg.BlobGasFeeCap.BitLen() == 0 {
 		vmctx.BlobBaseFee = new(big.Int)
 	}
-	if config != nil {
-		traceConfig = &config.TraceConfig
-	}
-	return api.traceTx(ctx, tx, msg, new(Context), vmctx, statedb, traceConfig)
+	return msg, tx, vmctx, nil
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
 func (api *API) traceTx(ctx context.Context, tx *types.Transaction, message *core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
+	res, _, err := api.traceTxApplied(ctx, tx, message, txctx, vmctx, statedb, config)
+	return res, err
+}
+
+// traceTxApplied is traceTx, additionally reporting whether the transaction was
+// applied to the state. If so, the state is finalised and its snapshots can't
+// be reverted to anymore, even if the tracer failed.
+func (api *API) traceTxApplied(ctx context.Context, tx *types.Transaction, message *core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, bool, error) {
 	var (
 		tracer  *Tracer
 		err     error
@@ -1004,7 +1173,7 @@ func (api *API) traceTx(ctx context.Context, tx *types.Transaction, message *cor
 	} else {
 		tracer, err = DefaultDirectory.New(*config.Tracer, txctx, config.TracerConfig, api.backend.ChainConfig())
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 	}
 	tracingStateDB := state.NewHookedState(statedb, tracer.Hooks)
@@ -1013,7 +1182,7 @@ func (api *API) traceTx(ctx context.Context, tx *types.Transaction, message *cor
 	// Define a meaningful timeout of a single transaction trace
 	if config.Timeout != nil {
 		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
-			return nil, err
+			return nil, false, err
 		}
 	}
 	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
@@ -1031,9 +1200,10 @@ func (api *API) traceTx(ctx context.Context, tx *types.Transaction, message *cor
 	statedb.SetTxContext(txctx.TxHash, txctx.TxIndex)
 	_, err = core.ApplyTransactionWithEVM(message, new(core.GasPool).AddGas(message.GasLimit), statedb, vmctx.BlockNumber, txctx.BlockHash, tx, &usedGas, evm)
 	if err != nil {
-		return nil, fmt.Errorf("tracing failed: %w", err)
+		return nil, false, fmt.Errorf("tracing failed: %w", err)
 	}
-	return tracer.GetResult()
+	res, err := tracer.GetResult()
+	return res, true, err
 }
 
 // APIs return the collection of RPC services the tracer package offers.
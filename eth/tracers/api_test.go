@@ -25,6 +25,7 @@ import (
 	"math/big"
 	"reflect"
 	"slices"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -493,6 +494,247 @@ func TestTraceCall(t *testing.T) {
 	}
 }
 
+func TestTraceCallMany(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts
+	accounts := newAccounts(3)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {})
+	defer backend.teardown()
+	api := NewAPI(backend)
+
+	bundles := []Bundle{
+		{
+			// Transfers only succeeding on top of the previous calls
+			Calls: []ethapi.TransactionArgs{
+				{
+					From:  &accounts[1].addr,
+					To:    &accounts[2].addr,
+					Value: (*hexutil.Big)(big.NewInt(1000)),
+				},
+				{
+					From:  &accounts[2].addr,
+					To:    &accounts[0].addr,
+					Value: (*hexutil.Big)(big.NewInt(1000)),
+				},
+				{
+					From:  &accounts[2].addr,
+					To:    &accounts[0].addr,
+					Value: (*hexutil.Big)(big.NewInt(1000)),
+				},
+			},
+			StateOverrides: &override.StateOverride{
+				accounts[1].addr: override.OverrideAccount{Balance: newRPCBalance(big.NewInt(1000))},
+			},
+		},
+		{
+			// Block overrides only apply to their bundle
+			Calls: []ethapi.TransactionArgs{
+				{
+					From:  &accounts[0].addr,
+					Input: &hexutil.Bytes{0x43}, // blocknumber
+				},
+			},
+			BlockOverrides: &override.BlockOverrides{Number: (*hexutil.Big)(big.NewInt(0x1337))},
+		},
+		{
+			Calls: []ethapi.TransactionArgs{
+				{
+					From:  &accounts[0].addr,
+					Input: &hexutil.Bytes{0x43}, // blocknumber
+				},
+			},
+		},
+	}
+	results, err := api.TraceCallMany(context.Background(), bundles, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil)
+	if err != nil {
+		t.Fatalf("failed to trace bundles: %v", err)
+	}
+	want := [][]string{
+		{
+			`{"gas":21000,"failed":false,"returnValue":"","structLogs":[]}`,
+			`{"gas":21000,"failed":false,"returnValue":"","structLogs":[]}`,
+			"",
+		},
+		{
+			`{"gas":53018,"failed":false,"returnValue":"","structLogs":[
+				{"pc":0,"op":"NUMBER","gas":24946984,"gasCost":2,"depth":1,"stack":[]},
+				{"pc":1,"op":"STOP","gas":24946982,"gasCost":0,"depth":1,"stack":["0x1337"]}]}`,
+		},
+		{
+			`{"gas":53018,"failed":false,"returnValue":"","structLogs":[
+				{"pc":0,"op":"NUMBER","gas":24946984,"gasCost":2,"depth":1,"stack":[]},
+				{"pc":1,"op":"STOP","gas":24946982,"gasCost":0,"depth":1,"stack":["0x1"]}]}`,
+		},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("bundle count mismatch, want %d, got %d", len(want), len(results))
+	}
+	for i := range want {
+		if len(results[i]) != len(want[i]) {
+			t.Fatalf("bundle %d: call count mismatch, want %d, got %d", i, len(want[i]), len(results[i]))
+		}
+		for j, expect := range want[i] {
+			result := results[i][j]
+			if expect == "" {
+				wantErr := fmt.Sprintf("tracing failed: insufficient funds for gas * price + value: address %s have 0 want 1000", accounts[2].addr)
+				if result.Error != wantErr {
+					t.Errorf("bundle %d call %d: error mismatch, want '%v', got '%v'", i, j, wantErr, result.Error)
+				}
+				continue
+			}
+			if result.Error != "" {
+				t.Errorf("bundle %d call %d: expect no error, got %v", i, j, result.Error)
+				continue
+			}
+			var have, want *logger.ExecutionResult
+			if err := json.Unmarshal(result.Result.(json.RawMessage), &have); err != nil {
+				t.Errorf("bundle %d call %d: failed to unmarshal result %v", i, j, err)
+			}
+			if err := json.Unmarshal([]byte(expect), &want); err != nil {
+				t.Errorf("bundle %d call %d: failed to unmarshal result %v", i, j, err)
+			}
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("bundle %d call %d: result mismatch, want %v, got %v", i, j, expect, string(result.Result.(json.RawMessage)))
+			}
+		}
+	}
+	// Tracing on 'pending' should fail
+	_, err = api.TraceCallMany(context.Background(), bundles, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber), nil)
+	if err == nil || err.Error() != "tracing on top of pending is not supported" {
+		t.Errorf("expected pending error, got %v", err)
+	}
+}
+
+// newBlockingTracer returns a tracer which blocks on the first opcode until it
+// is stopped, e.g. by timing out.
+func newBlockingTracer(ctx *Context, cfg json.RawMessage, chainConfig *params.ChainConfig) (*Tracer, error) {
+	var (
+		stop   = make(chan struct{})
+		reason error
+	)
+	return &Tracer{
+		Hooks: &tracing.Hooks{
+			OnOpcode: func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+				<-stop
+			},
+		},
+		GetResult: func() (json.RawMessage, error) {
+			if reason != nil {
+				return nil, reason
+			}
+			return json.RawMessage(`{}`), nil
+		},
+		Stop: func(err error) {
+			reason = err
+			close(stop)
+		},
+	}, nil
+}
+
+// Tests that the state changes of calls whose tracer fails are discarded, and
+// that the number of calls is capped.
+func TestTraceCallManyTimeout(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts = newAccounts(3)
+		contract = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+				contract:         {Code: []byte{byte(vm.NUMBER), byte(vm.STOP)}},
+			},
+		}
+	)
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {})
+	defer backend.teardown()
+	DefaultDirectory.Register("blockingTracer", newBlockingTracer, false)
+	api := NewAPI(backend)
+
+	var (
+		tracer  = "blockingTracer"
+		timeout = "100ms"
+		config  = &TraceCallConfig{TraceConfig: TraceConfig{Tracer: &tracer, Timeout: &timeout}}
+	)
+	bundles := []Bundle{{
+		// The second transfer only succeeds if the timed out one is discarded
+		Calls: []ethapi.TransactionArgs{
+			{
+				From:  &accounts[1].addr,
+				To:    &contract,
+				Value: (*hexutil.Big)(big.NewInt(1000)),
+			},
+			{
+				From:  &accounts[1].addr,
+				To:    &accounts[2].addr,
+				Value: (*hexutil.Big)(big.NewInt(1000)),
+			},
+		},
+		StateOverrides: &override.StateOverride{
+			accounts[1].addr: override.OverrideAccount{Balance: newRPCBalance(big.NewInt(1000))},
+		},
+	}}
+	results, err := api.TraceCallMany(context.Background(), bundles, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config)
+	if err != nil {
+		t.Fatalf("failed to trace bundles: %v", err)
+	}
+	if len(results) != 1 || len(results[0]) != 2 {
+		t.Fatalf("result count mismatch: %v", results)
+	}
+	if have := results[0][0].Error; have != "execution timeout" {
+		t.Errorf("timed out call: error mismatch, want 'execution timeout', got '%v'", have)
+	}
+	if have := results[0][1]; have.Error != "" || string(have.Result.(json.RawMessage)) != "{}" {
+		t.Errorf("transfer after the timed out call: have result %s, error '%v'", have.Result, have.Error)
+	}
+	// The state rebuilt after a failing tracer keeps the overrides and the calls
+	// applied before, in this and earlier bundles
+	bundles = []Bundle{
+		{
+			StateOverrides: &override.StateOverride{
+				accounts[1].addr: override.OverrideAccount{Balance: newRPCBalance(big.NewInt(1000))},
+			},
+		},
+		{
+			Calls: []ethapi.TransactionArgs{
+				{From: &accounts[1].addr, To: &accounts[2].addr, Value: (*hexutil.Big)(big.NewInt(400))},
+				{From: &accounts[1].addr, To: &contract, Value: (*hexutil.Big)(big.NewInt(600))},
+				{From: &accounts[1].addr, To: &accounts[2].addr, Value: (*hexutil.Big)(big.NewInt(600))},
+				{From: &accounts[1].addr, To: &accounts[2].addr, Value: (*hexutil.Big)(big.NewInt(1))},
+			},
+		},
+	}
+	results, err = api.TraceCallMany(context.Background(), bundles, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config)
+	if err != nil {
+		t.Fatalf("failed to trace bundles: %v", err)
+	}
+	if len(results) != 2 || len(results[1]) != 4 {
+		t.Fatalf("result count mismatch: %v", results)
+	}
+	for j, want := range []string{"", "execution timeout", "", "insufficient funds"} {
+		have := results[1][j].Error
+		if (want == "") != (have == "") || !strings.Contains(have, want) {
+			t.Errorf("call %d: error mismatch, want '%v', got '%v'", j, want, have)
+		}
+	}
+	// Requests with too many calls across all bundles are rejected
+	many := []Bundle{
+		{Calls: make([]ethapi.TransactionArgs, maxTraceCallManyCalls/2)},
+		{Calls: make([]ethapi.TransactionArgs, maxTraceCallManyCalls-maxTraceCallManyCalls/2+1)},
+	}
+	if _, err := api.TraceCallMany(context.Background(), many, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil); err == nil {
+		t.Error("too many calls accepted")
+	}
+}
+
 func TestTraceTransaction(t *testing.T) {
 	t.Parallel()
 
@@ -548,6 +790,72 @@ func TestTraceTransaction(t *testing.T) {
 	}
 }
 
+// echoCaller is a stateful precompile returning the sender of the call.
+type echoCaller struct{}
+
+func (echoCaller) RequiredGas(input []byte) uint64 { return 100 }
+
+func (echoCaller) Run(input []byte) ([]byte, error) { return nil, vm.ErrStatefulPrecompile }
+
+func (echoCaller) RunStateful(env *vm.PrecompileEnvironment, input []byte) ([]byte, error) {
+	return common.BytesToHash(env.Caller.Bytes()).Bytes(), nil
+}
+
+func init() {
+	if err := vm.RegisterPrecompile("tracers-test-echo", echoCaller{}); err != nil {
+		panic(err)
+	}
+}
+
+// Tests that transactions are traced with the precompiles installed by the
+// chain config at the time of their block.
+func TestTraceStatefulPrecompile(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts = newAccounts(1)
+		precomp  = common.HexToAddress("0x0300000000000000000000000000000000000001")
+		config   = *params.TestChainConfig
+		txs      []common.Hash
+	)
+	config.Precompiles = []params.PrecompileUpgrade{{Address: precomp, Time: 15, Contract: "tracers-test-echo"}}
+	genesis := &core.Genesis{
+		Config: &config,
+		Alloc:  types.GenesisAlloc{accounts[0].addr: {Balance: big.NewInt(params.Ether)}},
+	}
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, 2, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{
+			Nonce:    uint64(i),
+			To:       &precomp,
+			Gas:      params.TxGas + 100,
+			GasPrice: b.BaseFee(),
+		}), signer, accounts[0].key)
+		b.AddTx(tx)
+		txs = append(txs, tx.Hash())
+	})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	// Only the transaction past the activation runs the precompile
+	for i, want := range []*logger.ExecutionResult{
+		{Gas: params.TxGas, StructLogs: []json.RawMessage{}},
+		{Gas: params.TxGas + 100, ReturnValue: fmt.Sprintf("%x", common.BytesToHash(accounts[0].addr.Bytes())), StructLogs: []json.RawMessage{}},
+	} {
+		result, err := api.TraceTransaction(context.Background(), txs[i], nil)
+		if err != nil {
+			t.Fatalf("failed to trace transaction %d: %v", i, err)
+		}
+		var have *logger.ExecutionResult
+		if err := json.Unmarshal(result.(json.RawMessage), &have); err != nil {
+			t.Fatalf("failed to unmarshal result %v", err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("transaction %d: result mismatch: have %+v, want %+v", i, have, want)
+		}
+	}
+}
+
 func TestTraceBlock(t *testing.T) {
 	t.Parallel()
 
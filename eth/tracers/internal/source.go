@@ -0,0 +1,77 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package internal
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/compiler"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// SourceFrame follows the execution of a call frame through the source of the
+// contract it runs.
+type SourceFrame struct {
+	mapper  *compiler.SourceMapper
+	address common.Address // Address of the code executed
+	program *compiler.SourceProgram
+	started bool
+
+	pc     uint64 // Program counter of the last instruction
+	mapped uint64 // Program counter of the last instruction with a source
+	found  bool   // Whether any instruction had a source
+}
+
+// NewSourceFrame creates a source frame for the code at the given address. It
+// returns nil for contract creations, as their init code isn't mapped.
+func NewSourceFrame(mapper *compiler.SourceMapper, typ byte, to common.Address) *SourceFrame {
+	if mapper == nil || vm.OpCode(typ) == vm.CREATE || vm.OpCode(typ) == vm.CREATE2 {
+		return nil
+	}
+	return &SourceFrame{mapper: mapper, address: to}
+}
+
+// Step records the execution of the instruction at the given program counter.
+func (f *SourceFrame) Step(pc uint64, scope tracing.OpContext) {
+	if f == nil {
+		return
+	}
+	if !f.started {
+		f.program = f.mapper.Program(f.address, scope.ContractCode())
+		f.started = true
+	}
+	if f.program == nil {
+		return
+	}
+	f.pc = pc
+	if f.program.Mapped(pc) {
+		f.mapped, f.found = pc, true
+	}
+}
+
+// Location returns the source location of the last instruction executed. If it
+// was generated by the compiler, e.g. in a revert helper, the location of the
+// last instruction with a source is returned instead.
+func (f *SourceFrame) Location() *compiler.SourceLocation {
+	if f == nil || f.program == nil || !f.found {
+		return nil
+	}
+	if loc := f.program.Locate(f.pc); loc != nil {
+		return loc
+	}
+	return f.program.Locate(f.mapped)
+}
@@ -17,6 +17,7 @@
 package logger
 
 import (
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -27,11 +28,13 @@ import (
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/compiler"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers/internal"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
 )
@@ -48,6 +51,8 @@ type Config struct {
 	Limit            int  // maximum size of output, but zero means unlimited
 	// Chain overrides, can be used to execute a trace using future fork rules
 	Overrides *params.ChainConfig `json:"overrides,omitempty"`
+	// Source maps of the contracts, to locate each step in the source
+	SourceMaps *compiler.SourceMapper `json:"sourceMaps,omitempty"`
 }
 
 //go:generate go run github.com/fjl/gencodec -type StructLog -field-override structLogMarshaling -out gen_structlog.go
@@ -67,6 +72,7 @@ type StructLog struct {
 	Depth         int                         `json:"depth"`
 	RefundCounter uint64                      `json:"refund"`
 	Err           error                       `json:"-"`
+	Source        *compiler.SourceLocation    `json:"source,omitempty"`
 }
 
 // overrides for gencodec
@@ -96,6 +102,9 @@ func (s *StructLog) ErrorString() string {
 // WriteTo writes the human-readable log data into the supplied writer.
 func (s *StructLog) WriteTo(writer io.Writer) {
 	fmt.Fprintf(writer, "%-16spc=%08d gas=%v cost=%v", s.Op, s.Pc, s.Gas, s.GasCost)
+	if s.Source != nil {
+		fmt.Fprintf(writer, " source=%v", s.Source)
+	}
 	if s.Err != nil {
 		fmt.Fprintf(writer, " ERROR: %v", s.Err)
 	}
@@ -142,17 +151,18 @@ func (s *StructLog) WriteTo(writer io.Writer) {
 // storage:
 // Legacy has a storage field while non-legacy doesn't.
 type structLogLegacy struct {
-	Pc            uint64             `json:"pc"`
-	Op            string             `json:"op"`
-	Gas           uint64             `json:"gas"`
-	GasCost       uint64             `json:"gasCost"`
-	Depth         int                `json:"depth"`
-	Error         string             `json:"error,omitempty"`
-	Stack         *[]string          `json:"stack,omitempty"`
-	ReturnData    string             `json:"returnData,omitempty"`
-	Memory        *[]string          `json:"memory,omitempty"`
-	Storage       *map[string]string `json:"storage,omitempty"`
-	RefundCounter uint64             `json:"refund,omitempty"`
+	Pc            uint64                   `json:"pc"`
+	Op            string                   `json:"op"`
+	Gas           uint64                   `json:"gas"`
+	GasCost       uint64                   `json:"gasCost"`
+	Depth         int                      `json:"depth"`
+	Error         string                   `json:"error,omitempty"`
+	Stack         *[]string                `json:"stack,omitempty"`
+	ReturnData    string                   `json:"returnData,omitempty"`
+	Memory        *[]string                `json:"memory,omitempty"`
+	Storage       *map[string]string       `json:"storage,omitempty"`
+	RefundCounter uint64                   `json:"refund,omitempty"`
+	Source        *compiler.SourceLocation `json:"source,omitempty"`
 }
 
 // toLegacyJSON converts the structLog to legacy json-encoded legacy form.
@@ -165,6 +175,7 @@ func (s *StructLog) toLegacyJSON() json.RawMessage {
 		Depth:         s.Depth,
 		Error:         s.ErrorString(),
 		RefundCounter: s.RefundCounter,
+		Source:        s.Source,
 	}
 	if s.Stack != nil {
 		stack := make([]string, len(s.Stack))
@@ -215,10 +226,21 @@ type StructLogger struct {
 	logs       []json.RawMessage // buffer of json-encoded logs
 	resultSize int
 
+	sources []*sourceFrame           // source of the call frames, if source maps are configured
+	revert  *compiler.SourceLocation // source location of the failure
+
 	interrupt atomic.Bool // Atomic flag to signal execution interruption
 	reason    error       // Textual reason for the interruption
 }
 
+// sourceFrame follows a call frame through its source and records the failure
+// of its last call, to locate failures passed on by the frame.
+type sourceFrame struct {
+	*internal.SourceFrame
+	revert       *compiler.SourceLocation
+	revertOutput []byte
+}
+
 // NewStreamingStructLogger returns a new streaming logger.
 func NewStreamingStructLogger(cfg *Config, writer io.Writer) *StructLogger {
 	l := NewStructLogger(cfg)
@@ -239,12 +261,27 @@ func NewStructLogger(cfg *Config) *StructLogger {
 }
 
 func (l *StructLogger) Hooks() *tracing.Hooks {
-	return &tracing.Hooks{
+	hooks := &tracing.Hooks{
 		OnTxStart: l.OnTxStart,
 		OnTxEnd:   l.OnTxEnd,
 		OnExit:    l.OnExit,
 		OnOpcode:  l.OnOpcode,
 	}
+	if l.cfg.SourceMaps != nil {
+		hooks.OnEnter = l.OnEnter
+	}
+	return hooks
+}
+
+// OnEnter tracks the source of a new call frame if source maps are configured.
+func (l *StructLogger) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if l.cfg.SourceMaps == nil {
+		return
+	}
+	if n := len(l.sources); n > 0 {
+		l.sources[n-1].revert, l.sources[n-1].revertOutput = nil, nil
+	}
+	l.sources = append(l.sources, &sourceFrame{SourceFrame: internal.NewSourceFrame(l.cfg.SourceMaps, typ, to)})
 }
 
 // OnOpcode logs a new structured log message and pushes it out to the environment
@@ -255,6 +292,11 @@ func (l *StructLogger) OnOpcode(pc uint64, opcode byte, gas, cost uint64, scope
 	if l.interrupt.Load() {
 		return
 	}
+	var source *sourceFrame
+	if n := len(l.sources); n > 0 {
+		source = l.sources[n-1]
+		source.Step(pc, scope)
+	}
 	// check if already accumulated the size of the response.
 	if l.cfg.Limit != 0 && l.resultSize > l.cfg.Limit {
 		return
@@ -266,7 +308,10 @@ func (l *StructLogger) OnOpcode(pc uint64, opcode byte, gas, cost uint64, scope
 		stack        = scope.StackData()
 		stackLen     = len(stack)
 	)
-	log := StructLog{pc, op, gas, cost, nil, len(memory), nil, nil, nil, depth, l.env.StateDB.GetRefund(), err}
+	log := StructLog{pc, op, gas, cost, nil, len(memory), nil, nil, nil, depth, l.env.StateDB.GetRefund(), err, nil}
+	if source != nil {
+		log.Source = source.Location()
+	}
 	if l.cfg.EnableMemory {
 		log.Memory = memory
 	}
@@ -317,6 +362,9 @@ func (l *StructLogger) OnOpcode(pc uint64, opcode byte, gas, cost uint64, scope
 
 // OnExit is called a call frame finishes processing.
 func (l *StructLogger) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if n := len(l.sources); n > 0 {
+		l.exitSource(output, err)
+	}
 	if depth != 0 {
 		return
 	}
@@ -331,6 +379,26 @@ func (l *StructLogger) OnExit(depth int, output []byte, gasUsed uint64, err erro
 	//}
 }
 
+// exitSource pops the source of the exiting call frame. The location of a
+// failure is passed on to the caller, which reports it in turn if it fails with
+// the same output, e.g. by bubbling up a revert.
+func (l *StructLogger) exitSource(output []byte, err error) {
+	frame := l.sources[len(l.sources)-1]
+	l.sources = l.sources[:len(l.sources)-1]
+	if err == nil {
+		return
+	}
+	loc := frame.Location()
+	if frame.revert != nil && bytes.Equal(frame.revertOutput, output) {
+		loc = frame.revert
+	}
+	if n := len(l.sources); n > 0 {
+		l.sources[n-1].revert, l.sources[n-1].revertOutput = loc, common.CopyBytes(output)
+		return
+	}
+	l.revert = loc
+}
+
 func (l *StructLogger) GetResult() (json.RawMessage, error) {
 	// Tracing aborted
 	if l.reason != nil {
@@ -343,12 +411,16 @@ func (l *StructLogger) GetResult() (json.RawMessage, error) {
 	if failed && l.err != vm.ErrExecutionReverted {
 		returnVal = ""
 	}
-	return json.Marshal(&ExecutionResult{
+	result := &ExecutionResult{
 		Gas:         l.usedGas,
 		Failed:      failed,
 		ReturnValue: returnVal,
 		StructLogs:  l.logs,
-	})
+	}
+	if failed {
+		result.RevertLocation = l.revert
+	}
+	return json.Marshal(result)
 }
 
 // Stop terminates execution of the tracer at the first opportune moment.
@@ -488,4 +560,6 @@ type ExecutionResult struct {
 	Failed      bool              `json:"failed"`
 	ReturnValue string            `json:"returnValue"`
 	StructLogs  []json.RawMessage `json:"structLogs"`
+	// Source location of the failure, if source maps are configured
+	RevertLocation *compiler.SourceLocation `json:"revertLocation,omitempty"`
 }
@@ -19,12 +19,18 @@ package logger
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/compiler"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
 )
@@ -94,6 +100,8 @@ func TestStructLogMarshalingOmitEmpty(t *testing.T) {
 			`{"pc":0,"op":0,"gas":"0x0","gasCost":"0x0","memory":"0x0000","memSize":2,"stack":null,"depth":0,"refund":0,"opName":"STOP"}`},
 		{"with 0-size mem", &StructLog{Memory: make([]byte, 0)},
 			`{"pc":0,"op":0,"gas":"0x0","gasCost":"0x0","memSize":0,"stack":null,"depth":0,"refund":0,"opName":"STOP"}`},
+		{"with source", &StructLog{Source: &compiler.SourceLocation{File: "Guard.sol", Line: 3, Column: 9}},
+			`{"pc":0,"op":0,"gas":"0x0","gasCost":"0x0","memSize":0,"stack":null,"depth":0,"refund":0,"source":{"file":"Guard.sol","line":3,"column":9},"opName":"STOP"}`},
 	}
 
 	for _, tt := range tests {
@@ -108,3 +116,71 @@ func TestStructLogMarshalingOmitEmpty(t *testing.T) {
 		})
 	}
 }
+
+// Tests that the struct logger locates the steps and the failure of a call in
+// the source of the contracts.
+func TestStructLoggerSourceMaps(t *testing.T) {
+	var (
+		guard  = common.HexToAddress("0xbbbb")
+		caller = common.HexToAddress("0xaaaa")
+		source = "contract Guard {\n    function check() public {\n        require(false);\n    }\n}\n"
+		start  = strings.Index(source, "require(false)")
+	)
+	// The guard reverts with compiler generated code, the unknown caller
+	// bubbles the revert up
+	guardCode := program.New().Push(0).Push(0).Op(vm.REVERT).Bytes()
+	callerCode := program.New().Call(nil, guard, 0, 0, 0, 0, 0).Push(0).Push(0).Op(vm.REVERT).Bytes()
+
+	var cfg Config
+	err := json.Unmarshal([]byte(fmt.Sprintf(`{"sourceMaps": {
+		"input": {"sources": {"Guard.sol": {"content": %q}}},
+		"output": {
+			"sources": {"Guard.sol": {"id": 0}},
+			"contracts": {"Guard.sol": {"Guard": {"evm": {"deployedBytecode": {"object": "%x", "sourceMap": "%d:14:0;;-1:-1:-1"}}}}}
+		}
+	}}`, source, guardCode, start)), &cfg)
+	if err != nil {
+		t.Fatalf("failed to decode config: %v", err)
+	}
+	logger := NewStructLogger(&cfg)
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	statedb.SetCode(guard, guardCode)
+	statedb.SetCode(caller, callerCode)
+	_, _, err = runtime.Call(caller, nil, &runtime.Config{
+		ChainConfig: params.MergedTestChainConfig,
+		GasLimit:    100000,
+		State:       statedb,
+		EVMConfig:   vm.Config{Tracer: logger.Hooks()},
+	})
+	if !errors.Is(err, vm.ErrExecutionReverted) {
+		t.Fatalf("call error mismatch: have %v, want %v", err, vm.ErrExecutionReverted)
+	}
+	blob, err := logger.GetResult()
+	if err != nil {
+		t.Fatalf("failed to get result: %v", err)
+	}
+	var res struct {
+		RevertLocation *compiler.SourceLocation
+		StructLogs     []struct {
+			Depth  int
+			Op     string
+			Source *compiler.SourceLocation
+		}
+	}
+	if err := json.Unmarshal(blob, &res); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	want := compiler.SourceLocation{File: "Guard.sol", Line: 3, Column: 9}
+	if res.RevertLocation == nil || *res.RevertLocation != want {
+		t.Errorf("revert location mismatch: have %v, want %v", res.RevertLocation, &want)
+	}
+	for _, log := range res.StructLogs {
+		switch {
+		case log.Depth == 1 && log.Source != nil:
+			t.Errorf("unknown contract step %s located at %v", log.Op, log.Source)
+		case log.Depth == 2 && (log.Source == nil || *log.Source != want):
+			t.Errorf("contract step %s location mismatch: have %v, want %v", log.Op, log.Source, &want)
+		}
+	}
+}
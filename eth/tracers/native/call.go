@@ -24,11 +24,13 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/compiler"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/eth/tracers/internal"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -57,12 +59,17 @@ type callFrame struct {
 	Output       []byte          `json:"output,omitempty" rlp:"optional"`
 	Error        string          `json:"error,omitempty" rlp:"optional"`
 	RevertReason string          `json:"revertReason,omitempty"`
-	Calls        []callFrame     `json:"calls,omitempty" rlp:"optional"`
-	Logs         []callLog       `json:"logs,omitempty" rlp:"optional"`
+	// Source locations of the call in its caller and of the failing instruction,
+	// if source maps are configured
+	Location       *compiler.SourceLocation `json:"location,omitempty" rlp:"-"`
+	RevertLocation *compiler.SourceLocation `json:"revertLocation,omitempty" rlp:"-"`
+	Calls          []callFrame              `json:"calls,omitempty" rlp:"optional"`
+	Logs           []callLog                `json:"logs,omitempty" rlp:"optional"`
 	// Placed at end on purpose. The RLP will be decoded to 0 instead of
 	// nil if there are non-empty elements after in the struct.
 	Value            *big.Int `json:"value,omitempty" rlp:"optional"`
 	revertedSnapshot bool
+	source           *internal.SourceFrame
 }
 
 func (f callFrame) TypeString() string {
@@ -86,6 +93,7 @@ func (f *callFrame) processOutput(output []byte, err error, reverted bool) {
 	}
 	f.Error = err.Error()
 	f.revertedSnapshot = reverted
+	f.RevertLocation = f.source.Location()
 	if f.Type == vm.CREATE || f.Type == vm.CREATE2 {
 		f.To = nil
 	}
@@ -120,8 +128,9 @@ type callTracer struct {
 }
 
 type callTracerConfig struct {
-	OnlyTopCall bool `json:"onlyTopCall"` // If true, call tracer won't collect any subcalls
-	WithLog     bool `json:"withLog"`     // If true, call tracer will collect event logs
+	OnlyTopCall bool                   `json:"onlyTopCall"` // If true, call tracer won't collect any subcalls
+	WithLog     bool                   `json:"withLog"`     // If true, call tracer will collect event logs
+	SourceMaps  *compiler.SourceMapper `json:"sourceMaps"`  // If set, call tracer will locate calls and failures in the source
 }
 
 // newCallTracer returns a native go tracer which tracks
@@ -131,14 +140,19 @@ func newCallTracer(ctx *tracers.Context, cfg json.RawMessage, chainConfig *param
 	if err != nil {
 		return nil, err
 	}
+	hooks := &tracing.Hooks{
+		OnTxStart: t.OnTxStart,
+		OnTxEnd:   t.OnTxEnd,
+		OnEnter:   t.OnEnter,
+		OnExit:    t.OnExit,
+		OnLog:     t.OnLog,
+	}
+	// Source locations require following the execution of every frame
+	if t.config.SourceMaps != nil {
+		hooks.OnOpcode = t.OnOpcode
+	}
 	return &tracers.Tracer{
-		Hooks: &tracing.Hooks{
-			OnTxStart: t.OnTxStart,
-			OnTxEnd:   t.OnTxEnd,
-			OnEnter:   t.OnEnter,
-			OnExit:    t.OnExit,
-			OnLog:     t.OnLog,
-		},
+		Hooks:     hooks,
 		GetResult: t.GetResult,
 		Stop:      t.Stop,
 	}, nil
@@ -177,9 +191,27 @@ func (t *callTracer) OnEnter(depth int, typ byte, from common.Address, to common
 	if depth == 0 {
 		call.Gas = t.gasLimit
 	}
+	if t.config.SourceMaps != nil {
+		if depth > 0 {
+			call.Location = t.callstack[len(t.callstack)-1].source.Location()
+		}
+		call.source = internal.NewSourceFrame(t.config.SourceMaps, typ, to)
+	}
 	t.callstack = append(t.callstack, call)
 }
 
+// OnOpcode follows the execution of the current frame through its source.
+func (t *callTracer) OnOpcode(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	// Opcode depth is one more than the depth of the frame executing it
+	if t.config.OnlyTopCall && depth > 1 {
+		return
+	}
+	if t.interrupt.Load() || len(t.callstack) == 0 {
+		return
+	}
+	t.callstack[len(t.callstack)-1].source.Step(pc, scope)
+}
+
 // OnExit is called when EVM exits a scope, even if the scope didn't
 // execute any code.
 func (t *callTracer) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
@@ -0,0 +1,168 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/compiler"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+const sourceMapSource = `contract Guard {
+    function check() public {
+        require(false);
+    }
+}
+
+contract Caller {
+    function run() public {
+        guard.check();
+    }
+}
+`
+
+// sourceRange returns the "start:length:0" range of a snippet of the source.
+func sourceRange(snippet string) string {
+	return fmt.Sprintf("%d:%d:0", strings.Index(sourceMapSource, snippet), len(snippet))
+}
+
+// Tests that the call tracer locates calls and failures in the source of the
+// contracts, given their source maps.
+func TestCallTracerSourceMaps(t *testing.T) {
+	var (
+		guard  = common.HexToAddress("0xbbbb")
+		caller = common.HexToAddress("0xaaaa")
+	)
+	// The guard reverts with compiler generated code, the caller bubbles it up
+	guardCode := program.New().Push(0).Push(0).Op(vm.REVERT).Bytes()
+	guardMap := sourceRange("require(false)") + ";;-1:-1:-1"
+
+	callerCode := program.New().
+		Call(nil, guard, 0, 0, 0, 0, 0).
+		Push(0).Push(0).Op(vm.REVERT).
+		Bytes()
+	callerMap := sourceRange("guard.check()") + strings.Repeat(";", len(callerCode))
+
+	function := func(name, snippet string) map[string]any {
+		return map[string]any{"nodeType": "FunctionDefinition", "name": name, "kind": "function", "src": sourceRange(snippet)}
+	}
+	contract := func(name, snippet string, fn map[string]any) map[string]any {
+		return map[string]any{"nodeType": "ContractDefinition", "name": name, "src": sourceRange(snippet), "nodes": []any{fn}}
+	}
+	deployed := func(code []byte, srcmap string) map[string]any {
+		return map[string]any{"evm": map[string]any{"deployedBytecode": map[string]any{
+			"object":    hexutil.Encode(code)[2:],
+			"sourceMap": srcmap,
+			// Immutables prevent finding the contract by code hash
+			"immutableReferences": map[string]any{"1": []any{}},
+		}}}
+	}
+	output := map[string]any{
+		"sources": map[string]any{
+			"Test.sol": map[string]any{"id": 0, "ast": map[string]any{
+				"nodeType": "SourceUnit",
+				"src":      fmt.Sprintf("0:%d:0", len(sourceMapSource)),
+				"nodes": []any{
+					contract("Guard", sourceMapSource[:strings.Index(sourceMapSource, "contract Caller")], function("check", "function check() public {\n        require(false);\n    }")),
+					contract("Caller", sourceMapSource[strings.Index(sourceMapSource, "contract Caller"):], function("run", "function run() public {\n        guard.check();\n    }")),
+				},
+			}},
+		},
+		"contracts": map[string]any{
+			"Test.sol": map[string]any{
+				"Guard":  deployed(guardCode, guardMap),
+				"Caller": deployed(callerCode, callerMap),
+			},
+		},
+	}
+	input := map[string]any{"sources": map[string]any{"Test.sol": map[string]any{"content": sourceMapSource}}}
+	config, _ := json.Marshal(map[string]any{
+		"sourceMaps": map[string]any{
+			"input":  input,
+			"output": output,
+			"contracts": map[string]string{
+				guard.Hex():  "Guard",
+				caller.Hex(): "Test.sol:Caller",
+			},
+		},
+	})
+	tracer, err := tracers.DefaultDirectory.New("callTracer", &tracers.Context{}, config, params.MergedTestChainConfig)
+	require.NoError(t, err)
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	statedb.SetCode(guard, guardCode)
+	statedb.SetCode(caller, callerCode)
+
+	_, _, err = runtime.Call(caller, nil, &runtime.Config{
+		ChainConfig: params.MergedTestChainConfig,
+		GasLimit:    100000,
+		State:       statedb,
+		EVMConfig:   vm.Config{Tracer: tracer.Hooks},
+	})
+	require.ErrorIs(t, err, vm.ErrExecutionReverted)
+
+	res, err := tracer.GetResult()
+	require.NoError(t, err)
+
+	var frame struct {
+		Error          string
+		RevertLocation *compiler.SourceLocation
+		Calls          []struct {
+			Location       *compiler.SourceLocation
+			RevertLocation *compiler.SourceLocation
+		}
+	}
+	require.NoError(t, json.Unmarshal(res, &frame))
+	require.NotEmpty(t, frame.Error)
+	require.Len(t, frame.Calls, 1)
+
+	call := &compiler.SourceLocation{File: "Test.sol", Line: 9, Column: 9, Function: "Caller.run"}
+	require.Equal(t, call, frame.RevertLocation)
+	require.Equal(t, call, frame.Calls[0].Location)
+	require.Equal(t, &compiler.SourceLocation{File: "Test.sol", Line: 3, Column: 9, Function: "Guard.check"}, frame.Calls[0].RevertLocation)
+
+	// Unknown contracts aren't located
+	tracer, err = tracers.DefaultDirectory.New("callTracer", &tracers.Context{}, json.RawMessage(`{"sourceMaps": {"output": {}}}`), params.MergedTestChainConfig)
+	require.NoError(t, err)
+	_, _, err = runtime.Call(caller, nil, &runtime.Config{
+		ChainConfig: params.MergedTestChainConfig,
+		GasLimit:    100000,
+		State:       statedb,
+		EVMConfig:   vm.Config{Tracer: tracer.Hooks},
+	})
+	require.ErrorIs(t, err, vm.ErrExecutionReverted)
+	res, err = tracer.GetResult()
+	require.NoError(t, err)
+	require.NotContains(t, string(res), "ocation")
+
+	// Invalid source maps are rejected
+	_, err = tracers.DefaultDirectory.New("callTracer", &tracers.Context{}, json.RawMessage(`{"sourceMaps": {"output": {}, "contracts": {"0xbbbb": "Guard"}}}`), params.MergedTestChainConfig)
+	require.Error(t, err)
+}
@@ -15,26 +15,29 @@ var _ = (*accountMarshaling)(nil)
 // MarshalJSON marshals as JSON.
 func (a account) MarshalJSON() ([]byte, error) {
 	type account struct {
-		Balance *hexutil.Big                `json:"balance,omitempty"`
-		Code    hexutil.Bytes               `json:"code,omitempty"`
-		Nonce   uint64                      `json:"nonce,omitempty"`
-		Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+		Balance   *hexutil.Big                `json:"balance,omitempty"`
+		Code      hexutil.Bytes               `json:"code,omitempty"`
+		Nonce     uint64                      `json:"nonce,omitempty"`
+		Storage   map[common.Hash]common.Hash `json:"storage,omitempty"`
+		Variables map[string]any              `json:"variables,omitempty"`
 	}
 	var enc account
 	enc.Balance = (*hexutil.Big)(a.Balance)
 	enc.Code = a.Code
 	enc.Nonce = a.Nonce
 	enc.Storage = a.Storage
+	enc.Variables = a.Variables
 	return json.Marshal(&enc)
 }
 
 // UnmarshalJSON unmarshals from JSON.
 func (a *account) UnmarshalJSON(input []byte) error {
 	type account struct {
-		Balance *hexutil.Big                `json:"balance,omitempty"`
-		Code    *hexutil.Bytes              `json:"code,omitempty"`
-		Nonce   *uint64                     `json:"nonce,omitempty"`
-		Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+		Balance   *hexutil.Big                `json:"balance,omitempty"`
+		Code      *hexutil.Bytes              `json:"code,omitempty"`
+		Nonce     *uint64                     `json:"nonce,omitempty"`
+		Storage   map[common.Hash]common.Hash `json:"storage,omitempty"`
+		Variables map[string]any              `json:"variables,omitempty"`
 	}
 	var dec account
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -52,5 +55,8 @@ func (a *account) UnmarshalJSON(input []byte) error {
 	if dec.Storage != nil {
 		a.Storage = dec.Storage
 	}
+	if dec.Variables != nil {
+		a.Variables = dec.Variables
+	}
 	return nil
 }
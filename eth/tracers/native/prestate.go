@@ -19,6 +19,8 @@ package native
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"maps"
 	"math/big"
 	"sync/atomic"
 
@@ -47,7 +49,9 @@ type account struct {
 	Code    []byte                      `json:"code,omitempty"`
 	Nonce   uint64                      `json:"nonce,omitempty"`
 	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
-	empty   bool
+	// Variables are the storage slots decoded with the storage layout of the contract
+	Variables map[string]any `json:"variables,omitempty"`
+	empty     bool
 }
 
 func (a *account) exists() bool {
@@ -69,25 +73,50 @@ type prestateTracer struct {
 	reason    error       // Textual reason for the interruption
 	created   map[common.Address]bool
 	deleted   map[common.Address]bool
+	decoders  map[common.Address]*storageDecoder
+	preimages map[common.Hash][]byte
 }
 
 type prestateTracerConfig struct {
 	DiffMode       bool `json:"diffMode"`       // If true, this tracer will return state modifications
 	DisableCode    bool `json:"disableCode"`    // If true, this tracer will not return the contract code
 	DisableStorage bool `json:"disableStorage"` // If true, this tracer will not return the contract storage
+
+	// StorageLayouts are the solc storage layouts of contracts, by the address
+	// holding their storage. If set, the storage of these contracts is decoded
+	// into variables. Keys of mappings are recovered from the preimages of the
+	// KECCAK256 instructions executed by the contracts, like the EVM records
+	// them with EnablePreimageRecording.
+	StorageLayouts map[common.Address]*storageLayout `json:"storageLayouts"`
 }
 
+// maxPreimageSize is the size of the largest KECCAK256 input recorded to name
+// the keys of mappings, i.e. a key of 992 bytes followed by the mapping slot.
+const maxPreimageSize = 1024
+
 func newPrestateTracer(ctx *tracers.Context, cfg json.RawMessage, chainConfig *params.ChainConfig) (*tracers.Tracer, error) {
 	var config prestateTracerConfig
 	if err := json.Unmarshal(cfg, &config); err != nil {
 		return nil, err
 	}
 	t := &prestateTracer{
-		pre:     stateMap{},
-		post:    stateMap{},
-		config:  config,
-		created: make(map[common.Address]bool),
-		deleted: make(map[common.Address]bool),
+		pre:       stateMap{},
+		post:      stateMap{},
+		config:    config,
+		created:   make(map[common.Address]bool),
+		deleted:   make(map[common.Address]bool),
+		decoders:  make(map[common.Address]*storageDecoder),
+		preimages: make(map[common.Hash][]byte),
+	}
+	for addr, layout := range config.StorageLayouts {
+		if layout == nil {
+			continue
+		}
+		decoder, err := newStorageDecoder(layout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage layout of %v: %v", addr, err)
+		}
+		t.decoders[addr] = decoder
 	}
 	return &tracers.Tracer{
 		Hooks: &tracing.Hooks{
@@ -117,6 +146,16 @@ func (t *prestateTracer) OnOpcode(pc uint64, opcode byte, gas, cost uint64, scop
 	case stackLen >= 1 && (op == vm.SLOAD || op == vm.SSTORE):
 		slot := common.Hash(stackData[stackLen-1].Bytes32())
 		t.lookupStorage(caller, slot)
+	case stackLen >= 2 && op == vm.KECCAK256 && t.decoders[caller] != nil:
+		offset, size := stackData[stackLen-1], stackData[stackLen-2]
+		if !size.IsUint64() || size.Uint64() < common.HashLength || size.Uint64() > maxPreimageSize {
+			return
+		}
+		data, err := internal.GetMemoryCopyPadded(scope.MemoryData(), int64(offset.Uint64()), int64(size.Uint64()))
+		if err != nil {
+			return
+		}
+		t.preimages[crypto.Keccak256Hash(data)] = data
 	case stackLen >= 1 && (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE || op == vm.SELFDESTRUCT):
 		addr := common.Address(stackData[stackLen-1].Bytes20())
 		t.lookupAccount(addr)
@@ -174,8 +213,13 @@ func (t *prestateTracer) OnTxEnd(receipt *types.Receipt, err error) {
 	if err != nil {
 		return
 	}
+	for _, decoder := range t.decoders {
+		decoder.addPreimages(t.preimages)
+	}
 	if t.config.DiffMode {
 		t.processDiffState()
+	} else {
+		t.decodeStorage()
 	}
 	// the new created contracts' prestate were empty, so delete them
 	for a := range t.created {
@@ -254,6 +298,7 @@ func (t *prestateTracer) processDiffState() {
 					if newVal != (common.Hash{}) {
 						postAccount.Storage[key] = newVal
 					}
+					t.decodeDiff(addr, key, val, newVal, postAccount)
 				}
 			}
 		}
@@ -267,6 +312,44 @@ func (t *prestateTracer) processDiffState() {
 	}
 }
 
+// decodeDiff decodes the variables of a modified slot into the pre and post
+// state of the account. Variables packed with modified ones are left out.
+func (t *prestateTracer) decodeDiff(addr common.Address, slot, pre, post common.Hash, postAccount *account) {
+	decoder := t.decoders[addr]
+	if decoder == nil {
+		return
+	}
+	modified := func(field storageField) bool {
+		return !bytes.Equal(fieldBytes(field, pre), fieldBytes(field, post))
+	}
+	maps.Copy(initVariables(t.pre[addr]), decoder.decode(slot, pre, modified))
+	maps.Copy(initVariables(postAccount), decoder.decode(slot, post, modified))
+}
+
+// decodeStorage decodes the variables of the storage in the prestate.
+func (t *prestateTracer) decodeStorage() {
+	for addr, decoder := range t.decoders {
+		acc := t.pre[addr]
+		if acc == nil {
+			continue
+		}
+		for slot, val := range acc.Storage {
+			if vars := decoder.decode(slot, val, nil); vars != nil {
+				maps.Copy(initVariables(acc), vars)
+			}
+		}
+	}
+}
+
+// initVariables returns the decoded variables of an account, creating them if
+// needed.
+func initVariables(acc *account) map[string]any {
+	if acc.Variables == nil {
+		acc.Variables = make(map[string]any)
+	}
+	return acc.Variables
+}
+
 // lookupAccount fetches details of an account and adds it to the prestate
 // if it doesn't exist there.
 func (t *prestateTracer) lookupAccount(addr common.Address) {
@@ -0,0 +1,194 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+// vaultLayout is the storage layout of:
+//
+//	contract Vault {
+//	    struct Config { uint128 a; uint128 b; uint256 c; }
+//
+//	    address owner;
+//	    uint64 fee;
+//	    bool paused;
+//	    mapping(address => uint256) balances;
+//	    mapping(address => mapping(uint256 => bool)) approvals;
+//	    uint16[] history;
+//	    Config config;
+//	    string name;
+//	}
+const vaultLayout = `{
+	"storage": [
+		{"label": "owner", "offset": 0, "slot": "0", "type": "t_address"},
+		{"label": "fee", "offset": 20, "slot": "0", "type": "t_uint64"},
+		{"label": "paused", "offset": 28, "slot": "0", "type": "t_bool"},
+		{"label": "balances", "offset": 0, "slot": "1", "type": "t_mapping(t_address,t_uint256)"},
+		{"label": "approvals", "offset": 0, "slot": "2", "type": "t_mapping(t_address,t_mapping(t_uint256,t_bool))"},
+		{"label": "history", "offset": 0, "slot": "3", "type": "t_array(t_uint16)dyn_storage"},
+		{"label": "config", "offset": 0, "slot": "4", "type": "t_struct(Config)1_storage"},
+		{"label": "name", "offset": 0, "slot": "6", "type": "t_string_storage"}
+	],
+	"types": {
+		"t_address": {"encoding": "inplace", "label": "address", "numberOfBytes": "20"},
+		"t_bool": {"encoding": "inplace", "label": "bool", "numberOfBytes": "1"},
+		"t_uint16": {"encoding": "inplace", "label": "uint16", "numberOfBytes": "2"},
+		"t_uint64": {"encoding": "inplace", "label": "uint64", "numberOfBytes": "8"},
+		"t_uint128": {"encoding": "inplace", "label": "uint128", "numberOfBytes": "16"},
+		"t_uint256": {"encoding": "inplace", "label": "uint256", "numberOfBytes": "32"},
+		"t_string_storage": {"encoding": "bytes", "label": "string", "numberOfBytes": "32"},
+		"t_array(t_uint16)dyn_storage": {"encoding": "dynamic_array", "label": "uint16[]", "numberOfBytes": "32", "base": "t_uint16"},
+		"t_mapping(t_address,t_uint256)": {"encoding": "mapping", "label": "mapping(address => uint256)", "numberOfBytes": "32", "key": "t_address", "value": "t_uint256"},
+		"t_mapping(t_uint256,t_bool)": {"encoding": "mapping", "label": "mapping(uint256 => bool)", "numberOfBytes": "32", "key": "t_uint256", "value": "t_bool"},
+		"t_mapping(t_address,t_mapping(t_uint256,t_bool))": {"encoding": "mapping", "label": "mapping(address => mapping(uint256 => bool))", "numberOfBytes": "32", "key": "t_address", "value": "t_mapping(t_uint256,t_bool)"},
+		"t_struct(Config)1_storage": {"encoding": "inplace", "label": "struct Vault.Config", "numberOfBytes": "64", "members": [
+			{"label": "a", "offset": 0, "slot": "0", "type": "t_uint128"},
+			{"label": "b", "offset": 16, "slot": "0", "type": "t_uint128"},
+			{"label": "c", "offset": 0, "slot": "1", "type": "t_uint256"}
+		]}
+	}
+}`
+
+// traceVault runs a transaction updating the storage of the vault with the
+// prestate tracer in the given mode, and returns the decoded variables of the
+// vault in the pre and post state.
+func traceVault(t *testing.T, diffMode bool) (pre, post map[string]any) {
+	var (
+		vault = common.HexToAddress("0xbbbb")
+		owner = common.HexToAddress("0xcccc")
+		alice = common.HexToAddress("0xaaaa")
+		slot0 = func(fee byte) common.Hash {
+			word := common.BytesToHash(owner.Bytes())
+			word[32-20-8+7] = fee
+			return word
+		}
+		history = crypto.Keccak256Hash(common.Hash{31: 3}.Bytes())
+		name    = common.Hash{31: 2 * 5}
+	)
+	copy(name[:], "vault")
+
+	code := program.New().
+		// fee = 30, other variables of the slot unchanged
+		Sstore(0, slot0(30)).
+		// balances[alice] = 100
+		Push(100).
+		Mstore(common.LeftPadBytes(alice.Bytes(), 32), 0).Mstore(common.Hash{31: 1}.Bytes(), 32).
+		Push(64).Push(0).Op(vm.KECCAK256).Op(vm.SSTORE).
+		// approvals[alice][5] = true
+		Push(1).
+		Mstore(common.Hash{31: 2}.Bytes(), 32).
+		Push(64).Push(0).Op(vm.KECCAK256).
+		Push(32).Op(vm.MSTORE).Mstore(common.Hash{31: 5}.Bytes(), 0).
+		Push(64).Push(0).Op(vm.KECCAK256).Op(vm.SSTORE).
+		// history = [0, 0, 7]
+		Sstore(3, 3).
+		Sstore(history, common.Hash{27: 7}).
+		// config.b = 9, config.a unchanged
+		Sstore(4, common.Hash{15: 9, 31: 1}).
+		// name = "vault"
+		Sstore(6, name).
+		Op(vm.STOP).
+		Bytes()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	statedb.SetCode(vault, code)
+	statedb.SetState(vault, common.Hash{}, slot0(10))
+	statedb.SetState(vault, common.Hash{31: 4}, common.Hash{31: 1})
+
+	config := fmt.Sprintf(`{"diffMode": %v, "storageLayouts": {"%v": %s}}`, diffMode, vault, vaultLayout)
+	tracer, err := tracers.DefaultDirectory.New("prestateTracer", &tracers.Context{}, json.RawMessage(config), params.MergedTestChainConfig)
+	require.NoError(t, err)
+
+	_, _, err = runtime.Call(vault, nil, &runtime.Config{
+		ChainConfig: params.MergedTestChainConfig,
+		GasLimit:    1000000,
+		State:       statedb,
+		EVMConfig:   vm.Config{Tracer: tracer.Hooks},
+	})
+	require.NoError(t, err)
+
+	res, err := tracer.GetResult()
+	require.NoError(t, err)
+
+	type account struct {
+		Variables map[string]any
+	}
+	if !diffMode {
+		var accounts map[common.Address]account
+		require.NoError(t, json.Unmarshal(res, &accounts))
+		return accounts[vault].Variables, nil
+	}
+	var diff struct {
+		Pre  map[common.Address]account
+		Post map[common.Address]account
+	}
+	require.NoError(t, json.Unmarshal(res, &diff))
+	return diff.Pre[vault].Variables, diff.Post[vault].Variables
+}
+
+// Tests that the prestate tracer decodes the storage of contracts with their
+// storage layout, naming mapping values from the hashed keys.
+func TestPrestateTracerStorageLayout(t *testing.T) {
+	alice := common.HexToAddress("0xaaaa").Hex()
+
+	pre, post := traceVault(t, true)
+	require.Equal(t, map[string]any{
+		"fee":                         "10",
+		"balances[" + alice + "]":     "0",
+		"approvals[" + alice + "][5]": false,
+		"history.length":              "0",
+		"history[2]":                  "0",
+		"config.b":                    "0",
+		"name":                        "",
+	}, pre)
+	require.Equal(t, map[string]any{
+		"fee":                         "30",
+		"balances[" + alice + "]":     "100",
+		"approvals[" + alice + "][5]": true,
+		"history.length":              "3",
+		"history[2]":                  "7",
+		"config.b":                    "9",
+		"name":                        "vault",
+	}, post)
+
+	// Without diff mode, all variables of the accessed slots are decoded
+	pre, _ = traceVault(t, false)
+	require.Equal(t, common.HexToAddress("0xcccc").Hex(), pre["owner"])
+	require.Equal(t, "10", pre["fee"])
+	require.Equal(t, false, pre["paused"])
+	require.Equal(t, "1", pre["config.a"])
+	require.Equal(t, "0", pre["history[15]"])
+
+	// Invalid layouts are rejected
+	_, err := tracers.DefaultDirectory.New("prestateTracer", &tracers.Context{}, json.RawMessage(`{"storageLayouts": {"0xbbbb": {"storage": [{"label": "x", "slot": "0", "type": "t_unknown"}]}}}`), params.MergedTestChainConfig)
+	require.Error(t, err)
+}
@@ -0,0 +1,517 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/google/pprof/profile"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("gasProfiler", newGasProfiler, false)
+}
+
+// Output formats of the gas profiler.
+const (
+	profileFormatJSON      = "json"      // aggregates by function and opcode
+	profileFormatCollapsed = "collapsed" // folded stacks, one "frame;...;frame value" per line
+	profileFormatPprof     = "pprof"     // gzipped pprof protobuf, base64 encoded
+)
+
+// Metrics of the collapsed stacks.
+const (
+	profileMetricGas  = "gas"
+	profileMetricTime = "time"
+)
+
+// gasProfiler aggregates the gas consumed and the time spent by a transaction
+// per (code hash, PC) and per call frame. Gas is attributed to the step which
+// consumed it, excluding the gas used by the calls it made, so the profile of
+// a transaction adds up to the gas used by its execution. Intrinsic gas and
+// refunds are not part of the profile.
+//
+// Call frames are labeled by the called address and the 4byte selector of the
+// input, e.g. 0x6b17...1d0f:0xa9059cbb. Steps are labeled by opcode and PC.
+// The collapsed format is understood by flamegraph.pl and speedscope, the pprof
+// one by `go tool pprof` once decoded:
+//
+//	> debug.traceTransaction(hash, {tracer: "gasProfiler", tracerConfig: {format: "pprof"}})
+//	$ jq -r .result | base64 -d > tx.pb.gz && go tool pprof -http=: tx.pb.gz
+type gasProfiler struct {
+	config      gasProfilerConfig
+	chainConfig *params.ChainConfig
+	precompiles []common.Address
+
+	frames    []*profileFrame
+	gasUsed   uint64
+	opcodes   map[profileOpKey]*profileOp
+	functions map[string]*profileFunction
+	nodes     []profileNode       // call stacks seen, by frame id
+	nodeIDs   map[profileNode]int // frame ids by call stack
+	samples   map[profileSampleKey]profileSample
+
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+type gasProfilerConfig struct {
+	Format string `json:"format"` // json (default), collapsed or pprof
+	Metric string `json:"metric"` // value of the collapsed stacks, gas (default) or time
+}
+
+// profileFrame tracks a call frame while it executes.
+type profileFrame struct {
+	function *profileFunction
+	id       int    // id of the call stack leading to the frame
+	gas      uint64 // gas available on entry
+	start    time.Time
+
+	codeHash common.Hash
+	hashed   bool
+
+	step      profileStep   // last step executed, not yet accounted
+	stepping  bool          // whether step is set
+	childGas  uint64        // gas used by calls since the last step
+	childTime time.Duration // time spent in calls since the last step
+	totalGas  uint64        // gas used by all calls of the frame
+	selfGas   uint64        // gas accounted to the steps of the frame
+}
+
+// profileStep is an executed opcode whose cost isn't known until the next one.
+type profileStep struct {
+	pc    uint64
+	op    vm.OpCode
+	gas   uint64
+	start time.Time
+}
+
+type profileOpKey struct {
+	codeHash common.Hash
+	pc       uint64
+}
+
+type profileOp struct {
+	CodeHash common.Hash `json:"codeHash"`
+	PC       uint64      `json:"pc"`
+	Op       string      `json:"op"`
+	Count    uint64      `json:"count"`
+	Gas      uint64      `json:"gas"`
+	Time     uint64      `json:"time"` // nanoseconds
+}
+
+type profileFunction struct {
+	Function string         `json:"function"`
+	Address  common.Address `json:"address"`
+	Calls    uint64         `json:"calls"`
+	Gas      uint64         `json:"gas"`     // including the calls made
+	SelfGas  uint64         `json:"selfGas"` // excluding the calls made
+	Time     uint64         `json:"time"`    // nanoseconds, including the calls made
+}
+
+// profileNode is a call stack, identified by the id of the calling stack and
+// the label of the called frame. Root frames have a parent of -1.
+type profileNode struct {
+	parent int
+	label  string
+}
+
+// profileSampleKey identifies the samples of a step in a call stack. The gas
+// spent by a frame outside of its steps is sampled with step unset.
+type profileSampleKey struct {
+	frame int
+	pc    uint64
+	op    vm.OpCode
+	step  bool
+}
+
+type profileSample struct {
+	gas  uint64
+	time time.Duration
+}
+
+// profileStack is a sample with its folded stack, rendered for the output.
+type profileStack struct {
+	stack  []string
+	folded string
+	profileSample
+}
+
+// gasProfile is the JSON output of the profiler.
+type gasProfile struct {
+	GasUsed   uint64             `json:"gasUsed"`
+	Functions []*profileFunction `json:"functions"`
+	Opcodes   []*profileOp       `json:"opcodes"`
+}
+
+// newGasProfiler returns a native go tracer which profiles the gas usage and
+// execution time of a transaction.
+func newGasProfiler(ctx *tracers.Context, cfg json.RawMessage, chainConfig *params.ChainConfig) (*tracers.Tracer, error) {
+	var config gasProfilerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	switch config.Format {
+	case "":
+		config.Format = profileFormatJSON
+	case profileFormatJSON, profileFormatCollapsed, profileFormatPprof:
+	default:
+		return nil, fmt.Errorf("unknown profile format %q", config.Format)
+	}
+	switch config.Metric {
+	case "":
+		config.Metric = profileMetricGas
+	case profileMetricGas, profileMetricTime:
+	default:
+		return nil, fmt.Errorf("unknown profile metric %q", config.Metric)
+	}
+	t := &gasProfiler{
+		config:      config,
+		chainConfig: chainConfig,
+		opcodes:     make(map[profileOpKey]*profileOp),
+		functions:   make(map[string]*profileFunction),
+		nodeIDs:     make(map[profileNode]int),
+		samples:     make(map[profileSampleKey]profileSample),
+	}
+	return &tracers.Tracer{
+		Hooks: &tracing.Hooks{
+			OnTxStart: t.OnTxStart,
+			OnEnter:   t.OnEnter,
+			OnExit:    t.OnExit,
+			OnOpcode:  t.OnOpcode,
+		},
+		GetResult: t.GetResult,
+		Stop:      t.Stop,
+	}, nil
+}
+
+func (t *gasProfiler) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
+	rules := t.chainConfig.Rules(env.BlockNumber, env.Random != nil, env.Time)
+	t.precompiles = vm.ActivePrecompiles(rules)
+}
+
+// OnEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *gasProfiler) OnEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() {
+		return
+	}
+	label := t.label(vm.OpCode(typ), to, input)
+	function := t.functions[label]
+	if function == nil {
+		function = &profileFunction{Function: label, Address: to}
+		t.functions[label] = function
+	}
+	function.Calls++
+
+	node := profileNode{parent: -1, label: label}
+	if n := len(t.frames); n > 0 {
+		node.parent = t.frames[n-1].id
+	}
+	id, ok := t.nodeIDs[node]
+	if !ok {
+		id = len(t.nodes)
+		t.nodes = append(t.nodes, node)
+		t.nodeIDs[node] = id
+	}
+	t.frames = append(t.frames, &profileFrame{
+		function: function,
+		id:       id,
+		gas:      gas,
+		start:    time.Now(),
+	})
+}
+
+// label names the function executed by a call frame.
+func (t *gasProfiler) label(typ vm.OpCode, to common.Address, input []byte) string {
+	switch {
+	case typ == vm.CREATE || typ == vm.CREATE2:
+		return to.Hex() + ":constructor"
+	case typ == vm.SELFDESTRUCT:
+		return to.Hex() + ":selfdestruct"
+	case slices.Contains(t.precompiles, to):
+		return to.Hex() + ":precompile"
+	case len(input) < 4:
+		return to.Hex() + ":fallback"
+	default:
+		return to.Hex() + ":" + bytesToHex(input[:4])
+	}
+}
+
+// OnOpcode accounts the previous step of the frame and starts the next one.
+func (t *gasProfiler) OnOpcode(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	now := time.Now()
+	t.account(frame, gas, now)
+
+	if !frame.hashed {
+		frame.codeHash = crypto.Keccak256Hash(scope.ContractCode())
+		frame.hashed = true
+	}
+	frame.step = profileStep{pc: pc, op: vm.OpCode(op), gas: gas, start: now}
+	frame.stepping = true
+}
+
+// account attributes the gas consumed since the last step of the frame, apart
+// from its calls, to the step.
+func (t *gasProfiler) account(frame *profileFrame, gas uint64, now time.Time) {
+	if !frame.stepping {
+		return
+	}
+	step := &frame.step
+	var used uint64
+	if step.gas > gas+frame.childGas {
+		used = step.gas - gas - frame.childGas
+	}
+	elapsed := max(now.Sub(step.start)-frame.childTime, 0)
+
+	key := profileOpKey{frame.codeHash, step.pc}
+	op := t.opcodes[key]
+	if op == nil {
+		op = &profileOp{CodeHash: frame.codeHash, PC: step.pc, Op: step.op.String()}
+		t.opcodes[key] = op
+	}
+	op.Count++
+	op.Gas += used
+	op.Time += uint64(elapsed)
+
+	t.sample(profileSampleKey{frame: frame.id, pc: step.pc, op: step.op, step: true}, used, elapsed)
+
+	frame.selfGas += used
+	frame.stepping, frame.childGas, frame.childTime = false, 0, 0
+}
+
+// sample adds the gas and time spent at a step of a call stack.
+func (t *gasProfiler) sample(key profileSampleKey, gas uint64, elapsed time.Duration) {
+	s := t.samples[key]
+	s.gas += gas
+	s.time += elapsed
+	t.samples[key] = s
+}
+
+// OnExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *gasProfiler) OnExit(depth int, output []byte, gasUsed uint64, err error, reverted bool) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	var (
+		frame = t.frames[len(t.frames)-1]
+		now   = time.Now()
+	)
+	t.frames = t.frames[:len(t.frames)-1]
+
+	var left uint64
+	if frame.gas > gasUsed {
+		left = frame.gas - gasUsed
+	}
+	t.account(frame, left, now)
+
+	// Gas not consumed by steps nor calls is spent by the frame itself, e.g.
+	// in precompiles and code deposits
+	if gasUsed > frame.selfGas+frame.totalGas {
+		t.sample(profileSampleKey{frame: frame.id}, gasUsed-frame.selfGas-frame.totalGas, 0)
+		frame.selfGas = gasUsed - frame.totalGas
+	}
+	elapsed := now.Sub(frame.start)
+	frame.function.Gas += gasUsed
+	frame.function.SelfGas += frame.selfGas
+	frame.function.Time += uint64(elapsed)
+
+	if len(t.frames) == 0 {
+		t.gasUsed = gasUsed
+		return
+	}
+	parent := t.frames[len(t.frames)-1]
+	parent.childGas += gasUsed
+	parent.childTime += elapsed
+	parent.totalGas += gasUsed
+}
+
+// GetResult returns the profile in the configured format, and any error
+// arising from the encoding or forceful termination (via `Stop`).
+func (t *gasProfiler) GetResult() (json.RawMessage, error) {
+	var (
+		res any
+		err error
+	)
+	switch t.config.Format {
+	case profileFormatCollapsed:
+		res = t.collapsed()
+	case profileFormatPprof:
+		res, err = t.pprof()
+	default:
+		res = t.profile()
+	}
+	if err != nil {
+		return nil, err
+	}
+	blob, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	return blob, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *gasProfiler) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
+
+// profile aggregates the samples by function and opcode, heaviest first.
+func (t *gasProfiler) profile() *gasProfile {
+	res := &gasProfile{
+		GasUsed:   t.gasUsed,
+		Functions: make([]*profileFunction, 0, len(t.functions)),
+		Opcodes:   make([]*profileOp, 0, len(t.opcodes)),
+	}
+	for _, f := range t.functions {
+		res.Functions = append(res.Functions, f)
+	}
+	slices.SortFunc(res.Functions, func(a, b *profileFunction) int {
+		if a.Gas != b.Gas {
+			return cmpDesc(a.Gas, b.Gas)
+		}
+		return strings.Compare(a.Function, b.Function)
+	})
+	for _, op := range t.opcodes {
+		res.Opcodes = append(res.Opcodes, op)
+	}
+	slices.SortFunc(res.Opcodes, func(a, b *profileOp) int {
+		if a.Gas != b.Gas {
+			return cmpDesc(a.Gas, b.Gas)
+		}
+		if c := bytes.Compare(a.CodeHash[:], b.CodeHash[:]); c != 0 {
+			return c
+		}
+		return cmpDesc(b.PC, a.PC)
+	})
+	return res
+}
+
+// sortedSamples renders the stacks of the samples, ordered by folded stack.
+func (t *gasProfiler) sortedSamples() []*profileStack {
+	stacks := make([][]string, len(t.nodes))
+	for id, node := range t.nodes {
+		// Parents are seen before their children, so have lower ids
+		if node.parent < 0 {
+			stacks[id] = []string{node.label}
+		} else {
+			stacks[id] = append(slices.Clip(stacks[node.parent]), node.label)
+		}
+	}
+	samples := make([]*profileStack, 0, len(t.samples))
+	for key, sample := range t.samples {
+		stack := stacks[key.frame]
+		if key.step {
+			stack = append(slices.Clip(stack), fmt.Sprintf("%s@%d", key.op, key.pc))
+		}
+		samples = append(samples, &profileStack{
+			stack:         stack,
+			folded:        strings.Join(stack, ";"),
+			profileSample: sample,
+		})
+	}
+	slices.SortFunc(samples, func(a, b *profileStack) int {
+		return strings.Compare(a.folded, b.folded)
+	})
+	return samples
+}
+
+// collapsed renders the samples as folded stacks.
+func (t *gasProfiler) collapsed() string {
+	var b strings.Builder
+	for _, s := range t.sortedSamples() {
+		value := s.gas
+		if t.config.Metric == profileMetricTime {
+			value = uint64(s.time)
+		}
+		if value == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d\n", s.folded, value)
+	}
+	return b.String()
+}
+
+// pprof encodes the samples as a gzipped pprof profile with gas and time
+// values.
+func (t *gasProfiler) pprof() ([]byte, error) {
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "gas", Unit: "count"},
+			{Type: "time", Unit: "nanoseconds"},
+		},
+		PeriodType: &profile.ValueType{Type: "gas", Unit: "count"},
+		Period:     1,
+	}
+	locations := make(map[string]*profile.Location)
+	location := func(label string) *profile.Location {
+		if loc, ok := locations[label]; ok {
+			return loc
+		}
+		fn := &profile.Function{ID: uint64(len(p.Function) + 1), Name: label, SystemName: label}
+		p.Function = append(p.Function, fn)
+
+		loc := &profile.Location{ID: uint64(len(p.Location) + 1), Line: []profile.Line{{Function: fn}}}
+		p.Location = append(p.Location, loc)
+		locations[label] = loc
+		return loc
+	}
+	for _, s := range t.sortedSamples() {
+		sample := &profile.Sample{Value: []int64{int64(s.gas), int64(s.time)}}
+		for i := len(s.stack) - 1; i >= 0; i-- {
+			sample.Location = append(sample.Location, location(s.stack[i]))
+		}
+		p.Sample = append(p.Sample, sample)
+	}
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cmpDesc orders numbers in descending order.
+func cmpDesc(a, b uint64) int {
+	switch {
+	case a > b:
+		return -1
+	case a < b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/program"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/google/pprof/profile"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+// profileCall runs a call from a contract to another one, which writes to its
+// storage and calls the identity precompile, with the gas profiler in the given
+// configuration. It returns the output of the profiler and the gas used.
+func profileCall(t *testing.T, config string) (json.RawMessage, uint64) {
+	var (
+		caller = common.HexToAddress("0xaaaa")
+		callee = common.HexToAddress("0xbbbb")
+		gas    = uint256.NewInt(100000)
+	)
+	calleeCode := program.New().
+		Sstore(1, 1).
+		StaticCall(gas, 0x04, 0, 32, 0, 32).
+		Op(vm.STOP).
+		Bytes()
+	callerCode := program.New().
+		Mstore(common.FromHex("0xa9059cbb"), 0).
+		Call(gas, callee, 0, 0, 4, 0, 0).
+		Op(vm.STOP).
+		Bytes()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	statedb.SetCode(caller, callerCode)
+	statedb.SetCode(callee, calleeCode)
+
+	tracer, err := tracers.DefaultDirectory.New("gasProfiler", &tracers.Context{}, json.RawMessage(config), params.MergedTestChainConfig)
+	require.NoError(t, err)
+
+	cfg := &runtime.Config{
+		ChainConfig: params.MergedTestChainConfig,
+		GasLimit:    1000000,
+		State:       statedb,
+		EVMConfig:   vm.Config{Tracer: tracer.Hooks},
+	}
+	_, left, err := runtime.Call(caller, nil, cfg)
+	require.NoError(t, err)
+
+	res, err := tracer.GetResult()
+	require.NoError(t, err)
+	return res, cfg.GasLimit - left
+}
+
+// Tests that the gas profiler attributes all the gas used by a call to the
+// opcodes and call frames consuming it.
+func TestGasProfiler(t *testing.T) {
+	var (
+		caller   = common.HexToAddress("0xaaaa").Hex() + ":fallback"
+		callee   = common.HexToAddress("0xbbbb").Hex() + ":0xa9059cbb"
+		identity = common.BytesToAddress([]byte{0x04}).Hex() + ":precompile"
+	)
+	// The aggregated profile accounts all gas to functions and opcodes
+	res, gasUsed := profileCall(t, `{}`)
+
+	var prof struct {
+		GasUsed   uint64
+		Functions []struct {
+			Function string
+			Calls    uint64
+			Gas      uint64
+			SelfGas  uint64
+		}
+		Opcodes []struct {
+			Op  string
+			Gas uint64
+		}
+	}
+	require.NoError(t, json.Unmarshal(res, &prof))
+	require.Equal(t, gasUsed, prof.GasUsed)
+
+	var selfGas uint64
+	functions := make(map[string]uint64)
+	for _, f := range prof.Functions {
+		require.Equal(t, uint64(1), f.Calls, f.Function)
+		selfGas += f.SelfGas
+		functions[f.Function] = f.Gas
+	}
+	require.Equal(t, gasUsed, selfGas)
+	require.Equal(t, gasUsed, functions[caller])
+	require.Greater(t, functions[callee], functions[identity])
+	require.NotZero(t, functions[identity])
+
+	var opGas uint64
+	for _, op := range prof.Opcodes {
+		opGas += op.Gas
+	}
+	require.Equal(t, gasUsed, opGas+functions[identity])
+	require.Equal(t, "SSTORE", prof.Opcodes[0].Op)
+	require.Equal(t, params.SstoreSetGasEIP2200+params.ColdSloadCostEIP2929, prof.Opcodes[0].Gas)
+
+	// Collapsed stacks add up to the gas used
+	res, gasUsed = profileCall(t, `{"format": "collapsed"}`)
+
+	var collapsed string
+	require.NoError(t, json.Unmarshal(res, &collapsed))
+
+	var total uint64
+	stacks := make(map[string]uint64)
+	for _, line := range strings.Split(strings.TrimSpace(collapsed), "\n") {
+		i := strings.LastIndexByte(line, ' ')
+		value, err := strconv.ParseUint(line[i+1:], 10, 64)
+		require.NoError(t, err, line)
+		stacks[line[:i]] = value
+		total += value
+	}
+	require.Equal(t, gasUsed, total)
+	require.Contains(t, stacks, strings.Join([]string{caller, callee, identity}, ";"))
+
+	var sstore bool
+	for stack := range stacks {
+		if strings.HasPrefix(stack, caller+";"+callee+";SSTORE@") {
+			sstore = true
+		}
+	}
+	require.True(t, sstore, "SSTORE missing from the stacks")
+
+	// The pprof profile holds the gas and time of each stack
+	res, gasUsed = profileCall(t, `{"format": "pprof"}`)
+
+	var blob []byte
+	require.NoError(t, json.Unmarshal(res, &blob))
+	p, err := profile.Parse(bytes.NewReader(blob))
+	require.NoError(t, err)
+	require.Len(t, p.SampleType, 2)
+	require.GreaterOrEqual(t, len(p.Sample), len(stacks))
+
+	total = 0
+	for _, s := range p.Sample {
+		total += uint64(s.Value[0])
+	}
+	require.Equal(t, gasUsed, total)
+}
+
+func TestGasProfilerConfig(t *testing.T) {
+	for _, config := range []string{`{"format": "svg"}`, `{"metric": "memory"}`} {
+		_, err := tracers.DefaultDirectory.New("gasProfiler", &tracers.Context{}, json.RawMessage(config), params.MainnetChainConfig)
+		require.Error(t, err, config)
+	}
+}
+
+// profileScope is the scope of a contract executing the given code.
+type profileScope []byte
+
+func (s profileScope) MemoryData() []byte       { return nil }
+func (s profileScope) StackData() []uint256.Int { return nil }
+func (s profileScope) Caller() common.Address   { return common.Address{} }
+func (s profileScope) Address() common.Address  { return common.Address{} }
+func (s profileScope) CallValue() *uint256.Int  { return new(uint256.Int) }
+func (s profileScope) CallInput() []byte        { return nil }
+func (s profileScope) ContractCode() []byte     { return s }
+
+// BenchmarkGasProfilerOpcode measures the overhead of the profiler per executed
+// opcode, which must not allocate once the steps of a loop are known.
+func BenchmarkGasProfilerOpcode(b *testing.B) {
+	tracer, err := tracers.DefaultDirectory.New("gasProfiler", &tracers.Context{}, nil, params.MergedTestChainConfig)
+	require.NoError(b, err)
+
+	var (
+		hooks = tracer.Hooks
+		code  = profileScope(program.New().Push(1).Push(2).Op(vm.ADD).Op(vm.POP).Jump(0).Bytes())
+		scope = tracing.OpContext(code)
+		gas   = uint64(1 << 40)
+	)
+	hooks.OnTxStart(&tracing.VMContext{BlockNumber: big.NewInt(1)}, types.NewTx(&types.LegacyTx{}), common.Address{})
+	hooks.OnEnter(0, byte(vm.CALL), common.Address{}, common.HexToAddress("0xbbbb"), common.FromHex("0xa9059cbb"), gas, new(big.Int))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pc := uint64(i % len(code))
+		hooks.OnOpcode(pc, code[pc], gas, 3, scope, nil, 1, nil)
+		gas -= 3
+	}
+	b.StopTimer()
+
+	hooks.OnExit(0, nil, 1<<40-gas, nil, false)
+	_, err = tracer.GetResult()
+	require.NoError(b, err)
+}
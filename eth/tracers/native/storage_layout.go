@@ -0,0 +1,413 @@
+// Copyright 2025 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+// dynamicArraySpan bounds the number of slots of dynamic arrays and long byte
+// arrays, which grow from the hash of their slot without a known limit.
+const dynamicArraySpan = 1 << 40
+
+// storageLayout is the storage layout of a contract, as output by solc in the
+// storageLayout field of a contract.
+type storageLayout struct {
+	Storage []storageVariable       `json:"storage"`
+	Types   map[string]*storageType `json:"types"`
+}
+
+// storageVariable is a state variable, or a member of a struct.
+type storageVariable struct {
+	Label  string `json:"label"`
+	Offset int    `json:"offset"`
+	Slot   string `json:"slot"`
+	Type   string `json:"type"`
+}
+
+// storageType describes how a type is encoded in storage.
+type storageType struct {
+	Encoding      string            `json:"encoding"` // inplace, mapping, dynamic_array or bytes
+	Label         string            `json:"label"`
+	NumberOfBytes string            `json:"numberOfBytes"`
+	Key           string            `json:"key,omitempty"`     // mappings
+	Value         string            `json:"value,omitempty"`   // mappings
+	Base          string            `json:"base,omitempty"`    // arrays
+	Members       []storageVariable `json:"members,omitempty"` // structs
+}
+
+// size returns the number of bytes a type occupies in storage.
+func (t *storageType) size() int {
+	n, _ := strconv.Atoi(t.NumberOfBytes)
+	return n
+}
+
+// Pseudo types of the variables implied by dynamic arrays and byte arrays, with
+// identifiers distinct from the t_ prefixed ones of solc.
+const (
+	lengthTypeID = "length"
+	chunkTypeID  = "chunk"
+)
+
+var (
+	lengthType = &storageType{Encoding: "inplace", Label: "uint256", NumberOfBytes: "32"}
+	chunkType  = &storageType{Encoding: "inplace", Label: "bytes32", NumberOfBytes: "32"}
+)
+
+// storageField is a variable held by a slot, possibly packed with others.
+type storageField struct {
+	name   string
+	offset int
+	size   int
+	typ    *storageType
+}
+
+// storageMapping is the root slot of a mapping.
+type storageMapping struct {
+	name  string
+	key   *storageType
+	value string // type identifier
+}
+
+// storageArray is the data area of an array, starting at the base slot.
+type storageArray struct {
+	name   string
+	base   uint256.Int
+	length uint64 // number of elements, 0 if dynamic
+	elem   string // type identifier
+	size   int    // size of the elements
+}
+
+// span returns the number of slots of the array data.
+func (a *storageArray) span() uint64 {
+	if a.length == 0 {
+		return dynamicArraySpan
+	}
+	if perSlot := a.perSlot(); perSlot > 1 {
+		return (a.length + perSlot - 1) / perSlot
+	}
+	return a.length * a.slots()
+}
+
+// perSlot returns the number of elements packed in a slot, 1 if they aren't.
+func (a *storageArray) perSlot() uint64 {
+	if a.size > 0 && a.size <= 16 {
+		return uint64(32 / a.size)
+	}
+	return 1
+}
+
+// slots returns the number of slots occupied by an element.
+func (a *storageArray) slots() uint64 {
+	return max(uint64(a.size+31)/32, 1)
+}
+
+// storageDecoder names the slots of a contract with the variables stored in
+// them. Slots of mapping values are named from the preimages of their hash.
+type storageDecoder struct {
+	types    map[string]*storageType
+	fields   map[common.Hash][]storageField
+	mappings map[common.Hash]*storageMapping
+	arrays   []*storageArray
+	placed   map[common.Hash]bool // array slots whose elements were placed
+}
+
+// newStorageDecoder places the state variables of a layout in storage.
+func newStorageDecoder(layout *storageLayout) (*storageDecoder, error) {
+	d := &storageDecoder{
+		types:    maps.Clone(layout.Types),
+		fields:   make(map[common.Hash][]storageField),
+		mappings: make(map[common.Hash]*storageMapping),
+		placed:   make(map[common.Hash]bool),
+	}
+	if d.types == nil {
+		d.types = make(map[string]*storageType)
+	}
+	d.types[lengthTypeID], d.types[chunkTypeID] = lengthType, chunkType
+
+	for _, v := range layout.Storage {
+		slot, err := uint256.FromDecimal(v.Slot)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slot of %s: %v", v.Label, err)
+		}
+		if err := d.place(v.Label, slot, v.Offset, v.Type); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// place registers a variable of the given type stored at a slot and offset.
+func (d *storageDecoder) place(name string, slot *uint256.Int, offset int, typeID string) error {
+	typ := d.types[typeID]
+	if typ == nil {
+		return fmt.Errorf("unknown type %s of %s", typeID, name)
+	}
+	key := common.Hash(slot.Bytes32())
+	switch typ.Encoding {
+	case "mapping":
+		keyType := d.types[typ.Key]
+		if keyType == nil || d.types[typ.Value] == nil {
+			return fmt.Errorf("unknown key or value type of %s", name)
+		}
+		d.mappings[key] = &storageMapping{name: name, key: keyType, value: typ.Value}
+
+	case "dynamic_array":
+		elem := d.types[typ.Base]
+		if elem == nil {
+			return fmt.Errorf("unknown element type of %s", name)
+		}
+		d.fields[key] = append(d.fields[key], storageField{name + ".length", 0, 32, lengthType})
+		d.arrays = append(d.arrays, &storageArray{name: name, base: dataSlot(slot), elem: typ.Base, size: elem.size()})
+
+	case "bytes":
+		d.fields[key] = append(d.fields[key], storageField{name, 0, 32, typ})
+		d.arrays = append(d.arrays, &storageArray{name: name + ".data", base: dataSlot(slot), elem: chunkTypeID, size: 32})
+
+	case "inplace":
+		switch {
+		case len(typ.Members) > 0:
+			for _, m := range typ.Members {
+				rel, err := uint256.FromDecimal(m.Slot)
+				if err != nil {
+					return fmt.Errorf("invalid slot of %s.%s: %v", name, m.Label, err)
+				}
+				if err := d.place(name+"."+m.Label, new(uint256.Int).Add(slot, rel), m.Offset, m.Type); err != nil {
+					return err
+				}
+			}
+		case typ.Base != "":
+			elem := d.types[typ.Base]
+			if elem == nil {
+				return fmt.Errorf("unknown element type of %s", name)
+			}
+			length, err := staticLength(typ.Label)
+			if err != nil || length == 0 {
+				return fmt.Errorf("invalid static array %s: %s", name, typ.Label)
+			}
+			d.arrays = append(d.arrays, &storageArray{name: name, base: *slot, length: length, elem: typ.Base, size: elem.size()})
+		default:
+			d.fields[key] = append(d.fields[key], storageField{name, offset, typ.size(), typ})
+		}
+	default:
+		return fmt.Errorf("unknown encoding %q of %s", typ.Encoding, name)
+	}
+	return nil
+}
+
+// dataSlot returns the slot at which the data of a dynamic array starts.
+func dataSlot(slot *uint256.Int) uint256.Int {
+	var data uint256.Int
+	data.SetBytes32(crypto.Keccak256(common.Hash(slot.Bytes32()).Bytes()))
+	return data
+}
+
+// staticLength parses the length of a static array from its type label, e.g.
+// uint256[3] or struct S[2][4].
+func staticLength(label string) (uint64, error) {
+	open := strings.LastIndexByte(label, '[')
+	if open < 0 || !strings.HasSuffix(label, "]") {
+		return 0, errors.New("not an array")
+	}
+	return strconv.ParseUint(label[open+1:len(label)-1], 10, 64)
+}
+
+// resolve places the array elements stored at a slot, if any, and returns the
+// variables held by the slot.
+func (d *storageDecoder) resolve(slot common.Hash) []storageField {
+	if fields, ok := d.fields[slot]; ok || d.placed[slot] {
+		return fields
+	}
+	d.placed[slot] = true
+
+	target := new(uint256.Int).SetBytes32(slot[:])
+	for _, array := range d.arrays {
+		delta := new(uint256.Int).Sub(target, &array.base)
+		if !delta.IsUint64() || delta.Uint64() >= array.span() {
+			continue
+		}
+		n := delta.Uint64()
+		if perSlot := array.perSlot(); perSlot > 1 {
+			// Small elements are packed, place all elements of the slot
+			for i := n * perSlot; i < (n+1)*perSlot; i++ {
+				if array.length != 0 && i >= array.length {
+					break
+				}
+				d.place(fmt.Sprintf("%s[%d]", array.name, i), target, int(i%perSlot)*array.size, array.elem)
+			}
+		} else {
+			i := n / array.slots()
+			elem := new(uint256.Int).Add(&array.base, uint256.NewInt(i*array.slots()))
+			d.place(fmt.Sprintf("%s[%d]", array.name, i), elem, 0, array.elem)
+		}
+	}
+	return d.fields[slot]
+}
+
+// addPreimages places the mapping values whose slots are the hashes of the given
+// preimages, i.e. of a key followed by the slot of the mapping. Values of nested
+// mappings are placed once their parent mapping is.
+func (d *storageDecoder) addPreimages(preimages map[common.Hash][]byte) {
+	pending := make(map[common.Hash][]byte)
+	for hash, preimage := range preimages {
+		if len(preimage) >= 32 {
+			pending[hash] = preimage
+		}
+	}
+	for progress := true; progress; {
+		progress = false
+		for hash, preimage := range pending {
+			root := common.BytesToHash(preimage[len(preimage)-32:])
+			d.resolve(root)
+			mapping, ok := d.mappings[root]
+			if !ok {
+				continue
+			}
+			delete(pending, hash)
+			progress = true
+
+			key, ok := mappingKey(mapping.key, preimage[:len(preimage)-32])
+			if !ok {
+				continue
+			}
+			var slot uint256.Int
+			slot.SetBytes32(hash[:])
+			d.place(fmt.Sprintf("%s[%s]", mapping.name, key), &slot, 0, mapping.value)
+		}
+	}
+}
+
+// mappingKey formats the key of a mapping from its hashed encoding, which is
+// padded to 32 bytes for value types.
+func mappingKey(typ *storageType, data []byte) (string, bool) {
+	if typ.Encoding == "bytes" {
+		if typ.Label == "string" {
+			return strconv.Quote(string(data)), true
+		}
+		return hexutil.Encode(data), true
+	}
+	if len(data) != 32 {
+		return "", false
+	}
+	size := typ.size()
+	if size <= 0 || size > 32 {
+		return "", false
+	}
+	// Fixed bytes are left aligned, other value types right aligned
+	if isFixedBytes(typ.Label) {
+		return hexutil.Encode(data[:size]), true
+	}
+	return fmt.Sprint(decodeValue(typ, data[32-size:])), true
+}
+
+// isFixedBytes reports whether a type label is a fixed size byte array.
+func isFixedBytes(label string) bool {
+	if !strings.HasPrefix(label, "bytes") {
+		return false
+	}
+	_, err := strconv.Atoi(label[len("bytes"):])
+	return err == nil
+}
+
+// decode returns the values of the variables held by a slot. Only the variables
+// accepted by the filter are decoded.
+func (d *storageDecoder) decode(slot common.Hash, value common.Hash, filter func(storageField) bool) map[string]any {
+	var vars map[string]any
+	for _, field := range d.resolve(slot) {
+		if filter != nil && !filter(field) {
+			continue
+		}
+		if vars == nil {
+			vars = make(map[string]any)
+		}
+		vars[field.name] = decodeField(field, value)
+	}
+	return vars
+}
+
+// fieldBytes returns the bytes of a field within the slot holding it. Packed
+// fields are stored from the lowest order bytes on.
+func fieldBytes(field storageField, value common.Hash) []byte {
+	end := 32 - field.offset
+	start := end - field.size
+	if start < 0 || end > 32 || start > end {
+		return value[:]
+	}
+	return value[start:end]
+}
+
+// decodeField decodes the value of a variable from the slot holding it.
+func decodeField(field storageField, value common.Hash) any {
+	if field.typ.Encoding == "bytes" {
+		return decodeBytes(field.typ, value)
+	}
+	return decodeValue(field.typ, fieldBytes(field, value))
+}
+
+// decodeBytes decodes a string or byte array from its slot. Short ones are
+// stored in the slot along with twice their length, long ones only store twice
+// their length plus one, their data following the hash of the slot.
+func decodeBytes(typ *storageType, value common.Hash) any {
+	if value[31]&1 == 1 {
+		length := new(big.Int).Rsh(value.Big(), 1)
+		return map[string]any{"length": length.String()}
+	}
+	length := int(value[31] / 2)
+	if length > 31 {
+		length = 31
+	}
+	if typ.Label == "string" {
+		return string(value[:length])
+	}
+	return hexutil.Encode(value[:length])
+}
+
+// decodeValue decodes a value type from its bytes.
+func decodeValue(typ *storageType, data []byte) any {
+	label := typ.Label
+	switch {
+	case label == "bool":
+		for _, b := range data {
+			if b != 0 {
+				return true
+			}
+		}
+		return false
+	case strings.HasPrefix(label, "address"), strings.HasPrefix(label, "contract "):
+		return common.BytesToAddress(data).Hex()
+	case strings.HasPrefix(label, "uint"), strings.HasPrefix(label, "enum "):
+		return new(big.Int).SetBytes(data).String()
+	case strings.HasPrefix(label, "int"):
+		v := new(big.Int).SetBytes(data)
+		if len(data) > 0 && data[0]&0x80 != 0 {
+			v.Sub(v, new(big.Int).Lsh(common.Big1, uint(len(data)*8)))
+		}
+		return v.String()
+	default:
+		return hexutil.Encode(data)
+	}
+}
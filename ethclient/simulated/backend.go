@@ -105,7 +105,7 @@ func NewBackend(alloc types.GenesisAlloc, options ...func(nodeConf *node.Config,
 // newWithNode sets up a simulated backend on an existing node. The provided node
 // must not be started and will be started by this method.
 func newWithNode(stack *node.Node, conf *eth.Config, blockPeriod uint64) (*Backend, error) {
-	backend, err := eth.New(stack, conf, nil)
+	backend, err := eth.New(stack, conf)
 	if err != nil {
 		return nil, err
 	}
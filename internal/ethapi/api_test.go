@@ -1167,6 +1167,63 @@ func TestCall(t *testing.T) {
 	}
 }
 
+// echoCaller is a stateful precompile returning the sender of the call.
+type echoCaller struct{}
+
+func (echoCaller) RequiredGas(input []byte) uint64 { return 100 }
+
+func (echoCaller) Run(input []byte) ([]byte, error) { return nil, vm.ErrStatefulPrecompile }
+
+func (echoCaller) RunStateful(env *vm.PrecompileEnvironment, input []byte) ([]byte, error) {
+	return common.BytesToHash(env.Caller.Bytes()).Bytes(), nil
+}
+
+func init() {
+	if err := vm.RegisterPrecompile("ethapi-test-echo", echoCaller{}); err != nil {
+		panic(err)
+	}
+}
+
+// Tests that eth_call executes the precompiles installed by the chain config
+// at the time of the requested block.
+func TestCallStatefulPrecompile(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts = newAccounts(1)
+		precomp  = common.HexToAddress("0x0300000000000000000000000000000000000001")
+		config   = *params.MergedTestChainConfig
+	)
+	config.Precompiles = []params.PrecompileUpgrade{{Address: precomp, Time: 35, Contract: "ethapi-test-echo"}}
+	genesis := &core.Genesis{
+		Config: &config,
+		Alloc:  types.GenesisAlloc{accounts[0].addr: {Balance: big.NewInt(params.Ether)}},
+	}
+	api := NewBlockChainAPI(newTestBackend(t, 5, genesis, beacon.New(ethash.NewFaker()), func(i int, b *core.BlockGen) {
+		b.SetPoS()
+	}))
+	for _, tt := range []struct {
+		number rpc.BlockNumber
+		want   []byte
+	}{
+		{number: 2, want: []byte{}},
+		{number: 5, want: common.BytesToHash(accounts[0].addr.Bytes()).Bytes()},
+	} {
+		header, err := api.b.HeaderByNumber(context.Background(), tt.number)
+		if err != nil {
+			t.Fatalf("failed to get header %d: %v", tt.number, err)
+		}
+		args := TransactionArgs{From: &accounts[0].addr, To: &precomp}
+		result, err := api.Call(context.Background(), args, &rpc.BlockNumberOrHash{BlockNumber: &tt.number}, nil, nil)
+		if err != nil {
+			t.Fatalf("block %d: call failed: %v", tt.number, err)
+		}
+		if !bytes.Equal(result, tt.want) {
+			t.Errorf("block %d at time %d: result mismatch: have %x, want %x", tt.number, header.Time, result, tt.want)
+		}
+	}
+}
+
 func TestSimulateV1(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
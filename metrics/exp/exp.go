@@ -3,6 +3,7 @@
 package exp
 
 import (
+	"encoding/json"
 	"expvar"
 	"fmt"
 	"net/http"
@@ -45,6 +46,7 @@ func Exp(r metrics.Registry) {
 	// haven't found an elegant way, so just use a different endpoint
 	http.Handle("/debug/metrics", h)
 	http.Handle("/debug/metrics/prometheus", prometheus.Handler(r))
+	http.Handle("/debug/health", HealthHandler(r))
 }
 
 // ExpHandler will return an expvar powered metrics handler.
@@ -53,12 +55,42 @@ func ExpHandler(r metrics.Registry) http.Handler {
 	return http.HandlerFunc(e.expHandler)
 }
 
+// HealthHandler returns a handler running the healthchecks of the registry,
+// responding with the error of each check, null if healthy. The status code is
+// 503 if any check fails, allowing load balancers to probe it directly.
+func HealthHandler(r metrics.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.RunHealthchecks()
+
+		var (
+			checks = make(map[string]*string)
+			status = http.StatusOK
+		)
+		r.Each(func(name string, i interface{}) {
+			h, ok := i.(*metrics.Healthcheck)
+			if !ok {
+				return
+			}
+			checks[name] = nil
+			if err := h.Error(); err != nil {
+				msg := err.Error()
+				checks[name] = &msg
+				status = http.StatusServiceUnavailable
+			}
+		})
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(checks)
+	})
+}
+
 // Setup starts a dedicated metrics server at the given address.
 // This function enables metrics reporting separate from pprof.
 func Setup(address string) {
 	m := http.NewServeMux()
 	m.Handle("/debug/metrics", ExpHandler(metrics.DefaultRegistry))
 	m.Handle("/debug/metrics/prometheus", prometheus.Handler(metrics.DefaultRegistry))
+	m.Handle("/debug/health", HealthHandler(metrics.DefaultRegistry))
 	log.Info("Starting metrics server", "addr", fmt.Sprintf("http://%s/debug/metrics", address))
 	go func() {
 		if err := http.ListenAndServe(address, m); err != nil {
@@ -0,0 +1,76 @@
+package exp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestHealthHandler(t *testing.T) {
+	var (
+		registry = metrics.NewRegistry()
+		failing  error
+	)
+	registry.Register("chain", metrics.NewHealthcheck(func(h *metrics.Healthcheck) { h.Healthy() }))
+	registry.Register("indexer", metrics.NewHealthcheck(func(h *metrics.Healthcheck) {
+		if failing != nil {
+			h.Unhealthy(failing)
+		} else {
+			h.Healthy()
+		}
+	}))
+	registry.Register("blocks", metrics.NewCounter()) // not a healthcheck
+
+	server := httptest.NewServer(HealthHandler(registry))
+	defer server.Close()
+
+	check := func(status int, want map[string]*string) {
+		t.Helper()
+
+		res, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != status {
+			t.Errorf("status mismatch: have %d, want %d", res.StatusCode, status)
+		}
+		if ctype := res.Header.Get("Content-Type"); ctype != "application/json; charset=utf-8" {
+			t.Errorf("content type mismatch: have %q", ctype)
+		}
+		var have map[string]*string
+		if err := json.NewDecoder(res.Body).Decode(&have); err != nil {
+			t.Fatalf("invalid response body: %v", err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("checks mismatch: have %v, want %v", have, want)
+		}
+	}
+	check(http.StatusOK, map[string]*string{"chain": nil, "indexer": nil})
+
+	failing = errors.New("indexer lagging behind")
+	msg := failing.Error()
+	check(http.StatusServiceUnavailable, map[string]*string{"chain": nil, "indexer": &msg})
+
+	// The checks run on every request, so recovery is reported at once
+	failing = nil
+	check(http.StatusOK, map[string]*string{"chain": nil, "indexer": nil})
+}
+
+func TestHealthHandlerEmpty(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HealthHandler(metrics.NewRegistry()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/health", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status mismatch: have %d, want %d", rec.Code, http.StatusOK)
+	}
+	if body := rec.Body.String(); body != "{}\n" {
+		t.Errorf("body mismatch: have %q, want %q", body, "{}\n")
+	}
+}
@@ -557,6 +557,14 @@ func (n *Node) RegisterLifecycle(lifecycle Lifecycle) {
 	n.lifecycles = append(n.lifecycles, lifecycle)
 }
 
+// Lifecycles returns the lifecycles registered on the node so far.
+func (n *Node) Lifecycles() []Lifecycle {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return slices.Clone(n.lifecycles)
+}
+
 // RegisterProtocols adds backend's protocols to the node's p2p server.
 func (n *Node) RegisterProtocols(protocols []p2p.Protocol) {
 	n.lock.Lock()
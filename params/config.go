@@ -329,6 +329,20 @@ type ChainConfig struct {
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
+
+	// Precompiles schedules native contracts on top of the standard ones, in
+	// ascending order of activation
+	Precompiles []PrecompileUpgrade `json:"precompiles,omitempty"`
+}
+
+// PrecompileUpgrade installs the native contract registered under a name at an
+// address, from the first block with a timestamp at or after Time on. It
+// overrides any standard precompile at the address. An empty name removes the
+// contract at the address again.
+type PrecompileUpgrade struct {
+	Address  common.Address `json:"address"`
+	Time     uint64         `json:"time"`
+	Contract string         `json:"contract,omitempty"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -620,6 +634,22 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	// Precompile upgrades must be ordered and not collide
+	for i := 1; i < len(c.Precompiles); i++ {
+		prev, cur := c.Precompiles[i-1], c.Precompiles[i]
+		if prev.Time > cur.Time {
+			return fmt.Errorf("unsupported precompile ordering: %v upgraded at timestamp %v, but %v upgraded at timestamp %v",
+				prev.Address, prev.Time, cur.Address, cur.Time)
+		}
+	}
+	seen := make(map[PrecompileUpgrade]bool)
+	for _, upgrade := range c.Precompiles {
+		key := PrecompileUpgrade{Address: upgrade.Address, Time: upgrade.Time}
+		if seen[key] {
+			return fmt.Errorf("duplicate precompile upgrade: %v upgraded twice at timestamp %v", upgrade.Address, upgrade.Time)
+		}
+		seen[key] = true
+	}
 	return nil
 }
 
@@ -691,9 +721,31 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int,
 	if isForkTimestampIncompatible(c.VerkleTime, newcfg.VerkleTime, headTimestamp) {
 		return newTimestampCompatError("Verkle fork timestamp", c.VerkleTime, newcfg.VerkleTime)
 	}
+	if storedtime, newtime := precompileUpgradeMismatch(c.Precompiles, newcfg.Precompiles); isTimestampForked(storedtime, headTimestamp) || isTimestampForked(newtime, headTimestamp) {
+		return newTimestampCompatError("precompile upgrade timestamp", storedtime, newtime)
+	}
 	return nil
 }
 
+// precompileUpgradeMismatch returns the activation times of the first upgrades
+// differing between two precompile schedules, nil for a missing upgrade or if
+// the schedules are equal.
+func precompileUpgradeMismatch(stored, updated []PrecompileUpgrade) (*uint64, *uint64) {
+	for i := 0; i < len(stored) || i < len(updated); i++ {
+		var storedtime, newtime *uint64
+		if i < len(stored) {
+			storedtime = &stored[i].Time
+		}
+		if i < len(updated) {
+			newtime = &updated[i].Time
+		}
+		if storedtime == nil || newtime == nil || stored[i] != updated[i] {
+			return storedtime, newtime
+		}
+	}
+	return nil, nil
+}
+
 // BaseFeeChangeDenominator bounds the amount the base fee can change between blocks.
 func (c *ChainConfig) BaseFeeChangeDenominator() uint64 {
 	return DefaultBaseFeeChangeDenominator
@@ -857,6 +909,8 @@ func (err *ConfigCompatError) Error() string {
 // phases.
 type Rules struct {
 	ChainID                                                 *big.Int
+	Time                                                    uint64              // block timestamp the rules apply to
+	Precompiles                                             []PrecompileUpgrade // precompile schedule of the chain
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158               bool
 	IsEIP2929, IsEIP4762                                    bool
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul bool
@@ -876,6 +930,8 @@ func (c *ChainConfig) Rules(num *big.Int, isMerge bool, timestamp uint64) Rules
 	isVerkle := isMerge && c.IsVerkle(num, timestamp)
 	return Rules{
 		ChainID:          new(big.Int).Set(chainID),
+		Time:             timestamp,
+		Precompiles:      c.Precompiles,
 		IsHomestead:      c.IsHomestead(num),
 		IsEIP150:         c.IsEIP150(num),
 		IsEIP155:         c.IsEIP155(num),
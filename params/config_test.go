@@ -23,6 +23,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/require"
 )
 
@@ -110,6 +111,23 @@ func TestCheckCompatible(t *testing.T) {
 				RewindToTime: 9,
 			},
 		},
+		{
+			stored:        &ChainConfig{Precompiles: []PrecompileUpgrade{{Address: common.Address{1}, Time: 10, Contract: "a"}}},
+			new:           &ChainConfig{Precompiles: []PrecompileUpgrade{{Address: common.Address{1}, Time: 10, Contract: "a"}, {Address: common.Address{2}, Time: 20, Contract: "b"}}},
+			headTimestamp: 15,
+			wantErr:       nil,
+		},
+		{
+			stored:        &ChainConfig{Precompiles: []PrecompileUpgrade{{Address: common.Address{1}, Time: 10, Contract: "a"}}},
+			new:           &ChainConfig{Precompiles: []PrecompileUpgrade{{Address: common.Address{1}, Time: 10, Contract: "b"}}},
+			headTimestamp: 15,
+			wantErr: &ConfigCompatError{
+				What:         "precompile upgrade timestamp",
+				StoredTime:   newUint64(10),
+				NewTime:      newUint64(10),
+				RewindToTime: 9,
+			},
+		},
 	}
 
 	for _, test := range tests {